@@ -53,6 +53,13 @@ type SerialPeripheral interface {
 	Shutdown()
 }
 
+// PendingPeripheral is implemented by peripherals which can report, without
+// blocking, that data is waiting to be read. The status register then reports
+// the receive data register as full.
+type PendingPeripheral interface {
+	Pending() bool
+}
+
 func NewAcia6551(o Options) *Acia6551 {
 	acia := &Acia6551{
 		peripheral: o.Peripheral,
@@ -83,6 +90,46 @@ func (a *Acia6551) Shutdown() {
 	}
 }
 
+// Snapshot returns the register state, meeting the memory.Snapshotable
+// interface. The peripheral is not included.
+func (a *Acia6551) Snapshot() ([]byte, error) {
+	flags := byte(0)
+	for i, f := range []bool{a.rxFull, a.txEmpty, a.rxIrqEnabled, a.txIrqEnabled, a.overrun} {
+		if f {
+			flags |= 1 << uint(i)
+		}
+	}
+	return []byte{a.rx, a.tx, a.commandData, a.controlData, flags}, nil
+}
+
+// Restore the register state from a snapshot.
+func (a *Acia6551) Restore(data []byte) error {
+	if len(data) != 5 {
+		return fmt.Errorf("Invalid %s snapshot of %d bytes", a, len(data))
+	}
+	a.rx, a.tx, a.commandData, a.controlData = data[0], data[1], data[2], data[3]
+	for i, f := range []*bool{&a.rxFull, &a.txEmpty, &a.rxIrqEnabled, &a.txIrqEnabled, &a.overrun} {
+		*f = data[4]&(1<<uint(i)) != 0
+	}
+	return nil
+}
+
+// DebugState describes the registers and flags, meeting the
+// memory.DebugState interface. Unlike reading the status register it does
+// not poll the peripheral.
+func (a *Acia6551) DebugState() string {
+	return fmt.Sprintf("RX $%02X full %v overrun %v\n"+
+		"TX $%02X empty %v\n"+
+		"Command $%02X  Control $%02X\n"+
+		"IRQ enabled rx %v tx %v\n"+
+		"Peripheral %v\n",
+		a.rx, a.rxFull, a.overrun,
+		a.tx, a.txEmpty,
+		a.commandData, a.controlData,
+		a.rxIrqEnabled, a.txIrqEnabled,
+		a.peripheral)
+}
+
 // Emulates a hardware reset
 func (a *Acia6551) Reset() {
 	a.rx = 0
@@ -112,6 +159,18 @@ func (a *Acia6551) setCommand(data byte) {
 }
 
 func (a *Acia6551) statusRegister() byte {
+	if p, ok := a.peripheral.(PendingPeripheral); ok && !a.rxFull && p.Pending() {
+		read, data, err := a.peripheral.Read()
+		if err == nil && read {
+			a.rx = data
+			a.rxFull = true
+		}
+	}
+	return a.status()
+}
+
+// status returns the status register from the latched flags.
+func (a *Acia6551) status() byte {
 	status := byte(0)
 
 	if a.rxFull {
@@ -144,6 +203,22 @@ func (a *Acia6551) Read(address uint16) byte {
 	}
 }
 
+// Peek returns the latched value of a register without reading from the
+// peripheral or clearing the receive flags, as reading the data register would.
+func (a *Acia6551) Peek(address uint16) (byte, bool) {
+	switch address {
+	case aciaData:
+		return a.rx, true
+	case aciaStatus:
+		return a.status(), true
+	case aciaCommand:
+		return a.commandData, true
+	case aciaControl:
+		return a.controlData, true
+	}
+	return 0, false
+}
+
 func (a *Acia6551) Write(address uint16, data byte) {
 	switch address {
 	case aciaData:
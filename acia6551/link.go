@@ -0,0 +1,50 @@
+package acia6551
+
+// linkBuffer is how many bytes a link holds before further writes are lost,
+// as with a real serial line when the receiver does not keep up.
+const linkBuffer = 256
+
+// LinkPort is a SerialPeripheral at one end of a serial link between two
+// 6551's, usually in different machines. Neither end blocks, so the machines
+// can run at their own speed.
+type LinkPort struct {
+	in  chan byte
+	out chan byte
+}
+
+// NewLink returns the two ends of a serial link. Bytes written to one end are
+// read from the other.
+func NewLink() (*LinkPort, *LinkPort) {
+	a, b := make(chan byte, linkBuffer), make(chan byte, linkBuffer)
+	return &LinkPort{in: a, out: b}, &LinkPort{in: b, out: a}
+}
+
+func (l *LinkPort) Capabilities() int {
+	return BiDirectional
+}
+
+// Pending reports whether the other end has written data not yet read.
+func (l *LinkPort) Pending() bool {
+	return len(l.in) > 0
+}
+
+func (l *LinkPort) Read() (bool, byte, error) {
+	select {
+	case b := <-l.in:
+		return true, b, nil
+	default:
+		return false, 0, nil
+	}
+}
+
+func (l *LinkPort) Write(b byte) (bool, error) {
+	select {
+	case l.out <- b:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (l *LinkPort) Shutdown() {
+}
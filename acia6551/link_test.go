@@ -0,0 +1,60 @@
+package acia6551
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	a, b := NewLink()
+
+	a.Write('A')
+	b.Write('B')
+	if !a.Pending() || !b.Pending() {
+		t.Fatal("expected data pending at both ends")
+	}
+	if _, r, _ := b.Read(); r != 'A' {
+		t.Error(fmt.Sprintf("expected A got %c", r))
+	}
+	if _, r, _ := a.Read(); r != 'B' {
+		t.Error(fmt.Sprintf("expected B got %c", r))
+	}
+	if read, _, _ := a.Read(); read || a.Pending() {
+		t.Error("expected nothing left to read")
+	}
+
+	// Writes are lost rather than block once the other end stops reading
+	for i := 0; i < linkBuffer; i++ {
+		a.Write(byte(i))
+	}
+	if written, _ := a.Write(0); written {
+		t.Error("expected a write to a full link to be lost")
+	}
+}
+
+func TestPeekLeavesDataUnread(t *testing.T) {
+	a, b := NewLink()
+	acia := NewAcia6551(Options{Peripheral: a})
+
+	b.Write('X')
+	if status, _ := acia.Peek(aciaStatus); status&0x08 != 0 || !a.Pending() {
+		t.Error("expected peeking the status not to poll the link")
+	}
+	if status := acia.Read(aciaStatus); status&0x08 == 0 {
+		t.Fatal("expected a byte received")
+	}
+	for i := 0; i < 2; i++ {
+		if v, ok := acia.Peek(aciaData); !ok || v != 'X' {
+			t.Error(fmt.Sprintf("expected X got %c", v))
+		}
+	}
+	if status, _ := acia.Peek(aciaStatus); status&0x08 == 0 {
+		t.Error("expected peeking the data to leave it unread")
+	}
+	if v := acia.Read(aciaData); v != 'X' {
+		t.Error(fmt.Sprintf("expected X got %c", v))
+	}
+	if status, _ := acia.Peek(aciaStatus); status&0x08 != 0 {
+		t.Error("expected reading the data to empty the receiver")
+	}
+}
@@ -0,0 +1,120 @@
+package acia6551
+
+// InputEvent is a byte received by the guest, and the cpu cycle count when
+// it was read.
+type InputEvent struct {
+	Cycle uint64
+	Data  byte
+}
+
+// Recorder is a SerialPeripheral which passes each byte the guest reads from
+// the wrapped peripheral to record, so the session can be replayed.
+type Recorder struct {
+	peripheral SerialPeripheral
+	record     func(b byte)
+}
+
+// NewRecorder wraps a peripheral, which may be nil.
+func NewRecorder(peripheral SerialPeripheral, record func(b byte)) *Recorder {
+	return &Recorder{peripheral: peripheral, record: record}
+}
+
+func (r *Recorder) Capabilities() int {
+	if r.peripheral == nil {
+		return Nop
+	}
+	return r.peripheral.Capabilities()
+}
+
+// Pending passes on whether the wrapped peripheral has data waiting.
+func (r *Recorder) Pending() bool {
+	if p, ok := r.peripheral.(PendingPeripheral); ok {
+		return p.Pending()
+	}
+	return false
+}
+
+func (r *Recorder) Read() (bool, byte, error) {
+	if r.peripheral == nil {
+		return false, 0, nil
+	}
+	read, b, err := r.peripheral.Read()
+	if read && err == nil {
+		r.record(b)
+	}
+	return read, b, err
+}
+
+func (r *Recorder) Write(b byte) (bool, error) {
+	if r.peripheral == nil {
+		return true, nil
+	}
+	return r.peripheral.Write(b)
+}
+
+func (r *Recorder) Shutdown() {
+	if r.peripheral != nil {
+		r.peripheral.Shutdown()
+	}
+}
+
+// Replay is a SerialPeripheral which feeds recorded input to the guest, each
+// byte once the cpu reaches the cycle it was read at, so the guest sees the
+// same input at the same point as when it was recorded. Output is passed on
+// to the wrapped peripheral, and once the recording has been replayed input
+// is also read from it.
+type Replay struct {
+	peripheral SerialPeripheral
+	events     []InputEvent
+	clock      func() uint64
+}
+
+// NewReplay creates a Replay wrapping a peripheral, which may be nil.
+// clock returns the current cpu cycle count.
+func NewReplay(peripheral SerialPeripheral, events []InputEvent, clock func() uint64) *Replay {
+	return &Replay{peripheral: peripheral, events: events, clock: clock}
+}
+
+func (r *Replay) Capabilities() int {
+	return BiDirectional
+}
+
+// Pending returns true once the next recorded byte is due.
+func (r *Replay) Pending() bool {
+	if len(r.events) > 0 {
+		return r.clock() >= r.events[0].Cycle
+	}
+	if p, ok := r.peripheral.(PendingPeripheral); ok {
+		return p.Pending()
+	}
+	return false
+}
+
+func (r *Replay) Read() (bool, byte, error) {
+	if len(r.events) > 0 {
+		if r.clock() < r.events[0].Cycle {
+			return false, 0, nil
+		}
+		b := r.events[0].Data
+		r.events = r.events[1:]
+		return true, b, nil
+	}
+
+	if r.peripheral == nil || r.peripheral.Capabilities()&Read == 0 {
+		return false, 0, nil
+	}
+	return r.peripheral.Read()
+}
+
+func (r *Replay) Write(b byte) (bool, error) {
+	if r.peripheral == nil || r.peripheral.Capabilities()&Write == 0 {
+		return true, nil
+	}
+	return r.peripheral.Write(b)
+}
+
+func (r *Replay) Shutdown() {
+	if r.peripheral != nil {
+		r.peripheral.Shutdown()
+	}
+}
@@ -0,0 +1,37 @@
+package acia6551
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var cycles uint64
+	clock := func() uint64 { return cycles }
+
+	var recorded []InputEvent
+	recorder := NewRecorder(NewScript(nil, []ScriptStep{
+		{AfterCycles: 100, Type: "hi"},
+	}, clock), func(b byte) {
+		recorded = append(recorded, InputEvent{Cycle: cycles, Data: b})
+	})
+	for cycles = 0; cycles < 200; cycles += 50 {
+		recorder.Read()
+	}
+	expected := "[{100 104} {150 105}]"
+	if s := fmt.Sprint(recorded); s != expected {
+		t.Fatal(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+
+	replay := NewReplay(nil, recorded, clock)
+	var replayed []InputEvent
+	for cycles = 0; cycles < 200; cycles += 25 {
+		if replay.Pending() {
+			_, b, _ := replay.Read()
+			replayed = append(replayed, InputEvent{Cycle: cycles, Data: b})
+		}
+	}
+	if s := fmt.Sprint(replayed); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
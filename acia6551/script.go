@@ -0,0 +1,111 @@
+package acia6551
+
+import (
+	"strings"
+)
+
+// ScriptStep is a step in an input script. The step triggers once both
+// conditions are met, then its text is typed.
+type ScriptStep struct {
+	// AfterCycles is the number of cpu cycles to wait after the previous step
+	// completed, or after power on for the first step.
+	AfterCycles uint64 `yaml:"afterCycles"`
+
+	// AfterOutput is text to wait for the guest to output, e.g. a prompt.
+	AfterOutput string `yaml:"afterOutput"`
+
+	// Type is the text to type once triggered.
+	Type string `yaml:"type"`
+}
+
+// Script is a SerialPeripheral which types scripted input into the guest,
+// enabling automated demos and reproducible interactive sessions.
+// Output is passed on to the wrapped peripheral. Once the script has
+// completed, input is also read from the wrapped peripheral.
+type Script struct {
+	peripheral SerialPeripheral
+	steps      []ScriptStep
+	clock      func() uint64
+	since      uint64 // cycle count when the previous step completed
+	pending    []byte // text being typed
+	output     string // output seen since the previous step completed
+}
+
+// NewScript creates a Script wrapping a peripheral, which may be nil.
+// clock returns the current cpu cycle count.
+func NewScript(peripheral SerialPeripheral, steps []ScriptStep, clock func() uint64) *Script {
+	return &Script{
+		peripheral: peripheral,
+		steps:      steps,
+		clock:      clock,
+	}
+}
+
+func (s *Script) Capabilities() int {
+	return BiDirectional
+}
+
+// running returns true while the script has steps left or text to type.
+func (s *Script) running() bool {
+	return len(s.steps) > 0 || len(s.pending) > 0
+}
+
+// trigger starts typing the next step if its conditions are met.
+func (s *Script) trigger() {
+	if len(s.pending) > 0 || len(s.steps) == 0 {
+		return
+	}
+
+	step := s.steps[0]
+	if s.clock()-s.since < step.AfterCycles {
+		return
+	}
+	if step.AfterOutput != "" && !strings.Contains(s.output, step.AfterOutput) {
+		return
+	}
+
+	s.steps = s.steps[1:]
+	s.pending = []byte(step.Type)
+	s.since = s.clock()
+	s.output = ""
+}
+
+// Pending returns true if scripted input is ready to be read.
+func (s *Script) Pending() bool {
+	s.trigger()
+	return len(s.pending) > 0
+}
+
+func (s *Script) Read() (bool, byte, error) {
+	if s.Pending() {
+		b := s.pending[0]
+		s.pending = s.pending[1:]
+		return true, b, nil
+	}
+
+	if s.running() || s.peripheral == nil || s.peripheral.Capabilities()&Read == 0 {
+		return false, 0, nil
+	}
+	return s.peripheral.Read()
+}
+
+func (s *Script) Write(b byte) (bool, error) {
+	if len(s.steps) > 0 && s.steps[0].AfterOutput != "" {
+		s.output += string(b)
+		// Only the tail is needed to match the next step
+		if max := 2 * len(s.steps[0].AfterOutput); len(s.output) > max {
+			s.output = s.output[len(s.output)-max:]
+		}
+	}
+
+	if s.peripheral == nil || s.peripheral.Capabilities()&Write == 0 {
+		return true, nil
+	}
+	return s.peripheral.Write(b)
+}
+
+func (s *Script) Shutdown() {
+	if s.peripheral != nil {
+		s.peripheral.Shutdown()
+	}
+}
@@ -0,0 +1,36 @@
+package acia6551
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestScriptTriggers(t *testing.T) {
+	var cycles uint64
+	script := NewScript(nil, []ScriptStep{
+		{AfterCycles: 1000, Type: "A"},
+		{AfterOutput: "READY", Type: "B"},
+	}, func() uint64 { return cycles })
+
+	if script.Pending() {
+		t.Error("script typed before 1000 cycles")
+	}
+
+	cycles = 1000
+	if read, b, _ := script.Read(); !read || b != 'A' {
+		t.Error(fmt.Sprintf("expected A got %v %q", read, b))
+	}
+
+	for _, b := range []byte("NOT READ") {
+		script.Write(b)
+	}
+	if script.Pending() {
+		t.Error("script typed before prompt")
+	}
+	for _, b := range []byte("Y\r") {
+		script.Write(b)
+	}
+	if read, b, _ := script.Read(); !read || b != 'B' {
+		t.Error(fmt.Sprintf("expected B got %v %q", read, b))
+	}
+}
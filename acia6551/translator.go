@@ -0,0 +1,71 @@
+package acia6551
+
+import (
+	"unicode/utf8"
+
+	"github.com/peter-mount/go6502/charset"
+)
+
+// Translator is a SerialPeripheral which translates between the guest
+// character set and UTF-8 on the wrapped peripheral.
+type Translator struct {
+	peripheral SerialPeripheral
+	charset    *charset.Charset
+	input      []byte // partial UTF-8 sequence read from the peripheral
+}
+
+// NewTranslator wraps a peripheral, translating using the given charset.
+func NewTranslator(peripheral SerialPeripheral, cs *charset.Charset) *Translator {
+	return &Translator{peripheral: peripheral, charset: cs}
+}
+
+func (t *Translator) Capabilities() int {
+	return t.peripheral.Capabilities()
+}
+
+// Pending passes on whether the wrapped peripheral has data waiting.
+func (t *Translator) Pending() bool {
+	if p, ok := t.peripheral.(PendingPeripheral); ok {
+		return p.Pending()
+	}
+	return false
+}
+
+// Read reads a character from the peripheral, returning its guest code.
+// Characters with no guest equivalent are dropped.
+func (t *Translator) Read() (bool, byte, error) {
+	for {
+		read, b, err := t.peripheral.Read()
+		if err != nil || !read {
+			return read, b, err
+		}
+
+		t.input = append(t.input, b)
+		if !utf8.FullRune(t.input) {
+			continue
+		}
+
+		r, _ := utf8.DecodeRune(t.input)
+		t.input = t.input[:0]
+		if g, ok := t.charset.FromHost(r); ok {
+			return true, g, nil
+		}
+		return false, 0, nil
+	}
+}
+
+// Write translates a guest byte, writing it to the peripheral as UTF-8.
+func (t *Translator) Write(b byte) (bool, error) {
+	buf := make([]byte, utf8.UTFMax)
+	n := utf8.EncodeRune(buf, t.charset.ToHost(b))
+	for _, c := range buf[:n] {
+		if written, err := t.peripheral.Write(c); !written || err != nil {
+			return written, err
+		}
+	}
+	return true, nil
+}
+
+func (t *Translator) Shutdown() {
+	t.peripheral.Shutdown()
+}
@@ -0,0 +1,211 @@
+/*
+Package asm is a mini assembler for single 6502 instructions, e.g. for
+patching memory from the debugger.
+
+The usual syntax is accepted for each addressing mode:
+
+	NOP              implied
+	ASL or ASL A     accumulator
+	LDA #$10         immediate
+	LDA $10          zeropage, or absolute for addresses above $FF
+	LDA $1234,X      indexed by X or Y
+	JMP ($1234)      indirect
+	LDA ($10,X)      indexed indirect
+	LDA ($10),Y      indirect indexed
+	BNE $1234        relative, given the branch target
+
+Operands are numbers in hex ($12 or 0x12), binary (%1010) or decimal,
+unless an Assembler with a Resolve function is used.
+*/
+package asm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// Assembler assembles instructions, resolving operands with Resolve.
+type Assembler struct {
+	// Resolve evaluates an operand, e.g. a symbol or expression. If nil
+	// only numbers are accepted.
+	Resolve func(operand string) (int, error)
+}
+
+// Assemble assembles a single instruction at pc using only numeric operands.
+func Assemble(pc uint16, line string) ([]byte, error) {
+	return (&Assembler{}).Assemble(pc, line)
+}
+
+// Assemble assembles a single instruction at pc, returning its bytes.
+func (a *Assembler) Assemble(pc uint16, line string) ([]byte, error) {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+
+	fields := strings.SplitN(line, " ", 2)
+	mnemonic := strings.ToUpper(fields[0])
+	operand := ""
+	if len(fields) > 1 {
+		operand = strings.ReplaceAll(strings.TrimSpace(fields[1]), " ", "")
+	}
+
+	if mnemonic == "" {
+		return nil, fmt.Errorf("Missing instruction")
+	}
+	if !known(mnemonic) {
+		return nil, fmt.Errorf("Unknown instruction %s", mnemonic)
+	}
+
+	// Instructions without an operand
+	if operand == "" || strings.EqualFold(operand, "A") {
+		for _, mode := range []string{"implied", "accumulator"} {
+			if ot, ok := cpu.LookupOpType(mnemonic, mode); ok {
+				return []byte{ot.Opcode}, nil
+			}
+		}
+		if operand == "" {
+			return nil, fmt.Errorf("%s requires an operand", mnemonic)
+		}
+	}
+
+	if strings.HasPrefix(operand, "#") {
+		v, err := a.value(operand[1:], 0xFF)
+		if err != nil {
+			return nil, err
+		}
+		return a.encode(mnemonic, []string{"immediate"}, v)
+	}
+
+	if _, ok := cpu.LookupOpType(mnemonic, "relative"); ok {
+		target, err := a.value(operand, 0xFFFF)
+		if err != nil {
+			return nil, err
+		}
+		offset := target - int(pc) - 2
+		if offset < -128 || offset > 127 {
+			return nil, fmt.Errorf("Branch to $%04X out of range", target)
+		}
+		return a.encode(mnemonic, []string{"relative"}, offset&0xFF)
+	}
+
+	upper := strings.ToUpper(operand)
+	switch {
+	case strings.HasPrefix(upper, "(") && strings.HasSuffix(upper, ",X)"):
+		return a.operand(mnemonic, operand[1:len(operand)-3], "(indirect,X)")
+	case strings.HasPrefix(upper, "(") && strings.HasSuffix(upper, "),Y"):
+		return a.operand(mnemonic, operand[1:len(operand)-3], "(indirect),Y")
+	case strings.HasPrefix(upper, "(") && strings.HasSuffix(upper, ")"):
+		return a.operand(mnemonic, operand[1:len(operand)-1], "(indirect)")
+	case strings.HasSuffix(upper, ",X"):
+		return a.operand(mnemonic, operand[:len(operand)-2], "zeropageX", "absoluteX")
+	case strings.HasSuffix(upper, ",Y"):
+		return a.operand(mnemonic, operand[:len(operand)-2], "zeropageY", "absoluteY")
+	default:
+		return a.operand(mnemonic, operand, "zeropage", "absolute")
+	}
+}
+
+// addressingModes are the names of the addressing modes.
+var addressingModes = []string{
+	"implied", "accumulator", "immediate", "relative",
+	"zeropage", "zeropageX", "zeropageY", "absolute", "absoluteX", "absoluteY",
+	"(indirect)", "(indirect,X)", "(indirect),Y",
+}
+
+// known returns true if mnemonic is an instruction.
+func known(mnemonic string) bool {
+	for _, mode := range addressingModes {
+		if _, ok := cpu.LookupOpType(mnemonic, mode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// operand assembles an instruction with an address operand, in the first of
+// the modes it fits.
+func (a *Assembler) operand(mnemonic, operand string, modes ...string) ([]byte, error) {
+	v, err := a.value(operand, 0xFFFF)
+	if err != nil {
+		return nil, err
+	}
+
+	// Zero page modes only fit addresses in the zero page
+	var fits []string
+	for _, mode := range modes {
+		if v <= 0xFF || !strings.HasPrefix(mode, "zeropage") && mode != "(indirect,X)" && mode != "(indirect),Y" {
+			fits = append(fits, mode)
+		}
+	}
+	if len(fits) == 0 {
+		return nil, fmt.Errorf("$%04X is not in the zero page", v)
+	}
+	return a.encode(mnemonic, fits, v)
+}
+
+// encode returns the bytes for an instruction in the first mode it supports.
+func (a *Assembler) encode(mnemonic string, modes []string, v int) ([]byte, error) {
+	for _, mode := range modes {
+		ot, ok := cpu.LookupOpType(mnemonic, mode)
+		if !ok {
+			continue
+		}
+		switch ot.Bytes {
+		case 2:
+			return []byte{ot.Opcode, byte(v)}, nil
+		case 3:
+			return []byte{ot.Opcode, byte(v), byte(v >> 8)}, nil
+		default:
+			return []byte{ot.Opcode}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s does not support %s addressing", mnemonic, modes[len(modes)-1])
+}
+
+// value evaluates an operand, checking it fits in max.
+func (a *Assembler) value(s string, max int) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("Missing operand")
+	}
+
+	var (
+		v   int
+		err error
+	)
+	if a.Resolve != nil {
+		v, err = a.Resolve(s)
+	} else {
+		v, err = parseNumber(s)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 || v > max {
+		return 0, fmt.Errorf("Operand %s out of range", s)
+	}
+	return v, nil
+}
+
+// parseNumber parses a number in hex, binary or decimal.
+func parseNumber(s string) (int, error) {
+	var (
+		v   uint64
+		err error
+	)
+	switch {
+	case strings.HasPrefix(s, "$"):
+		v, err = strconv.ParseUint(s[1:], 16, 16)
+	case strings.HasPrefix(s, "%"):
+		v, err = strconv.ParseUint(s[1:], 2, 16)
+	default:
+		v, err = strconv.ParseUint(s, 0, 16)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Invalid operand %q", s)
+	}
+	return int(v), nil
+}
@@ -0,0 +1,42 @@
+package asm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	for line, expected := range map[string]string{
+		"NOP":          "[EA]",
+		"asl a":        "[0A]",
+		"LSR":          "[4A]",
+		"LDA #$10":     "[A9 10]",
+		"LDA $10":      "[A5 10]",
+		"LDA $1234":    "[AD 34 12]",
+		"STA $0200,X":  "[9D 00 02]",
+		"LDX $10,Y":    "[B6 10]",
+		"LDA $1234,Y":  "[B9 34 12]",
+		"JMP ($FFFC)":  "[6C FC FF]",
+		"LDA ($10,X)":  "[A1 10]",
+		"STA ($FE),Y":  "[91 FE]",
+		"BNE $1000":    "[D0 FE]",
+		"BEQ $1010":    "[F0 0E]",
+		"JSR %1111":    "[20 0F 00]",
+		"LDY #10 ; ok": "[A0 0A]",
+	} {
+		code, err := Assemble(0x1000, line)
+		if err != nil {
+			t.Error(fmt.Sprintf("%s: %v", line, err))
+			continue
+		}
+		if actual := fmt.Sprintf("% X", code); "["+actual+"]" != expected {
+			t.Error(fmt.Sprintf("%s expected %s got [%s]", line, expected, actual))
+		}
+	}
+
+	for _, line := range []string{"", "FOO", "LDA", "LDA #$100", "BNE $2000", "STA #1", "LDA ($1234),Y"} {
+		if _, err := Assemble(0x1000, line); err == nil {
+			t.Error(fmt.Sprintf("expected %q to fail", line))
+		}
+	}
+}
@@ -1,14 +1,59 @@
 package main
 
 import (
+	"errors"
 	"github.com/peter-mount/go6502/machine"
+	"github.com/peter-mount/go6502/romedit"
 	"github.com/peter-mount/golib/kernel"
 	"log"
+	"os"
 )
 
 func main() {
-	err := kernel.Launch(&machine.Machine{})
+	if len(os.Args) > 1 && os.Args[1] == "romedit" {
+		if err := romedit.Main(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "init" {
+		if err := writeTemplate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err := kernel.Launch(&machine.Machine{}, &machine.HttpApi{})
+	var status machine.ExitStatus
+	if errors.As(err, &status) {
+		os.Exit(int(status))
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// writeTemplate writes a commented example config to the named file, by
+// default config.yaml, unless it already exists.
+func writeTemplate(args []string) error {
+	file := "config.yaml"
+	if len(args) > 0 {
+		file = args[0]
+	}
+
+	template, err := machine.Template()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(template); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
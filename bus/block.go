@@ -0,0 +1,154 @@
+package bus
+
+import (
+	"fmt"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+// ReadBlock reads n bytes starting at address a, for DMA devices. A block
+// spanning several devices is split between them, and devices implementing
+// memory.BlockMemory are accessed as a single copy. Watches and tracing are
+// bypassed, but reads may have side effects on devices, so the debugger uses
+// PeekBlock.
+func (b *Bus) ReadBlock(a uint16, n int) ([]byte, error) {
+	data := make([]byte, n)
+	err := b.block(a, n, func(mem memory.Memory, start uint16, offset, length int) {
+		buf := data[offset : offset+length]
+		if bm, ok := mem.(memory.BlockMemory); ok {
+			bm.ReadBlock(start, buf)
+			return
+		}
+		for i := range buf {
+			buf[i] = mem.Read(start + uint16(i))
+		}
+	})
+	return data, err
+}
+
+// Peek reads the byte at address a without side effects, e.g. for the
+// debugger. Returns false if the address is unmapped or its device does not
+// implement memory.Peeker.
+func (b *Bus) Peek(a uint16) (byte, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	be, err := b.backendFor(a)
+	if err != nil {
+		return 0, false
+	}
+	return peek(be.mem, a)
+}
+
+// PeekBlock reads n bytes starting at address a as for Peek, failing if any
+// of them can't be read without side effects.
+func (b *Bus) PeekBlock(a uint16, n int) ([]byte, error) {
+	if n < 0 || int(a)+n > 0x10000 {
+		return nil, fmt.Errorf("Invalid block of %d bytes at 0x%04X", n, a)
+	}
+	data := make([]byte, n)
+	for i := range data {
+		v, ok := b.Peek(a + uint16(i))
+		if !ok {
+			return nil, fmt.Errorf("Cannot read 0x%04X without side effects", a+uint16(i))
+		}
+		data[i] = v
+	}
+	return data, nil
+}
+
+// WriteBlock writes data starting at address a, split between devices as for
+// ReadBlock.
+func (b *Bus) WriteBlock(a uint16, data []byte) error {
+	return b.block(a, len(data), func(mem memory.Memory, start uint16, offset, length int) {
+		buf := data[offset : offset+length]
+		if bm, ok := mem.(memory.BlockMemory); ok {
+			bm.WriteBlock(start, buf)
+			return
+		}
+		for i, v := range buf {
+			mem.Write(start+uint16(i), v)
+		}
+	})
+}
+
+// block calls fn for each part of the block mapped to a single device, with
+// the device, the address within it, and the offset and length in the block.
+func (b *Bus) block(a uint16, n int, fn func(mem memory.Memory, start uint16, offset, length int)) error {
+	if n < 0 || int(a)+n > 0x10000 {
+		return fmt.Errorf("Invalid block of %d bytes at 0x%04X", n, a)
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for offset := 0; offset < n; {
+		address := a + uint16(offset)
+		be, err := b.backendFor(address)
+		if err != nil {
+			return err
+		}
+
+		length := int(be.end) - int(address) + 1
+		if length > n-offset {
+			length = n - offset
+		}
+
+		// Only unmirrored devices can be accessed directly
+		if om, ok := be.mem.(OffsetMemory); ok {
+			fn(om.Memory, address-om.Offset, offset, length)
+		} else {
+			fn(be.mem, address, offset, length)
+		}
+		offset += length
+	}
+	return nil
+}
+
+// Fill writes value to n bytes starting at address a.
+func (b *Bus) Fill(a uint16, n int, value byte) error {
+	if n < 0 {
+		return fmt.Errorf("Invalid block of %d bytes at 0x%04X", n, a)
+	}
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = value
+	}
+	return b.WriteBlock(a, data)
+}
+
+// Copy copies n bytes from src to dst. Overlapping blocks are copied as if
+// via an intermediate buffer.
+func (b *Bus) Copy(src, dst uint16, n int) error {
+	data, err := b.ReadBlock(src, n)
+	if err != nil {
+		return err
+	}
+	return b.WriteBlock(dst, data)
+}
+
+// Compare compares n bytes at addresses a and c, returning the offsets at
+// which they differ.
+func (b *Bus) Compare(a, c uint16, n int) ([]int, error) {
+	other, err := b.ReadBlock(c, n)
+	if err != nil {
+		return nil, err
+	}
+	return b.Verify(a, other)
+}
+
+// Verify compares memory starting at address a with expected, returning the
+// offsets at which they differ.
+func (b *Bus) Verify(a uint16, expected []byte) ([]int, error) {
+	data, err := b.ReadBlock(a, len(expected))
+	if err != nil {
+		return nil, err
+	}
+	var diffs []int
+	for i := range data {
+		if data[i] != expected[i] {
+			diffs = append(diffs, i)
+		}
+	}
+	return diffs, nil
+}
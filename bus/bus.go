@@ -6,28 +6,55 @@ package bus
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/peter-mount/go6502/memory"
 )
 
 type busEntry struct {
-	mem   memory.Memory
-	name  string
-	start uint16
-	end   uint16
+	mem    memory.Memory // device wrapped to translate bus addresses
+	device memory.Memory // the device as attached
+	name   string
+	start  uint16
+	end    uint16
+	wait   uint64 // wait states added to each access
 }
 
 // Bus is a 16-bit address, 8-bit data bus, which maps reads and writes
 // at different locations to different backend Memory. For example the
 // lower 32K could be RAM, the upper 8KB ROM, and some I/O in the middle.
+//
+// Devices may be attached and detached while the cpu is running, e.g. to
+// hot-swap a cartridge. A detached device is not accessed once Detach returns.
 type Bus struct {
+	mutex   sync.RWMutex // guards entries
 	entries []busEntry
+	watches []watch
+	watchId int
+	pc      uint16
+	trace   *trace
+	openBus OpenBus
+	fault   FaultFunc
+	last    byte // last value on the data bus
+	waits   uint64
 }
 
 func (b *Bus) String() string {
 	return fmt.Sprintf("Address bus (TODO: describe)")
 }
 
+// Region describes the address range occupied by an attached backend.
+type Region struct {
+	Name   string
+	Start  uint16
+	End    uint16
+	Device memory.Memory // the backend as attached, nil if not from the bus
+}
+
+func (r Region) String() string {
+	return fmt.Sprintf("%s $%04X-$%04X", r.Name, r.Start, r.End)
+}
+
 func CreateBus() (*Bus, error) {
 	return &Bus{entries: make([]busEntry, 0)}, nil
 }
@@ -35,17 +62,96 @@ func CreateBus() (*Bus, error) {
 // Attach maps a bus address range to a backend Memory implementation,
 // which could be RAM, ROM, I/O device etc.
 func (b *Bus) Attach(mem memory.Memory, name string, offset uint16) error {
+	if mem.Size() < 1 || int(offset)+mem.Size() > 0x10000 {
+		return fmt.Errorf("Invalid size %d for %s at 0x%04X", mem.Size(), name, offset)
+	}
 	om := OffsetMemory{Offset: offset, Memory: mem}
 	end := offset + uint16(mem.Size()-1)
-	entry := busEntry{mem: om, name: name, start: offset, end: end}
-	b.entries = append(b.entries, entry)
+	entry := busEntry{mem: om, device: mem, name: name, start: offset, end: end}
+	b.attach(entry)
+	return nil
+}
+
+// AttachMirrored maps a backend Memory implementation repeatedly across a
+// window of the given size, emulating hardware with incomplete address
+// decoding. e.g. a 4 byte ACIA mirrored across a 256 byte window responds
+// at offset, offset+4, offset+8 etc.
+func (b *Bus) AttachMirrored(mem memory.Memory, name string, offset uint16, window int) error {
+	if mem.Size() < 1 || window < mem.Size() || int(offset)+window > 0x10000 {
+		return fmt.Errorf("Invalid mirror window %d for %s at 0x%04X", window, name, offset)
+	}
+	mm := MirroredMemory{Offset: offset, Window: window, Memory: mem}
+	end := offset + uint16(window-1)
+	entry := busEntry{mem: mm, device: mem, name: name, start: offset, end: end}
+	b.attach(entry)
 	return nil
 }
 
-func (b *Bus) backendFor(a uint16) (memory.Memory, error) {
+func (b *Bus) attach(entry busEntry) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.entries = append(b.entries, entry)
+}
+
+// Detach removes the named backend from the bus, returning it so the caller
+// can shut it down or attach it elsewhere. Once removed its address range is
+// unmapped, or served by any backend previously hidden beneath it.
+func (b *Bus) Detach(name string) (memory.Memory, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i, be := range b.entries {
+		if be.name == name {
+			// Copy so entries held by concurrent readers are unaffected
+			entries := make([]busEntry, 0, len(b.entries)-1)
+			entries = append(entries, b.entries[:i]...)
+			b.entries = append(entries, b.entries[i+1:]...)
+			return be.device, nil
+		}
+	}
+	return nil, fmt.Errorf("No backend named %q", name)
+}
+
+// Regions returns the address ranges of the attached backends, in the order
+// they were attached.
+func (b *Bus) Regions() []Region {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	regions := make([]Region, 0, len(b.entries))
 	for _, be := range b.entries {
+		regions = append(regions, Region{Name: be.name, Start: be.start, End: be.end, Device: be.device})
+	}
+	return regions
+}
+
+// SetWaitStates adds cycles to each access to the named backend, modelling
+// slow devices. The cpu adds them to its cycle count.
+func (b *Bus) SetWaitStates(name string, cycles int) error {
+	if cycles < 0 {
+		return fmt.Errorf("Invalid wait states %d for %s", cycles, name)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for i := range b.entries {
+		if b.entries[i].name == name {
+			b.entries[i].wait = uint64(cycles)
+			return nil
+		}
+	}
+	return fmt.Errorf("No backend named %q", name)
+}
+
+// WaitStates returns the wait states accumulated by accesses since the
+// previous call.
+func (b *Bus) WaitStates() uint64 {
+	waits := b.waits
+	b.waits = 0
+	return waits
+}
+
+func (b *Bus) backendFor(a uint16) (*busEntry, error) {
+	for i, be := range b.entries {
 		if a >= be.start && a <= be.end {
-			return be.mem, nil
+			return &b.entries[i], nil
 		}
 	}
 	return nil, fmt.Errorf("No backend for address 0x%04X", a)
@@ -54,20 +160,46 @@ func (b *Bus) backendFor(a uint16) (memory.Memory, error) {
 // Shutdown tells the address bus a shutdown is occurring, and to pass the
 // message on to subordinates.
 func (b *Bus) Shutdown() {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
 	for _, be := range b.entries {
 		be.mem.Shutdown()
 	}
 }
 
+// Reset passes the RESB signal on to each backend implementing
+// memory.Resetter. RAM and ROM contents are unaffected.
+func (b *Bus) Reset() {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, be := range b.entries {
+		if r, ok := be.device.(memory.Resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
 // Read returns the byte from memory mapped to the given address.
 // e.g. if ROM is mapped to 0xC000, then Read(0xC0FF) returns the byte at
 // 0x00FF in that RAM device.
 func (b *Bus) Read(a uint16) byte {
-	mem, err := b.backendFor(a)
+	b.mutex.RLock()
+	be, err := b.backendFor(a)
 	if err != nil {
-		panic(err)
+		b.mutex.RUnlock()
+		b.last = b.unmapped(AccessRead, a, err)
+		return b.last
+	}
+	value := be.mem.Read(a)
+	b.waits += be.wait
+	b.mutex.RUnlock()
+	b.last = value
+	if len(b.watches) > 0 {
+		b.notify(AccessRead, a, value)
+	}
+	if b.trace != nil {
+		b.trace.access(AccessRead, a, value, b.pc, be.name)
 	}
-	value := mem.Read(a)
 	return value
 }
 
@@ -81,11 +213,36 @@ func (b *Bus) Read16(a uint16) uint16 {
 
 // Write the byte to the device mapped to the given address.
 func (b *Bus) Write(a uint16, value byte) {
-	mem, err := b.backendFor(a)
+	b.last = value
+	b.mutex.RLock()
+	be, err := b.backendFor(a)
+	if err != nil {
+		b.mutex.RUnlock()
+		b.unmapped(AccessWrite, a, err)
+		return
+	}
+	be.mem.Write(a, value)
+	b.waits += be.wait
+	b.mutex.RUnlock()
+	if len(b.watches) > 0 {
+		b.notify(AccessWrite, a, value)
+	}
+	if b.trace != nil {
+		b.trace.access(AccessWrite, a, value, b.pc, be.name)
+	}
+}
+
+// Poke writes a byte directly to the device mapped to the given address,
+// bypassing watches, tracing and ROM write protection. This is used to load
+// programs into memory.
+func (b *Bus) Poke(a uint16, value byte) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	be, err := b.backendFor(a)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	mem.Write(a, value)
+	return poke(be.mem, a, value)
 }
 
 // Write16 writes the given 16-bit value to the specifie address, storing it
@@ -0,0 +1,259 @@
+package bus
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestWatchReadsAndWrites(t *testing.T) {
+	b, _ := CreateBus()
+	b.Attach(memory.NewRam(0x8000), "ram", 0x0000)
+
+	var hits []string
+	id := b.Watch(0x0200, 0x02FF, AccessWrite, func(access Access, a uint16, v byte, pc uint16) {
+		hits = append(hits, fmt.Sprintf("%v $%04X=$%02X @$%04X", access, a, v, pc))
+	})
+
+	b.SetPC(0x1234)
+	b.Write(0x0200, 0x42)
+	b.Write(0x0300, 0x01) // outside range
+	b.Read(0x0200)        // not watched for reads
+
+	expected := "write $0200=$42 @$1234"
+	if len(hits) != 1 || hits[0] != expected {
+		t.Error(fmt.Sprintf("expected [%s] got %v", expected, hits))
+	}
+
+	b.Unwatch(id)
+	b.Write(0x0200, 0x43)
+	if len(hits) != 1 {
+		t.Error("watch still triggered after Unwatch")
+	}
+}
+
+func TestTraceFiltersByRange(t *testing.T) {
+	b, _ := CreateBus()
+	b.Attach(memory.NewRam(0x8000), "ram", 0x0000)
+
+	var buf bytes.Buffer
+	b.Trace(&buf, Region{Start: 0x0200, End: 0x02FF})
+	b.SetPC(0xE000)
+	b.Write(0x0200, 0x42)
+	b.Write(0x0100, 0x01)
+	b.Read(0x0200)
+
+	expected := "W $0200 $42 pc:$E000 ram\nR $0200 $42 pc:$E000 ram\n"
+	if buf.String() != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, buf.String()))
+	}
+}
+
+func TestAttachMirrored(t *testing.T) {
+	b, _ := CreateBus()
+	dev, _ := memory.NewBanked(4, 1)
+	if err := b.AttachMirrored(dev, "acia", 0x9000, 256); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Write(0x9001, 0x42)
+	for _, a := range []uint16{0x9001, 0x9005, 0x90FD} {
+		if v := b.Read(a); v != 0x42 {
+			t.Error(fmt.Sprintf("$%04X read $%02X expected $42", a, v))
+		}
+	}
+}
+
+func TestDetachUncoversHiddenBackend(t *testing.T) {
+	b, _ := CreateBus()
+	cart, _ := memory.NewBanked(0x0100, 1)
+	cart.Write(0x0000, 0xAA)
+	b.Attach(cart, "cart", 0x0000)
+
+	ram := memory.NewRam(0x8000)
+	ram.Write(0x0000, 0x55)
+	b.Attach(ram, "ram", 0x0000)
+
+	if v := b.Read(0x0000); v != 0xAA {
+		t.Error(fmt.Sprintf("expected $AA from cart got $%02X", v))
+	}
+
+	if dev, err := b.Detach("cart"); err != nil || dev != cart {
+		t.Error(fmt.Sprintf("expected cart to be detached got %v %v", dev, err))
+	}
+	if v := b.Read(0x0000); v != 0x55 {
+		t.Error(fmt.Sprintf("expected $55 from ram got $%02X", v))
+	}
+
+	if _, err := b.Detach("cart"); err == nil {
+		t.Error("expected error detaching cart twice")
+	}
+}
+
+func TestOpenBus(t *testing.T) {
+	b, _ := CreateBus()
+	b.Attach(memory.NewRam(0x8000), "ram", 0x0000)
+
+	var faults []string
+	b.SetOpenBus(OpenBusLast, func(access Access, a uint16, pc uint16) {
+		faults = append(faults, fmt.Sprintf("%v $%04X", access, a))
+	})
+
+	b.Write(0x0010, 0x42)
+	b.Read(0x0010)
+	if v := b.Read(0x9000); v != 0x42 {
+		t.Error(fmt.Sprintf("expected last value $42 got $%02X", v))
+	}
+	b.Write(0x9000, 0x01)
+	if len(faults) != 2 || faults[0] != "read $9000" || faults[1] != "write $9000" {
+		t.Error(fmt.Sprintf("expected read and write faults got %v", faults))
+	}
+
+	b.SetOpenBus(OpenBusFF, nil)
+	if v := b.Read(0x9000); v != 0xFF {
+		t.Error(fmt.Sprintf("expected $FF got $%02X", v))
+	}
+}
+
+func TestAttachDifferentlySizedRam(t *testing.T) {
+	b, _ := CreateBus()
+	low := memory.NewRam(0x0400)
+	high := memory.NewRam(0x2000)
+	b.Attach(low, "low", 0x0000)
+	b.Attach(high, "high", 0x4000)
+
+	b.Write(0x03FF, 0x11)
+	b.Write(0x4000, 0x22)
+	if low.Read(0x03FF) != 0x11 || high.Read(0x0000) != 0x22 {
+		t.Error("writes did not reach the expected ram")
+	}
+
+	regions := b.Regions()
+	if len(regions) != 2 || regions[0].End != 0x03FF || regions[1].End != 0x5FFF {
+		t.Error(fmt.Sprintf("unexpected regions %v", regions))
+	}
+
+	if err := b.Attach(memory.NewRam(0x2000), "overflow", 0xF000); err == nil {
+		t.Error("expected error attaching ram beyond $FFFF")
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	b, _ := CreateBus()
+	ram := memory.NewRam(0x1000)
+	banked, _ := memory.NewBanked(0x0100, 2)
+	b.Attach(ram, "ram", 0x0000)
+	b.Attach(banked, "banked", 0x8000)
+
+	b.Write(0x0010, 0x42)
+	banked.SelectBank(1)
+	b.Write(0x8000, 0x24)
+
+	var buf bytes.Buffer
+	if err := b.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	b.Write(0x0010, 0x00)
+	b.Write(0x8000, 0x00)
+	banked.SelectBank(0)
+
+	if err := b.Restore(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if v := b.Read(0x0010); v != 0x42 {
+		t.Error(fmt.Sprintf("ram expected $42 got $%02X", v))
+	}
+	if banked.Bank() != 1 || b.Read(0x8000) != 0x24 {
+		t.Error(fmt.Sprintf("banked expected bank 1 $24 got bank %d $%02X", banked.Bank(), b.Read(0x8000)))
+	}
+}
+
+func TestBlockSpansDevices(t *testing.T) {
+	b, _ := CreateBus()
+	low := memory.NewRam(0x0100)
+	high := memory.NewRam(0x0100)
+	b.Attach(low, "low", 0x0000)
+	b.Attach(high, "high", 0x0100)
+	b.AttachMirrored(memory.NewRam(0x0004), "mirror", 0x0200, 0x0010)
+
+	data := []byte{1, 2, 3, 4, 5, 6}
+	if err := b.WriteBlock(0x00FD, data); err != nil {
+		t.Fatal(err)
+	}
+	if low.Read(0x00FF) != 3 || high.Read(0x0000) != 4 || high.Read(0x0002) != 6 {
+		t.Error("block not split between devices")
+	}
+	if pages := fmt.Sprint(high.DirtyPages()); pages != "[0]" {
+		t.Error(fmt.Sprintf("expected high page 0 dirty got %s", pages))
+	}
+
+	read, err := b.ReadBlock(0x00FD, len(data))
+	if err != nil || !bytes.Equal(read, data) {
+		t.Error(fmt.Sprintf("expected %v got %v %v", data, read, err))
+	}
+
+	b.WriteBlock(0x0200, []byte{9, 8, 7, 6})
+	read, _ = b.ReadBlock(0x0204, 4)
+	if !bytes.Equal(read, []byte{9, 8, 7, 6}) {
+		t.Error(fmt.Sprintf("expected mirrored block got %v", read))
+	}
+
+	if _, err := b.ReadBlock(0x0300, 1); err == nil {
+		t.Error("expected error reading unmapped block")
+	}
+}
+
+func TestFillCopyCompare(t *testing.T) {
+	b, _ := CreateBus()
+	b.Attach(memory.NewRam(0x1000), "ram", 0x0000)
+
+	if err := b.Fill(0x0100, 8, 0xEA); err != nil {
+		t.Fatal(err)
+	}
+	b.Write(0x0103, 0x00)
+
+	// Overlapping copy
+	if err := b.Copy(0x0100, 0x0104, 8); err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0xEA, 0xEA, 0xEA, 0x00, 0xEA, 0xEA, 0xEA, 0x00, 0xEA, 0xEA, 0xEA, 0xEA}
+	if diffs, err := b.Verify(0x0100, expected); err != nil || len(diffs) != 0 {
+		t.Error(fmt.Sprintf("unexpected differences %v %v", diffs, err))
+	}
+
+	diffs, err := b.Compare(0x0100, 0x0108, 4)
+	if err != nil || fmt.Sprint(diffs) != "[3]" {
+		t.Error(fmt.Sprintf("expected difference at [3] got %v %v", diffs, err))
+	}
+}
+
+func TestPeekHasNoSideEffects(t *testing.T) {
+	b, _ := CreateBus()
+	b.AttachMirrored(memory.NewRam(0x100), "ram", 0x0000, 0x200)
+	reads := 0
+	b.Attach(&memory.Handler{Name: "io", Length: 1, OnRead: func(uint16) byte {
+		reads++
+		return 0x42
+	}}, "io", 0x0300)
+
+	b.Write(0x0010, 0x55)
+	if v, ok := b.Peek(0x0110); !ok || v != 0x55 {
+		t.Error(fmt.Sprintf("expected mirrored $55 got $%02X %v", v, ok))
+	}
+	if _, ok := b.Peek(0x0300); ok || reads != 0 {
+		t.Error(fmt.Sprintf("expected a handler not to be peeked, read %d times", reads))
+	}
+	if _, ok := b.Peek(0x0400); ok {
+		t.Error("expected unmapped address not to be peeked")
+	}
+
+	if data, err := b.PeekBlock(0x010F, 2); err != nil || !bytes.Equal(data, []byte{0, 0x55}) {
+		t.Error(fmt.Sprintf("expected [0 85] got %v %v", data, err))
+	}
+	if _, err := b.PeekBlock(0x01FF, 2); err == nil {
+		t.Error("expected a block into unmapped memory to fail")
+	}
+}
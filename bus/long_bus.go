@@ -0,0 +1,155 @@
+package bus
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+// LongAddressSpace is the size of the address space of a LongBus.
+const LongAddressSpace = 1 << 24
+
+type longEntry struct {
+	mem   memory.LongMemory
+	name  string
+	start uint32
+	end   uint32
+}
+
+// LongBus is a 24-bit address, 8-bit data bus, for a 65816 or systems with
+// more than 64K of memory. A 6502 sees a 64K window of it, obtained with
+// Window and attached to its Bus.
+type LongBus struct {
+	mutex   sync.RWMutex // guards entries
+	entries []longEntry
+}
+
+// CreateLongBus creates an empty 24-bit bus.
+func CreateLongBus() *LongBus {
+	return &LongBus{}
+}
+
+// Attach maps a 16-bit Memory at a 24-bit address.
+func (b *LongBus) Attach(mem memory.Memory, name string, offset uint32) error {
+	return b.AttachLong(memory.Long(mem), name, offset)
+}
+
+// AttachLong maps a LongMemory at a 24-bit address.
+func (b *LongBus) AttachLong(mem memory.LongMemory, name string, offset uint32) error {
+	if mem.Size() < 1 || int(offset)+mem.Size() > LongAddressSpace {
+		return fmt.Errorf("Invalid size %d for %s at 0x%06X", mem.Size(), name, offset)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.entries = append(b.entries, longEntry{
+		mem:   mem,
+		name:  name,
+		start: offset,
+		end:   offset + uint32(mem.Size()-1),
+	})
+	return nil
+}
+
+func (b *LongBus) backendFor(a uint32) (*longEntry, error) {
+	for i, be := range b.entries {
+		if a >= be.start && a <= be.end {
+			return &b.entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("No backend for address 0x%06X", a)
+}
+
+// Shutdown passes the shutdown on to each attached device.
+func (b *LongBus) Shutdown() {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	for _, be := range b.entries {
+		be.mem.Shutdown()
+	}
+}
+
+// ReadLong returns the byte from the device mapped to the 24-bit address.
+func (b *LongBus) ReadLong(a uint32) byte {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	be, err := b.backendFor(a)
+	if err != nil {
+		panic(err)
+	}
+	return be.mem.ReadLong(a - be.start)
+}
+
+// WriteLong writes the byte to the device mapped to the 24-bit address.
+func (b *LongBus) WriteLong(a uint32, value byte) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	be, err := b.backendFor(a)
+	if err != nil {
+		panic(err)
+	}
+	be.mem.WriteLong(a-be.start, value)
+}
+
+// Size of the address space, meeting the memory.LongMemory interface so
+// buses can be nested.
+func (b *LongBus) Size() int {
+	return LongAddressSpace
+}
+
+// Window returns a view of part of the bus as 16-bit Memory of the given
+// size, starting at base. Attaching it to a Bus lets a 6502 see that part of
+// the address space. The base can be moved with SetBase, e.g. by a bank
+// mapping register.
+func (b *LongBus) Window(base uint32, size int) (*Window, error) {
+	if size < 1 || size > 0x10000 {
+		return nil, fmt.Errorf("Invalid window size %d", size)
+	}
+	w := &Window{bus: b, size: size}
+	return w, w.SetBase(base)
+}
+
+// Window is a 16-bit view of part of a LongBus.
+type Window struct {
+	bus  *LongBus
+	base uint32
+	size int
+}
+
+// Shutdown is part of the Memory interface, but takes no action for Window.
+// The LongBus is shut down separately.
+func (w *Window) Shutdown() {
+}
+
+func (w *Window) String() string {
+	return fmt.Sprintf("(Window $%06X-$%06X)", w.base, w.base+uint32(w.size-1))
+}
+
+// Base returns the 24-bit address of the start of the window.
+func (w *Window) Base() uint32 {
+	return w.base
+}
+
+// SetBase moves the window to start at base.
+func (w *Window) SetBase(base uint32) error {
+	if int(base)+w.size > LongAddressSpace {
+		return fmt.Errorf("Window at 0x%06X exceeds address space", base)
+	}
+	w.base = base
+	return nil
+}
+
+// Read a byte from the LongBus at the window base plus the address.
+func (w *Window) Read(a uint16) byte {
+	return w.bus.ReadLong(w.base + uint32(a))
+}
+
+// Write a byte to the LongBus at the window base plus the address.
+func (w *Window) Write(a uint16, value byte) {
+	w.bus.WriteLong(w.base+uint32(a), value)
+}
+
+// Size of the window in bytes.
+func (w *Window) Size() int {
+	return w.size
+}
@@ -0,0 +1,36 @@
+package bus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestLongBusWindow(t *testing.T) {
+	long := CreateLongBus()
+	long.AttachLong(memory.NewLongRam(0x40000), "ram", 0x000000)
+	long.Attach(memory.NewRam(0x100), "io", 0x050000)
+
+	window, err := long.Window(0x010000, 0x4000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := CreateBus()
+	b.Attach(window, "window", 0x4000)
+
+	b.Write(0x4010, 0x42)
+	if v := long.ReadLong(0x010010); v != 0x42 {
+		t.Error(fmt.Sprintf("expected $42 at $010010 got $%02X", v))
+	}
+
+	window.SetBase(0x050000)
+	b.Write(0x40FF, 0x24)
+	if v := long.ReadLong(0x0500FF); v != 0x24 {
+		t.Error(fmt.Sprintf("expected $24 at $0500FF got $%02X", v))
+	}
+
+	if err := window.SetBase(0xFFF000); err == nil {
+		t.Error("expected error moving window beyond address space")
+	}
+}
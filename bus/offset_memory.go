@@ -20,6 +20,12 @@ func (om OffsetMemory) Read(a uint16) byte {
 	return om.Memory.Read(a - om.Offset)
 }
 
+// Peek reads a byte from the underlying Memory without side effects, if it
+// supports memory.Peeker.
+func (om OffsetMemory) Peek(a uint16) (byte, bool) {
+	return peek(om.Memory, a-om.Offset)
+}
+
 func (om OffsetMemory) String() string {
 	return fmt.Sprintf("OffsetMemory(%v)", om.Memory)
 }
@@ -29,3 +35,68 @@ func (om OffsetMemory) String() string {
 func (om OffsetMemory) Write(a uint16, value byte) {
 	om.Memory.Write(a-om.Offset, value)
 }
+
+// Poke stores a byte in the underlying Memory bypassing write protection if
+// it supports memory.Poker.
+func (om OffsetMemory) Poke(a uint16, value byte) error {
+	return poke(om.Memory, a-om.Offset, value)
+}
+
+// MirroredMemory wraps a Memory object which repeats throughout a window of
+// the address space, rewriting addresses by the offset then modulo the size
+// of the underlying Memory.
+type MirroredMemory struct {
+	Offset uint16
+	Window int
+	memory.Memory
+}
+
+// Read returns a byte from the underlying Memory after rewriting the address.
+func (mm MirroredMemory) Read(a uint16) byte {
+	return mm.Memory.Read(mm.address(a))
+}
+
+// Peek reads a byte from the underlying Memory without side effects, if it
+// supports memory.Peeker.
+func (mm MirroredMemory) Peek(a uint16) (byte, bool) {
+	return peek(mm.Memory, mm.address(a))
+}
+
+// Size of the window the Memory is mirrored across.
+func (mm MirroredMemory) Size() int {
+	return mm.Window
+}
+
+func (mm MirroredMemory) String() string {
+	return fmt.Sprintf("MirroredMemory(%v)", mm.Memory)
+}
+
+// Write stores a byte in the underlying Memory after rewriting the address.
+func (mm MirroredMemory) Write(a uint16, value byte) {
+	mm.Memory.Write(mm.address(a), value)
+}
+
+// Poke stores a byte in the underlying Memory bypassing write protection if
+// it supports memory.Poker.
+func (mm MirroredMemory) Poke(a uint16, value byte) error {
+	return poke(mm.Memory, mm.address(a), value)
+}
+
+func (mm MirroredMemory) address(a uint16) uint16 {
+	return uint16(int(a-mm.Offset) % mm.Memory.Size())
+}
+
+func peek(mem memory.Memory, a uint16) (byte, bool) {
+	if p, ok := mem.(memory.Peeker); ok {
+		return p.Peek(a)
+	}
+	return 0, false
+}
+
+func poke(mem memory.Memory, a uint16, value byte) error {
+	if p, ok := mem.(memory.Poker); ok {
+		return p.Poke(a, value)
+	}
+	mem.Write(a, value)
+	return nil
+}
@@ -0,0 +1,58 @@
+package bus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpenBus is how the bus responds to accesses to unmapped addresses.
+type OpenBus uint8
+
+const (
+	OpenBusPanic OpenBus = iota // Panic, the default
+	OpenBusFF                   // Reads return $FF and writes are ignored
+	OpenBusLast                 // Reads return the last value on the data bus
+)
+
+// ParseOpenBus returns the OpenBus mode named "panic", "ff" or "last".
+func ParseOpenBus(s string) (OpenBus, error) {
+	switch strings.ToLower(s) {
+	case "", "panic":
+		return OpenBusPanic, nil
+	case "ff":
+		return OpenBusFF, nil
+	case "last", "open":
+		return OpenBusLast, nil
+	default:
+		return OpenBusPanic, fmt.Errorf("Unknown open bus mode %q", s)
+	}
+}
+
+// FaultFunc is called on an access to an unmapped address, with the type of
+// access, the address, and the address of the instruction performing it.
+type FaultFunc func(access Access, address uint16, pc uint16)
+
+// SetOpenBus sets how the bus responds to accesses to unmapped addresses.
+// If fault is not nil it is called on each such access, e.g. to report it
+// to the debugger or to stop the machine.
+func (b *Bus) SetOpenBus(mode OpenBus, fault FaultFunc) {
+	b.openBus = mode
+	b.fault = fault
+}
+
+// unmapped handles an access to an unmapped address, returning the value
+// floating on the data bus.
+func (b *Bus) unmapped(access Access, a uint16, err error) byte {
+	if b.fault != nil {
+		b.fault(access, a, b.pc)
+	}
+
+	switch b.openBus {
+	case OpenBusFF:
+		return 0xFF
+	case OpenBusLast:
+		return b.last
+	default:
+		panic(err)
+	}
+}
@@ -0,0 +1,72 @@
+package bus
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+// deviceSnapshot is the saved state of one device on the bus.
+type deviceSnapshot struct {
+	Name  string
+	Start uint16
+	Data  []byte
+}
+
+// Snapshot writes the state of every attached device implementing
+// memory.Snapshotable to w.
+func (b *Bus) Snapshot(w io.Writer) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var snapshots []deviceSnapshot
+	for _, be := range b.entries {
+		if s, ok := be.device.(memory.Snapshotable); ok {
+			data, err := s.Snapshot()
+			if err != nil {
+				return fmt.Errorf("Snapshot of %s failed: %v", be.name, err)
+			}
+			snapshots = append(snapshots, deviceSnapshot{Name: be.name, Start: be.start, Data: data})
+		}
+	}
+	return gob.NewEncoder(w).Encode(snapshots)
+}
+
+// Restore reads a snapshot written by Snapshot, restoring the state of each
+// device. Devices are matched by name and address, so the bus must be
+// configured as it was when the snapshot was taken.
+func (b *Bus) Restore(r io.Reader) error {
+	var snapshots []deviceSnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshots); err != nil {
+		return err
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, snapshot := range snapshots {
+		be := b.entryFor(snapshot.Name, snapshot.Start)
+		if be == nil {
+			return fmt.Errorf("No backend %s at 0x%04X to restore", snapshot.Name, snapshot.Start)
+		}
+		s, ok := be.device.(memory.Snapshotable)
+		if !ok {
+			return fmt.Errorf("Backend %s cannot be restored", snapshot.Name)
+		}
+		if err := s.Restore(snapshot.Data); err != nil {
+			return fmt.Errorf("Restore of %s failed: %v", snapshot.Name, err)
+		}
+	}
+	return nil
+}
+
+func (b *Bus) entryFor(name string, start uint16) *busEntry {
+	for i, be := range b.entries {
+		if be.name == name && be.start == start {
+			return &b.entries[i]
+		}
+	}
+	return nil
+}
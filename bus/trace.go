@@ -0,0 +1,44 @@
+package bus
+
+import (
+	"fmt"
+	"io"
+)
+
+type trace struct {
+	w      io.Writer
+	ranges []Region
+}
+
+// Trace logs every read and write within the given address ranges to w, with
+// the value, the address of the instruction performing the access and the
+// name of the device accessed. With no ranges every access is logged.
+// A nil writer disables tracing.
+func (b *Bus) Trace(w io.Writer, ranges ...Region) {
+	if w == nil {
+		b.trace = nil
+		return
+	}
+	b.trace = &trace{w: w, ranges: ranges}
+}
+
+func (t *trace) access(access Access, a uint16, value byte, pc uint16, name string) {
+	if len(t.ranges) > 0 && !t.inRange(a) {
+		return
+	}
+
+	dir := "R"
+	if access == AccessWrite {
+		dir = "W"
+	}
+	fmt.Fprintf(t.w, "%s $%04X $%02X pc:$%04X %s\n", dir, a, value, pc, name)
+}
+
+func (t *trace) inRange(a uint16) bool {
+	for _, r := range t.ranges {
+		if a >= r.Start && a <= r.End {
+			return true
+		}
+	}
+	return false
+}
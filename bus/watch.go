@@ -0,0 +1,73 @@
+package bus
+
+// Access is the type of bus access a watch is triggered by.
+type Access uint8
+
+const (
+	AccessRead      Access = 1 << iota // Watch reads
+	AccessWrite                        // Watch writes
+	AccessReadWrite = AccessRead | AccessWrite
+)
+
+func (a Access) String() string {
+	switch a {
+	case AccessRead:
+		return "read"
+	case AccessWrite:
+		return "write"
+	default:
+		return "read/write"
+	}
+}
+
+// WatchFunc is called when a watched address is accessed, with the type of
+// access, the address, the value read or written, and the address of the
+// instruction performing the access.
+type WatchFunc func(access Access, address uint16, value byte, pc uint16)
+
+type watch struct {
+	id     int
+	start  uint16
+	end    uint16
+	access Access
+	fn     WatchFunc
+}
+
+// Watch registers a callback for accesses to the address range start..end
+// inclusive. Returns an id which can be passed to Unwatch.
+func (b *Bus) Watch(start, end uint16, access Access, fn WatchFunc) int {
+	b.watchId++
+	b.watches = append(b.watches, watch{id: b.watchId, start: start, end: end, access: access, fn: fn})
+	return b.watchId
+}
+
+// Unwatch removes a watch registered with Watch. It is safe to call from
+// within a WatchFunc.
+func (b *Bus) Unwatch(id int) {
+	watches := make([]watch, 0, len(b.watches))
+	for _, w := range b.watches {
+		if w.id != id {
+			watches = append(watches, w)
+		}
+	}
+	b.watches = watches
+}
+
+// SetPC records the address of the instruction currently executing, which is
+// passed to watches. This is called by the cpu.
+func (b *Bus) SetPC(pc uint16) {
+	b.pc = pc
+}
+
+// PC returns the address of the instruction currently executing.
+func (b *Bus) PC() uint16 {
+	return b.pc
+}
+
+func (b *Bus) notify(access Access, a uint16, value byte) {
+	for _, w := range b.watches {
+		if w.access&access != 0 && a >= w.start && a <= w.end {
+			w.fn(access, a, value, b.pc)
+		}
+	}
+}
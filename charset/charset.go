@@ -0,0 +1,144 @@
+/*
+	Package charset translates between the character codes used by a guest
+	machine and the host terminal, so machines using non-ASCII character
+	ROMs such as PETSCII or ATASCII display correctly.
+
+	Custom tables are loaded from a text file, one mapping per line of the
+	guest code in hex and the host character:
+
+		# Map the pound sign
+		5C = £
+		5E = ↑
+
+	Codes not listed keep their ASCII meaning.
+*/
+package charset
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Charset maps guest bytes to host runes and back.
+type Charset struct {
+	Name     string
+	toHost   [256]rune
+	fromHost map[rune]byte
+}
+
+// newCharset returns an ASCII charset to which mappings can be added.
+func newCharset(name string) *Charset {
+	c := &Charset{Name: name, fromHost: make(map[rune]byte)}
+	for i := range c.toHost {
+		c.toHost[i] = rune(i)
+	}
+	return c
+}
+
+// set maps a guest byte to a host rune in both directions.
+func (c *Charset) set(guest byte, host rune) {
+	c.toHost[guest] = host
+	c.fromHost[host] = guest
+}
+
+// ToHost returns the host character for a guest byte.
+func (c *Charset) ToHost(b byte) rune {
+	return c.toHost[b]
+}
+
+// FromHost returns the guest byte for a host character. Returns false if
+// the character has no guest equivalent.
+func (c *Charset) FromHost(r rune) (byte, bool) {
+	if b, exists := c.fromHost[r]; exists {
+		return b, true
+	}
+	if r < 0x100 && c.toHost[r] == r {
+		return byte(r), true
+	}
+	return 0, false
+}
+
+// ASCII passes characters through unchanged.
+func ASCII() *Charset {
+	return newCharset("ascii")
+}
+
+// PETSCII is the Commodore character set in its shifted (lower case) mode,
+// where $41-$5A are lower case and $C1-$DA upper case.
+func PETSCII() *Charset {
+	c := newCharset("petscii")
+	for i := byte(0); i < 26; i++ {
+		c.set(0x41+i, rune('a'+i))
+		c.set(0x61+i, rune('A'+i))
+		c.set(0xC1+i, rune('A'+i))
+	}
+	c.set(0x0D, '\n')
+	c.set(0x14, '\b')
+	c.set(0x5C, '£')
+	c.set(0x5E, '↑')
+	c.set(0x5F, '←')
+	return c
+}
+
+// ATASCII is the Atari 8-bit character set, where EOL is $9B.
+func ATASCII() *Charset {
+	c := newCharset("atascii")
+	c.set(0x9B, '\n')
+	c.set(0x7E, '\b')
+	c.set(0x7F, '\t')
+	c.set(0xFD, '\a')
+	return c
+}
+
+// Lookup returns a built in charset by name, or loads a custom table if name
+// is a file.
+func Lookup(name string) (*Charset, error) {
+	switch strings.ToLower(name) {
+	case "", "ascii":
+		return ASCII(), nil
+	case "petscii":
+		return PETSCII(), nil
+	case "atascii":
+		return ATASCII(), nil
+	default:
+		return Load(name)
+	}
+}
+
+// Load reads a custom table from a file.
+func Load(path string) (*Charset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := newCharset(path)
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(text, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d expected code = character", path, line)
+		}
+		guest, err := strconv.ParseUint(strings.TrimSpace(fields[0]), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d %v", path, line, err)
+		}
+		host := strings.TrimSpace(fields[1])
+		if utf8.RuneCountInString(host) != 1 {
+			return nil, fmt.Errorf("%s:%d expected a single character", path, line)
+		}
+		r, _ := utf8.DecodeRuneInString(host)
+		c.set(byte(guest), r)
+	}
+	return c, s.Err()
+}
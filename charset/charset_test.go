@@ -0,0 +1,22 @@
+package charset
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPetsciiRoundTrip(t *testing.T) {
+	c := PETSCII()
+	for guest, host := range map[byte]rune{0x41: 'a', 0xC1: 'A', 0x0D: '\n', 0x5C: '£', 0x31: '1'} {
+		if r := c.ToHost(guest); r != host {
+			t.Error(fmt.Sprintf("$%02X expected %q got %q", guest, host, r))
+		}
+	}
+
+	if b, ok := c.FromHost('a'); !ok || b != 0x41 {
+		t.Error(fmt.Sprintf("a expected $41 got $%02X", b))
+	}
+	if _, ok := c.FromHost('€'); ok {
+		t.Error("expected no PETSCII code for €")
+	}
+}
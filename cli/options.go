@@ -1,7 +1,7 @@
 /*
-	Package cli provides command line support for go6502.
+Package cli provides command line support for go6502.
 
-	It parses CLI flags and exposes the resulting options.
+It parses CLI flags and exposes the resulting options.
 */
 package cli
 
@@ -13,24 +13,36 @@ import (
 
 // Options stores the value of command line options after they're parsed.
 type Options struct {
-	Debug           bool
-	DebugCmds       commandList
-	DebugSymbolFile string
-	Ili9340         bool
-	SdCard          string
-	Speedometer     bool
-	ViaDumpAscii    bool
-	ViaDumpBinary   bool
-	ViaSsd1306      bool
+	CharRom           string
+	Debug             bool
+	DebugBatch        bool
+	DebugCmds         commandList
+	DebugScript       string
+	DebugSymbolFile   string
+	DebugSymbolFormat string
+	DebugWeb          string
+	DebugRPC          string
+	Ili9340           bool
+	SdCard            string
+	Speedometer       bool
+	ViaDumpAscii      bool
+	ViaDumpBinary     bool
+	ViaSsd1306        bool
 }
 
 // ParseFlags uses the flag stdlib package to parse CLI options.
 func ParseFlags() *Options {
 	opt := &Options{}
 
+	flag.StringVar(&opt.CharRom, "char-rom", "", "Character ROM to attach at $B000")
 	flag.BoolVar(&opt.Debug, "debug", false, "Run debugger")
+	flag.BoolVar(&opt.DebugBatch, "debug-batch", false, "Run the debugger commands without a terminal then exit, non-zero if any failed.")
 	flag.Var(&opt.DebugCmds, "debug-commands", "Debugger commands to run, semicolon separated.")
-	flag.StringVar(&opt.DebugSymbolFile, "debug-symbol-file", "", "ld65 debug file to load.")
+	flag.StringVar(&opt.DebugScript, "debug-script", "", "Debugger commands to run from a file.")
+	flag.StringVar(&opt.DebugSymbolFile, "debug-symbol-file", "", "Symbol file to load.")
+	flag.StringVar(&opt.DebugSymbolFormat, "debug-symbol-format", "", "Symbol file format: dbg, vice or map. Detected if omitted.")
+	flag.StringVar(&opt.DebugWeb, "debug-web", "", "Serve the debugger web UI on this address, e.g. localhost:6502")
+	flag.StringVar(&opt.DebugRPC, "debug-rpc", "", "Serve the debugger JSON-RPC interface on this address, e.g. localhost:6503 or unix:/tmp/go6502.sock")
 	flag.StringVar(&opt.SdCard, "sd-card", "", "Load file as SD card")
 	flag.BoolVar(&opt.Speedometer, "speedometer", false, "Measure effective clock speed")
 	flag.BoolVar(&opt.ViaDumpBinary, "via-dump-binary", false, "6522 dumps binary output")
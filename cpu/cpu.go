@@ -55,49 +55,176 @@ type Cpu struct {
 	// different back-end devices.
 	Bus *bus.Bus
 
-	monitor  Monitor
+	// Cycles is the total number of clock cycles executed so far.
+	Cycles uint64
+
+	// Quirks selects which NMOS 6502 bugs are emulated.
+	Quirks Quirks
+
+	// Exit designates an "end of test" condition for test ROMs.
+	Exit ExitTrap
+
+	// InterruptTiming controls when pending interrupts are serviced.
+	InterruptTiming InterruptTiming
+
+	// CycleAccurate adds the extra cycles taken when indexed reads cross a
+	// page boundary and when branches are taken.
+	CycleAccurate bool
+
+	// extraCycles taken by the current instruction.
+	extraCycles uint64
+
+	interrupts interruptState
+
+	monitors []Monitor
 	ExitChan chan int
 }
 
+// Quirks are behaviours of the original NMOS 6502 which were corrected in the
+// CMOS 65C02. The zero value emulates the corrected behaviour.
+type Quirks struct {
+	// IndirectJumpBug causes JMP ($xxFF) to fetch the high byte of the target
+	// from $xx00 instead of the start of the next page.
+	IndirectJumpBug bool
+
+	// DummyWrite causes read-modify-write instructions to write the
+	// unmodified value back before writing the result.
+	DummyWrite bool
+
+	// DummyRead causes indexed addressing to read from the address before
+	// the carry into the high byte is fixed. Reads only do this when crossing
+	// a page, writes and read-modify-write instructions always do.
+	DummyRead bool
+}
+
+var (
+	// NMOS emulates the original MOS 6502.
+	NMOS = Quirks{IndirectJumpBug: true, DummyWrite: true, DummyRead: true}
+
+	// CMOS emulates the WDC 65C02.
+	CMOS = Quirks{}
+)
+
+// QuirksFor returns the Quirks for the named profile, either "nmos" or "cmos".
+func QuirksFor(profile string) (Quirks, error) {
+	switch strings.ToLower(profile) {
+	case "nmos", "6502":
+		return NMOS, nil
+	case "cmos", "65c02":
+		return CMOS, nil
+	default:
+		return Quirks{}, fmt.Errorf("Unknown cpu profile %q", profile)
+	}
+}
+
+// ExitTrap halts the machine when a designated opcode is executed, sending
+// the accumulator on ExitChan as the exit status, or when an address is
+// written to, sending the byte written. This allows automated test ROMs to
+// report success or failure. Bytes written to the message address are passed
+// to OnMessage, so a test can explain a failure before exiting.
+type ExitTrap struct {
+	Opcode         bool
+	OpcodeValue    byte
+	Address        bool
+	AddressValue   uint16
+	Message        bool
+	MessageAddress uint16
+	OnMessage      func(b byte)
+}
+
 // A Monitor is a blocking observer of instruction execution.
 type Monitor interface {
 	BeforeExecute(Instruction)
 	Shutdown()
 }
 
-// AttachMonitor sets the given Monitor to observe instructions before they
+// AttachMonitor adds the given Monitor to observe instructions before they
 // execute, in a blocking manner. This allows for logging, analysis, and
-// interactive debugging.
+// interactive debugging. Monitors are called in the order they were attached.
 func (c *Cpu) AttachMonitor(m Monitor) {
-	c.monitor = m
+	c.monitors = append(c.monitors, m)
 }
 
 // Shutdown tells the CPU to shut-down, and to pass the message on
 // to subordinates such as the address bus.
 func (c *Cpu) Shutdown() {
 	c.Bus.Shutdown()
-	if c.monitor != nil {
-		c.monitor.Shutdown()
+	c.ShutdownMonitors()
+}
+
+// ShutdownMonitors tells the attached monitors to shut-down, in the order
+// they were attached, without shutting down the address bus.
+func (c *Cpu) ShutdownMonitors() {
+	for _, m := range c.monitors {
+		m.Shutdown()
 	}
 }
 
+// PowerOn emulates applying power to the CPU. The registers are cleared and
+// the reset sequence is run, loading the program counter from the reset
+// vector.
+func (c *Cpu) PowerOn() {
+	c.AC = 0
+	c.X = 0
+	c.Y = 0
+	c.SP = 0
+	c.SR = 0x34 // Manual says xx1101xx, this sets 00110100.
+	c.Cycles = 0
+	c.interrupts = interruptState{}
+	c.Reset()
+}
+
 // Reset the CPU, emulating triggering the RESB line.
 // From 65C02 manual: All Registers are initialized by software except the
 // Decimal and Interrupt disable mode select bits of the Processor Status
 // Register (P) are initialized by hardware. ... The program counter is loaded
 // with the reset vector from locations FFFC (low byte) and FFFD (high byte).
+//
+// Unlike PowerOn, a warm reset preserves A, X and Y. The reset sequence
+// performs three suppressed stack pushes, so SP is decremented by 3.
+// Devices on the bus which implement memory.Resetter are also reset, but
+// memory contents are untouched.
 func (c *Cpu) Reset() {
+	c.Bus.Reset()
+	c.SP -= 3
+	c.setStatus(sInterrupt, true)
+	c.setStatus(sDecimal, false)
 	c.PC = c.Bus.Read16(0xFFFC)
-	c.SR = 0x34 // Manual says xx1101xx, this sets 00110100.
 }
 
+// Step executes the instruction at PC. If a monitor moves PC, e.g. by
+// resetting the CPU, the instruction is abandoned without being executed.
+// If an interrupt is pending at the instruction boundary, Step runs the
+// interrupt sequence instead.
 func (c *Cpu) Step() {
-	in := ReadInstruction(c.PC, c.Bus)
-	if c.monitor != nil {
-		c.monitor.BeforeExecute(in)
+	if c.serviceInterrupt() {
+		return
+	}
+
+	// Wait states are counted for the fetch and execution, but not for
+	// accesses by monitors such as the debugger
+	c.Bus.WaitStates()
+	opcode := c.Bus.Read(c.PC)
+	if c.Exit.Opcode && opcode == c.Exit.OpcodeValue {
+		c.ExitChan <- int(c.AC)
+		return
+	}
+	in := readInstruction(c.PC, opcode, c.Bus)
+	waits := c.Bus.WaitStates()
+
+	c.Bus.SetPC(in.Address)
+	for _, m := range c.monitors {
+		m.BeforeExecute(in)
+		if c.PC != in.Address {
+			return
+		}
 	}
+	c.Bus.WaitStates()
+
 	c.PC += uint16(in.Bytes)
+	c.extraCycles = 0
 	c.execute(in)
+	c.Cycles += uint64(in.Cycles) + c.extraCycles + waits + c.Bus.WaitStates()
 }
 
 func (c *Cpu) String() string {
@@ -126,12 +253,19 @@ func (c *Cpu) memoryAddress(in Instruction) uint16 {
 	case absolute:
 		return in.Op16
 	case absoluteX:
-		return in.Op16 + uint16(c.X)
+		return c.indexed(in, in.Op16, c.X)
 	case absoluteY:
-		return in.Op16 + uint16(c.Y)
+		return c.indexed(in, in.Op16, c.Y)
 
 	// indirect, e.g. jmp (020e)
+	// The NMOS 6502 doesn't carry into the high byte of the pointer, so
+	// jmp ($10FF) reads its target from $10FF and $1000.
 	case indirect:
+		if c.Quirks.IndirectJumpBug && in.Op16&0xFF == 0xFF {
+			lo := uint16(c.Bus.Read(in.Op16))
+			hi := uint16(c.Bus.Read(in.Op16 & 0xFF00))
+			return hi<<8 | lo
+		}
 		return c.Bus.Read16(in.Op16)
 
 	// Indexed Indirect (X)
@@ -151,7 +285,7 @@ func (c *Cpu) memoryAddress(in Instruction) uint16 {
 	// The address is loaded, and then the Y register is added to it.
 	// The resulting loaded_address + Y becomes the effective operand.
 	case indirectY:
-		return c.Bus.Read16(uint16(in.Op8)) + uint16(c.Y)
+		return c.indexed(in, c.Bus.Read16(uint16(in.Op8)), c.Y)
 
 	case zeropage:
 		return uint16(in.Op8)
@@ -160,11 +294,54 @@ func (c *Cpu) memoryAddress(in Instruction) uint16 {
 	case zeropageY:
 		return uint16(in.Op8 + c.Y)
 	default:
-		panic(fmt.Sprintf("unhandled addressing", in.addressing))
+		panic(fmt.Sprintf("unhandled addressing %d", in.addressing))
 		//panic("unhandled addressing")
 	}
 }
 
+// indexed adds an index to a base address. The 6502 adds the index to the
+// low byte first, then fixes the high byte in an extra cycle if there was a
+// carry. Instructions which write always take the extra cycle.
+func (c *Cpu) indexed(in Instruction, base uint16, index byte) uint16 {
+	address := base + uint16(index)
+	crossed := address&0xFF00 != base&0xFF00
+	writes := in.writes()
+
+	if c.Quirks.DummyRead && (crossed || writes) {
+		c.Bus.Read(base&0xFF00 | address&0x00FF)
+	}
+	if c.CycleAccurate && crossed && !writes {
+		c.extraCycles++
+	}
+	return address
+}
+
+// write a byte to the bus, unless it is to the exit trap or message address.
+// These addresses need not be mapped to a device.
+func (c *Cpu) write(address uint16, value byte) {
+	if c.Exit.Address && address == c.Exit.AddressValue {
+		c.ExitChan <- int(value)
+		return
+	}
+	if c.Exit.Message && address == c.Exit.MessageAddress {
+		if c.Exit.OnMessage != nil {
+			c.Exit.OnMessage(value)
+		}
+		return
+	}
+	c.Bus.Write(address, value)
+}
+
+// readModify reads the operand of a read-modify-write instruction, performing
+// the NMOS dummy write of the unmodified value if enabled.
+func (c *Cpu) readModify(address uint16) uint8 {
+	value := c.Bus.Read(address)
+	if c.Quirks.DummyWrite {
+		c.write(address, value)
+	}
+	return value
+}
+
 func (c *Cpu) getStatus(bit uint8) bool {
 	return c.getStatusInt(bit) == 1
 }
@@ -200,12 +377,21 @@ func (c *Cpu) statusString() string {
 }
 
 func (c *Cpu) branch(in Instruction) {
+	from := c.PC
 	relative := int8(in.Op8) // signed
 	if relative >= 0 {
 		c.PC += uint16(relative)
 	} else {
 		c.PC -= uint16(-relative)
 	}
+
+	// A taken branch takes an extra cycle, plus another if it crosses a page.
+	if c.CycleAccurate {
+		c.extraCycles++
+		if from&0xFF00 != c.PC&0xFF00 {
+			c.extraCycles++
+		}
+	}
 }
 
 func (c *Cpu) execute(in Instruction) {
@@ -282,6 +468,8 @@ func (c *Cpu) execute(in Instruction) {
 		c.ROL(in)
 	case ror:
 		c.ROR(in)
+	case rti:
+		c.RTI(in)
 	case rts:
 		c.RTS(in)
 	case sbc:
@@ -338,10 +526,10 @@ func (c *Cpu) ASL(in Instruction) {
 		c.updateStatus(c.AC)
 	default:
 		address := c.memoryAddress(in)
-		value := c.Bus.Read(address)
+		value := c.readModify(address)
 		c.setStatus(sCarry, (value>>7) == 1) // carry = old bit 7
 		value <<= 1
-		c.Bus.Write(address, value)
+		c.write(address, value)
 		c.updateStatus(value)
 	}
 }
@@ -397,18 +585,9 @@ func (c *Cpu) BPL(in Instruction) {
 }
 
 // BRK: software interrupt
+// BRK is followed by a padding byte, so the return address pushed is PC+1.
 func (c *Cpu) BRK(in Instruction) {
-	// Push PC
-	c.Bus.Write16(c.stackHead(-1), c.PC-1)
-	c.SP -= 2
-
-	// push SR
-	c.Bus.Write(0x0100+uint16(c.SP), c.SR)
-	c.SP--
-
-	// Move to interrupt vector & set interrupt flag
-	c.PC = c.Bus.Read16(0xfffe)
-	c.setStatus(sInterrupt, true)
+	c.interrupt(c.PC+1, irqVector, true)
 	c.setStatus(sBreak, true)
 }
 
@@ -451,8 +630,8 @@ func (c *Cpu) CPY(in Instruction) {
 // DEC: Decrement.
 func (c *Cpu) DEC(in Instruction) {
 	address := c.memoryAddress(in)
-	value := c.Bus.Read(address) - 1
-	c.Bus.Write(address, value)
+	value := c.readModify(address) - 1
+	c.write(address, value)
 	c.updateStatus(value)
 }
 
@@ -478,8 +657,8 @@ func (c *Cpu) EOR(in Instruction) {
 // INC: Increment.
 func (c *Cpu) INC(in Instruction) {
 	address := c.memoryAddress(in)
-	value := c.Bus.Read(address) + 1
-	c.Bus.Write(address, value)
+	value := c.readModify(address) + 1
+	c.write(address, value)
 	c.updateStatus(value)
 }
 
@@ -534,10 +713,10 @@ func (c *Cpu) LSR(in Instruction) {
 		c.updateStatus(c.AC)
 	default:
 		address := c.memoryAddress(in)
-		value := c.Bus.Read(address)
+		value := c.readModify(address)
 		c.setStatus(sCarry, value&1 == 1)
 		value >>= 1
-		c.Bus.Write(address, value)
+		c.write(address, value)
 		c.updateStatus(value)
 	}
 }
@@ -554,7 +733,7 @@ func (c *Cpu) ORA(in Instruction) {
 
 // PHA: Push accumulator onto stack.
 func (c *Cpu) PHA(in Instruction) {
-	c.Bus.Write(0x0100+uint16(c.SP), c.AC)
+	c.write(0x0100+uint16(c.SP), c.AC)
 	c.SP--
 }
 
@@ -574,10 +753,10 @@ func (c *Cpu) ROL(in Instruction) {
 		c.updateStatus(c.AC)
 	default:
 		address := c.memoryAddress(in)
-		value := c.Bus.Read(address)
+		value := c.readModify(address)
 		c.setStatus(sCarry, value>>7 == 1)
 		value = value<<1 | carry
-		c.Bus.Write(address, value)
+		c.write(address, value)
 		c.updateStatus(value)
 	}
 }
@@ -592,14 +771,22 @@ func (c *Cpu) ROR(in Instruction) {
 		c.updateStatus(c.AC)
 	default:
 		address := c.memoryAddress(in)
-		value := c.Bus.Read(address)
+		value := c.readModify(address)
 		c.setStatus(sCarry, value&1 == 1)
 		value = value>>1 | carry<<7
-		c.Bus.Write(address, value)
+		c.write(address, value)
 		c.updateStatus(value)
 	}
 }
 
+// RTI: Return from interrupt.
+func (c *Cpu) RTI(in Instruction) {
+	c.SP++
+	c.SR = c.Bus.Read(c.stackHead(0))
+	c.PC = c.Bus.Read16(c.stackHead(1))
+	c.SP += 2
+}
+
 // RTS: Return from subroutine.
 func (c *Cpu) RTS(in Instruction) {
 	c.PC = c.Bus.Read16(c.stackHead(1))
@@ -638,17 +825,17 @@ func (c *Cpu) SEI(in Instruction) {
 
 // STA: Store accumulator to memory.
 func (c *Cpu) STA(in Instruction) {
-	c.Bus.Write(c.memoryAddress(in), c.AC)
+	c.write(c.memoryAddress(in), c.AC)
 }
 
 // STX: Store index register X to memory.
 func (c *Cpu) STX(in Instruction) {
-	c.Bus.Write(c.memoryAddress(in), c.X)
+	c.write(c.memoryAddress(in), c.X)
 }
 
 // STY: Store index register Y to memory.
 func (c *Cpu) STY(in Instruction) {
-	c.Bus.Write(c.memoryAddress(in), c.Y)
+	c.write(c.memoryAddress(in), c.Y)
 }
 
 // TAX: Transfer accumulator to index register X.
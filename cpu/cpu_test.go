@@ -9,11 +9,11 @@ import (
 )
 
 func createCpu() *Cpu {
-	ram := &memory.Ram{}
+	ram := memory.NewRam(0x8000)
 	addressBus, _ := bus.CreateBus()
 	addressBus.Attach(ram, "ram", 0x8000) // upper 32K
 	cpu := &Cpu{Bus: addressBus}
-	cpu.Reset()
+	cpu.PowerOn()
 	return cpu
 }
 
@@ -36,3 +36,205 @@ func TestBitInstruction(t *testing.T) {
 		t.Error(fmt.Sprintf("SR expected %s got %s\n", expectedStatus, actualStatus))
 	}
 }
+
+func TestIndirectJumpBug(t *testing.T) {
+	cpu := createCpu()
+	cpu.Bus.Write(0x80FF, 0x34)
+	cpu.Bus.Write(0x8000, 0x12) // NMOS high byte
+	cpu.Bus.Write(0x8100, 0x56) // CMOS high byte
+
+	instruction := Instruction{OpType: optypes[0x6C], Op16: 0x80FF}
+
+	cpu.JMP(instruction)
+	if cpu.PC != 0x5634 {
+		t.Error(fmt.Sprintf("CMOS expected PC $5634 got $%04X\n", cpu.PC))
+	}
+
+	cpu.Quirks = NMOS
+	cpu.JMP(instruction)
+	if cpu.PC != 0x1234 {
+		t.Error(fmt.Sprintf("NMOS expected PC $1234 got $%04X\n", cpu.PC))
+	}
+}
+
+func TestWarmResetPreservesRegisters(t *testing.T) {
+	cpu := createCpu()
+	cpu.AC, cpu.X, cpu.Y = 1, 2, 3
+	cpu.Bus.Write(0x8000, 0x42)
+
+	cpu.Reset()
+
+	if cpu.AC != 1 || cpu.X != 2 || cpu.Y != 3 {
+		t.Error(fmt.Sprintf("registers not preserved: %v\n", cpu))
+	}
+	if cpu.SP != 0xFA {
+		t.Error(fmt.Sprintf("expected SP $FA got $%02X\n", cpu.SP))
+	}
+	if cpu.Bus.Read(0x8000) != 0x42 {
+		t.Error("RAM not preserved")
+	}
+}
+
+func TestExitTrapOpcode(t *testing.T) {
+	cpu := createCpu()
+	cpu.ExitChan = make(chan int, 1)
+	cpu.Exit = ExitTrap{Opcode: true, OpcodeValue: 0x02}
+	cpu.PC = 0x8000
+	cpu.AC = 0x2A
+	cpu.Bus.Write(0x8000, 0x02)
+
+	cpu.Step()
+
+	select {
+	case status := <-cpu.ExitChan:
+		if status != 0x2A {
+			t.Error(fmt.Sprintf("expected exit status 42 got %d\n", status))
+		}
+	default:
+		t.Error("exit trap opcode did not exit")
+	}
+}
+
+func TestExitTrapOpcodeFetchesOnce(t *testing.T) {
+	cpu := createCpu()
+	cpu.Exit = ExitTrap{Opcode: true, OpcodeValue: 0x02}
+	cpu.PC = 0x8000
+	cpu.Bus.Write(0x8000, 0xEA) // NOP
+
+	reads := 0
+	cpu.Bus.Watch(0x8000, 0x8000, bus.AccessRead, func(bus.Access, uint16, byte, uint16) {
+		reads++
+	})
+	cpu.Step()
+
+	if reads != 1 {
+		t.Error(fmt.Sprintf("expected the opcode read once got %d\n", reads))
+	}
+}
+
+func TestExitTrapAddress(t *testing.T) {
+	cpu := createCpu()
+	cpu.ExitChan = make(chan int, 1)
+	var message []byte
+	cpu.Exit = ExitTrap{
+		Address: true, AddressValue: 0xF001,
+		Message: true, MessageAddress: 0xF002,
+		OnMessage: func(b byte) { message = append(message, b) },
+	}
+	cpu.PC = 0x8000
+	cpu.AC, cpu.X = 'K', 0x03
+	cpu.Bus.Write(0x8000, 0x8D) // STA $F002
+	cpu.Bus.Write16(0x8001, 0xF002)
+	cpu.Bus.Write(0x8003, 0x8E) // STX $F001
+	cpu.Bus.Write16(0x8004, 0xF001)
+
+	cpu.Step()
+	cpu.Step()
+
+	if string(message) != "K" {
+		t.Error(fmt.Sprintf("expected message K got %q\n", message))
+	}
+	select {
+	case status := <-cpu.ExitChan:
+		if status != 3 {
+			t.Error(fmt.Sprintf("expected the written byte 3 as exit status got %d\n", status))
+		}
+	default:
+		t.Error("exit trap address did not exit")
+	}
+}
+
+func TestInterruptLatency(t *testing.T) {
+	cpu := createCpu()
+	cpu.Bus.Attach(memory.NewRam(0x8000), "stack", 0x0000)
+	cpu.Bus.Write16(0xFFFE, 0x9000)
+	cpu.Bus.Write(0x8000, 0xEA) // NOP
+	cpu.Bus.Write(0x8001, 0xEA) // NOP
+	cpu.PC = 0x8000
+	cpu.SP = 0xFF
+	cpu.setStatus(sInterrupt, false)
+	cpu.InterruptTiming.Latency = 1
+
+	// Asserted at an instruction boundary, so the NOP completes first.
+	cpu.SetIRQ(true)
+	cpu.Step()
+	if cpu.PC != 0x8001 {
+		t.Error(fmt.Sprintf("expected IRQ deferred, PC $%04X\n", cpu.PC))
+	}
+
+	cycles := cpu.Cycles
+	cpu.Step()
+	if cpu.PC != 0x9000 {
+		t.Error(fmt.Sprintf("expected IRQ serviced, PC $%04X\n", cpu.PC))
+	}
+	if cpu.Cycles-cycles != 7 {
+		t.Error(fmt.Sprintf("expected 7 cycle interrupt sequence, got %d\n", cpu.Cycles-cycles))
+	}
+	if cpu.Bus.Read16(0x01FE) != 0x8001 {
+		t.Error(fmt.Sprintf("expected return address $8001, got $%04X\n", cpu.Bus.Read16(0x01FE)))
+	}
+}
+
+func TestPageCrossDummyReadAndCycles(t *testing.T) {
+	cpu := createCpu()
+	cpu.Quirks = NMOS
+	cpu.CycleAccurate = true
+	cpu.X = 0x10
+	cpu.Bus.Write(0x8000, 0xBD) // LDA $80F8,X
+	cpu.Bus.Write16(0x8001, 0x80F8)
+	cpu.Bus.Write(0x8108, 0x42)
+	cpu.PC = 0x8000
+
+	var reads []uint16
+	cpu.Bus.Watch(0x8000, 0xFFFF, bus.AccessRead, func(_ bus.Access, a uint16, _ byte, _ uint16) {
+		reads = append(reads, a)
+	})
+
+	cycles := cpu.Cycles
+	cpu.Step()
+
+	if cpu.AC != 0x42 {
+		t.Error(fmt.Sprintf("expected AC $42 got $%02X\n", cpu.AC))
+	}
+	if cpu.Cycles-cycles != 5 {
+		t.Error(fmt.Sprintf("expected 5 cycles got %d\n", cpu.Cycles-cycles))
+	}
+	// opcode, operand, dummy read of $8008, then $8108
+	if len(reads) != 5 || reads[3] != 0x8008 || reads[4] != 0x8108 {
+		t.Error(fmt.Sprintf("unexpected bus reads %04X\n", reads))
+	}
+}
+
+func TestWaitStatesAddCycles(t *testing.T) {
+	cpu := createCpu()
+	cpu.Bus.Attach(memory.NewRam(0x0100), "slow", 0x0000)
+	cpu.Bus.SetWaitStates("slow", 2)
+	cpu.Bus.Write(0x9000, 0xAD) // LDA $0010
+	cpu.Bus.Write16(0x9001, 0x0010)
+	cpu.PC = 0x9000
+
+	cycles := cpu.Cycles
+	cpu.Step()
+
+	// 4 cycles plus 2 wait states for the single access to the slow device
+	if cpu.Cycles-cycles != 6 {
+		t.Error(fmt.Sprintf("expected 6 cycles got %d\n", cpu.Cycles-cycles))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	in, err := Decode(0x1000, []byte{0x4C, 0x34, 0x12, 0xEA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.String() != "JMP absolute $1234" || in.Address != 0x1000 {
+		t.Error(fmt.Sprintf("expected JMP absolute $1234 at $1000 got %v at $%04X", in, in.Address))
+	}
+
+	if _, err := Decode(0x1000, []byte{0x4C, 0x34}); err == nil {
+		t.Error("expected truncated instruction to fail")
+	}
+	if _, err := Decode(0x1000, []byte{0x02}); err == nil {
+		t.Error("expected illegal opcode to fail")
+	}
+}
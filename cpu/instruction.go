@@ -12,6 +12,9 @@ import (
 type Instruction struct {
 	OpType
 
+	// Address is the location of the opcode in memory.
+	Address uint16
+
 	// The single-byte operand, for 2-byte instructions.
 	Op8 uint8
 
@@ -35,12 +38,17 @@ func (in Instruction) String() (s string) {
 // address. An instruction may be 1, 2 or 3 bytes long, including its optional
 // 8 or 16 bit operand.
 func ReadInstruction(pc uint16, bus *bus.Bus) Instruction {
-	opcode := bus.Read(pc)
+	return readInstruction(pc, bus.Read(pc), bus)
+}
+
+// readInstruction reads the operand of an instruction whose opcode has
+// already been read, so the opcode is only fetched once.
+func readInstruction(pc uint16, opcode byte, bus *bus.Bus) Instruction {
 	optype, ok := optypes[opcode]
 	if !ok {
 		panic(fmt.Sprintf("Illegal opcode $%02X at $%04X", opcode, pc))
 	}
-	in := Instruction{OpType: optype}
+	in := Instruction{OpType: optype, Address: pc}
 	switch in.Bytes {
 	case 1: // no operand
 	case 2:
@@ -52,3 +60,27 @@ func ReadInstruction(pc uint16, bus *bus.Bus) Instruction {
 	}
 	return in
 }
+
+// Decode decodes the instruction at the start of data, for disassembling
+// memory read without side effects, e.g. by bus.Bus.PeekBlock. data must hold
+// the whole instruction but may be longer.
+func Decode(pc uint16, data []byte) (Instruction, error) {
+	if len(data) == 0 {
+		return Instruction{}, fmt.Errorf("No instruction at $%04X", pc)
+	}
+	optype, ok := optypes[data[0]]
+	if !ok {
+		return Instruction{}, fmt.Errorf("Illegal opcode $%02X at $%04X", data[0], pc)
+	}
+	in := Instruction{OpType: optype, Address: pc}
+	if len(data) < int(in.Bytes) {
+		return Instruction{}, fmt.Errorf("Truncated %s at $%04X", optype.Name(), pc)
+	}
+	switch in.Bytes {
+	case 2:
+		in.Op8 = data[1]
+	case 3:
+		in.Op16 = uint16(data[2])<<8 | uint16(data[1])
+	}
+	return in, nil
+}
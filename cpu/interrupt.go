@@ -0,0 +1,130 @@
+package cpu
+
+// Interrupt vectors.
+const (
+	nmiVector = 0xFFFA
+	irqVector = 0xFFFE
+)
+
+// defaultInterruptCycles is the length of the 6502 interrupt sequence.
+const defaultInterruptCycles = 7
+
+// InterruptTiming controls how quickly a pending interrupt is serviced.
+//
+// A real 6502 only recognises an interrupt between instructions, so one
+// asserted part way through an instruction waits until that instruction has
+// completed. Like the real chip, an interrupt asserted too close to the end of
+// an instruction is deferred until the following instruction has completed.
+type InterruptTiming struct {
+	// Latency is the number of cycles an interrupt must have been pending
+	// before an instruction boundary to be serviced there. 0 services an
+	// interrupt at the first boundary after it is asserted. Increase this to
+	// relax the timing, e.g. to emulate slow interrupt controllers.
+	Latency uint64
+
+	// Cycles taken by the interrupt sequence. 0 uses the 7 cycles of the
+	// real 6502.
+	Cycles uint64
+}
+
+// Interrupt identifies the source of a hardware interrupt.
+type Interrupt int
+
+const (
+	IRQ Interrupt = iota
+	NMI
+)
+
+func (i Interrupt) String() string {
+	if i == NMI {
+		return "NMI"
+	}
+	return "IRQ"
+}
+
+// An InterruptMonitor is a Monitor which is also told when a hardware
+// interrupt is serviced, as the interrupt sequence is not an instruction so
+// is not passed to BeforeExecute. It is called after the sequence, with PC at
+// the handler and returnAddress the address of the interrupted instruction.
+type InterruptMonitor interface {
+	AfterInterrupt(source Interrupt, returnAddress uint16)
+}
+
+// interruptState holds the state of the IRQ and NMI lines.
+type interruptState struct {
+	irq      bool   // IRQ line is asserted (level triggered)
+	irqSince uint64 // cycle count when IRQ was asserted
+	nmi      bool   // NMI edge is waiting to be serviced
+	nmiSince uint64 // cycle count when NMI was triggered
+}
+
+// SetIRQ sets the state of the IRQ line. The IRQ is level triggered, so
+// remains pending until the device releases it, and is ignored while the
+// interrupt disable flag is set.
+func (c *Cpu) SetIRQ(active bool) {
+	if active && !c.interrupts.irq {
+		c.interrupts.irqSince = c.Cycles
+	}
+	c.interrupts.irq = active
+}
+
+// NMI triggers a non-maskable interrupt. The NMI is edge triggered, so is
+// serviced once regardless of the interrupt disable flag.
+func (c *Cpu) NMI() {
+	if !c.interrupts.nmi {
+		c.interrupts.nmiSince = c.Cycles
+	}
+	c.interrupts.nmi = true
+}
+
+// serviceInterrupt runs the interrupt sequence if an interrupt is pending and
+// has been recognised. Returns true if an interrupt was serviced.
+func (c *Cpu) serviceInterrupt() bool {
+	s := &c.interrupts
+	returnAddress := c.PC
+	var source Interrupt
+	switch {
+	case s.nmi && c.Cycles-s.nmiSince >= c.InterruptTiming.Latency:
+		s.nmi = false
+		source = NMI
+		c.interrupt(returnAddress, nmiVector, false)
+	case s.irq && !c.getStatus(sInterrupt) && c.Cycles-s.irqSince >= c.InterruptTiming.Latency:
+		source = IRQ
+		c.interrupt(returnAddress, irqVector, false)
+	default:
+		return false
+	}
+
+	cycles := c.InterruptTiming.Cycles
+	if cycles == 0 {
+		cycles = defaultInterruptCycles
+	}
+	c.Cycles += cycles
+
+	for _, m := range c.monitors {
+		if im, ok := m.(InterruptMonitor); ok {
+			im.AfterInterrupt(source, returnAddress)
+		}
+	}
+	return true
+}
+
+// interrupt pushes the return address and status register then jumps via the
+// given vector. brk is set for the BRK instruction so the handler can
+// distinguish it from a hardware IRQ.
+func (c *Cpu) interrupt(returnAddress uint16, vector uint16, brk bool) {
+	c.Bus.Write16(c.stackHead(-1), returnAddress)
+	c.SP -= 2
+
+	sr := c.SR | 1<<5
+	if brk {
+		sr |= 1 << sBreak
+	} else {
+		sr &^= 1 << sBreak
+	}
+	c.write(c.stackHead(0), sr)
+	c.SP--
+
+	c.PC = c.Bus.Read16(vector)
+	c.setStatus(sInterrupt, true)
+}
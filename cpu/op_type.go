@@ -195,6 +195,21 @@ func (ot OpType) Name() (s string) {
 	return instructionNames[ot.id]
 }
 
+// Addressing returns the name of the addressing mode, e.g. immediate.
+func (ot OpType) Addressing() string {
+	return addressingNames[ot.addressing]
+}
+
+// writes returns true if the instruction writes to its memory operand,
+// including read-modify-write instructions.
+func (ot OpType) writes() bool {
+	switch ot.id {
+	case sta, stx, sty, asl, lsr, rol, ror, inc, dec:
+		return ot.addressing != accumulator
+	}
+	return false
+}
+
 func (ot OpType) IsAbsolute() bool {
 	return ot.addressing == absolute
 }
@@ -353,3 +368,26 @@ var optypes = map[uint8]OpType{
 	0x98: OpType{0x98, tya, implied, 1, 2},
 	0xFF: OpType{0xFF, _end, implied, 1, 1},
 }
+
+// LookupOpType returns the OpType for a mnemonic, e.g. LDA, in an addressing
+// mode named as by Addressing, e.g. zeropageX. This is the inverse of
+// decoding, for assemblers.
+func LookupOpType(mnemonic, addressing string) (OpType, bool) {
+	for _, ot := range optypes {
+		if ot.id != _end && ot.Name() == mnemonic && ot.Addressing() == addressing {
+			return ot, true
+		}
+	}
+	return OpType{}, false
+}
+
+// KnownMnemonic returns true if mnemonic, e.g. LDA, is an instruction in the
+// opcode table, in any addressing mode.
+func KnownMnemonic(mnemonic string) bool {
+	for _, ot := range optypes {
+		if ot.id != _end && ot.Name() == mnemonic {
+			return true
+		}
+	}
+	return false
+}
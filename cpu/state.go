@@ -0,0 +1,42 @@
+package cpu
+
+// State is the register and interrupt state of the CPU which, with the state
+// of the devices on the bus, is enough to resume execution later.
+type State struct {
+	PC               uint16
+	AC, X, Y, SP, SR byte
+	Cycles           uint64
+	IRQ              bool
+	IRQSince         uint64
+	NMI              bool
+	NMISince         uint64
+}
+
+// State returns the current state of the CPU.
+func (c *Cpu) State() State {
+	return State{
+		PC:       c.PC,
+		AC:       c.AC,
+		X:        c.X,
+		Y:        c.Y,
+		SP:       c.SP,
+		SR:       c.SR,
+		Cycles:   c.Cycles,
+		IRQ:      c.interrupts.irq,
+		IRQSince: c.interrupts.irqSince,
+		NMI:      c.interrupts.nmi,
+		NMISince: c.interrupts.nmiSince,
+	}
+}
+
+// SetState restores a State returned by State.
+func (c *Cpu) SetState(s State) {
+	c.PC, c.AC, c.X, c.Y, c.SP, c.SR = s.PC, s.AC, s.X, s.Y, s.SP, s.SR
+	c.Cycles = s.Cycles
+	c.interrupts = interruptState{
+		irq:      s.IRQ,
+		irqSince: s.IRQSince,
+		nmi:      s.NMI,
+		nmiSince: s.NMISince,
+	}
+}
@@ -0,0 +1,68 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/asm"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "asm",
+		aliases: []string{"a"},
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Assemble instructions into memory, e.g. asm $F000",
+		detail: "Each line entered is assembled and written at the next address, e.g. JMP $F000 or LDA ($10),Y.\n" +
+			"Operands may be expressions. A blank line or . ends assembly. ROM can be patched too.",
+		handler: (*Debugger).commandAsm,
+	})
+}
+
+func (d *Debugger) commandAsm(c *cmd, _ cpu.Instruction) (bool, error) {
+	addr, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+
+	assembler := &asm.Assembler{Resolve: d.asmOperand}
+
+	// Don't repeat assembly on a blank line
+	d.lastCmd = nil
+
+	for {
+		line, err := d.nextInput(fmt.Sprintf("$%04X asm> ", addr))
+		if err != nil {
+			return false, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || line == "." {
+			return false, nil
+		}
+
+		code, err := assembler.Assemble(addr, line)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for i, b := range code {
+			if err := d.cpu.Bus.Poke(addr+uint16(i), b); err != nil {
+				return false, err
+			}
+		}
+		addr += uint16(len(code))
+	}
+}
+
+// asmOperand evaluates an assembler operand. A bare register name is rejected
+// as it would assemble whatever the register happens to hold, e.g. LDA X.
+func (d *Debugger) asmOperand(operand string) (int, error) {
+	switch strings.ToUpper(operand) {
+	case "A", "AC", "X", "Y", "SP", "SR", "P", "PC", ".":
+		return 0, fmt.Errorf("Register %s is not a valid operand", operand)
+	}
+	return d.evaluate(operand, 0xFFFF)
+}
@@ -0,0 +1,42 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/asm"
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestAsmRejectsRegisters(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x1000), "ram", 0)
+
+	d := &Debugger{
+		cpu:     &cpu.Cpu{Bus: b, X: 3, Y: 4},
+		symbols: debugSymbols{{address: 0x0200, name: "buffer"}},
+	}
+	assembler := &asm.Assembler{Resolve: d.asmOperand}
+
+	for _, line := range []string{"LDA X", "STA y", "JMP PC", "LDA (X),Y", "LDX SP,Y", "JMP (.)"} {
+		if code, err := assembler.Assemble(0x0400, line); err == nil {
+			t.Error(fmt.Sprintf("expected %q to fail, got % X", line, code))
+		}
+	}
+
+	for line, expected := range map[string]string{
+		"LDA buffer,X": "BD 00 02",
+		"LDA #X+1":     "A9 04",
+		"ASL A":        "0A",
+	} {
+		code, err := assembler.Assemble(0x0400, line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprintf("% X", code); actual != expected {
+			t.Error(fmt.Sprintf("%s expected %s got %s", line, expected, actual))
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// maxFrames limits the shadow call stack, as code which never returns, e.g.
+// by resetting SP, would otherwise grow it without bound.
+const maxFrames = 256
+
+// frame is an entry in the shadow call stack: a subroutine call or an
+// interrupt which has not yet returned.
+type frame struct {
+	kind          string // JSR, BRK, IRQ or NMI
+	caller        uint16 // address of the JSR or interrupted instruction
+	target        uint16 // address of the subroutine or handler
+	returnAddress uint16
+	sp            byte // SP before the call, which returning restores
+}
+
+func init() {
+	commands.register(&command{
+		name:    "backtrace",
+		aliases: []string{"bt"},
+		summary: "Show the subroutines and interrupts leading to the current PC.",
+		detail: "Calls are tracked from when the debugger started. A frame is dropped once\n" +
+			"SP rises above where it was before the call, so code which discards return\n" +
+			"addresses is followed too.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			d.backtrace()
+			return false, nil
+		},
+	})
+}
+
+// trackCalls maintains the shadow call stack for the instruction about to
+// execute.
+func (d *Debugger) trackCalls(in cpu.Instruction) {
+	d.unwindFrames()
+
+	switch in.Name() {
+	case "JSR":
+		d.pushFrame(frame{
+			kind:          "JSR",
+			caller:        in.Address,
+			target:        in.Op16,
+			returnAddress: in.Address + 3,
+			sp:            d.cpu.SP,
+		})
+	case "BRK":
+		d.pushFrame(frame{
+			kind:          "BRK",
+			caller:        in.Address,
+			target:        d.peek16(0xFFFE),
+			returnAddress: in.Address + 2,
+			sp:            d.cpu.SP,
+		})
+	}
+}
+
+// AfterInterrupt records hardware interrupts in the shadow call stack, and
+// checks for breakpoints on them.
+func (d *Debugger) AfterInterrupt(source cpu.Interrupt, returnAddress uint16) {
+	d.unwindFrames()
+	d.pushFrame(frame{
+		kind:          source.String(),
+		caller:        returnAddress,
+		target:        d.cpu.PC,
+		returnAddress: returnAddress,
+		sp:            d.cpu.SP + 3,
+	})
+	d.interruptBreakpoints(source.String(), returnAddress)
+}
+
+func (d *Debugger) pushFrame(f frame) {
+	if len(d.frames) == maxFrames {
+		d.frames = d.frames[1:]
+	}
+	d.frames = append(d.frames, f)
+}
+
+// unwindFrames drops frames which have returned, i.e. SP is back at or above
+// where it was before the call.
+func (d *Debugger) unwindFrames() {
+	for len(d.frames) > 0 && d.frames[len(d.frames)-1].sp <= d.cpu.SP {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+}
+
+func (d *Debugger) backtrace() {
+	d.unwindFrames()
+
+	fmt.Printf("#0  $%04X%s\n", d.cpu.PC, d.labelSuffix(d.cpu.PC))
+	for i := len(d.frames) - 1; i >= 0; i-- {
+		f := d.frames[i]
+		fmt.Printf("#%-2d $%04X%s %s $%04X%s returns to $%04X\n",
+			len(d.frames)-i,
+			f.caller, d.labelSuffix(f.caller),
+			f.kind,
+			f.target, d.labelSuffix(f.target),
+			f.returnAddress)
+	}
+}
+
+// peek16 reads a little-endian word without side effects on devices.
+func (d *Debugger) peek16(addr uint16) uint16 {
+	return uint16(d.peek(addr+1))<<8 | uint16(d.peek(addr))
+}
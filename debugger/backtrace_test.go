@@ -0,0 +1,50 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestBacktraceFollowsCallsAndInterrupts(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{
+		0x20, 0x00, 0x20, // $1000 JSR $2000
+		0xEA, // $1003 NOP
+	})
+	b.WriteBlock(0x2000, []byte{
+		0xEA, // $2000 NOP
+		0x60, // $2001 RTS
+	})
+	b.WriteBlock(0x3000, []byte{0x40}) // $3000 RTI
+	b.WriteBlock(0xFFFC, []byte{0x00, 0x10, 0x00, 0x30})
+
+	c := &cpu.Cpu{Bus: b}
+	d := &Debugger{cpu: c, run: true}
+	c.AttachMonitor(d)
+	c.PowerOn()
+	c.SP = 0xFF
+	c.SR &^= 0x04 // CLI
+
+	c.Step() // JSR
+	c.Step() // NOP
+	c.SetIRQ(true)
+	c.Step() // IRQ
+	c.SetIRQ(false)
+
+	d.unwindFrames()
+	if len(d.frames) != 2 || d.frames[0].kind != "JSR" || d.frames[1].kind != "IRQ" || d.frames[1].caller != 0x2001 {
+		t.Fatal(fmt.Sprintf("expected JSR then IRQ at $2001 got %v", d.frames))
+	}
+
+	c.Step() // RTI
+	c.Step() // RTS
+	c.Step() // NOP
+	if len(d.frames) != 0 {
+		t.Error(fmt.Sprintf("expected no frames after returning got %v", d.frames))
+	}
+}
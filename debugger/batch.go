@@ -0,0 +1,77 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// batch holds the state of batch mode, where the debugger runs its queued
+// commands without a terminal then shuts down the emulator. The exit status
+// is non-zero if any command failed, including an assert.
+type batch struct {
+	enabled  bool
+	asserts  int
+	failures int
+	exited   bool
+}
+
+func init() {
+	commands.register(&command{
+		name:    "assert",
+		usage:   "<expression>",
+		minArgs: 1,
+		maxArgs: -1,
+		summary: "Fail unless an expression holds, e.g. assert A==$40 && [result]==1234",
+		detail: "A failed assertion is reported as an error, abandoning any queued commands.\n" +
+			"In batch mode the emulator then exits with a non-zero status.\n" +
+			"See help expressions.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.assert(strings.Join(c.arguments, " "))
+		},
+	})
+}
+
+// Batch runs the debugger without a terminal. Once the queued commands have
+// run, or exit is used, the emulator is shut down with a non-zero exit status
+// if any command or assertion failed.
+func (d *Debugger) Batch() {
+	d.batch.enabled = true
+	d.color = false
+}
+
+// assert returns an error if the expression evaluates to zero.
+func (d *Debugger) assert(s string) error {
+	eval, err := d.parseExpression(s)
+	if err != nil {
+		return err
+	}
+	d.batch.asserts++
+	if eval(d) == 0 {
+		return fmt.Errorf("Assertion failed at $%04X: %s", d.cpu.PC, s)
+	}
+	return nil
+}
+
+// failed records a failed command, which in batch mode fails the run.
+func (d *Debugger) failed() {
+	d.batch.failures++
+}
+
+// exit shuts down the emulator, reporting the result in batch mode.
+func (d *Debugger) exit() {
+	if d.batch.exited {
+		return
+	}
+	d.batch.exited = true
+
+	status := 0
+	if d.batch.enabled {
+		fmt.Printf("Batch complete: %d assertions, %d failures\n", d.batch.asserts, d.batch.failures)
+		if d.batch.failures > 0 {
+			status = 1
+		}
+	}
+	d.cpu.ExitChan <- status
+}
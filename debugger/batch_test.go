@@ -0,0 +1,34 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestBatchExitsWithFailedAssertion(t *testing.T) {
+	for _, test := range []struct {
+		commands []string
+		status   int
+	}{
+		{[]string{"assert A==1", "assert X==0 && A!=0"}, 0},
+		{[]string{"assert A==2", "assert A==1"}, 1},
+		{[]string{"bogus"}, 1},
+	} {
+		c := &cpu.Cpu{AC: 1, ExitChan: make(chan int, 1)}
+		d := &Debugger{cpu: c}
+		d.Batch()
+		d.QueueCommands(test.commands)
+
+		for !d.commandLoop(cpu.Instruction{}) {
+			// until the queue is empty
+		}
+		if !d.run {
+			t.Error(fmt.Sprintf("%v: expected execution to continue once exited", test.commands))
+		}
+		if status := <-c.ExitChan; status != test.status {
+			t.Error(fmt.Sprintf("%v: expected exit status %d got %d", test.commands, test.status, status))
+		}
+	}
+}
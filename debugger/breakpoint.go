@@ -0,0 +1,363 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// Kinds of breakpoint.
+const (
+	breakAddress = iota
+	breakInstruction
+	breakRegister
+	breakInterrupt
+	breakDevice
+)
+
+// interruptVectors are the vectors taken by each interrupt.
+var interruptVectors = map[string]uint16{
+	"NMI": 0xFFFA,
+	"IRQ": 0xFFFE,
+	"BRK": 0xFFFE,
+}
+
+// breakpoint stops execution before an instruction when its PC, mnemonic or
+// a register value matches, and its condition if any holds. A tracepoint is
+// an address breakpoint which prints a message and continues instead. A
+// device breakpoint stops after an instruction accessing a device.
+type breakpoint struct {
+	id          int
+	kind        int
+	enabled     bool
+	address     uint16
+	instruction string
+	register    string // A, X, Y, SP, SR or a flag N V B D I Z C
+	compare     string // comparison operator, e.g. == or >=
+	value       byte
+	interrupt   string        // IRQ, NMI or BRK
+	region      bus.Region    // device accessed
+	access      bus.Access    // type of device access
+	watchId     int           // bus watch of a device breakpoint
+	source      string        // file:line the address was given as, if any
+	message     *traceMessage // printed instead of stopping by a tracepoint
+	cond        *condition
+	hits        int // times matched, including those ignored
+	ignore      int // hits still to ignore
+}
+
+func (b *breakpoint) String() string {
+	var s string
+	switch b.kind {
+	case breakAddress:
+		s = fmt.Sprintf("PC address = $%04X", b.address)
+		if b.source != "" {
+			s = fmt.Sprintf("%s ($%04X)", b.source, b.address)
+		}
+		if b.message != nil {
+			s = fmt.Sprintf("trace %s %q", s, b.message.text)
+		}
+	case breakInstruction:
+		s = "instruction " + b.instruction
+	case breakRegister:
+		op := b.compare
+		if op == "==" || op == "" {
+			op = "="
+		}
+		s = fmt.Sprintf("%s %s $%02X (%d)", b.register, op, b.value, b.value)
+	case breakInterrupt:
+		s = b.interrupt
+	case breakDevice:
+		s = fmt.Sprintf("%v device %s", b.access, b.region.Name)
+	}
+	return s + b.cond.describe()
+}
+
+// matches returns true if the breakpoint is hit by the instruction about to
+// execute.
+func (b *breakpoint) matches(d *Debugger, in cpu.Instruction) bool {
+	if !b.enabled {
+		return false
+	}
+
+	switch b.kind {
+	case breakAddress:
+		if d.cpu.PC != b.address {
+			return false
+		}
+	case breakInstruction:
+		if in.Name() != b.instruction {
+			return false
+		}
+	case breakRegister:
+		if !compareOperators[b.compare](d.register(b.register), b.value) {
+			return false
+		}
+	case breakInterrupt:
+		// Matched by interruptBreakpoints when the interrupt is serviced
+		return false
+	case breakDevice:
+		// Matched by the bus watch when the device is accessed
+		return false
+	}
+	return b.cond.holds(d)
+}
+
+// hit counts a match, returning false if it is to be ignored.
+func (b *breakpoint) hit() bool {
+	b.hits++
+	if b.ignore > 0 {
+		b.ignore--
+		return false
+	}
+	return true
+}
+
+// compareOperators are the comparisons a register breakpoint may make.
+var compareOperators = map[string]func(a, b byte) bool{
+	"":   func(a, b byte) bool { return a == b },
+	"==": func(a, b byte) bool { return a == b },
+	"!=": func(a, b byte) bool { return a != b },
+	"<":  func(a, b byte) bool { return a < b },
+	"<=": func(a, b byte) bool { return a <= b },
+	">":  func(a, b byte) bool { return a > b },
+	">=": func(a, b byte) bool { return a >= b },
+}
+
+// register returns the value of a register by name, or 0 or 1 for a flag.
+func (d *Debugger) register(name string) byte {
+	switch name {
+	case "X":
+		return d.cpu.X
+	case "Y":
+		return d.cpu.Y
+	case "SP":
+		return d.cpu.SP
+	case "SR":
+		return d.cpu.SR
+	case "A":
+		return d.cpu.AC
+	default:
+		return d.cpu.SR >> flagBits[strings.ToLower(name)] & 1
+	}
+}
+
+// addBreakpoint adds an enabled breakpoint, assigning its id, unless it
+// duplicates an existing one when it returns false.
+func (d *Debugger) addBreakpoint(b *breakpoint) bool {
+	for _, e := range d.breakpoints {
+		if e.String() == b.String() {
+			fmt.Printf("Breakpoint %d already set: %v\n", e.id, e)
+			return false
+		}
+	}
+	d.breakpointId++
+	b.id = d.breakpointId
+	b.enabled = true
+	d.breakpoints = append(d.breakpoints, b)
+	fmt.Printf("Breakpoint %d set: %v\n", b.id, b)
+	return true
+}
+
+// removeBreakpoints deletes all the breakpoints.
+func (d *Debugger) removeBreakpoints() {
+	for _, b := range d.breakpoints {
+		d.unwatchBreakpoint(b)
+	}
+	d.breakpoints = nil
+}
+
+// unwatchBreakpoint removes the bus watch of a device breakpoint.
+func (d *Debugger) unwatchBreakpoint(b *breakpoint) {
+	if b.watchId != 0 {
+		d.cpu.Bus.Unwatch(b.watchId)
+		b.watchId = 0
+	}
+}
+
+// breakpoint returns the breakpoint with the id in s.
+func (d *Debugger) breakpoint(s string) (*breakpoint, error) {
+	id, err := strconv.Atoi(s)
+	if err == nil {
+		for _, b := range d.breakpoints {
+			if b.id == id {
+				return b, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("No breakpoint %s", s)
+}
+
+// interruptBreakpoints stops execution if there is a breakpoint for an
+// interrupt which has just been serviced, with PC at the handler.
+func (d *Debugger) interruptBreakpoints(source string, returnAddress uint16) {
+	for _, b := range d.breakpoints {
+		if b.enabled && b.kind == breakInterrupt && b.interrupt == source && b.cond.holds(d) && b.hit() {
+			vector := interruptVectors[source]
+			fmt.Printf("Breakpoint %d for %s via $%04X to $%04X%s, interrupted $%04X%s\n",
+				b.id, b.String(), vector, d.cpu.PC, d.labelSuffix(d.cpu.PC),
+				returnAddress, d.labelSuffix(returnAddress))
+			d.run = false
+		}
+	}
+}
+
+// brkBreakpoints detects BRK being serviced, which happens as the BRK
+// instruction executes so is seen at the following instruction.
+func (d *Debugger) brkBreakpoints(in cpu.Instruction) {
+	if d.brkPending {
+		d.brkPending = false
+		d.interruptBreakpoints("BRK", d.brkAddress)
+	}
+	if in.Name() == "BRK" {
+		d.brkPending = true
+		d.brkAddress = in.Address
+	}
+}
+
+func init() {
+	for source, summary := range map[string]string{
+		"IRQ": "Break when an IRQ is serviced.",
+		"NMI": "Break when an NMI is serviced.",
+		"BRK": "Break when a BRK instruction is serviced.",
+	} {
+		source := source
+		commands.register(&command{
+			name:        "break-" + strings.ToLower(source),
+			usage:       "[if <condition>]",
+			conditional: true,
+			summary:     summary,
+			detail: "Stops at the first instruction of the handler, showing the vector taken and the interrupted PC.\n" +
+				conditionHelp,
+			handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+				cond, err := d.parseCondition(c.condition)
+				if err != nil {
+					return false, err
+				}
+				d.addBreakpoint(&breakpoint{kind: breakInterrupt, interrupt: source, cond: cond})
+				return false, nil
+			},
+		})
+	}
+
+	commands.register(&command{
+		name:    "breakpoints",
+		aliases: []string{"bl"},
+		summary: "List the breakpoints.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			d.listBreakpoints()
+			return false, nil
+		},
+	})
+	commands.register(&command{
+		name:    "delete",
+		aliases: []string{"del"},
+		usage:   "<id>|all",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Delete a breakpoint, or all of them.",
+		handler: (*Debugger).commandDelete,
+	})
+	commands.register(&command{
+		name:    "enable",
+		usage:   "<id>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Enable a disabled breakpoint.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.enableBreakpoint(c.arguments[0], true)
+		},
+	})
+	commands.register(&command{
+		name:    "ignore",
+		usage:   "<id> <count>",
+		minArgs: 2,
+		maxArgs: 2,
+		summary: "Ignore the next count hits of a breakpoint, e.g. ignore 1 499",
+		detail:  "Ignored hits are still counted in the breakpoint list. A count of 0 stops ignoring.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.ignoreBreakpoint(c.arguments[0], c.arguments[1])
+		},
+	})
+	commands.register(&command{
+		name:    "disable",
+		usage:   "<id>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Disable a breakpoint without deleting it.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.enableBreakpoint(c.arguments[0], false)
+		},
+	})
+}
+
+func (d *Debugger) listBreakpoints() {
+	if len(d.breakpoints) == 0 {
+		fmt.Println("No breakpoints.")
+		return
+	}
+	for _, b := range d.breakpoints {
+		state := "enabled"
+		if !b.enabled {
+			state = "disabled"
+		}
+		ignore := ""
+		if b.ignore > 0 {
+			ignore = fmt.Sprintf(", ignoring next %d", b.ignore)
+		}
+		fmt.Printf("%3d %-8s %v (hits %d%s)\n", b.id, state, b, b.hits, ignore)
+	}
+}
+
+func (d *Debugger) commandDelete(c *cmd, _ cpu.Instruction) (bool, error) {
+	if c.arguments[0] == "all" {
+		d.removeBreakpoints()
+		fmt.Println("All breakpoints deleted")
+		return false, nil
+	}
+
+	b, err := d.breakpoint(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+	for i, e := range d.breakpoints {
+		if e == b {
+			d.unwatchBreakpoint(b)
+			d.breakpoints = append(d.breakpoints[:i:i], d.breakpoints[i+1:]...)
+			break
+		}
+	}
+	fmt.Printf("Breakpoint %d deleted\n", b.id)
+	return false, nil
+}
+
+func (d *Debugger) enableBreakpoint(id string, enabled bool) error {
+	b, err := d.breakpoint(id)
+	if err != nil {
+		return err
+	}
+	b.enabled = enabled
+	if enabled {
+		fmt.Printf("Breakpoint %d enabled\n", b.id)
+	} else {
+		fmt.Printf("Breakpoint %d disabled\n", b.id)
+	}
+	return nil
+}
+
+func (d *Debugger) ignoreBreakpoint(id, count string) error {
+	b, err := d.breakpoint(id)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return fmt.Errorf("Invalid count %q", count)
+	}
+	b.ignore = n
+	fmt.Printf("Breakpoint %d will ignore the next %d hits\n", b.id, n)
+	return nil
+}
@@ -0,0 +1,177 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestBreakpointsEnableDisableDelete(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{PC: 0x1000}, run: true}
+	d.addBreakpoint(&breakpoint{kind: breakAddress, address: 0x1000})
+	d.addBreakpoint(&breakpoint{kind: breakAddress, address: 0x2000})
+
+	d.doBreakpoints(cpu.Instruction{})
+	if d.run {
+		t.Error("expected breakpoint 1 to stop execution")
+	}
+
+	if err := d.enableBreakpoint("1", false); err != nil {
+		t.Fatal(err)
+	}
+	d.run = true
+	d.doBreakpoints(cpu.Instruction{})
+	if !d.run {
+		t.Error("expected disabled breakpoint to be ignored")
+	}
+
+	if _, err := d.commandDelete(&cmd{arguments: []string{"1"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.breakpoints) != 1 || d.breakpoints[0].id != 2 {
+		t.Error("expected only breakpoint 2 to remain")
+	}
+	if err := d.enableBreakpoint("1", true); err == nil {
+		t.Error("expected deleted breakpoint to be unknown")
+	}
+}
+
+func TestBreakOnInterrupts(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{0xEA, 0x00, 0x00}) // NOP, BRK
+	b.WriteBlock(0xF000, []byte{0xEA})             // handler
+	b.WriteBlock(0xFFFA, []byte{0x00, 0xF0, 0x00, 0x10, 0x00, 0xF0})
+
+	c := &cpu.Cpu{Bus: b}
+	d := &Debugger{cpu: c, run: true}
+	c.AttachMonitor(d)
+	c.PowerOn()
+	d.addBreakpoint(&breakpoint{kind: breakInterrupt, interrupt: "NMI"})
+	d.addBreakpoint(&breakpoint{kind: breakInterrupt, interrupt: "BRK"})
+
+	c.NMI()
+	c.Step()
+	if d.run || c.PC != 0xF000 {
+		t.Error(fmt.Sprintf("expected NMI to break at $F000 got $%04X", c.PC))
+	}
+
+	d.run = true
+	c.PC = 0x1001
+	c.Step() // BRK
+	d.QueueCommands([]string{"step"})
+	c.Step() // handler, stopping at the prompt
+	if d.run {
+		t.Error("expected BRK to break")
+	}
+}
+
+func TestBreakpointIgnoreCount(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{PC: 0x1000}, run: true}
+	d.addBreakpoint(&breakpoint{kind: breakAddress, address: 0x1000})
+	if err := d.ignoreBreakpoint("1", "2"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		d.doBreakpoints(cpu.Instruction{})
+		if d.run != (i < 3) {
+			t.Error(fmt.Sprintf("hit %d: expected run %v", i, i < 3))
+		}
+	}
+	if b := d.breakpoints[0]; b.hits != 3 || b.ignore != 0 {
+		t.Error(fmt.Sprintf("expected 3 hits and none to ignore got %d and %d", b.hits, b.ignore))
+	}
+
+	if err := d.ignoreBreakpoint("1", "-1"); err == nil {
+		t.Error("expected negative count to fail")
+	}
+}
+
+func TestBreakRegisterComparisons(t *testing.T) {
+	c := &cpu.Cpu{SP: 0x30, SR: 0x01, X: 0x80}
+	d := &Debugger{cpu: c, run: true}
+
+	for _, test := range []struct {
+		input string
+		hit   bool
+	}{
+		{"br x >= $80", true},
+		{"br x > $80", false},
+		{"br sp < $20", false},
+		{"br sp <= $30", true},
+		{"br c 1", true},
+		{"br z 1", false},
+		{"br sr != 1", false},
+	} {
+		d.breakpoints = nil
+		args := strings.Fields(test.input)[1:]
+		if _, err := d.commandBreakRegister(&cmd{arguments: args}, cpu.Instruction{}); err != nil {
+			t.Fatal(err)
+		}
+		d.run = true
+		d.doBreakpoints(cpu.Instruction{})
+		if d.run == test.hit {
+			t.Error(fmt.Sprintf("%s: expected hit %v", test.input, test.hit))
+		}
+	}
+
+	for _, bad := range []string{"br x =< 1", "br q 1", "br c 2"} {
+		args := strings.Fields(bad)[1:]
+		if _, err := d.commandBreakRegister(&cmd{arguments: args}, cpu.Instruction{}); err == nil {
+			t.Error(fmt.Sprintf("expected %s to fail", bad))
+		}
+	}
+}
+
+func TestMultipleBreakpointsInOneCommand(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{PC: 0x1000}, run: true}
+	bi := &cmd{command: commands.lookup("bi"), arguments: []string{"brk", "RTI"}}
+	if _, err := bi.command.handler(d, bi, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.commandBreakAddress(&cmd{arguments: []string{"$1000", "$2000"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	// Duplicates are not added again
+	if _, err := d.commandBreakAddress(&cmd{arguments: []string{"$2000"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.breakpoints) != 4 {
+		t.Fatal(fmt.Sprintf("expected 4 breakpoints got %d", len(d.breakpoints)))
+	}
+	if d.breakpoints[1].instruction != "RTI" || d.breakpoints[3].address != 0x2000 {
+		t.Error(fmt.Sprintf("unexpected breakpoints %v %v", d.breakpoints[1], d.breakpoints[3]))
+	}
+
+	// An invalid address adds none of them
+	if _, err := d.commandBreakAddress(&cmd{arguments: []string{"$3000", "$FFFFF"}}, cpu.Instruction{}); err == nil {
+		t.Error("expected invalid address to fail")
+	}
+	if len(d.breakpoints) != 4 {
+		t.Error("expected no breakpoints added by the failed command")
+	}
+
+	// Nor does an unknown mnemonic or a bad condition
+	for _, bad := range []*cmd{
+		{arguments: []string{"NOP", "LDZ"}},
+		{arguments: []string{"NOP", "RTS"}, condition: "X =="},
+	} {
+		bad.command = commands.lookup("bi")
+		if _, err := bad.command.handler(d, bad, cpu.Instruction{}); err == nil {
+			t.Error(fmt.Sprintf("expected bi %v to fail", bad.arguments))
+		}
+	}
+	if len(d.breakpoints) != 4 {
+		t.Error(fmt.Sprintf("expected no breakpoints added by the failed commands got %d", len(d.breakpoints)))
+	}
+
+	d.doBreakpoints(cpu.Instruction{})
+	if d.run || d.breakpoints[2].hits != 1 || d.breakpoints[3].hits != 0 {
+		t.Error("expected only the breakpoint at $1000 to be hit")
+	}
+}
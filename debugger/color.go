@@ -0,0 +1,91 @@
+package debugger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used when color is enabled.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiChanged = "\x1b[1;33m" // bold yellow
+	ansiSymbol  = "\x1b[36m"   // cyan
+	ansiName    = "\x1b[2m"    // dim
+)
+
+// registers is a snapshot of the cpu registers, to highlight those which
+// changed between stops.
+type registers struct {
+	pc           uint16
+	ac, x, y, sp uint8
+	sr           uint8
+}
+
+func (d *Debugger) registers() registers {
+	c := d.cpu
+	return registers{pc: c.PC, ac: c.AC, x: c.X, y: c.Y, sp: c.SP, sr: c.SR}
+}
+
+// isTerminal returns true if f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in an ANSI color if color is enabled.
+func (d *Debugger) paint(color, s string) string {
+	if !d.color || s == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// registerDump formats the registers as cpu.Cpu.String() does, highlighting
+// registers and flags which changed since the previous stop.
+func (d *Debugger) registerDump() string {
+	r, last := d.registers(), d.lastStop
+	value := func(name, v string, changed bool) string {
+		if changed {
+			v = d.paint(ansiChanged, v)
+		}
+		return d.paint(ansiName, name+":") + v
+	}
+
+	const chars = "nv_bdizc"
+	flags := make([]string, 8)
+	for i := range flags {
+		bit := uint8(1) << uint(7-i)
+		flag := "-"
+		if r.sr&bit != 0 {
+			flag = chars[i : i+1]
+		}
+		if r.sr&bit != last.sr&bit {
+			flag = d.paint(ansiChanged, flag)
+		}
+		flags[i] = flag
+	}
+
+	return strings.Join([]string{
+		"CPU",
+		value("PC", fmt.Sprintf("0x%04X", r.pc), r.pc != last.pc),
+		value("AC", fmt.Sprintf("0x%02X", r.ac), r.ac != last.ac),
+		value("X", fmt.Sprintf("0x%02X", r.x), r.x != last.x),
+		value("Y", fmt.Sprintf("0x%02X", r.y), r.y != last.y),
+		value("SP", fmt.Sprintf("0x%02X", r.sp), r.sp != last.sp),
+		d.paint(ansiName, "SR:") + strings.Join(flags, ""),
+	}, " ")
+}
+
+// setColor handles set color on|off.
+func (d *Debugger) setColor(state string) error {
+	switch strings.ToLower(state) {
+	case "on":
+		d.color = true
+	case "off":
+		d.color = false
+	default:
+		return fmt.Errorf("Invalid color setting %q, expected on or off", state)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestRegisterDumpHighlightsChanges(t *testing.T) {
+	c := &cpu.Cpu{PC: 0x1000, AC: 0x01, SR: 0x20}
+	d := &Debugger{cpu: c}
+	d.lastStop = d.registers()
+
+	expected := c.String()
+	if actual := d.registerDump(); actual != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, actual))
+	}
+
+	if err := d.setColor("on"); err != nil {
+		t.Fatal(err)
+	}
+	c.AC = 0x02
+	c.SR |= 0x01
+	expected = ansiName + "PC:" + ansiReset + "0x1000"
+	actual := d.registerDump()
+	if !strings.Contains(actual, expected) {
+		t.Error(fmt.Sprintf("expected unchanged PC %q in %q", expected, actual))
+	}
+	for _, changed := range []string{ansiChanged + "0x02" + ansiReset, ansiChanged + "c" + ansiReset} {
+		if !strings.Contains(actual, changed) {
+			t.Error(fmt.Sprintf("expected %q to be highlighted in %q", changed, actual))
+		}
+	}
+
+	if err := d.setColor("blue"); err == nil {
+		t.Error("expected invalid color setting to fail")
+	}
+}
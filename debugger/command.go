@@ -0,0 +1,164 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// command describes a debugger command: how it is invoked, its help text and
+// the handler which implements it.
+type command struct {
+	// name is the canonical name of the command, e.g. "break-address".
+	name string
+
+	// aliases are alternative names, e.g. "ba".
+	aliases []string
+
+	// usage describes the arguments, e.g. "<address>".
+	usage string
+
+	// minArgs and maxArgs bound the number of arguments accepted.
+	// maxArgs of -1 means no upper limit.
+	minArgs int
+	maxArgs int
+
+	// conditional commands accept a trailing "if <condition>", which is
+	// removed from the arguments before they are validated.
+	conditional bool
+
+	// summary is the one-line description shown by help.
+	summary string
+
+	// detail is the extended description shown by help <command>.
+	detail string
+
+	// handler runs the command. Returns true when control is to be released
+	// back to the cpu.
+	handler func(d *Debugger, c *cmd, in cpu.Instruction) (release bool, err error)
+}
+
+// cmd is an invocation of a command.
+type cmd struct {
+	command   *command
+	input     string
+	arguments []string
+	condition string
+}
+
+// commandRegistry holds the available commands, indexed by name and alias.
+type commandRegistry struct {
+	commands []*command
+	index    map[string]*command
+}
+
+// commands is the registry of all debugger commands. Commands register
+// themselves from init() in the file which implements them.
+var commands = &commandRegistry{index: make(map[string]*command)}
+
+// register adds a command to the registry, panicking if the name or one of
+// its aliases is already taken.
+func (r *commandRegistry) register(c *command) {
+	for _, name := range append([]string{c.name}, c.aliases...) {
+		if _, exists := r.index[name]; exists {
+			panic(fmt.Sprintf("debugger command %q registered twice", name))
+		}
+		r.index[name] = c
+	}
+	r.commands = append(r.commands, c)
+	sort.Slice(r.commands, func(i, j int) bool {
+		return r.commands[i].name < r.commands[j].name
+	})
+}
+
+// lookup returns the command with the given name or alias, or nil.
+func (r *commandRegistry) lookup(name string) *command {
+	return r.index[strings.ToLower(name)]
+}
+
+// names returns all command names and aliases, for tab completion.
+func (r *commandRegistry) names() (names []string) {
+	for name := range r.index {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// validate checks the number of arguments passed to a command.
+func (c *command) validate(arguments []string) error {
+	if len(arguments) < c.minArgs || (c.maxArgs >= 0 && len(arguments) > c.maxArgs) {
+		return fmt.Errorf("Usage: %s", c.synopsis())
+	}
+	return nil
+}
+
+// synopsis returns the command name followed by its usage.
+func (c *command) synopsis() string {
+	if c.usage == "" {
+		return c.name
+	}
+	return c.name + " " + c.usage
+}
+
+func (c *command) aliasString() string {
+	if len(c.aliases) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (alias: %s)", strings.Join(c.aliases, ", "))
+}
+
+func init() {
+	commands.register(&command{
+		name:    "help",
+		aliases: []string{"h", "?"},
+		usage:   "[command]",
+		maxArgs: 1,
+		summary: "This help, or detailed help for a command.",
+		detail:  "With no arguments lists all commands.\nWith a command name or alias shows the usage of that command.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			if len(c.arguments) == 1 {
+				return false, commandHelpFor(c.arguments[0])
+			}
+			commandHelp()
+			return false, nil
+		},
+	})
+}
+
+func commandHelp() {
+	fmt.Println("")
+	fmt.Println("pda6502 debuger")
+	fmt.Println("---------------")
+	for _, c := range commands.commands {
+		fmt.Printf("%s%s %s\n", c.synopsis(), c.aliasString(), c.summary)
+	}
+	fmt.Println("(blank) Repeat the previous command.")
+	fmt.Println("")
+	fmt.Println("Hex input formats: 0x1234 $1234")
+	fmt.Println("Addresses and values may be expressions, e.g. label+5, . for the PC.")
+	fmt.Println("help <command> shows detailed help for a command, help expressions the syntax.")
+}
+
+func commandHelpFor(name string) error {
+	if strings.EqualFold(name, "expressions") {
+		fmt.Println(expressionHelp)
+		return nil
+	}
+
+	c := commands.lookup(name)
+	if c == nil {
+		return fmt.Errorf("Unknown command %q", name)
+	}
+	fmt.Printf("Usage: %s\n", c.synopsis())
+	if len(c.aliases) > 0 {
+		fmt.Printf("Aliases: %s\n", strings.Join(c.aliases, ", "))
+	}
+	fmt.Println(c.summary)
+	if c.detail != "" {
+		fmt.Println(c.detail)
+	}
+	return nil
+}
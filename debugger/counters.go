@@ -0,0 +1,104 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// counters counts the instructions executed, for reporting at each stop and
+// for run-for.
+type counters struct {
+	reached      uint64 // instructions reached, including the current one
+	lastExecuted uint64 // executed at the previous stop
+	lastCycles   uint64
+	runFor       bool
+	runForCycles bool
+	runForTarget uint64
+}
+
+func init() {
+	commands.register(&command{
+		name:    "run-for",
+		usage:   "<count> [instructions|cycles]",
+		minArgs: 1,
+		maxArgs: 2,
+		summary: "Run for exactly count instructions or at least count cycles, e.g. run-for 1000 cycles",
+		detail: "Stops at the first instruction after the count is reached, or earlier at a breakpoint.\n" +
+			"As instructions take several cycles a count of cycles may be exceeded by a few.",
+		handler: (*Debugger).commandRunFor,
+	})
+}
+
+// executed is the number of instructions executed. The current instruction
+// has been reached but not yet executed.
+func (c *counters) executed() uint64 {
+	if c.reached == 0 {
+		return 0
+	}
+	return c.reached - 1
+}
+
+// countInstruction counts the instruction about to execute, stopping if the
+// run-for count has been reached.
+func (d *Debugger) countInstruction() {
+	c := &d.counters
+	c.reached++
+
+	if c.runFor {
+		current := c.executed()
+		if c.runForCycles {
+			current = d.cpu.Cycles
+		}
+		if current >= c.runForTarget {
+			c.runFor = false
+			d.run = false
+		}
+	}
+}
+
+// abandonInstruction uncounts instructions which will not execute, e.g. when
+// the PC is moved.
+func (d *Debugger) abandonInstruction(n uint64) {
+	if n > d.counters.reached {
+		n = d.counters.reached
+	}
+	d.counters.reached -= n
+}
+
+// showCounters prints the totals, and the change since the previous stop.
+func (d *Debugger) showCounters() {
+	c := &d.counters
+	executed, cycles := c.executed(), d.cpu.Cycles
+	fmt.Printf("Instructions %d (+%d) Cycles %d (+%d)\n",
+		executed, executed-c.lastExecuted, cycles, cycles-c.lastCycles)
+	c.lastExecuted, c.lastCycles = executed, cycles
+}
+
+func (d *Debugger) commandRunFor(c *cmd, _ cpu.Instruction) (bool, error) {
+	n, err := strconv.ParseUint(c.arguments[0], 10, 64)
+	if err != nil || n < 1 {
+		return false, fmt.Errorf("Invalid count %q", c.arguments[0])
+	}
+
+	unit := "instructions"
+	if len(c.arguments) > 1 {
+		unit = strings.ToLower(c.arguments[1])
+	}
+	switch unit {
+	case "instructions", "instruction", "i":
+		d.counters.runForCycles = false
+		d.counters.runForTarget = d.counters.executed() + n
+	case "cycles", "cycle", "c":
+		d.counters.runForCycles = true
+		d.counters.runForTarget = d.cpu.Cycles + n
+	default:
+		return false, fmt.Errorf("Invalid unit %q, expected instructions or cycles", c.arguments[1])
+	}
+
+	d.counters.runFor = true
+	d.run = true
+	return true, nil
+}
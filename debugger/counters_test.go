@@ -0,0 +1,47 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestRunForInstructions(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	d.countInstruction() // stopped at the first instruction
+
+	if _, err := d.commandRunFor(&cmd{arguments: []string{"3"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 3; i++ {
+		d.countInstruction()
+		if d.run != (i < 3) {
+			t.Error(fmt.Sprintf("instruction %d: expected run %v", i, i < 3))
+		}
+	}
+	if executed := d.counters.executed(); executed != 3 {
+		t.Error(fmt.Sprintf("expected 3 instructions executed got %d", executed))
+	}
+}
+
+func TestRunForCycles(t *testing.T) {
+	c := &cpu.Cpu{Cycles: 100}
+	d := &Debugger{cpu: c}
+	if _, err := d.commandRunFor(&cmd{arguments: []string{"10", "cycles"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, cycles := range []uint64{104, 108, 111} {
+		c.Cycles = cycles
+		d.countInstruction()
+		if d.run != (cycles < 110) {
+			t.Error(fmt.Sprintf("at %d cycles: expected run %v", cycles, cycles < 110))
+		}
+	}
+
+	for _, bad := range [][]string{{"0"}, {"x"}, {"5", "seconds"}} {
+		if _, err := d.commandRunFor(&cmd{arguments: bad}, cpu.Instruction{}); err == nil {
+			t.Error(fmt.Sprintf("expected %v to fail", bad))
+		}
+	}
+}
@@ -13,26 +13,27 @@
 		CPU PC:0xF320 AC:0x00 X:0x00 Y:0x00 SP:0x00 SR:--_b----
 		Next: LDX immediate $FF
 		$F320> break-register X $FF
-		Breakpoint set: X = $FF (255)
+		Breakpoint 1 set: X = $FF (255)
 		$F320> continue
-		Breakpoint for X = $FF (255)
+		Breakpoint 1 for X = $FF (255)
 		CPU PC:0xF322 AC:0x00 X:0xFF Y:0x00 SP:0x00 SR:n-_b----
 		Next: TXS implied
 		$F322> step
-		Breakpoint for X = $FF (255)
+		Breakpoint 1 for X = $FF (255)
 		CPU PC:0xF323 AC:0x00 X:0xFF Y:0x00 SP:0xFF SR:n-_b----
 		Next: CLI implied
 		$F323>
-		Breakpoint for X = $FF (255)
+		Breakpoint 1 for X = $FF (255)
 		CPU PC:0xF324 AC:0x00 X:0xFF Y:0x00 SP:0xFF SR:n-_b-i--
 		Next: CLD implied
 		$F324>
-		Breakpoint for X = $FF (255)
+		Breakpoint 1 for X = $FF (255)
 		CPU PC:0xF325 AC:0x00 X:0xFF Y:0x00 SP:0xFF SR:n-_b-i--
 		Next: JMP absolute $F07B
 		$F325> break-instruction nop
+		Breakpoint 2 set: instruction NOP
 		$F325> r
-		Breakpoint for X = $FF (255)
+		Breakpoint 1 for X = $FF (255)
 		CPU PC:0xF07B AC:0x00 X:0xFF Y:0x00 SP:0xFF SR:n-_b-i--
 		Next: LDA immediate $00
 		$F07B> q
@@ -41,83 +42,108 @@ package debugger
 
 /**
  * TODO:
- * -  Command argument validation.
- * -  Handle missing/multiple labels when entering address.
- * -  Resolve addresses to symbols non-absolute instructions, e.g. branch.
- * -  Tab completion from commands, not just debug symbols.
  * -  `step n` e.g. `step 100` to step 100 instructions.
- * -  Read and write CLI history file.
  */
 
 import (
+	"bufio"
 	"fmt"
-	"strconv"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
 	"github.com/peterh/liner"
 )
 
-const (
-	debugCmdNone = iota
-	debugCmdBreakAddress
-	debugCmdBreakInstruction
-	debugCmdBreakRegister
-	debugCmdContinue
-	debugCmdExit
-	debugCmdHelp
-	debugCmdInvalid
-	debugCmdNext
-	debugCmdRead
-	debugCmdRead16
-	debugCmdRead32
-	debugCmdStep
-)
-
 type Debugger struct {
-	symbols           debugSymbols
-	inputQueue        []string
-	cpu               *cpu.Cpu
-	liner             *liner.State
-	lastCmd           *cmd
-	run               bool
-	breakAddress      bool
-	breakAddressValue uint16
-	breakInstruction  string
-	breakRegA         bool
-	breakRegAValue    byte
-	breakRegX         bool
-	breakRegXValue    byte
-	breakRegY         bool
-	breakRegYValue    byte
-}
-
-type cmd struct {
-	id        int
-	input     string
-	arguments []string
+	symbols          debugSymbols
+	inputQueue       []string
+	cpu              *cpu.Cpu
+	liner            *liner.State
+	lastCmd          *cmd
+	run              bool
+	breakpoints      []*breakpoint
+	breakpointId     int
+	tempBreak        bool
+	tempBreakAddress uint16
+	finishing        bool
+	finishDepth      int
+	finishReturned   bool
+	frames           []frame
+	traceFile        *os.File
+	trace            *bufio.Writer
+	historyFile      string
+	rewinder         rewinder
+	profiler         profiler
+	sortedSymbols    debugSymbols
+	brkPending       bool
+	brkAddress       uint16
+	displays         []*display
+	displayId        int
+	lastDisasm       *cmd
+	disasmNext       uint16
+	watchpoints      map[uint16]*watchpoint
+	prompting        bool
+	color            bool
+	lastStop         registers
+	batch            batch
+	sources          *sourceMap
+	web              *webServer
+	terminal         chan terminalInput // pending read while serving the web UI
+	last             lastRing
+	counters         counters
+	memDiffs         []*memDiff
+	macros           map[string]string // alias commands by name
+	symbolFile       string
+	symbolFormat     string
+	reload           func(config bool) error
 }
 
-// NewDebugger creates a debugger.
+// NewDebugger creates a debugger, loading symbols from symbolFile if set.
+// symbolFormat is dbg, vice or map, or "" to detect it from the file.
 // Be sure to defer a call to Debugger.Shutdown() afterwards, or your terminal
 // will be left in a broken state.
-func NewDebugger(cpu *cpu.Cpu, debugFile string) *Debugger {
-	var symbols debugSymbols
-	if len(debugFile) > 0 {
+func NewDebugger(cpu *cpu.Cpu, symbolFile, symbolFormat string) *Debugger {
+	d := &Debugger{
+		liner:        liner.NewLiner(),
+		cpu:          cpu,
+		symbolFile:   symbolFile,
+		symbolFormat: symbolFormat,
+		historyFile:  historyPath(),
+		color:        isTerminal(os.Stdout),
+	}
+	d.loadSymbols()
+	d.readHistory()
+
+	if _, err := os.Stat(sessionFile); err == nil {
+		fmt.Println("Loading session from", sessionFile)
+		if err := d.Source(sessionFile); err != nil {
+			fmt.Println(err)
+		}
+	}
+	return d
+}
+
+// loadSymbols reads the symbols, and source lines from an ld65 debug file.
+// A bad symbol file shouldn't stop the emulator, so it continues without.
+func (d *Debugger) loadSymbols() {
+	d.symbols, d.sources, d.sortedSymbols = nil, nil, nil
+	if len(d.symbolFile) > 0 {
 		var err error
-		symbols, err = readDebugSymbols(debugFile)
+		d.symbols, err = readSymbols(d.symbolFile, d.symbolFormat)
 		if err != nil {
-			panic(err)
+			fmt.Println("Symbols not loaded:", err)
+		} else if d.symbolFormat == "" || strings.EqualFold(d.symbolFormat, SymbolsDebug) {
+			d.sources, err = readSourceMap(d.symbolFile)
+			if err != nil {
+				fmt.Println("Source lines not loaded:", err)
+			}
 		}
 	}
-
-	liner := liner.NewLiner()
-	liner.SetCompleter(linerCompleter(symbols))
-
-	return &Debugger{
-		liner:   liner,
-		cpu:     cpu,
-		symbols: symbols,
+	if d.liner != nil {
+		d.liner.SetCompleter(linerCompleter(d.symbols))
 	}
 }
 
@@ -137,6 +163,15 @@ func linerCompleter(symbols debugSymbols) func(string) []string {
 		tail := line[i:]
 		tailLower := strings.ToLower(tail)
 
+		if i == 0 {
+			for _, name := range commands.names() {
+				if strings.HasPrefix(name, tailLower) {
+					c = append(c, name)
+				}
+			}
+			return
+		}
+
 		for _, l := range symbols.uniqueLabels() {
 			if strings.HasPrefix(strings.ToLower(l), tailLower) {
 				c = append(c, prefix+l)
@@ -149,6 +184,12 @@ func linerCompleter(symbols debugSymbols) func(string) []string {
 // Shutdown the debugger session, including resetting the terminal to its previous
 // state.
 func (d *Debugger) Shutdown() {
+	if err := d.stopTrace(); err != nil {
+		fmt.Println(err)
+	}
+	if err := d.writeHistory(); err != nil {
+		fmt.Println(err)
+	}
 	d.liner.Close()
 }
 
@@ -158,57 +199,73 @@ func (d *Debugger) QueueCommands(cmds []string) {
 	d.inputQueue = append(d.inputQueue, cmds...)
 }
 
-func (d *Debugger) checkRegBreakpoint(regStr string, on bool, expect byte, actual byte) {
-	if on && actual == expect {
-		fmt.Printf("Breakpoint for %s = $%02X (%d)\n", regStr, expect, expect)
-		d.run = false
-	}
+// Break stops execution before the next instruction, e.g. when a fault is
+// detected mid instruction.
+func (d *Debugger) Break(reason string) {
+	fmt.Println("Break:", reason)
+	d.run = false
 }
 
 func (d *Debugger) doBreakpoints(in cpu.Instruction) {
-	inName := in.Name()
-
-	if inName == d.breakInstruction {
-		fmt.Printf("Breakpoint for instruction %s\n", inName)
-		d.run = false
+	for _, b := range d.breakpoints {
+		if b.matches(d, in) && b.hit() {
+			if b.message != nil {
+				fmt.Println(b.message.format(d))
+				continue
+			}
+			fmt.Printf("Breakpoint %d for %v, hit %d\n", b.id, b, b.hits)
+			d.run = false
+		}
 	}
 
-	if d.breakAddress && d.cpu.PC == d.breakAddressValue {
-		fmt.Printf("Breakpoint for PC address = $%04X\n", d.breakAddressValue)
+	if d.tempBreak && d.cpu.PC == d.tempBreakAddress {
 		d.run = false
 	}
-
-	d.checkRegBreakpoint("A", d.breakRegA, d.breakRegAValue, d.cpu.AC)
-	d.checkRegBreakpoint("X", d.breakRegX, d.breakRegXValue, d.cpu.X)
-	d.checkRegBreakpoint("Y", d.breakRegY, d.breakRegYValue, d.cpu.Y)
+	// The temporary breakpoint is removed once stopped, wherever that was
+	if !d.run {
+		d.tempBreak = false
+	}
 }
 
 // BeforeExecute receives each cpu.Instruction just before the program
 // counter is incremented and the instruction executed.
 func (d *Debugger) BeforeExecute(in cpu.Instruction) {
 
+	d.checkpoint(in)
+	d.countInstruction()
+	d.recordLast(in)
+	d.traceInstruction(in)
+	d.profile(in)
+	d.trackCalls(in)
 	d.doBreakpoints(in)
+	d.brkBreakpoints(in)
+	d.doFinish(in)
+	d.webPause()
 
 	if d.run {
 		return
 	}
 
-	fmt.Println(d.cpu)
-
-	var symbols []string
-	if in.IsAbsolute() {
-		symbols = d.symbols.labelsFor(in.Op16)
-	}
+	fmt.Println(d.registerDump())
+	d.lastStop = d.registers()
+	d.showCounters()
 
+	symbols := d.targetSymbols(in, true)
 	if len(symbols) > 0 {
-		fmt.Printf("Next: %v (%s)\n", in, strings.Join(symbols, ","))
+		fmt.Printf("Next: %v (%s)\n", in, d.paint(ansiSymbol, strings.Join(symbols, ",")))
 	} else {
 		fmt.Println("Next:", in)
 	}
 
+	d.showSource()
+	d.showDisplays()
+	d.showMemDiffs()
+
 	for !d.commandLoop(in) {
 		// next
 	}
+	d.rewinder.sync()
+	d.snapshotMemDiffs()
 }
 
 // Returns true when control is to be released.
@@ -222,68 +279,269 @@ func (d *Debugger) commandLoop(in cpu.Instruction) (release bool) {
 		cmd, err = d.getCommand()
 	}
 	if err != nil {
-		panic(err)
+		// End of input, e.g. Ctrl-D, so shut down and run until stopped
+		if err != io.EOF {
+			fmt.Println(err)
+		}
+		fmt.Println()
+		d.exit()
+		d.run = true
+		return true
 	}
 
-	switch cmd.id {
-	case debugCmdBreakAddress:
-		d.commandBreakAddress(cmd)
-	case debugCmdBreakInstruction:
-		d.breakInstruction = strings.ToUpper(cmd.arguments[0])
-	case debugCmdBreakRegister:
-		d.commandBreakRegister(cmd)
-	case debugCmdContinue:
-		d.run = true
-		release = true
-	case debugCmdExit:
-		d.cpu.ExitChan <- 0
-	case debugCmdHelp:
-		d.commandHelp(cmd)
-	case debugCmdNext:
-		d.commandNext(in)
-		release = true
-	case debugCmdNone:
-		// pass
-	case debugCmdRead:
-		d.commandRead(cmd)
-	case debugCmdRead16:
-		d.commandRead16(cmd)
-	case debugCmdRead32:
-		d.commandRead32(cmd)
-	case debugCmdStep:
-		release = true
-	case debugCmdInvalid:
-		fmt.Println("Invalid command.")
-	default:
-		panic("Unknown command code.")
+	if cmd.command == nil {
+		if strings.TrimSpace(cmd.input) != "" {
+			fmt.Println("Invalid command.")
+			d.failed()
+			d.abandonQueue()
+		}
+		return
+	}
+
+	err = cmd.command.validate(cmd.arguments)
+	if err == nil {
+		release, err = d.runCommand(cmd, in)
+	}
+	if err != nil {
+		fmt.Println(err)
+		d.failed()
+		d.abandonQueue()
 	}
 
 	return
 }
 
+// runCommand runs a command's handler, returning any panic as an error so a
+// failing command doesn't take down the emulator.
+func (d *Debugger) runCommand(c *cmd, in cpu.Instruction) (release bool, err error) {
+	d.prompting = true
+	defer func() {
+		d.prompting = false
+		if r := recover(); r != nil {
+			release, err = false, fmt.Errorf("%s failed: %v", c.command.name, r)
+		}
+	}()
+	return c.command.handler(d, c, in)
+}
+
+func init() {
+	commands.register(&command{
+		name:        "break-address",
+		aliases:     []string{"break-addr", "ba"},
+		usage:       "<address>... [if <condition>]",
+		minArgs:     1,
+		maxArgs:     -1,
+		conditional: true,
+		summary:     "Break when PC reaches an address, e.g. ba 0x1000 Halt",
+		detail: "The address may be hex, decimal, a symbol or . for the current PC.\n" +
+			"Each address given is a separate breakpoint.\n" +
+			conditionHelp,
+		handler: (*Debugger).commandBreakAddress,
+	})
+	commands.register(&command{
+		name:        "break-instruction",
+		aliases:     []string{"bi"},
+		usage:       "<mnemonic>... [if <condition>]",
+		minArgs:     1,
+		maxArgs:     -1,
+		conditional: true,
+		summary:     "Break before an instruction executes, e.g. bi BRK RTI",
+		detail: "Each mnemonic given is a separate breakpoint.\n" +
+			conditionHelp,
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			// Check everything first so a mistake adds no breakpoints
+			mnemonics := make([]string, len(c.arguments))
+			for i, mnemonic := range c.arguments {
+				mnemonics[i] = strings.ToUpper(mnemonic)
+				if !cpu.KnownMnemonic(mnemonics[i]) {
+					return false, fmt.Errorf("Unknown instruction %s", mnemonic)
+				}
+			}
+			cond, err := d.parseCondition(c.condition)
+			if err != nil {
+				return false, err
+			}
+			for _, mnemonic := range mnemonics {
+				d.addBreakpoint(&breakpoint{
+					kind:        breakInstruction,
+					instruction: mnemonic,
+					cond:        cond,
+				})
+			}
+			return false, nil
+		},
+	})
+	commands.register(&command{
+		name:        "break-register",
+		aliases:     []string{"break-reg", "br"},
+		usage:       "<a|x|y|sp|sr|flag> [==|!=|<|<=|>|>=] <value> [if <condition>]",
+		minArgs:     2,
+		maxArgs:     3,
+		conditional: true,
+		summary:     "Break when a register holds a value, e.g. br x 128 or br sp < $20",
+		detail: "The flags are n v b d i z c with the value 0 or 1, e.g. br c 1\n" +
+			conditionHelp,
+		handler: (*Debugger).commandBreakRegister,
+	})
+	commands.register(&command{
+		name:    "continue",
+		aliases: []string{"c"},
+		summary: "Run continuously until breakpoint.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			d.run = true
+			return true, nil
+		},
+	})
+	commands.register(&command{
+		name:    "exit",
+		aliases: []string{"quit", "q"},
+		summary: "Shut down the emulator.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			d.exit()
+			return false, nil
+		},
+	})
+	commands.register(&command{
+		name:    "load",
+		usage:   "<file> [format] [address]",
+		minArgs: 1,
+		maxArgs: 3,
+		summary: "Load a program file into memory, e.g. load prog.hex",
+		detail:  "The format is ihex, srec or raw, or if omitted is determined by the file extension. Raw binaries are loaded at address.",
+		handler: (*Debugger).commandLoad,
+	})
+	commands.register(&command{
+		name:    "next",
+		aliases: []string{"n"},
+		summary: "Next instruction; step over subroutines.",
+		detail:  "Sets a breakpoint after the current instruction then continues.",
+		handler: func(d *Debugger, _ *cmd, in cpu.Instruction) (bool, error) {
+			d.commandNext(in)
+			return true, nil
+		},
+	})
+	commands.register(&command{
+		name:    "until",
+		aliases: []string{"u"},
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Continue until PC reaches address, e.g. until Halt",
+		detail:  "Sets a temporary breakpoint which is removed once hit, or when execution stops elsewhere.",
+		handler: (*Debugger).commandUntil,
+	})
+	commands.register(&command{
+		name:    "read",
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Read and display 8-bit integer at address.",
+		handler: (*Debugger).commandRead,
+	})
+	commands.register(&command{
+		name:    "read16",
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Read and display 16-bit integer at address.",
+		handler: (*Debugger).commandRead16,
+	})
+	commands.register(&command{
+		name:    "read32",
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Read and display 32-bit integer at address.",
+		handler: (*Debugger).commandRead32,
+	})
+	commands.register(&command{
+		name:    "reset",
+		summary: "Warm reset the CPU and devices, preserving RAM.",
+		detail:  "The current instruction is abandoned and the debugger stops at the reset vector.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			d.commandReset()
+			return true, nil
+		},
+	})
+	commands.register(&command{
+		name:    "step",
+		aliases: []string{"st", "s"},
+		summary: "Run only the current instruction.",
+		handler: func(_ *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			return true, nil
+		},
+	})
+}
+
 // Set a breakpoint for the address after the current instruction, then
 // continue execution. Steps over JSR, JMP etc. Probably doesn't do good
 // things for branch instructions.
 func (d *Debugger) commandNext(in cpu.Instruction) {
 	addr := uint16(d.cpu.PC + uint16(in.Bytes))
-	d.breakAddress = true
-	d.breakAddressValue = addr
+	d.tempBreak = true
+	d.tempBreakAddress = addr
 	d.run = true
 }
 
-func (d *Debugger) commandRead(cmd *cmd) {
+func (d *Debugger) commandUntil(c *cmd, _ cpu.Instruction) (bool, error) {
+	addr, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+	d.tempBreak = true
+	d.tempBreakAddress = addr
+	d.run = true
+	return true, nil
+}
+
+// Perform a warm reset. The current instruction is abandoned and the debugger
+// stops again at the reset vector.
+func (d *Debugger) commandReset() {
+	d.cpu.Reset()
+	d.frames = nil
+	// The abandoned instruction is recorded again if it executes
+	d.last.drop(1)
+	d.abandonInstruction(1)
+	fmt.Printf("Reset to $%04X\n", d.cpu.PC)
+}
+
+func (d *Debugger) commandLoad(cmd *cmd, _ cpu.Instruction) (bool, error) {
+	var (
+		format  string
+		address uint16
+		err     error
+	)
+	if len(cmd.arguments) > 1 {
+		format = cmd.arguments[1]
+	}
+	if len(cmd.arguments) > 2 {
+		address, err = d.parseUint16(cmd.arguments[2])
+		if err != nil {
+			return false, err
+		}
+	}
+	program, err := memory.LoadFile(cmd.arguments[0], format, address, d.cpu.Bus)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Loaded %s: %v\n", cmd.arguments[0], program)
+	return false, nil
+}
+
+func (d *Debugger) commandRead(cmd *cmd, _ cpu.Instruction) (bool, error) {
 	addr, err := d.parseUint16(cmd.arguments[0])
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	v := d.cpu.Bus.Read(addr)
 	fmt.Printf("$%04X => $%02X 0b%08b %d %q\n", addr, v, v, v, v)
+	return false, nil
 }
 
-func (d *Debugger) commandRead16(cmd *cmd) {
+func (d *Debugger) commandRead16(cmd *cmd, _ cpu.Instruction) (bool, error) {
 	addr, err := d.parseUint16(cmd.arguments[0])
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	addrLo := addr
 	addrHi := addr + 1
@@ -291,12 +549,13 @@ func (d *Debugger) commandRead16(cmd *cmd) {
 	vHi := uint16(d.cpu.Bus.Read(addrHi))
 	v := vHi<<8 | vLo
 	fmt.Printf("$%04X,%04X => $%04X 0b%016b %d\n", addrLo, addrHi, v, v, v)
+	return false, nil
 }
 
-func (d *Debugger) commandRead32(cmd *cmd) {
+func (d *Debugger) commandRead32(cmd *cmd, _ cpu.Instruction) (bool, error) {
 	addr, err := d.parseUint16(cmd.arguments[0])
 	if err != nil {
-		panic(err)
+		return false, err
 	}
 	addr0 := addr
 	addr1 := addr + 1
@@ -308,139 +567,142 @@ func (d *Debugger) commandRead32(cmd *cmd) {
 	v3 := uint32(d.cpu.Bus.Read(addr3))
 	v := v3<<24 | v2<<16 | v1<<8 | v0
 	fmt.Printf("$%04X..%04X => $%08X 0b%032b %d\n", addr0, addr3, v, v, v)
+	return false, nil
 }
 
-func (d *Debugger) commandHelp(cmd *cmd) {
-	fmt.Println("")
-	fmt.Println("pda6502 debuger")
-	fmt.Println("---------------")
-	fmt.Println("break-address <addr> (alias: ba) e.g. ba 0x1000")
-	fmt.Println("break-instruction <mnemonic> (alias: bi) e.g. bi NOP")
-	fmt.Println("break-register <x|y|a> <value> (alias: br) e.g. br x 128")
-	fmt.Println("continue (alias: c) Run continuously until breakpoint.")
-	fmt.Println("exit (alias: quit, q) Shut down the emulator.")
-	fmt.Println("help (alias: h, ?) This help.")
-	fmt.Println("next (alias: n) Next instruction; step over subroutines.")
-	fmt.Println("read <address> - Read and display 8-bit integer at address.")
-	fmt.Println("read16 <address> - Read and display 16-bit integer at address.")
-	fmt.Println("read32 <address> - Read and display 32-bit integer at address.")
-	fmt.Println("step (alias: s) Run only the current instruction.")
-	fmt.Println("(blank) Repeat the previous command.")
-	fmt.Println("")
-	fmt.Println("Hex input formats: 0x1234 $1234")
-	fmt.Println("Commands expecting uint16 treat . as current address (PC).")
-}
-
-func (d *Debugger) commandBreakAddress(cmd *cmd) {
-	addr, err := d.parseUint16(cmd.arguments[0])
-	if err != nil {
-		panic(err)
+func (d *Debugger) commandBreakAddress(cmd *cmd, _ cpu.Instruction) (bool, error) {
+	// Parse every address first so a typo adds none of them
+	var addrs []uint16
+	for _, arg := range cmd.arguments {
+		addr, err := d.parseUint16(arg)
+		if err != nil {
+			return false, err
+		}
+		addrs = append(addrs, addr)
 	}
-	d.breakAddress = true
-	d.breakAddressValue = addr
-	fmt.Printf("break-address set to $%04X\n", addr)
+	for _, addr := range addrs {
+		cond, err := d.parseCondition(cmd.condition)
+		if err != nil {
+			return false, err
+		}
+		d.addBreakpoint(&breakpoint{kind: breakAddress, address: addr, cond: cond})
+	}
+	return false, nil
 }
 
-func (d *Debugger) commandBreakRegister(cmd *cmd) {
+func (d *Debugger) commandBreakRegister(cmd *cmd, _ cpu.Instruction) (bool, error) {
 	regStr := cmd.arguments[0]
-	valueStr := cmd.arguments[1]
-
-	var ptr *byte
-	switch regStr {
-	case "A", "a", "AC", "ac":
-		d.breakRegA = true
-		ptr = &d.breakRegAValue
-	case "X", "x":
-		d.breakRegX = true
-		ptr = &d.breakRegXValue
-	case "Y", "y":
-		d.breakRegY = true
-		ptr = &d.breakRegYValue
-	default:
-		panic(fmt.Errorf("Invalid register for break-register"))
+	valueStr := cmd.arguments[len(cmd.arguments)-1]
+
+	compare := "=="
+	if len(cmd.arguments) == 3 {
+		compare = cmd.arguments[1]
+		if _, exists := compareOperators[compare]; !exists || compare == "" {
+			return false, fmt.Errorf("Invalid comparison %q for break-register", compare)
+		}
 	}
 
 	value, err := d.parseUint8(valueStr)
 	if err != nil {
-		panic(err)
+		return false, err
+	}
+	cond, err := d.parseCondition(cmd.condition)
+	if err != nil {
+		return false, err
 	}
 
-	fmt.Printf("Breakpoint set: %s = $%02X (%d)\n", regStr, value, value)
+	var register string
+	switch strings.ToUpper(regStr) {
+	case "A", "AC":
+		register = "A"
+	case "X", "Y", "SP", "SR":
+		register = strings.ToUpper(regStr)
+	case "P":
+		register = "SR"
+	default:
+		if _, flag := flagBits[strings.ToLower(regStr)]; !flag {
+			return false, fmt.Errorf("Invalid register for break-register")
+		}
+		if value > 1 {
+			return false, fmt.Errorf("Flag %s can only be 0 or 1", regStr)
+		}
+		register = strings.ToUpper(regStr)
+	}
 
-	*ptr = value
+	d.addBreakpoint(&breakpoint{kind: breakRegister, register: register, compare: compare, value: value, cond: cond})
+	return false, nil
 }
 
 func (d *Debugger) getCommand() (*cmd, error) {
 	var (
-		id        int
-		cmdString string
 		arguments []string
 		c         *cmd
 		input     string
 		err       error
 	)
 
-	if len(d.inputQueue) > 0 {
-		input = d.inputQueue[0]
-		d.inputQueue = d.inputQueue[1:]
-		fmt.Printf("%s%s\n", d.prompt(), input)
-	} else {
-		input, err = d.readInput()
-		if err != nil {
-			return nil, err
-		}
+	input, err = d.nextInput(d.prompt())
+	if err != nil {
+		return nil, err
 	}
 
 	fields := strings.Fields(input)
 
-	if len(fields) >= 1 {
-		cmdString = strings.ToLower(fields[0])
+	if len(fields) == 0 {
+		if d.lastCmd != nil {
+			return d.lastCmd, nil
+		}
+		return &cmd{input: input}, nil
 	}
+
 	if len(fields) >= 2 {
 		arguments = fields[1:]
 	}
 
-	switch cmdString {
-	case "":
-		id = debugCmdNone
-	case "break-address", "break-addr", "ba":
-		id = debugCmdBreakAddress
-	case "break-instruction", "bi":
-		id = debugCmdBreakInstruction
-	case "break-register", "break-reg", "br":
-		id = debugCmdBreakRegister
-	case "continue", "c":
-		id = debugCmdContinue
-	case "exit", "quit", "q":
-		id = debugCmdExit
-	case "help", "h", "?":
-		id = debugCmdHelp
-	case "next", "n":
-		id = debugCmdNext
-	case "read":
-		id = debugCmdRead
-	case "read16":
-		id = debugCmdRead16
-	case "read32":
-		id = debugCmdRead32
-	case "step", "st", "s":
-		id = debugCmdStep
-	default:
-		id = debugCmdInvalid
+	command := commands.lookup(fields[0])
+	if command == nil {
+		command = d.macroCommand(fields[0])
 	}
-
-	if id == debugCmdNone && d.lastCmd != nil {
-		c = d.lastCmd
-	} else {
-		c = &cmd{id, input, arguments}
-		d.lastCmd = c
+	c = &cmd{command: command, input: input, arguments: arguments}
+	if c.command != nil && c.command.conditional {
+		c.arguments, c.condition = splitCondition(arguments)
 	}
+	d.lastCmd = c
 
 	return c, nil
 }
 
-func (d *Debugger) readInput() (string, error) {
-	input, err := d.liner.Prompt(d.prompt())
+// splitCondition separates a trailing "if <condition>" from the arguments.
+func splitCondition(arguments []string) ([]string, string) {
+	for i, a := range arguments {
+		if strings.EqualFold(a, "if") {
+			return arguments[:i], strings.Join(arguments[i+1:], " ")
+		}
+	}
+	return arguments, ""
+}
+
+// nextInput returns the next queued command, or reads one from the user.
+func (d *Debugger) nextInput(prompt string) (string, error) {
+	if len(d.inputQueue) > 0 {
+		input := d.inputQueue[0]
+		d.inputQueue = d.inputQueue[1:]
+		fmt.Printf("%s%s\n", prompt, input)
+		return input, nil
+	}
+	if d.batch.enabled {
+		// Out of commands so shut down, continuing until the machine stops
+		d.exit()
+		return "continue", nil
+	}
+	if d.web != nil {
+		return d.webInput(prompt)
+	}
+	return d.readInput(prompt)
+}
+
+func (d *Debugger) readInput(prompt string) (string, error) {
+	input, err := d.liner.Prompt(prompt)
 	if err != nil {
 		return "", err
 	}
@@ -453,27 +715,14 @@ func (d *Debugger) prompt() string {
 	return fmt.Sprintf("$%04X %s> ", d.cpu.PC, symbols)
 }
 
+// parseUint8 evaluates an expression giving a byte value.
 func (d *Debugger) parseUint8(s string) (uint8, error) {
-	s = strings.Replace(s, "$", "0x", 1)
-	result, err := strconv.ParseUint(s, 0, 8)
-	return uint8(result), err
+	v, err := d.evaluate(s, 0xFF)
+	return uint8(v), err
 }
 
+// parseUint16 evaluates an expression giving an address or 16-bit value.
 func (d *Debugger) parseUint16(s string) (uint16, error) {
-	if s == "." {
-		return d.cpu.PC, nil
-	}
-
-	addresses := d.symbols.addressesFor(s)
-	if len(addresses) == 1 {
-		return addresses[0], nil
-	} else if len(addresses) > 1 {
-		// TODO: show addresses as hex, not dec, in error.
-		// TODO: include addresses as []uint16 in error.
-		return 0, fmt.Errorf("Multiple addresses for %s: %v", s, addresses)
-	}
-
-	s = strings.Replace(s, "$", "0x", 1)
-	result, err := strconv.ParseUint(s, 0, 16)
-	return uint16(result), err
+	v, err := d.evaluate(s, 0xFFFF)
+	return uint16(v), err
 }
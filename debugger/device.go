@@ -0,0 +1,82 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:        "break-device",
+		aliases:     []string{"bd"},
+		usage:       "<name> [r|w|rw] [if <condition>]",
+		minArgs:     1,
+		maxArgs:     2,
+		conditional: true,
+		summary:     "Break when a device on the bus is accessed, e.g. bd VIA w",
+		detail: "Breaks after the instruction reading or writing any address of the device, default rw,\n" +
+			"reporting the register offset and value. info device lists the device names.\n" +
+			conditionHelp,
+		handler: (*Debugger).commandBreakDevice,
+	})
+}
+
+func (d *Debugger) commandBreakDevice(c *cmd, _ cpu.Instruction) (bool, error) {
+	var region *bus.Region
+	for _, r := range d.cpu.Bus.Regions() {
+		if strings.EqualFold(r.Name, c.arguments[0]) {
+			r := r
+			region = &r
+			break
+		}
+	}
+	if region == nil {
+		return false, fmt.Errorf("No device named %q", c.arguments[0])
+	}
+
+	access := bus.AccessReadWrite
+	if len(c.arguments) > 1 {
+		var err error
+		if access, err = parseAccess(c.arguments[1]); err != nil {
+			return false, err
+		}
+	}
+
+	cond, err := d.parseCondition(c.condition)
+	if err != nil {
+		return false, err
+	}
+
+	b := &breakpoint{kind: breakDevice, region: *region, access: access, cond: cond}
+	if d.addBreakpoint(b) {
+		b.watchId = d.cpu.Bus.Watch(region.Start, region.End, access, d.deviceTriggered(b))
+	}
+	return false, nil
+}
+
+// deviceTriggered returns the bus watch for a device breakpoint. As with a
+// watchpoint the instruction completes, so execution stops before the next.
+func (d *Debugger) deviceTriggered(b *breakpoint) bus.WatchFunc {
+	return func(access bus.Access, addr uint16, value byte, pc uint16) {
+		// Ignore accesses made by debugger commands, e.g. read
+		if d.prompting || !b.enabled || !b.cond.holds(d) || !b.hit() {
+			return
+		}
+		fmt.Printf("Breakpoint %d for %v: %v offset $%02X ($%04X) = $%02X pc:$%04X%s, hit %d\n",
+			b.id, b.region.Name, access, b.offset(addr), addr, value, pc, d.labelSuffix(pc), b.hits)
+		d.run = false
+	}
+}
+
+// offset returns the register of the device accessed at addr, allowing for
+// devices mirrored across a larger window.
+func (b *breakpoint) offset(addr uint16) int {
+	offset := int(addr - b.region.Start)
+	if b.region.Device != nil && b.region.Device.Size() > 0 {
+		offset %= b.region.Device.Size()
+	}
+	return offset
+}
@@ -0,0 +1,49 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestBreakDevice(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x6000), "ram", 0)
+	b.AttachMirrored(memory.NewRam(4), "ACIA", 0x6000, 0x100)
+	d := &Debugger{cpu: &cpu.Cpu{Bus: b}, run: true}
+
+	if _, err := d.commandBreakDevice(&cmd{arguments: []string{"acia", "w"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	bp := d.breakpoints[0]
+	if s := bp.String(); s != "write device ACIA" {
+		t.Error(fmt.Sprintf("unexpected breakpoint %s", s))
+	}
+
+	b.Read(0x6001)
+	b.Write(0x1000, 1)
+	if !d.run {
+		t.Error("expected reads and other devices to be ignored")
+	}
+
+	b.Write(0x6046, 1)
+	if d.run || bp.hits != 1 || bp.offset(0x6046) != 2 {
+		t.Error(fmt.Sprintf("expected write to break at offset 2 got hits %d offset %d", bp.hits, bp.offset(0x6046)))
+	}
+
+	if _, err := d.commandDelete(&cmd{arguments: []string{"1"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	d.run = true
+	b.Write(0x6000, 1)
+	if !d.run {
+		t.Error("expected deleted breakpoint to be unwatched")
+	}
+
+	if _, err := d.commandBreakDevice(&cmd{arguments: []string{"VIA"}}, cpu.Instruction{}); err == nil {
+		t.Error("expected unknown device to fail")
+	}
+}
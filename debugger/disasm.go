@@ -0,0 +1,147 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// defaultDisasmCount is the number of instructions disassembled when no
+// count is given.
+const defaultDisasmCount = 10
+
+func init() {
+	commands.register(&command{
+		name:    "disasm",
+		aliases: []string{"d"},
+		usage:   "[address] [count]",
+		maxArgs: 2,
+		summary: "Disassemble instructions, e.g. d . 20",
+		detail: "Disassembles count instructions, default 10, from address or the current PC.\n" +
+			"Repeating the command with a blank line continues from where it left off.",
+		handler: (*Debugger).commandDisasm,
+	})
+}
+
+func (d *Debugger) commandDisasm(c *cmd, _ cpu.Instruction) (bool, error) {
+	addr := d.cpu.PC
+	count := defaultDisasmCount
+	var err error
+
+	if len(c.arguments) > 0 {
+		addr, err = d.parseUint16(c.arguments[0])
+		if err != nil {
+			return false, err
+		}
+	}
+	if len(c.arguments) > 1 {
+		_, err = fmt.Sscan(c.arguments[1], &count)
+		if err != nil || count < 1 {
+			return false, fmt.Errorf("Invalid count %q", c.arguments[1])
+		}
+	}
+
+	// A blank line repeats the command, so continue rather than repeat
+	if c == d.lastDisasm {
+		addr = d.disasmNext
+	}
+
+	for i := 0; i < count; i++ {
+		next, err := d.disassemble(addr)
+		if err != nil {
+			return false, err
+		}
+		if next < addr {
+			// Wrapped past $FFFF
+			break
+		}
+		addr = next
+	}
+
+	d.lastDisasm = c
+	d.disasmNext = addr
+	return false, nil
+}
+
+// disasmLine is a disassembled instruction.
+type disasmLine struct {
+	address uint16
+	data    []byte
+	text    string   // instruction, or .byte if it could not be decoded
+	labels  []string // at address
+	symbols []string // for the operand
+}
+
+func (l disasmLine) String() string {
+	if len(l.symbols) > 0 {
+		return fmt.Sprintf("$%04X  %-9s %s (%s)", l.address, hexBytes(l.data), l.text, strings.Join(l.symbols, ","))
+	}
+	return fmt.Sprintf("$%04X  %-9s %s", l.address, hexBytes(l.data), l.text)
+}
+
+// decodeAt disassembles the instruction at addr, returning the address of the
+// one following it. Memory is read without side effects on devices.
+func (d *Debugger) decodeAt(addr uint16) (disasmLine, uint16, error) {
+	// Read as much of the longest instruction as can be read
+	var (
+		data []byte
+		err  error
+	)
+	for n := 3; n > 0; n-- {
+		if int(addr)+n > 0x10000 {
+			continue
+		}
+		data, err = d.cpu.Bus.PeekBlock(addr, n)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return disasmLine{}, addr, err
+	}
+
+	line := disasmLine{address: addr, labels: d.symbols.labelsFor(addr)}
+
+	in, err := cpu.Decode(addr, data)
+	if err != nil {
+		// Show undecodable bytes as data so the listing can continue
+		line.data = data[:1]
+		line.text = fmt.Sprintf(".byte $%02X", data[0])
+		return line, addr + 1, nil
+	}
+
+	line.data = data[:in.Bytes]
+	line.text = in.String()
+	line.symbols = d.targetSymbols(in, addr == d.cpu.PC)
+	return line, addr + uint16(in.Bytes), nil
+}
+
+// disassemble prints the instruction at addr, returning the address of the
+// one following it.
+func (d *Debugger) disassemble(addr uint16) (uint16, error) {
+	line, next, err := d.decodeAt(addr)
+	if err != nil {
+		return addr, err
+	}
+
+	for _, label := range line.labels {
+		fmt.Printf("%s:\n", label)
+	}
+
+	marker := "  "
+	if addr == d.cpu.PC {
+		marker = "=>"
+	}
+	fmt.Printf("%s %v\n", marker, line)
+	return next, nil
+}
+
+// hexBytes formats bytes as space separated hex.
+func hexBytes(data []byte) string {
+	s := make([]string, len(data))
+	for i, b := range data {
+		s[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,92 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// display is an expression shown each time the debugger stops.
+type display struct {
+	id   int
+	text string
+	eval evalFunc
+}
+
+func init() {
+	commands.register(&command{
+		name:    "display",
+		aliases: []string{"disp"},
+		usage:   "[expression]",
+		maxArgs: -1,
+		summary: "Show an expression every time execution stops, e.g. display peek($0200)",
+		detail: "With no expression shows the current displays. Memory is shown with peek(address)\n" +
+			"for a byte or [address] for a word. See help expressions.",
+		handler: (*Debugger).commandDisplay,
+	})
+	commands.register(&command{
+		name:    "undisplay",
+		usage:   "<id>|all",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Remove a display.",
+		handler: (*Debugger).commandUndisplay,
+	})
+}
+
+func (d *Debugger) commandDisplay(c *cmd, _ cpu.Instruction) (bool, error) {
+	if len(c.arguments) == 0 {
+		if len(d.displays) == 0 {
+			fmt.Println("No displays.")
+		}
+		d.showDisplays()
+		return false, nil
+	}
+
+	text := strings.Join(c.arguments, "")
+	eval, err := d.parseExpression(text)
+	if err != nil {
+		return false, err
+	}
+	d.displayId++
+	disp := &display{id: d.displayId, text: text, eval: eval}
+	d.displays = append(d.displays, disp)
+	d.showDisplay(disp)
+	return false, nil
+}
+
+func (d *Debugger) commandUndisplay(c *cmd, _ cpu.Instruction) (bool, error) {
+	if c.arguments[0] == "all" {
+		d.displays = nil
+		return false, nil
+	}
+
+	id, err := strconv.Atoi(c.arguments[0])
+	if err == nil {
+		for i, disp := range d.displays {
+			if disp.id == id {
+				d.displays = append(d.displays[:i:i], d.displays[i+1:]...)
+				return false, nil
+			}
+		}
+	}
+	return false, fmt.Errorf("No display %s", c.arguments[0])
+}
+
+// showDisplays shows each display, when execution stops.
+func (d *Debugger) showDisplays() {
+	for _, disp := range d.displays {
+		d.showDisplay(disp)
+	}
+}
+
+func (d *Debugger) showDisplay(disp *display) {
+	v := disp.eval(d)
+	if v >= 0 && v <= 0xFF {
+		fmt.Printf("%d: %s = $%02X (%d)\n", disp.id, disp.text, v, v)
+	} else {
+		fmt.Printf("%d: %s = $%04X (%d)\n", disp.id, disp.text, v&0xFFFF, v)
+	}
+}
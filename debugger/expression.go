@@ -0,0 +1,369 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expressions are accepted wherever a command takes an address or value,
+// e.g. label+5, $1000+X or [$00FE]. They may not contain spaces.
+//
+// Operands are the registers A, X, Y, SP, SR and PC, . for the PC, numbers in
+// hex ($1234 or 0x1234) or decimal, symbols, [address] for the 16-bit word in
+// memory (i.e. indirection) and peek(address) for a byte.
+//
+// Operators, from lowest precedence, are || && then == != < <= > >= then
+// | ^ & << >> + - * / % and the unary ! - ~. Comparisons and logical
+// operators return 1 for true, 0 for false.
+
+// evalFunc evaluates a parsed expression against the current machine state.
+type evalFunc func(d *Debugger) int
+
+// parseExpression parses an expression, to be evaluated later.
+func (d *Debugger) parseExpression(s string) (evalFunc, error) {
+	p := &exprParser{d: d, tokens: tokenize(s)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("Missing expression")
+	}
+	eval, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected %q in %s", p.tokens[p.pos], s)
+	}
+	return eval, nil
+}
+
+// evaluate parses and evaluates an expression, checking the result is
+// within 0 and max.
+func (d *Debugger) evaluate(s string, max int) (int, error) {
+	eval, err := d.parseExpression(s)
+	if err != nil {
+		return 0, err
+	}
+	v := eval(d)
+	if v < 0 || v > max {
+		return 0, fmt.Errorf("%s = %d is out of range", s, v)
+	}
+	return v, nil
+}
+
+// condition is a boolean expression guarding a breakpoint, e.g.
+// A==$40 && X>2. It is parsed once when the breakpoint is set and evaluated
+// on each hit.
+type condition struct {
+	text string
+	eval evalFunc
+}
+
+func (c *condition) String() string {
+	return c.text
+}
+
+// holds returns true if there is no condition or it evaluates to non-zero.
+func (c *condition) holds(d *Debugger) bool {
+	return c == nil || c.eval(d) != 0
+}
+
+// describe describes a condition for messages, "" if there is none.
+func (c *condition) describe() string {
+	if c == nil {
+		return ""
+	}
+	return " if " + c.text
+}
+
+// parseCondition parses a condition, returning nil if s is empty.
+func (d *Debugger) parseCondition(s string) (*condition, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	eval, err := d.parseExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	return &condition{text: s, eval: eval}, nil
+}
+
+// exprOperators are the operator tokens, longest first.
+var exprOperators = []string{
+	"==", "!=", "<=", ">=", "&&", "||", "<<", ">>",
+	"<", ">", "!", "(", ")", "[", "]", "+", "-", "*", "/", "%", "&", "|", "^", "~",
+}
+
+// exprSeparators end an operand.
+const exprSeparators = " \t=!<>&|()[]+-*/%^~"
+
+func tokenize(s string) (tokens []string) {
+	for i := 0; i < len(s); {
+		if s[i] == ' ' || s[i] == '\t' {
+			i++
+			continue
+		}
+
+		op := ""
+		for _, o := range exprOperators {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op != "" {
+			tokens = append(tokens, op)
+			i += len(op)
+			continue
+		}
+
+		j := i
+		for j < len(s) && !strings.ContainsAny(s[j:j+1], exprSeparators) {
+			j++
+		}
+		if j == i {
+			// A lone = so let the parser report it
+			j++
+		}
+		tokens = append(tokens, s[i:j])
+		i = j
+	}
+	return
+}
+
+type exprParser struct {
+	d      *Debugger
+	tokens []string
+	pos    int
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// binaryOperators are the operators at each level of precedence, lowest
+// first, above the logical operators.
+var binaryOperators = []map[string]func(a, b int) int{
+	{
+		"==": func(a, b int) int { return boolInt(a == b) },
+		"!=": func(a, b int) int { return boolInt(a != b) },
+		"<":  func(a, b int) int { return boolInt(a < b) },
+		"<=": func(a, b int) int { return boolInt(a <= b) },
+		">":  func(a, b int) int { return boolInt(a > b) },
+		">=": func(a, b int) int { return boolInt(a >= b) },
+	},
+	{"|": func(a, b int) int { return a | b }},
+	{"^": func(a, b int) int { return a ^ b }},
+	{"&": func(a, b int) int { return a & b }},
+	{
+		"<<": func(a, b int) int { return a << uint(b&31) },
+		">>": func(a, b int) int { return a >> uint(b&31) },
+	},
+	{
+		"+": func(a, b int) int { return a + b },
+		"-": func(a, b int) int { return a - b },
+	},
+	{
+		"*": func(a, b int) int { return a * b },
+		"/": func(a, b int) int {
+			if b == 0 {
+				return 0
+			}
+			return a / b
+		},
+		"%": func(a, b int) int {
+			if b == 0 {
+				return 0
+			}
+			return a % b
+		},
+	},
+}
+
+func (p *exprParser) or() (evalFunc, error) {
+	left, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.next()
+		var right evalFunc
+		right, err = p.and()
+		l, r := left, right
+		left = func(d *Debugger) int { return boolInt(l(d) != 0 || r(d) != 0) }
+	}
+	return left, err
+}
+
+func (p *exprParser) and() (evalFunc, error) {
+	left, err := p.binary(0)
+	for err == nil && p.peek() == "&&" {
+		p.next()
+		var right evalFunc
+		right, err = p.binary(0)
+		l, r := left, right
+		left = func(d *Debugger) int { return boolInt(l(d) != 0 && r(d) != 0) }
+	}
+	return left, err
+}
+
+// binary parses operators at the given level of precedence. Comparisons do
+// not chain, e.g. 1<2<3 is an error.
+func (p *exprParser) binary(level int) (evalFunc, error) {
+	operand := p.unary
+	if level+1 < len(binaryOperators) {
+		operand = func() (evalFunc, error) { return p.binary(level + 1) }
+	}
+
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, exists := binaryOperators[level][p.peek()]
+		if !exists {
+			return left, nil
+		}
+		p.next()
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l, r := left, right
+		left = func(d *Debugger) int { return op(l(d), r(d)) }
+
+		if level == 0 {
+			return left, nil
+		}
+	}
+}
+
+func (p *exprParser) unary() (evalFunc, error) {
+	t := p.next()
+	switch t {
+	case "":
+		return nil, fmt.Errorf("Incomplete expression")
+
+	case "!", "-", "~":
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		switch t {
+		case "!":
+			return func(d *Debugger) int { return boolInt(operand(d) == 0) }, nil
+		case "-":
+			return func(d *Debugger) int { return -operand(d) }, nil
+		default:
+			return func(d *Debugger) int { return ^operand(d) & 0xFFFF }, nil
+		}
+
+	case "(":
+		inner, err := p.bracketed(")")
+		if err != nil {
+			return nil, err
+		}
+		return inner, nil
+
+	case "[":
+		inner, err := p.bracketed("]")
+		if err != nil {
+			return nil, err
+		}
+		return func(d *Debugger) int { return int(d.peek16(uint16(inner(d)))) }, nil
+	}
+
+	if strings.ContainsAny(t, exprSeparators) {
+		return nil, fmt.Errorf("Unexpected %q in expression", t)
+	}
+
+	if strings.EqualFold(t, "peek") && p.peek() == "(" {
+		p.next()
+		inner, err := p.bracketed(")")
+		if err != nil {
+			return nil, err
+		}
+		return func(d *Debugger) int { return int(d.peek(uint16(inner(d)))) }, nil
+	}
+
+	return p.d.parseOperand(t)
+}
+
+// bracketed parses an expression followed by the closing bracket.
+func (p *exprParser) bracketed(closing string) (evalFunc, error) {
+	inner, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.next() != closing {
+		return nil, fmt.Errorf("Missing %s in expression", closing)
+	}
+	return inner, nil
+}
+
+// parseOperand parses a register, number or symbol.
+func (d *Debugger) parseOperand(s string) (evalFunc, error) {
+	switch strings.ToUpper(s) {
+	case "A", "AC":
+		return func(d *Debugger) int { return int(d.cpu.AC) }, nil
+	case "X":
+		return func(d *Debugger) int { return int(d.cpu.X) }, nil
+	case "Y":
+		return func(d *Debugger) int { return int(d.cpu.Y) }, nil
+	case "SP":
+		return func(d *Debugger) int { return int(d.cpu.SP) }, nil
+	case "SR", "P":
+		return func(d *Debugger) int { return int(d.cpu.SR) }, nil
+	case "PC", ".":
+		return func(d *Debugger) int { return int(d.cpu.PC) }, nil
+	}
+
+	if addresses := d.symbols.addressesFor(s); len(addresses) > 1 {
+		hex := make([]string, len(addresses))
+		for i, a := range addresses {
+			hex[i] = fmt.Sprintf("$%04X", a)
+		}
+		return nil, fmt.Errorf("Multiple addresses for %s: %s", s, strings.Join(hex, " "))
+	} else if len(addresses) == 1 {
+		v := int(addresses[0])
+		return func(*Debugger) int { return v }, nil
+	}
+
+	v, err := strconv.ParseUint(strings.Replace(s, "$", "0x", 1), 0, 16)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown symbol or invalid value %q", s)
+	}
+	return func(*Debugger) int { return int(v) }, nil
+}
+
+// peek reads a byte without side effects on devices, returning 0 if it is
+// unmapped or can't be read that way.
+func (d *Debugger) peek(addr uint16) byte {
+	v, _ := d.cpu.Bus.Peek(addr)
+	return v
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// expressionHelp is shown by help expressions.
+const expressionHelp = `Expressions may be used wherever an address or value is expected, without spaces.
+Operands:  A X Y SP SR PC registers, . the PC, $1234 0x1234 1234 numbers, symbols,
+           [address] the 16-bit word at address, peek(address) the byte at address
+Operators: || && == != < <= > >= | ^ & << >> + - * / % and unary ! - ~
+e.g. d label+5, ba [$FFFC], read $1000+X, br a 0 if peek(count)>=10`
+
+// conditionHelp is the help text for commands accepting a condition.
+const conditionHelp = "A condition limits the break to when it holds, e.g. if A==$40 && X>2\n" +
+	"Conditions are expressions, see help expressions."
@@ -0,0 +1,62 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestExpressions(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x1000), "ram", 0)
+	b.Write(0x0200, 0x7F)
+
+	d := &Debugger{
+		cpu:     &cpu.Cpu{Bus: b, AC: 0x40, X: 3},
+		symbols: debugSymbols{{address: 0x0200, name: "buffer"}},
+	}
+
+	for text, expected := range map[string]bool{
+		"A==$40 && X>2":          true,
+		"A==$40 && X>3":          false,
+		"A!=$40 || X>=3":         true,
+		"!(A==$40)":              false,
+		"[buffer]==$7F":          true,
+		"[$0200] < 0x80 && Y==0": true,
+	} {
+		cond, err := d.parseCondition(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond.holds(d) != expected {
+			t.Error(fmt.Sprintf("%s expected %v", text, expected))
+		}
+	}
+
+	b.Write(0x00FE, 0x01)
+	b.Write(0x00FF, 0x02)
+	for text, expected := range map[string]uint16{
+		"buffer+5":        0x0205,
+		"$1000+X":         0x1003,
+		"[$00FE]":         0x0201,
+		"[$FE]+X*2":       0x0207,
+		"(buffer>>8)&$FF": 0x02,
+	} {
+		actual, err := d.parseUint16(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Error(fmt.Sprintf("%s expected $%04X got $%04X", text, expected, actual))
+		}
+	}
+
+	for _, text := range []string{"A==", "A==$40 &&", "(A==1", "A=1", "Q==1", "1<2<3", "buffer-$1000"} {
+		if _, err := d.parseUint16(text); err == nil {
+			t.Error(fmt.Sprintf("expected %q to fail", text))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// maxFindResults limits the matches shown by find.
+const maxFindResults = 64
+
+func init() {
+	commands.register(&command{
+		name:    "find",
+		usage:   `<address> <length> <bytes...|"string">`,
+		minArgs: 3,
+		maxArgs: -1,
+		summary: `Search memory for bytes or a string, e.g. find $8000 $1000 "READY"`,
+		detail: "Bytes are given as separate values, e.g. find . 256 $A9 $00.\n" +
+			"Matches are shown with the nearest preceding symbol. Unmapped memory is skipped.",
+		handler: (*Debugger).commandFind,
+	})
+}
+
+func (d *Debugger) commandFind(c *cmd, _ cpu.Instruction) (bool, error) {
+	start, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+	length, err := strconv.ParseUint(strings.Replace(c.arguments[1], "$", "0x", 1), 0, 32)
+	if err != nil || length == 0 {
+		return false, fmt.Errorf("Invalid length %q", c.arguments[1])
+	}
+	if int(start)+int(length) > 0x10000 {
+		length = uint64(0x10000 - int(start))
+	}
+
+	pattern, err := d.parsePattern(c)
+	if err != nil {
+		return false, err
+	}
+
+	// Read memory without side effects, noting what is unmapped or can't be
+	// read that way
+	data := make([]byte, length)
+	mapped := make([]bool, length)
+	for i := range data {
+		data[i], mapped[i] = d.cpu.Bus.Peek(start + uint16(i))
+	}
+
+	found := 0
+	for i := 0; i+len(pattern) <= len(data); i++ {
+		if !bytes.Equal(data[i:i+len(pattern)], pattern) || !allMapped(mapped[i:i+len(pattern)]) {
+			continue
+		}
+		found++
+		if found > maxFindResults {
+			fmt.Println("...")
+			break
+		}
+		addr := start + uint16(i)
+		fmt.Printf("$%04X %s\n", addr, d.nearestSymbol(addr))
+	}
+	if found == 0 {
+		fmt.Println("Not found")
+	}
+	return false, nil
+}
+
+// parsePattern returns the bytes to find, from a quoted string in the input
+// or the byte values following the address and length.
+func (d *Debugger) parsePattern(c *cmd) ([]byte, error) {
+	if i := strings.Index(c.input, `"`); i >= 0 {
+		s, err := strconv.Unquote(strings.TrimSpace(c.input[i:]))
+		if err != nil || s == "" {
+			return nil, fmt.Errorf("Invalid string %s", c.input[i:])
+		}
+		return []byte(s), nil
+	}
+
+	var pattern []byte
+	for _, a := range c.arguments[2:] {
+		b, err := d.parseUint8(a)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid byte %q", a)
+		}
+		pattern = append(pattern, b)
+	}
+	return pattern, nil
+}
+
+func allMapped(mapped []bool) bool {
+	for _, m := range mapped {
+		if !m {
+			return false
+		}
+	}
+	return true
+}
+
+// nearestSymbol describes an address relative to the closest symbol at or
+// before it, e.g. message+$03, or "" if there is none.
+func (d *Debugger) nearestSymbol(addr uint16) string {
+	var nearest *debugSymbol
+	for i, s := range d.symbols {
+		if s.name != "" && s.address <= addr && (nearest == nil || s.address > nearest.address) {
+			nearest = &d.symbols[i]
+		}
+	}
+	switch {
+	case nearest == nil:
+		return ""
+	case nearest.address == addr:
+		return nearest.name
+	default:
+		return fmt.Sprintf("%s+$%02X", nearest.name, addr-nearest.address)
+	}
+}
@@ -0,0 +1,67 @@
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "finish",
+		aliases: []string{"fin"},
+		summary: "Run until the current subroutine returns.",
+		detail: "Stops at the instruction following the JSR which called the subroutine.\n" +
+			"Nested JSR/RTS and BRK/RTI pairs are tracked so recursion and interrupts are handled.\n" +
+			"Stopping at a breakpoint first cancels the finish.",
+		handler: func(d *Debugger, _ *cmd, in cpu.Instruction) (bool, error) {
+			d.finishing = true
+			d.finishDepth = 0
+			d.finishReturned = false
+			d.trackFinish(in)
+			d.run = true
+			return true, nil
+		},
+	})
+}
+
+// doFinish stops execution once a finish command has returned from the
+// subroutine it was issued in.
+func (d *Debugger) doFinish(in cpu.Instruction) {
+	if !d.finishing {
+		return
+	}
+
+	if d.finishReturned {
+		fmt.Printf("Returned to $%04X%s\n", d.cpu.PC, d.labelSuffix(d.cpu.PC))
+		d.run = false
+	}
+
+	// Stopped, either returned or at a breakpoint
+	if !d.run {
+		d.finishing = false
+		return
+	}
+
+	d.trackFinish(in)
+}
+
+// trackFinish follows the subroutine nesting for the instruction about to
+// execute.
+func (d *Debugger) trackFinish(in cpu.Instruction) {
+	switch in.Name() {
+	case "JSR", "BRK":
+		d.finishDepth++
+	case "RTS":
+		if d.finishDepth == 0 {
+			d.finishReturned = true
+		} else {
+			d.finishDepth--
+		}
+	case "RTI":
+		// Hardware interrupts are not seen, so ignore their RTI
+		if d.finishDepth > 0 {
+			d.finishDepth--
+		}
+	}
+}
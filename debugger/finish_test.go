@@ -0,0 +1,28 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestFinishTracksNesting(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	d.finishing = true
+
+	jsr, _ := cpu.Decode(0, []byte{0x20, 0x00, 0x10})
+	rts, _ := cpu.Decode(0, []byte{0x60})
+	rti, _ := cpu.Decode(0, []byte{0x40})
+	nop, _ := cpu.Decode(0, []byte{0xEA})
+
+	d.run = true
+	for _, in := range []cpu.Instruction{jsr, nop, rts, rti, nop, rts, nop} {
+		if !d.run {
+			t.Fatal("stopped before returning")
+		}
+		d.doFinish(in)
+	}
+	if d.run || d.finishing {
+		t.Error("expected finish to stop after the outer RTS")
+	}
+}
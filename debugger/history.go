@@ -0,0 +1,79 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// historyFilename is the file in the home directory holding command history
+// between sessions.
+const historyFilename = ".go6502_history"
+
+func init() {
+	commands.register(&command{
+		name:    "history",
+		summary: "Show the command history.",
+		detail:  "History is kept in ~/" + historyFilename + " between sessions.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.history()
+		},
+	})
+}
+
+// historyPath returns the history file, or "" if there is no home directory.
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFilename)
+}
+
+// readHistory loads the history saved by a previous session. A missing file
+// is not an error.
+func (d *Debugger) readHistory() {
+	if d.historyFile == "" {
+		return
+	}
+	f, err := os.Open(d.historyFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := d.liner.ReadHistory(f); err != nil {
+		fmt.Println("Reading history:", err)
+	}
+}
+
+// writeHistory saves the history for the next session.
+func (d *Debugger) writeHistory() error {
+	if d.historyFile == "" {
+		return nil
+	}
+	f, err := os.Create(d.historyFile)
+	if err != nil {
+		return err
+	}
+	_, err = d.liner.WriteHistory(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (d *Debugger) history() error {
+	var buf bytes.Buffer
+	if _, err := d.liner.WriteHistory(&buf); err != nil {
+		return err
+	}
+	s := bufio.NewScanner(&buf)
+	for i := 1; s.Scan(); i++ {
+		fmt.Printf("%4d  %s\n", i, s.Text())
+	}
+	return s.Err()
+}
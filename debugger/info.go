@@ -0,0 +1,79 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "info",
+		usage:   "device [name]",
+		minArgs: 1,
+		maxArgs: 2,
+		summary: "Describe the internal state of a device on the bus, e.g. info device VIA",
+		detail: "With no name lists the devices, marking those which can describe their state with *.\n" +
+			"Unlike reading their registers this has no side effects on the device.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			if !strings.EqualFold(c.arguments[0], "device") {
+				return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+			}
+			if len(c.arguments) == 1 {
+				d.listDevices()
+				return false, nil
+			}
+			return false, d.infoDevice(c.arguments[1])
+		},
+	})
+}
+
+// debugState returns the state of a device, looking through wrappers which
+// inject faults.
+func debugState(m memory.Memory) (memory.DebugState, bool) {
+	for {
+		if s, ok := m.(memory.DebugState); ok {
+			return s, true
+		}
+		f, ok := m.(*memory.Faulty)
+		if !ok {
+			return nil, false
+		}
+		m = f.Memory
+	}
+}
+
+func (d *Debugger) listDevices() {
+	for _, r := range d.cpu.Bus.Regions() {
+		marker := " "
+		if _, ok := debugState(r.Device); ok {
+			marker = "*"
+		}
+		fmt.Printf("%s %v %v\n", marker, r, r.Device)
+	}
+}
+
+func (d *Debugger) infoDevice(name string) error {
+	var region *bus.Region
+	for _, r := range d.cpu.Bus.Regions() {
+		if strings.EqualFold(r.Name, name) {
+			r := r
+			region = &r
+			break
+		}
+	}
+	if region == nil {
+		return fmt.Errorf("No device named %q", name)
+	}
+
+	s, ok := debugState(region.Device)
+	if !ok {
+		return fmt.Errorf("%s (%v) cannot describe its state", region.Name, region.Device)
+	}
+	fmt.Printf("%v %v\n", region, region.Device)
+	fmt.Print(s.DebugState())
+	return nil
+}
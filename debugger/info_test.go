@@ -0,0 +1,27 @@
+package debugger
+
+import (
+	"testing"
+
+	"github.com/peter-mount/go6502/acia6551"
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestInfoDevice(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x8000), "ram", 0)
+	b.Attach(memory.NewFaulty(acia6551.NewAcia6551(acia6551.Options{}), memory.FaultModel{}), "ACIA", 0x9000)
+	d := &Debugger{cpu: &cpu.Cpu{Bus: b}}
+
+	if err := d.infoDevice("acia"); err != nil {
+		t.Error(err)
+	}
+	if err := d.infoDevice("ram"); err == nil {
+		t.Error("expected RAM to have no state to describe")
+	}
+	if err := d.infoDevice("missing"); err == nil {
+		t.Error("expected unknown device to fail")
+	}
+}
@@ -0,0 +1,94 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// lastDepth is the number of executed instructions kept for last.
+const lastDepth = 1000
+
+// executed is an instruction and the registers before it executed.
+type executed struct {
+	in           cpu.Instruction
+	ac, x, y, sp byte
+	sr           byte
+}
+
+// lastRing holds the most recently executed instructions, recorded even
+// while running.
+type lastRing struct {
+	ring  []executed
+	next  int
+	count int
+}
+
+func init() {
+	commands.register(&command{
+		name:    "last",
+		usage:   "[count]",
+		maxArgs: 1,
+		summary: "Show the last instructions executed, e.g. last 50",
+		detail: fmt.Sprintf("Shows up to the last %d instructions, oldest first, with the registers before each executed.\n", lastDepth) +
+			"The default count is 20.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			count := 20
+			if len(c.arguments) > 0 {
+				n, err := strconv.Atoi(c.arguments[0])
+				if err != nil || n < 1 {
+					return false, fmt.Errorf("Invalid count %q", c.arguments[0])
+				}
+				count = n
+			}
+			d.showLast(count)
+			return false, nil
+		},
+	})
+}
+
+// recordLast adds the instruction about to execute to the ring.
+func (d *Debugger) recordLast(in cpu.Instruction) {
+	l := &d.last
+	if l.ring == nil {
+		l.ring = make([]executed, lastDepth)
+	}
+	c := d.cpu
+	l.ring[l.next] = executed{in: in, ac: c.AC, x: c.X, y: c.Y, sp: c.SP, sr: c.SR}
+	l.next = (l.next + 1) % len(l.ring)
+	if l.count < len(l.ring) {
+		l.count++
+	}
+}
+
+// drop removes the n most recent instructions, when they are rewound.
+func (l *lastRing) drop(n int) {
+	if n > l.count {
+		n = l.count
+	}
+	l.count -= n
+	if l.ring != nil {
+		l.next = (l.next - n + len(l.ring)) % len(l.ring)
+	}
+}
+
+// showLast prints the last count instructions executed. The instruction
+// about to execute is recorded but excluded, as it has not yet executed.
+func (d *Debugger) showLast(count int) {
+	l := &d.last
+	available := l.count - 1
+	if available < 1 {
+		fmt.Println("No instructions executed.")
+		return
+	}
+	if count > available {
+		count = available
+	}
+
+	for i := count; i > 0; i-- {
+		e := l.ring[(l.next-1-i+2*len(l.ring))%len(l.ring)]
+		fmt.Printf("$%04X  %-16v A:%02X X:%02X Y:%02X SP:%02X SR:%s%s\n",
+			e.in.Address, e.in, e.ac, e.x, e.y, e.sp, statusFlags(e.sr), d.labelSuffix(e.in.Address))
+	}
+}
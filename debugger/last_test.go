@@ -0,0 +1,29 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestLastWrapsRing(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	for i := 0; i < lastDepth+5; i++ {
+		d.cpu.X = byte(i)
+		d.recordLast(cpu.Instruction{Address: uint16(i)})
+	}
+
+	if d.last.count != lastDepth {
+		t.Error(fmt.Sprintf("expected %d instructions got %d", lastDepth, d.last.count))
+	}
+	// The latest is the instruction about to execute
+	newest := d.last.ring[(d.last.next-1+lastDepth)%lastDepth]
+	if newest.in.Address != lastDepth+4 || newest.x != byte((lastDepth+4)&0xFF) {
+		t.Error(fmt.Sprintf("expected newest at $%04X got $%04X", lastDepth+4, newest.in.Address))
+	}
+	oldest := d.last.ring[d.last.next]
+	if oldest.in.Address != 5 {
+		t.Error(fmt.Sprintf("expected oldest at $0005 got $%04X", oldest.in.Address))
+	}
+}
@@ -0,0 +1,164 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// macroDepth limits how deeply aliases may expand to other aliases, so one
+// expanding to itself fails rather than looping.
+const macroDepth = 16
+
+func init() {
+	commands.register(&command{
+		name:    "alias",
+		usage:   `[name ["command; command..."]]`,
+		maxArgs: -1,
+		summary: `Define a command running a sequence of commands, e.g. alias ss "step; dump $0200 16"`,
+		detail: "Arguments given to the alias are appended to its last command. Aliases may use other aliases\n" +
+			"but not hide a debugger command. With a name shows that alias, with no arguments lists them.",
+		handler: (*Debugger).commandAlias,
+	})
+	commands.register(&command{
+		name:    "unalias",
+		usage:   "<name>|all",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Remove an alias.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			name := strings.ToLower(c.arguments[0])
+			if name == "all" {
+				d.macros = nil
+				return false, nil
+			}
+			if _, exists := d.macros[name]; !exists {
+				return false, fmt.Errorf("No alias %s", c.arguments[0])
+			}
+			delete(d.macros, name)
+			return false, nil
+		},
+	})
+}
+
+func (d *Debugger) commandAlias(c *cmd, _ cpu.Instruction) (bool, error) {
+	if len(c.arguments) == 0 {
+		if len(d.macros) == 0 {
+			fmt.Println("No aliases.")
+		}
+		for _, name := range d.macroNames() {
+			fmt.Printf("%s %q\n", name, d.macros[name])
+		}
+		return false, nil
+	}
+
+	name := strings.ToLower(c.arguments[0])
+	if len(c.arguments) == 1 {
+		body, exists := d.macros[name]
+		if !exists {
+			return false, fmt.Errorf("No alias %s", c.arguments[0])
+		}
+		fmt.Printf("%s %q\n", name, body)
+		return false, nil
+	}
+
+	if command := commands.lookup(name); command != nil {
+		return false, fmt.Errorf("Alias %s would hide the %s command", name, command.name)
+	}
+
+	// The commands are the rest of the input after the name, optionally quoted
+	body := strings.TrimSpace(c.input)
+	for i := 0; i < 2; i++ {
+		body = strings.TrimSpace(body[len(strings.Fields(body)[0]):])
+	}
+	if strings.HasPrefix(body, `"`) {
+		quoted, err := strconv.QuotedPrefix(body)
+		if err != nil || strings.TrimSpace(body[len(quoted):]) != "" {
+			return false, fmt.Errorf("Invalid commands %s", body)
+		}
+		body, _ = strconv.Unquote(quoted)
+	}
+	if len(splitMacro(body)) == 0 {
+		return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+	}
+
+	if d.macros == nil {
+		d.macros = make(map[string]string)
+	}
+	d.macros[name] = body
+	return false, nil
+}
+
+// macroNames returns the names of the aliases in order.
+func (d *Debugger) macroNames() []string {
+	var names []string
+	for name := range d.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitMacro returns the commands in an alias.
+func splitMacro(body string) []string {
+	var lines []string
+	for _, s := range strings.Split(body, ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			lines = append(lines, s)
+		}
+	}
+	return lines
+}
+
+// macroCommand returns a command running the alias name, or nil if there is
+// none. Running it queues the commands ahead of any already queued, so a
+// failing command abandons the rest.
+func (d *Debugger) macroCommand(name string) *command {
+	name = strings.ToLower(name)
+	if _, exists := d.macros[name]; !exists {
+		return nil
+	}
+	return &command{
+		name:    name,
+		maxArgs: -1,
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			expanded, err := d.expandMacro(name, c.arguments, 0)
+			if err != nil {
+				return false, err
+			}
+			d.inputQueue = append(expanded, d.inputQueue...)
+			return false, nil
+		},
+	}
+}
+
+// expandMacro returns the commands run by an alias, expanding any aliases it
+// uses.
+func (d *Debugger) expandMacro(name string, arguments []string, depth int) ([]string, error) {
+	if depth >= macroDepth {
+		return nil, fmt.Errorf("Alias %s nests too deeply", name)
+	}
+
+	lines := splitMacro(d.macros[name])
+	if len(arguments) > 0 {
+		lines[len(lines)-1] += " " + strings.Join(arguments, " ")
+	}
+
+	var expanded []string
+	for _, c := range lines {
+		fields := strings.Fields(c)
+		if _, exists := d.macros[strings.ToLower(fields[0])]; !exists {
+			expanded = append(expanded, c)
+			continue
+		}
+		inner, err := d.expandMacro(strings.ToLower(fields[0]), fields[1:], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, inner...)
+	}
+	return expanded, nil
+}
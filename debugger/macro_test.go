@@ -0,0 +1,62 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func defineAlias(d *Debugger, input string) error {
+	c := &cmd{command: commands.lookup("alias"), input: input, arguments: strings.Fields(input)[1:]}
+	_, err := d.commandAlias(c, cpu.Instruction{})
+	return err
+}
+
+func TestAliasExpandsToQueuedCommands(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	for _, input := range []string{
+		`alias ss "step; dump $0200 16"`,
+		`alias twice ss; ss`,
+		`alias loop loop`,
+	} {
+		if err := defineAlias(d, input); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expanded, err := d.expandMacro("twice", []string{"4"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[step dump $0200 16 step dump $0200 16 4]"
+	if actual := fmt.Sprint(expanded); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+
+	if _, err := d.expandMacro("loop", nil, 0); err == nil {
+		t.Error("expected recursive alias to fail")
+	}
+	if err := defineAlias(d, `alias step "next"`); err == nil {
+		t.Error("expected alias hiding a command to fail")
+	}
+
+	// The alias is run as a command, queuing its commands
+	d.QueueCommands([]string{"SS", "q"})
+	c, err := d.getCommand()
+	if err != nil || c.command == nil {
+		t.Fatal("expected alias to be found")
+	}
+	if _, err := c.command.handler(d, c, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	expected = "[step dump $0200 16 q]"
+	if actual := fmt.Sprint(d.inputQueue); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+
+	if session := string(d.session()); !strings.Contains(session, `alias ss "step; dump $0200 16"`) {
+		t.Error("expected alias in session")
+	}
+}
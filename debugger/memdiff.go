@@ -0,0 +1,155 @@
+package debugger
+
+import (
+	"fmt"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// memDiff is a range of memory snapshotted when execution resumes, with any
+// bytes changed shown at the next stop.
+type memDiff struct {
+	address  uint16
+	length   int
+	snapshot []byte
+}
+
+// memDiffLines limits how many changed bytes are shown for each range.
+const memDiffLines = 32
+
+func init() {
+	commands.register(&command{
+		name:    "memdiff",
+		usage:   "[on <address> <length>|off [<address>]]",
+		maxArgs: 3,
+		summary: "Show bytes in a memory range which changed between stops, e.g. memdiff on $0200 256",
+		detail: "The range is read when execution resumes and compared at the next stop.\n" +
+			"off removes the range at address, or all ranges. With no arguments lists the ranges.",
+		handler: (*Debugger).commandMemDiff,
+	})
+}
+
+func (d *Debugger) commandMemDiff(c *cmd, _ cpu.Instruction) (bool, error) {
+	if len(c.arguments) == 0 {
+		if len(d.memDiffs) == 0 {
+			fmt.Println("No memdiff ranges.")
+		}
+		for _, m := range d.memDiffs {
+			fmt.Printf("$%04X-$%04X (%d bytes)\n", m.address, int(m.address)+m.length-1, m.length)
+		}
+		return false, nil
+	}
+
+	switch c.arguments[0] {
+	case "on":
+		if len(c.arguments) != 3 {
+			return false, fmt.Errorf("Usage: memdiff on <address> <length>")
+		}
+		addr, err := d.parseUint16(c.arguments[1])
+		if err != nil {
+			return false, err
+		}
+		length, err := d.evaluate(c.arguments[2], 0x10000-int(addr))
+		if err != nil {
+			return false, err
+		}
+		if length == 0 {
+			return false, fmt.Errorf("Invalid length %s", c.arguments[2])
+		}
+		return false, d.addMemDiff(addr, length)
+
+	case "off":
+		if len(c.arguments) == 1 {
+			d.memDiffs = nil
+			return false, nil
+		}
+		addr, err := d.parseUint16(c.arguments[1])
+		if err != nil {
+			return false, err
+		}
+		for i, m := range d.memDiffs {
+			if m.address == addr {
+				d.memDiffs = append(d.memDiffs[:i:i], d.memDiffs[i+1:]...)
+				return false, nil
+			}
+		}
+		return false, fmt.Errorf("No memdiff range at $%04X", addr)
+	}
+	return false, fmt.Errorf("Unknown memdiff option %s", c.arguments[0])
+}
+
+// addMemDiff adds a range, replacing any at the same address.
+func (d *Debugger) addMemDiff(addr uint16, length int) error {
+	m := &memDiff{address: addr, length: length}
+	if err := d.snapshotMemDiff(m); err != nil {
+		return err
+	}
+	for i, e := range d.memDiffs {
+		if e.address == addr {
+			d.memDiffs[i] = m
+			return nil
+		}
+	}
+	d.memDiffs = append(d.memDiffs, m)
+	return nil
+}
+
+func (d *Debugger) snapshotMemDiff(m *memDiff) error {
+	data, err := d.cpu.Bus.PeekBlock(m.address, m.length)
+	if err != nil {
+		return err
+	}
+	m.snapshot = data
+	return nil
+}
+
+// changes returns the offsets of the bytes in data which differ from the
+// snapshot.
+func (m *memDiff) changes(data []byte) []int {
+	var changed []int
+	for i := range data {
+		if data[i] != m.snapshot[i] {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+// snapshotMemDiffs reads each range as execution resumes.
+func (d *Debugger) snapshotMemDiffs() {
+	for _, m := range d.memDiffs {
+		if err := d.snapshotMemDiff(m); err != nil {
+			m.snapshot = nil
+		}
+	}
+}
+
+// showMemDiffs prints the bytes changed in each range since it was read.
+func (d *Debugger) showMemDiffs() {
+	for _, m := range d.memDiffs {
+		if m.snapshot == nil {
+			continue
+		}
+		data, err := d.cpu.Bus.PeekBlock(m.address, m.length)
+		if err != nil {
+			fmt.Printf("memdiff $%04X: %v\n", m.address, err)
+			continue
+		}
+
+		changed := m.changes(data)
+		if len(changed) == 0 {
+			continue
+		}
+
+		fmt.Printf("memdiff $%04X-$%04X: %d bytes changed\n", m.address, int(m.address)+m.length-1, len(changed))
+		for n, i := range changed {
+			if n == memDiffLines {
+				fmt.Printf("  ... and %d more\n", len(changed)-n)
+				break
+			}
+			addr := m.address + uint16(i)
+			fmt.Printf("  $%04X%s: $%02X -> %s\n", addr, d.labelSuffix(addr), m.snapshot[i],
+				d.paint(ansiChanged, fmt.Sprintf("$%02X", data[i])))
+		}
+	}
+}
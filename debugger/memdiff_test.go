@@ -0,0 +1,40 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestMemDiffShowsChangesSinceResume(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	d := &Debugger{cpu: &cpu.Cpu{Bus: b}}
+
+	if _, err := d.commandMemDiff(&cmd{arguments: []string{"on", "$0200", "16"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+	b.Write(0x0203, 0x41)
+	b.Write(0x0210, 0x42) // outside the range
+	d.snapshotMemDiffs()
+	b.Write(0x0205, 0x43)
+
+	m := d.memDiffs[0]
+	data, _ := b.ReadBlock(m.address, m.length)
+	if changed := fmt.Sprint(m.changes(data)); changed != "[5]" {
+		t.Error(fmt.Sprintf("expected [5] changed got %s", changed))
+	}
+
+	for _, bad := range [][]string{{"on", "$FFF0", "17"}, {"on", "$0200", "0"}, {"on", "$0200"}, {"off", "$0300"}, {"sideways"}} {
+		if _, err := d.commandMemDiff(&cmd{arguments: bad}, cpu.Instruction{}); err == nil {
+			t.Error(fmt.Sprintf("expected %v to fail", bad))
+		}
+	}
+
+	if _, err := d.commandMemDiff(&cmd{arguments: []string{"off", "$0200"}}, cpu.Instruction{}); err != nil || len(d.memDiffs) != 0 {
+		t.Error("expected the range to be removed")
+	}
+}
@@ -0,0 +1,134 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// noSymbol is where cycles outside any symbol are attributed.
+const noSymbol = "(no symbol)"
+
+// profiler attributes executed cycles to the symbol enclosing the PC.
+type profiler struct {
+	running      bool
+	lastSymbol   string
+	lastCycles   uint64
+	cycles       map[string]uint64
+	instructions map[string]uint64
+}
+
+func init() {
+	commands.register(&command{
+		name:    "profile",
+		usage:   "start|stop|report [count]",
+		minArgs: 1,
+		maxArgs: 2,
+		summary: "Profile cycles spent in each symbol.",
+		detail: "start clears the profile and records while running, stop pauses recording and report\n" +
+			"lists the symbols using the most cycles, default 20. Cycles are attributed to the\n" +
+			"nearest symbol at or before the PC, so subroutines are counted separately from their callers.",
+		handler: (*Debugger).commandProfile,
+	})
+}
+
+func (d *Debugger) commandProfile(c *cmd, _ cpu.Instruction) (bool, error) {
+	p := &d.profiler
+	switch c.arguments[0] {
+	case "start":
+		*p = profiler{
+			running:      true,
+			cycles:       make(map[string]uint64),
+			instructions: make(map[string]uint64),
+		}
+		fmt.Println("Profiling started")
+	case "stop":
+		p.running = false
+		p.lastSymbol = ""
+		fmt.Println("Profiling stopped")
+	case "report":
+		count := 20
+		if len(c.arguments) > 1 {
+			n, err := strconv.Atoi(c.arguments[1])
+			if err != nil || n < 1 {
+				return false, fmt.Errorf("Invalid count %q", c.arguments[1])
+			}
+			count = n
+		}
+		p.report(count)
+	default:
+		return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+	}
+	return false, nil
+}
+
+// profile attributes the cycles since the previous instruction to its symbol.
+func (d *Debugger) profile(in cpu.Instruction) {
+	p := &d.profiler
+	if !p.running {
+		return
+	}
+
+	if p.lastSymbol != "" {
+		p.cycles[p.lastSymbol] += d.cpu.Cycles - p.lastCycles
+		p.instructions[p.lastSymbol]++
+	}
+
+	p.lastSymbol = d.enclosingSymbol(in.Address)
+	p.lastCycles = d.cpu.Cycles
+}
+
+func (p *profiler) report(count int) {
+	if len(p.cycles) == 0 {
+		fmt.Println("No profile, use profile start")
+		return
+	}
+
+	var total uint64
+	names := make([]string, 0, len(p.cycles))
+	for name, cycles := range p.cycles {
+		names = append(names, name)
+		total += cycles
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if p.cycles[names[i]] != p.cycles[names[j]] {
+			return p.cycles[names[i]] > p.cycles[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > count {
+		names = names[:count]
+	}
+
+	fmt.Printf("%-24s %12s %6s %12s\n", "Symbol", "Cycles", "%", "Instructions")
+	for _, name := range names {
+		cycles := p.cycles[name]
+		fmt.Printf("%-24s %12d %6.2f %12d\n", name, cycles, 100*float64(cycles)/float64(total), p.instructions[name])
+	}
+	fmt.Printf("%-24s %12d\n", "Total", total)
+}
+
+// enclosingSymbol returns the nearest symbol at or before addr.
+func (d *Debugger) enclosingSymbol(addr uint16) string {
+	if d.sortedSymbols == nil {
+		for _, s := range d.symbols {
+			if s.name != "" {
+				d.sortedSymbols = append(d.sortedSymbols, s)
+			}
+		}
+		sort.SliceStable(d.sortedSymbols, func(i, j int) bool {
+			return d.sortedSymbols[i].address < d.sortedSymbols[j].address
+		})
+	}
+
+	// First symbol after addr
+	i := sort.Search(len(d.sortedSymbols), func(i int) bool {
+		return d.sortedSymbols[i].address > addr
+	})
+	if i == 0 {
+		return noSymbol
+	}
+	return d.sortedSymbols[i-1].name
+}
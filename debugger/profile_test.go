@@ -0,0 +1,34 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestProfileAttributesCyclesToEnclosingSymbol(t *testing.T) {
+	d := &Debugger{
+		cpu: &cpu.Cpu{},
+		symbols: debugSymbols{
+			{address: 0x1000, name: "main"},
+			{address: 0x2000, name: "delay"},
+		},
+	}
+	if _, err := d.commandProfile(&cmd{arguments: []string{"start"}}, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []struct {
+		pc     uint16
+		cycles uint64
+	}{{0x0800, 2}, {0x1000, 6}, {0x2000, 3}, {0x2002, 3}, {0x1003, 0}} {
+		d.profile(cpu.Instruction{Address: step.pc})
+		d.cpu.Cycles += step.cycles
+	}
+
+	p := d.profiler
+	if p.cycles[noSymbol] != 2 || p.cycles["main"] != 6 || p.cycles["delay"] != 6 || p.instructions["delay"] != 2 {
+		t.Error(fmt.Sprintf("unexpected profile %v %v", p.cycles, p.instructions))
+	}
+}
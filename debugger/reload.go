@@ -0,0 +1,46 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "reload",
+		usage:   "[config]",
+		maxArgs: 1,
+		summary: "Reload the ROM images, programs and symbols, then reset the CPU.",
+		detail: "With config the machine config is read again first, so ROM and program files may change.\n" +
+			"RAM is preserved, and the debugger stops at the reset vector. Sending SIGHUP reloads the same way.",
+		handler: (*Debugger).commandReload,
+	})
+}
+
+// SetReload sets the function reloading the machine's ROMs and programs.
+func (d *Debugger) SetReload(reload func(config bool) error) {
+	d.reload = reload
+}
+
+func (d *Debugger) commandReload(c *cmd, _ cpu.Instruction) (bool, error) {
+	config := false
+	if len(c.arguments) > 0 {
+		if !strings.EqualFold(c.arguments[0], "config") {
+			return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+		}
+		config = true
+	}
+	if d.reload == nil {
+		return false, fmt.Errorf("Reload needs a machine config")
+	}
+
+	if err := d.reload(config); err != nil {
+		return false, err
+	}
+	d.loadSymbols()
+	d.commandReset()
+	// Release so the cpu abandons the current instruction
+	return true, nil
+}
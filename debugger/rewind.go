@@ -0,0 +1,209 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+// rewindDepth is the number of instructions which can be stepped back.
+const rewindDepth = 1024
+
+// checkpoint is the machine state before an instruction executed: the
+// registers, and the previous contents of the RAM it wrote to.
+type checkpoint struct {
+	instructionPC uint16
+	cycles        uint64
+	ac, x, y      byte
+	sp, sr        byte
+	writes        []byteUndo
+}
+
+// byteUndo is the previous contents of a byte of RAM written by an
+// instruction.
+type byteUndo struct {
+	ram    *ramShadow
+	offset int
+	value  byte
+}
+
+// ramShadow holds a copy of a RAM, kept up to date by watching writes to it,
+// so the previous contents of each byte written can be recorded.
+type ramShadow struct {
+	ram    *memory.Ram
+	shadow []byte
+}
+
+// rewinder records checkpoints in a ring buffer so execution can be stepped
+// backwards. Only RAM attached directly to the bus is restored. Nothing is
+// recorded until the first checkpoint creates the ring.
+type rewinder struct {
+	rams    []*ramShadow
+	ring    []checkpoint
+	next    int // index of the next checkpoint in ring
+	count   int // number of checkpoints held
+	pending *checkpoint
+}
+
+func init() {
+	commands.register(&command{
+		name:    "rstep",
+		aliases: []string{"rs"},
+		summary: "Step back one instruction.",
+		detail:  "Restores the registers and RAM to before the previous instruction executed.",
+		handler: func(d *Debugger, _ *cmd, _ cpu.Instruction) (bool, error) {
+			return d.rewind(1)
+		},
+	})
+	commands.register(&command{
+		name:    "rewind",
+		usage:   "[count]",
+		maxArgs: 1,
+		summary: "Step back count instructions, e.g. rewind 100",
+		detail: fmt.Sprintf("Up to the last %d instructions can be rewound, with no count shows how many are available.\n", rewindDepth) +
+			"Registers and RAM are restored, but not the state of other devices.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			if len(c.arguments) == 0 {
+				fmt.Printf("%d instructions can be rewound\n", d.rewinder.count)
+				return false, nil
+			}
+			n, err := strconv.Atoi(c.arguments[0])
+			if err != nil || n < 1 {
+				return false, fmt.Errorf("Invalid count %q", c.arguments[0])
+			}
+			return d.rewind(n)
+		},
+	})
+}
+
+// checkpoint records the state before the instruction about to execute,
+// completing the checkpoint of the previous one with the bytes it wrote.
+func (d *Debugger) checkpoint(in cpu.Instruction) {
+	r := &d.rewinder
+	if r.ring == nil {
+		r.ring = make([]checkpoint, rewindDepth)
+		r.attach(d.cpu.Bus)
+	}
+
+	if r.pending != nil {
+		r.ring[r.next] = *r.pending
+		r.next = (r.next + 1) % len(r.ring)
+		if r.count < len(r.ring) {
+			r.count++
+		}
+	}
+
+	r.begin(in.Address, d.cpu)
+}
+
+// begin starts the checkpoint of the instruction at pc, which completes when
+// the next one starts.
+func (r *rewinder) begin(pc uint16, c *cpu.Cpu) {
+	r.pending = &checkpoint{
+		instructionPC: pc,
+		cycles:        c.Cycles,
+		ac:            c.AC,
+		x:             c.X,
+		y:             c.Y,
+		sp:            c.SP,
+		sr:            c.SR,
+	}
+}
+
+// attach watches writes to each RAM on the bus, including any mirrors of it.
+func (r *rewinder) attach(b *bus.Bus) {
+	shadows := make(map[*memory.Ram]*ramShadow)
+	for _, region := range b.Regions() {
+		ram, ok := region.Device.(*memory.Ram)
+		if !ok {
+			continue
+		}
+		rs, exists := shadows[ram]
+		if !exists {
+			rs = &ramShadow{ram: ram, shadow: append([]byte(nil), ram.Data()...)}
+			shadows[ram] = rs
+			r.rams = append(r.rams, rs)
+		}
+		start := region.Start
+		b.Watch(region.Start, region.End, bus.AccessWrite, func(_ bus.Access, a uint16, v byte, _ uint16) {
+			r.record(rs, int(a-start)%len(rs.shadow), v)
+		})
+	}
+}
+
+// record notes the previous contents of a byte of RAM as it is written.
+func (r *rewinder) record(rs *ramShadow, offset int, v byte) {
+	if r.pending != nil {
+		r.pending.writes = append(r.pending.writes, byteUndo{ram: rs, offset: offset, value: rs.shadow[offset]})
+	}
+	rs.shadow[offset] = v
+}
+
+// sync updates the shadows after RAM may have changed without a bus write
+// being seen, e.g. when patched from the debugger prompt.
+func (r *rewinder) sync() {
+	for _, rs := range r.rams {
+		copy(rs.shadow, rs.ram.Data())
+	}
+}
+
+// rewind restores the state before the last n instructions. Returns true
+// to release the current instruction so the cpu continues from the restored
+// PC.
+func (d *Debugger) rewind(n int) (bool, error) {
+	r := &d.rewinder
+	if r.count == 0 {
+		return false, fmt.Errorf("Nothing to rewind")
+	}
+	if n > r.count {
+		return false, fmt.Errorf("Only %d instructions can be rewound", r.count)
+	}
+
+	// Bytes written since the last checkpoint are part of the current state
+	if r.pending != nil {
+		r.restore(r.pending.writes)
+		r.pending = nil
+	}
+
+	var cp checkpoint
+	for i := 0; i < n; i++ {
+		r.next = (r.next + len(r.ring) - 1) % len(r.ring)
+		r.count--
+		cp = r.ring[r.next]
+		r.ring[r.next] = checkpoint{}
+		r.restore(cp.writes)
+	}
+
+	c := d.cpu
+	c.Cycles = cp.cycles
+	c.AC, c.X, c.Y, c.SP, c.SR = cp.ac, cp.x, cp.y, cp.sp, cp.sr
+	fmt.Printf("Rewound %d instructions\n", n)
+	d.last.drop(n)
+	d.abandonInstruction(uint64(n))
+
+	if c.PC == cp.instructionPC {
+		// The current instruction won't be checkpointed again when it runs
+		r.begin(c.PC, c)
+		fmt.Println(c)
+		return false, nil
+	}
+	c.PC = cp.instructionPC
+	// The abandoned instruction is recorded again when it executes
+	d.last.drop(1)
+	d.abandonInstruction(1)
+	d.run = false
+	// Release so the cpu abandons the current instruction
+	return true, nil
+}
+
+// restore writes back the previous contents of bytes, in reverse order.
+func (r *rewinder) restore(writes []byteUndo) {
+	for i := len(writes) - 1; i >= 0; i-- {
+		w := writes[i]
+		w.ram.ram.Data()[w.offset] = w.value
+		w.ram.shadow[w.offset] = w.value
+	}
+}
@@ -0,0 +1,127 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestRewindRestoresRegistersAndRam(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{
+		0xA9, 0x01, // $1000 LDA #$01
+		0x8D, 0x00, 0x02, // $1002 STA $0200
+		0xE8,             // $1005 INX
+		0x8D, 0x00, 0x03, // $1006 STA $0300
+		0xEA, // $1009 NOP
+	})
+	b.WriteBlock(0xFFFC, []byte{0x00, 0x10})
+
+	c := &cpu.Cpu{Bus: b}
+	d := &Debugger{cpu: c, run: true}
+	c.AttachMonitor(d)
+	c.PowerOn()
+
+	for i := 0; i < 4; i++ {
+		c.Step()
+	}
+	// Checkpoints complete at the start of the next instruction
+	d.checkpoint(cpu.ReadInstruction(c.PC, b))
+
+	if _, err := d.rewind(3); err != nil {
+		t.Fatal(err)
+	}
+	if c.PC != 0x1002 || c.AC != 0x01 || c.X != 0 {
+		t.Error(fmt.Sprintf("expected PC $1002 A $01 X $00 got %v", c))
+	}
+	if b.Read(0x0200) != 0 || b.Read(0x0300) != 0 {
+		t.Error("expected stores to be undone")
+	}
+
+	if _, err := d.rewind(2); err == nil {
+		t.Error("expected rewinding past the first instruction to fail")
+	}
+}
+
+func TestRewindRecordsOnlyBytesWritten(t *testing.T) {
+	b, _ := bus.CreateBus()
+	ram := memory.NewRam(0x10000)
+	b.Attach(ram, "ram", 0)
+	b.WriteBlock(0x1000, []byte{
+		0xEA,             // $1000 NOP
+		0x8D, 0x00, 0x02, // $1001 STA $0200
+	})
+	b.WriteBlock(0xFFFC, []byte{0x00, 0x10})
+	b.Write(0x0200, 0x55)
+
+	c := &cpu.Cpu{Bus: b}
+	d := &Debugger{cpu: c, run: true}
+	c.AttachMonitor(d)
+	c.PowerOn()
+	ram.ClearDirty()
+
+	// A patch made outside the bus is picked up when the prompt returns
+	c.Step()
+	ram.Data()[0x0200] = 0x66
+	d.rewinder.sync()
+	c.Step()
+	d.checkpoint(cpu.ReadInstruction(c.PC, b))
+
+	if len(d.rewinder.ring[0].writes) != 0 {
+		t.Error("expected no writes recorded for NOP")
+	}
+	if writes := d.rewinder.ring[1].writes; len(writes) != 1 || writes[0].offset != 0x0200 || writes[0].value != 0x66 {
+		t.Error(fmt.Sprintf("expected one write to $0200 recorded got %+v", writes))
+	}
+	if pages := fmt.Sprint(ram.DirtyPages()); pages != "[2]" {
+		t.Error(fmt.Sprintf("expected the dirty pages left alone got %s", pages))
+	}
+
+	if _, err := d.rewind(2); err != nil {
+		t.Fatal(err)
+	}
+	if v := b.Read(0x0200); v != 0x66 {
+		t.Error(fmt.Sprintf("expected $0200 restored to $66 got $%02X", v))
+	}
+}
+
+func TestRewindToCurrentInstructionKeepsRecording(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{
+		0x8D, 0x00, 0x02, // $1000 STA $0200
+		0x4C, 0x00, 0x10, // $1003 JMP $1000
+	})
+	b.WriteBlock(0xFFFC, []byte{0x00, 0x10})
+	b.Write(0x0200, 0x55)
+
+	c := &cpu.Cpu{Bus: b}
+	d := &Debugger{cpu: c, run: true}
+	c.AttachMonitor(d)
+	c.PowerOn()
+
+	c.Step()
+	c.Step()
+	d.checkpoint(cpu.ReadInstruction(c.PC, b))
+
+	// Back round the loop to the STA the cpu is stopped at
+	if release, err := d.rewind(2); err != nil || release {
+		t.Fatal(fmt.Sprintf("expected to stay at $1000 got %v %v", release, err))
+	}
+
+	// The STA then runs from the prompt without a new checkpoint
+	b.Write(0x0200, c.AC)
+	c.PC = 0x1003
+	d.checkpoint(cpu.ReadInstruction(c.PC, b))
+
+	if _, err := d.rewind(1); err != nil {
+		t.Fatal(err)
+	}
+	if v := b.Read(0x0200); c.PC != 0x1000 || v != 0x55 {
+		t.Error(fmt.Sprintf("expected PC $1000 and $0200 restored to $55 got $%04X $%02X", c.PC, v))
+	}
+}
@@ -0,0 +1,207 @@
+package debugger
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"sync/atomic"
+)
+
+// RPC is the JSON-RPC interface to the debugger, for editors and other front
+// ends. Commands are run as if typed at the prompt, sharing the web front
+// end's way of interleaving them with the terminal, so methods which run a
+// command return once the debugger is waiting for the next.
+type RPC struct {
+	d *Debugger
+}
+
+// Empty is the argument or reply of methods which take or return nothing.
+type Empty struct{}
+
+// CommandArgs is a debugger command, as typed at the prompt.
+type CommandArgs struct {
+	Command string `json:"command"`
+}
+
+// StepArgs is how many instructions Step executes, 1 if not set.
+type StepArgs struct {
+	Count int `json:"count"`
+}
+
+// BreakpointArgs is where SetBreakpoint breaks: an address expression or
+// file:line, and an optional condition.
+type BreakpointArgs struct {
+	Location  string `json:"location"`
+	Condition string `json:"condition"`
+}
+
+// MemoryArgs is the memory ReadMemory reads.
+type MemoryArgs struct {
+	Address string `json:"address"`
+	Length  int    `json:"length"`
+}
+
+// StateArgs selects the memory included in the state, the PC if "".
+type StateArgs struct {
+	Memory string `json:"memory"`
+}
+
+// ServeRPC starts the JSON-RPC interface listening on addr, a TCP address
+// e.g. localhost:6503 or unix:path for a unix socket. Methods are called as
+// Debugger.Step, Debugger.Continue, Debugger.SetBreakpoint,
+// Debugger.ReadMemory, Debugger.State, Debugger.Command and Debugger.Pause.
+func (d *Debugger) ServeRPC(addr string) error {
+	network := "tcp"
+	if strings.HasPrefix(addr, "unix:") {
+		network, addr = "unix", strings.TrimPrefix(addr, "unix:")
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	if err := d.serveRPC(listener); err != nil {
+		return err
+	}
+	fmt.Printf("Debugger JSON-RPC on %s %s\n", network, listener.Addr())
+	return nil
+}
+
+func (d *Debugger) serveRPC(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName("Debugger", &RPC{d: d}); err != nil {
+		return err
+	}
+	if d.web == nil {
+		d.web = newWebServer()
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				fmt.Println("Debugger RPC:", err)
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return nil
+}
+
+// run sends a command to the debugger, waiting until it has finished if wait
+// is set.
+func (r *RPC) run(command string, wait bool) error {
+	c := remoteCommand{input: command}
+	var done chan error
+	if wait {
+		done = make(chan error, 1)
+		c.done = done
+	}
+
+	select {
+	case r.d.web.commands <- c:
+	default:
+		return fmt.Errorf("Too many queued commands")
+	}
+	if wait {
+		return <-done
+	}
+	return nil
+}
+
+// Command runs a debugger command, returning the state once it has finished.
+// The output of the command is shown in the terminal.
+func (r *RPC) Command(args CommandArgs, reply *State) error {
+	if strings.TrimSpace(args.Command) == "" {
+		return fmt.Errorf("Missing command")
+	}
+	if err := r.run(args.Command, true); err != nil {
+		return err
+	}
+	*reply = r.d.webState("")
+	return nil
+}
+
+// Step executes instructions, returning the state once stopped.
+func (r *RPC) Step(args StepArgs, reply *State) error {
+	if args.Count < 0 {
+		return fmt.Errorf("Invalid count %d", args.Count)
+	}
+	for i := 0; i < args.Count || i == 0; i++ {
+		if err := r.run("step", true); err != nil {
+			return err
+		}
+	}
+	*reply = r.d.webState("")
+	return nil
+}
+
+// Continue resumes execution, returning without waiting for it to stop. Poll
+// State until it is no longer running.
+func (r *RPC) Continue(_ Empty, _ *Empty) error {
+	return r.run("continue", false)
+}
+
+// Pause stops execution before the next instruction.
+func (r *RPC) Pause(_ Empty, _ *Empty) error {
+	atomic.StoreInt32(&r.d.web.pause, 1)
+	return nil
+}
+
+// SetBreakpoint adds a breakpoint, returning the breakpoints.
+func (r *RPC) SetBreakpoint(args BreakpointArgs, reply *[]BreakpointState) error {
+	if strings.TrimSpace(args.Location) == "" || strings.ContainsAny(args.Location+args.Condition, ";\n") {
+		return fmt.Errorf("Invalid breakpoint %q", args.Location)
+	}
+	command := "break " + args.Location
+	if args.Condition != "" {
+		command += " if " + args.Condition
+	}
+	if err := r.run(command, true); err != nil {
+		return err
+	}
+	*reply = r.d.webState("").Breakpoints
+	return nil
+}
+
+// ReadMemory returns the bytes from an address expression, which is only
+// possible while stopped.
+func (r *RPC) ReadMemory(args MemoryArgs, reply *[]int) error {
+	if args.Length < 1 || args.Length > 0x10000 {
+		return fmt.Errorf("Invalid length %d", args.Length)
+	}
+
+	d := r.d
+	d.web.mu.Lock()
+	defer d.web.mu.Unlock()
+	if !d.web.stopped {
+		return fmt.Errorf("Running, memory can only be read when stopped")
+	}
+
+	addr, err := d.parseUint16(args.Address)
+	if err != nil {
+		return err
+	}
+	if int(addr)+args.Length > 0x10000 {
+		return fmt.Errorf("Invalid length %d from $%04X", args.Length, addr)
+	}
+	data, err := d.cpu.Bus.PeekBlock(addr, args.Length)
+	if err != nil {
+		return err
+	}
+	values := make([]int, len(data))
+	for i, b := range data {
+		values[i] = int(b)
+	}
+	*reply = values
+	return nil
+}
+
+// State returns the registers, disassembly, breakpoints and 256 bytes of
+// memory, or only that the cpu is running.
+func (r *RPC) State(args StateArgs, reply *State) error {
+	*reply = r.d.webState(args.Memory)
+	return nil
+}
@@ -0,0 +1,77 @@
+package debugger
+
+import (
+	"fmt"
+	"net"
+	"net/rpc/jsonrpc"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestRPC(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{0xEA, 0xA9, 0x41}) // NOP, LDA #$41
+
+	// A terminal read which never completes
+	d := &Debugger{cpu: &cpu.Cpu{Bus: b, PC: 0x1000}, terminal: make(chan terminalInput)}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	if err := d.serveRPC(listener); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := jsonrpc.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var state State
+	if err := client.Call("Debugger.State", StateArgs{}, &state); err != nil || !state.Running {
+		t.Fatal(fmt.Sprintf("expected running before the debugger stops got %+v %v", state, err))
+	}
+	var memory []int
+	if err := client.Call("Debugger.ReadMemory", MemoryArgs{Address: "$1000", Length: 3}, &memory); err == nil {
+		t.Error("expected reading memory while running to fail")
+	}
+
+	// The debugger waits for commands as it would at a breakpoint
+	go func() {
+		for {
+			d.commandLoop(cpu.Instruction{})
+		}
+	}()
+
+	var breakpoints []BreakpointState
+	if err := client.Call("Debugger.SetBreakpoint", BreakpointArgs{Location: "$1234", Condition: "X==1"}, &breakpoints); err != nil {
+		t.Fatal(err)
+	}
+	if len(breakpoints) != 1 || breakpoints[0].Description != "PC address = $1234 if X==1" {
+		t.Error(fmt.Sprintf("unexpected breakpoints %v", breakpoints))
+	}
+
+	if err := client.Call("Debugger.ReadMemory", MemoryArgs{Address: "$1000", Length: 3}, &memory); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(memory) != "[234 169 65]" {
+		t.Error(fmt.Sprintf("unexpected memory %v", memory))
+	}
+
+	if err := client.Call("Debugger.Command", CommandArgs{Command: "set x 7"}, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Running || state.Registers.X != 7 {
+		t.Error(fmt.Sprintf("expected X set got %+v", state.Registers))
+	}
+
+	if err := client.Call("Debugger.Command", CommandArgs{Command: "nonsense"}, &state); err == nil {
+		t.Error("expected an invalid command to fail")
+	}
+}
@@ -0,0 +1,59 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "source",
+		usage:   "<file>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Run debugger commands from a file.",
+		detail: "One command per line. Blank lines and lines starting with # are ignored.\n" +
+			"If a command fails the rest of the script is skipped.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			return false, d.Source(c.arguments[0])
+		},
+	})
+}
+
+// Source queues the commands in a script file to run before any already
+// queued, so scripts may source other scripts.
+func (d *Debugger) Source(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var script []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			script = append(script, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	d.inputQueue = append(script, d.inputQueue...)
+	return nil
+}
+
+// abandonQueue drops queued commands after one fails, as later commands
+// usually depend on it.
+func (d *Debugger) abandonQueue() {
+	if len(d.inputQueue) > 0 {
+		fmt.Printf("Skipping %d queued commands\n", len(d.inputQueue))
+		d.inputQueue = nil
+	}
+}
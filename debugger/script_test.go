@@ -0,0 +1,43 @@
+package debugger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestSourceQueuesScriptBeforeQueuedCommands(t *testing.T) {
+	path := t.TempDir() + "/setup.gdb"
+	script := "# Break in the reset handler\nba $E000\n\n  bi nop  \nc\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Debugger{}
+	d.QueueCommands([]string{"q"})
+	if err := d.Source(path); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[ba $E000 bi nop c q]"
+	if actual := fmt.Sprint(d.inputQueue); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+
+	d.abandonQueue()
+	if len(d.inputQueue) != 0 {
+		t.Error("expected queue to be abandoned")
+	}
+}
+
+func TestCommandPanicIsReported(t *testing.T) {
+	// Reading without a bus panics in the handler
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	d.QueueCommands([]string{"read $1000", "step"})
+	d.commandLoop(cpu.Instruction{})
+	if d.batch.failures != 1 || len(d.inputQueue) != 0 {
+		t.Error("expected the failed command to be reported and the queue abandoned")
+	}
+}
@@ -0,0 +1,130 @@
+package debugger
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// sessionFile is loaded from the current directory at startup, if present,
+// so a project can keep its debugger setup alongside its source.
+const sessionFile = ".go6502dbg"
+
+func init() {
+	commands.register(&command{
+		name:    "save-session",
+		usage:   "[file]",
+		maxArgs: 1,
+		summary: "Save breakpoints, watchpoints, displays, memdiff ranges, aliases and options to a file.",
+		detail: "The session is written as debugger commands, by default to " + sessionFile + "\n" +
+			"which is loaded automatically when the debugger starts in the same directory.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			file := sessionFile
+			if len(c.arguments) > 0 {
+				file = c.arguments[0]
+			}
+			if err := ioutil.WriteFile(file, d.session(), 0644); err != nil {
+				return false, err
+			}
+			fmt.Println("Session saved to", file)
+			return false, nil
+		},
+	})
+	commands.register(&command{
+		name:    "load-session",
+		usage:   "[file]",
+		maxArgs: 1,
+		summary: "Replace breakpoints, watchpoints, displays, memdiff ranges and aliases with those in a file.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			file := sessionFile
+			if len(c.arguments) > 0 {
+				file = c.arguments[0]
+			}
+			return false, d.loadSession(file)
+		},
+	})
+}
+
+// session returns the commands which recreate the current session.
+func (d *Debugger) session() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# go6502 debugger session")
+	if !d.color {
+		fmt.Fprintln(&buf, "set color off")
+	}
+	for _, name := range d.macroNames() {
+		fmt.Fprintf(&buf, "alias %s %q\n", name, d.macros[name])
+	}
+
+	for i, b := range d.breakpoints {
+		switch b.kind {
+		case breakAddress:
+			if b.message != nil {
+				fmt.Fprintf(&buf, "trace-address $%04X %q", b.address, b.message.text)
+			} else if b.source != "" {
+				fmt.Fprintf(&buf, "break %s", b.source)
+			} else {
+				fmt.Fprintf(&buf, "break-address $%04X", b.address)
+			}
+		case breakInstruction:
+			fmt.Fprintf(&buf, "break-instruction %s", b.instruction)
+		case breakRegister:
+			fmt.Fprintf(&buf, "break-register %s %s $%02X", b.register, b.compare, b.value)
+		case breakInterrupt:
+			fmt.Fprintf(&buf, "break-%s", strings.ToLower(b.interrupt))
+		case breakDevice:
+			fmt.Fprintf(&buf, "break-device %s %s", b.region.Name, accessNames[b.access])
+		}
+		fmt.Fprintln(&buf, b.cond.describe())
+		// Breakpoints are numbered from 1 when loaded
+		if !b.enabled {
+			fmt.Fprintf(&buf, "disable %d\n", i+1)
+		}
+		if b.ignore > 0 {
+			fmt.Fprintf(&buf, "ignore %d %d\n", i+1, b.ignore)
+		}
+	}
+
+	var addresses []int
+	for addr := range d.watchpoints {
+		addresses = append(addresses, int(addr))
+	}
+	sort.Ints(addresses)
+	for _, addr := range addresses {
+		w := d.watchpoints[uint16(addr)]
+		fmt.Fprintf(&buf, "watch $%04X %s\n", w.address, accessNames[w.access])
+	}
+
+	for _, disp := range d.displays {
+		fmt.Fprintf(&buf, "display %s\n", disp.text)
+	}
+
+	for _, m := range d.memDiffs {
+		fmt.Fprintf(&buf, "memdiff on $%04X %d\n", m.address, m.length)
+	}
+	return buf.Bytes()
+}
+
+// loadSession clears the session then queues the commands in file.
+func (d *Debugger) loadSession(file string) error {
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	d.removeBreakpoints()
+	d.breakpointId = 0
+	for addr := range d.watchpoints {
+		d.removeWatchpoint(addr)
+	}
+	d.displays = nil
+	d.displayId = 0
+	d.memDiffs = nil
+	d.macros = nil
+
+	return d.Source(file)
+}
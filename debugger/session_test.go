@@ -0,0 +1,48 @@
+package debugger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestSaveAndLoadSession(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{}}
+	cond, err := d.parseCondition("X>2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.addBreakpoint(&breakpoint{kind: breakAddress, address: 0x1000, cond: cond})
+	d.addBreakpoint(&breakpoint{kind: breakInterrupt, interrupt: "IRQ"})
+	if err := d.enableBreakpoint("2", false); err != nil {
+		t.Fatal(err)
+	}
+	d.displays = append(d.displays, &display{id: 1, text: "[$00FE]"})
+
+	expected := "# go6502 debugger session\n" +
+		"set color off\n" +
+		"break-address $1000 if X>2\n" +
+		"break-irq\n" +
+		"disable 2\n" +
+		"display [$00FE]\n"
+	session := d.session()
+	if actual := string(session); actual != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, actual))
+	}
+
+	path := t.TempDir() + "/session.gdb"
+	if err := ioutil.WriteFile(path, session, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.loadSession(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.breakpoints) != 0 || len(d.displays) != 0 || d.breakpointId != 0 {
+		t.Error("expected session to be cleared before loading")
+	}
+	if len(d.inputQueue) != 5 || d.inputQueue[1] != "break-address $1000 if X>2" {
+		t.Error(fmt.Sprintf("expected session commands to be queued got %v", d.inputQueue))
+	}
+}
@@ -0,0 +1,129 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// flagBits maps status flag names to their bit in SR.
+var flagBits = map[string]uint{
+	"c": 0,
+	"z": 1,
+	"i": 2,
+	"d": 3,
+	"b": 4,
+	"v": 6,
+	"n": 7,
+}
+
+func init() {
+	commands.register(&command{
+		name:    "goto",
+		aliases: []string{"g"},
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Move the PC without executing anything, e.g. goto main",
+		detail:  "The current instruction is abandoned and the debugger stops at the new address.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			pc, err := d.parseUint16(c.arguments[0])
+			if err != nil {
+				return false, err
+			}
+			return d.setPC(pc), nil
+		},
+	})
+	commands.register(&command{
+		name:    "set",
+		usage:   "<pc|a|x|y|sp|sr> <value> | flag <n|v|b|d|i|z|c> <0|1> | color <on|off>",
+		minArgs: 2,
+		maxArgs: 3,
+		summary: "Set a register or status flag, e.g. set pc $F000",
+		detail: "Setting PC abandons the current instruction and stops at the new address.\n" +
+			"The PC may be a symbol or . as for other addresses.\n" +
+			"set color off disables colored output, which is on when writing to a terminal.",
+		handler: (*Debugger).commandSet,
+	})
+}
+
+func (d *Debugger) commandSet(c *cmd, _ cpu.Instruction) (bool, error) {
+	register := strings.ToLower(c.arguments[0])
+
+	if register == "flag" {
+		if len(c.arguments) != 3 {
+			return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+		}
+		return false, d.setFlag(c.arguments[1], c.arguments[2])
+	}
+	if len(c.arguments) != 2 {
+		return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+	}
+
+	if register == "color" {
+		return false, d.setColor(c.arguments[1])
+	}
+
+	if register == "pc" {
+		pc, err := d.parseUint16(c.arguments[1])
+		if err != nil {
+			return false, err
+		}
+		return d.setPC(pc), nil
+	}
+
+	value, err := d.parseUint8(c.arguments[1])
+	if err != nil {
+		return false, err
+	}
+
+	switch register {
+	case "a", "ac":
+		d.cpu.AC = value
+	case "x":
+		d.cpu.X = value
+	case "y":
+		d.cpu.Y = value
+	case "sp":
+		d.cpu.SP = value
+	case "sr", "p":
+		d.cpu.SR = value
+	default:
+		return false, fmt.Errorf("Invalid register %q", c.arguments[0])
+	}
+	fmt.Println(d.registerDump())
+	return false, nil
+}
+
+// setPC moves the PC without executing anything. Returns true to release
+// the current instruction, which the cpu then abandons.
+func (d *Debugger) setPC(pc uint16) bool {
+	if pc == d.cpu.PC {
+		return false
+	}
+	d.cpu.PC = pc
+	d.run = false
+	d.abandonInstruction(1)
+	fmt.Printf("PC set to $%04X%s\n", pc, d.labelSuffix(pc))
+	return true
+}
+
+// setFlag sets or clears a flag in the status register.
+func (d *Debugger) setFlag(flag, state string) error {
+	bit, exists := flagBits[strings.ToLower(flag)]
+	if !exists {
+		return fmt.Errorf("Invalid flag %q", flag)
+	}
+
+	switch state {
+	case "1":
+		d.cpu.SR |= 1 << bit
+	case "0":
+		d.cpu.SR &^= 1 << bit
+	default:
+		return fmt.Errorf("Invalid flag value %q, expected 0 or 1", state)
+	}
+	fmt.Println(d.registerDump())
+	return nil
+}
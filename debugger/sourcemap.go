@@ -0,0 +1,322 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// sourceLocation is a line of assembler source and the address of the code
+// it generated.
+type sourceLocation struct {
+	file    string
+	line    int
+	address uint16
+}
+
+func (l sourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.file, l.line)
+}
+
+// sourceMap maps addresses to source lines, from the file, line, seg and
+// span records of an ld65 debug file. ca65 must be run with -g for these to
+// be present.
+type sourceMap struct {
+	dir       string // of the debug file, to resolve relative source paths
+	lines     []sourceLocation
+	locations map[uint16]sourceLocation
+	text      map[string][]string
+}
+
+// readSourceMap reads the source line information from an ld65 debug file,
+// returning nil if there is none.
+func readSourceMap(path string) (*sourceMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var (
+		files    = make(map[string]string)
+		segments = make(map[string]uint64)
+		spans    = make(map[string][2]uint64) // start address and size
+		lines    []map[string]string
+	)
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		tab := strings.IndexByte(s.Text(), '\t')
+		if tab < 0 {
+			continue
+		}
+		kind, attrs := s.Text()[:tab], parseDebugAttributes(s.Text()[tab+1:])
+
+		switch kind {
+		case "file":
+			files[attrs["id"]] = attrs["name"]
+		case "seg":
+			start, err := strconv.ParseUint(attrs["start"], 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid segment start %q", path, n, attrs["start"])
+			}
+			segments[attrs["id"]] = start
+		case "span":
+			start, err := strconv.ParseUint(attrs["start"], 0, 32)
+			if err != nil {
+				return nil, fmt.Errorf("%s line %d: invalid span start %q", path, n, attrs["start"])
+			}
+			size, _ := strconv.ParseUint(attrs["size"], 0, 32)
+			spans[attrs["id"]] = [2]uint64{segments[attrs["seg"]] + start, size}
+		case "line":
+			// Lines within macro expansions (type 2) are reported at the
+			// line invoking the macro
+			if attrs["type"] != "2" && attrs["span"] != "" {
+				lines = append(lines, attrs)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	m := &sourceMap{
+		dir:       filepath.Dir(path),
+		locations: make(map[uint16]sourceLocation),
+		text:      make(map[string][]string),
+	}
+	for _, attrs := range lines {
+		line, err := strconv.Atoi(attrs["line"])
+		if err != nil {
+			continue
+		}
+		for _, id := range strings.Split(attrs["span"], "+") {
+			span, exists := spans[id]
+			if !exists {
+				continue
+			}
+			loc := sourceLocation{file: files[attrs["file"]], line: line, address: uint16(span[0])}
+			m.lines = append(m.lines, loc)
+			for a := span[0]; a < span[0]+span[1] && a <= 0xFFFF; a++ {
+				if _, exists := m.locations[uint16(a)]; !exists {
+					m.locations[uint16(a)] = loc
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// parseDebugAttributes parses the key=value,key="value" list of a debug file
+// record.
+func parseDebugAttributes(s string) map[string]string {
+	attrs := make(map[string]string)
+	for s != "" {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, "\"") {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				end = len(s) - 1
+			}
+			value, s = s[1:end+1], s[end+1:]
+			if strings.HasPrefix(s, "\"") {
+				s = s[1:]
+			}
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		attrs[key] = value
+		s = strings.TrimPrefix(s, ",")
+	}
+	return attrs
+}
+
+// at returns the source line which generated the code at addr.
+func (m *sourceMap) at(addr uint16) (sourceLocation, bool) {
+	if m == nil {
+		return sourceLocation{}, false
+	}
+	loc, exists := m.locations[addr]
+	return loc, exists
+}
+
+// matchesFile returns true if name is the file, or its trailing path.
+func matchesFile(file, name string) bool {
+	return file == name || strings.HasSuffix(file, "/"+name)
+}
+
+// address returns the first line with code at or after line in file, as gdb
+// does when breaking on a blank line or comment.
+func (m *sourceMap) address(file string, line int) (sourceLocation, error) {
+	if m == nil {
+		return sourceLocation{}, fmt.Errorf("No source line information, assemble with ca65 -g")
+	}
+
+	var (
+		best  sourceLocation
+		found bool
+		known bool
+	)
+	for _, l := range m.lines {
+		if !matchesFile(l.file, file) {
+			continue
+		}
+		known = true
+		if l.line >= line && (!found || l.line < best.line || (l.line == best.line && l.address < best.address)) {
+			best, found = l, true
+		}
+	}
+	switch {
+	case !known:
+		return best, fmt.Errorf("No source file %s", file)
+	case !found:
+		return best, fmt.Errorf("No code at or after %s:%d", file, line)
+	}
+	return best, nil
+}
+
+// source returns the lines of a source file, read relative to the current
+// directory or failing that the debug file.
+func (m *sourceMap) source(file string) ([]string, error) {
+	if text, exists := m.text[file]; exists {
+		return text, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) && !filepath.IsAbs(file) {
+		data, err = ioutil.ReadFile(filepath.Join(m.dir, file))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	m.text[file] = text
+	return text, nil
+}
+
+// parseLocation parses file:line, or an address to look up.
+func (d *Debugger) parseLocation(s string) (sourceLocation, error) {
+	if i := strings.LastIndexByte(s, ':'); i > 0 {
+		line, err := strconv.Atoi(s[i+1:])
+		if err != nil || line < 1 {
+			return sourceLocation{}, fmt.Errorf("Invalid line number in %s", s)
+		}
+		return d.sources.address(s[:i], line)
+	}
+
+	addr, err := d.parseUint16(s)
+	if err != nil {
+		return sourceLocation{}, err
+	}
+	loc, exists := d.sources.at(addr)
+	if !exists {
+		return loc, fmt.Errorf("No source line for $%04X", addr)
+	}
+	return loc, nil
+}
+
+// showSource prints the source line for the current PC, if known.
+func (d *Debugger) showSource() {
+	loc, exists := d.sources.at(d.cpu.PC)
+	if !exists {
+		return
+	}
+	text, err := d.sources.source(loc.file)
+	if err != nil || loc.line > len(text) {
+		fmt.Println(d.paint(ansiSymbol, loc.String()))
+		return
+	}
+	fmt.Printf("%s: %s\n", d.paint(ansiSymbol, loc.String()), text[loc.line-1])
+}
+
+func init() {
+	commands.register(&command{
+		name:        "break",
+		aliases:     []string{"b"},
+		usage:       "<file:line>|<address> [if <condition>]",
+		minArgs:     1,
+		maxArgs:     1,
+		conditional: true,
+		summary:     "Break at a source line, e.g. b main.s:123",
+		detail: "Breaks at the first code at or after the line, using the source line information\n" +
+			"in an ld65 debug file. Otherwise as for break-address.\n" +
+			conditionHelp,
+		handler: func(d *Debugger, c *cmd, in cpu.Instruction) (bool, error) {
+			if !strings.Contains(c.arguments[0], ":") {
+				return d.commandBreakAddress(c, in)
+			}
+			loc, err := d.parseLocation(c.arguments[0])
+			if err != nil {
+				return false, err
+			}
+			cond, err := d.parseCondition(c.condition)
+			if err != nil {
+				return false, err
+			}
+			d.addBreakpoint(&breakpoint{kind: breakAddress, address: loc.address, source: loc.String(), cond: cond})
+			return false, nil
+		},
+	})
+	commands.register(&command{
+		name:    "list",
+		aliases: []string{"l"},
+		usage:   "[file:line|address]",
+		maxArgs: 1,
+		summary: "List the source around the current PC or a location.",
+		handler: func(d *Debugger, c *cmd, _ cpu.Instruction) (bool, error) {
+			at := "."
+			if len(c.arguments) > 0 {
+				at = c.arguments[0]
+			}
+			loc, err := d.parseLocation(at)
+			if err != nil {
+				return false, err
+			}
+			return false, d.listSource(loc)
+		},
+	})
+}
+
+// listLines is how many lines list shows.
+const listLines = 10
+
+// listSource prints the lines around a location, marking the current line.
+func (d *Debugger) listSource(loc sourceLocation) error {
+	text, err := d.sources.source(loc.file)
+	if err != nil {
+		return err
+	}
+
+	current, _ := d.sources.at(d.cpu.PC)
+	first := loc.line - listLines/2
+	if first < 1 {
+		first = 1
+	}
+	for n := first; n < first+listLines && n <= len(text); n++ {
+		marker := "  "
+		if current.file == loc.file && current.line == n {
+			marker = "=>"
+		}
+		fmt.Printf("%s %5d  %s\n", marker, n, text[n-1])
+	}
+	return nil
+}
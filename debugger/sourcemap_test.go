@@ -0,0 +1,67 @@
+package debugger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestSourceMap(t *testing.T) {
+	dir := t.TempDir()
+	dbg := "version\tmajor=2,minor=0\n" +
+		"file\tid=0,name=\"src/main.s\",size=64,mtime=0x5F000000,mod=0\n" +
+		"line\tid=0,file=0,line=3,span=0\n" +
+		"line\tid=1,file=0,line=5,span=1\n" +
+		"line\tid=2,file=0,line=7,type=2,span=2\n" +
+		"seg\tid=0,name=\"CODE\",start=0x00E000,size=0x0005,addrsize=absolute,type=ro\n" +
+		"span\tid=0,seg=0,start=0,size=2\n" +
+		"span\tid=1,seg=0,start=2,size=3\n" +
+		"span\tid=2,seg=0,start=2,size=3\n" +
+		"sym\tid=0,name=\"reset\",addrsize=absolute,scope=0,def=0,val=0xE000,type=lab\n"
+	if err := ioutil.WriteFile(dir+"/kernel.dbg", []byte(dbg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := readSourceMap(dir + "/kernel.dbg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for addr, expected := range map[uint16]string{0xE000: "src/main.s:3", 0xE001: "src/main.s:3", 0xE002: "src/main.s:5"} {
+		if loc, exists := m.at(addr); !exists || loc.String() != expected {
+			t.Error(fmt.Sprintf("$%04X: expected %s got %v", addr, expected, loc))
+		}
+	}
+	if _, exists := m.at(0xE005); exists {
+		t.Error("expected no source line after the segment")
+	}
+
+	loc, err := m.address("main.s", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.address != 0xE002 || loc.line != 5 {
+		t.Error(fmt.Sprintf("expected main.s:4 to resolve to line 5 at $E002 got %v at $%04X", loc, loc.address))
+	}
+	if _, err := m.address("main.s", 6); err == nil {
+		t.Error("expected no code after line 5")
+	}
+	if _, err := m.address("other.s", 1); err == nil {
+		t.Error("expected unknown file to fail")
+	}
+
+	d := &Debugger{cpu: &cpu.Cpu{}, sources: m}
+	if _, err := d.parseLocation("main.s:x"); err == nil {
+		t.Error("expected invalid line number to fail")
+	}
+}
+
+func TestParseDebugAttributes(t *testing.T) {
+	attrs := parseDebugAttributes(`id=0,name="a,b.s",size=12`)
+	expected := "map[id:0 name:a,b.s size:12]"
+	if actual := fmt.Sprint(attrs); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+}
@@ -0,0 +1,131 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Symbol file formats.
+const (
+	SymbolsDebug = "dbg"  // ld65 --dbgfile
+	SymbolsVice  = "vice" // VICE monitor labels, also ld65 -Ln
+	SymbolsMap   = "map"  // ld65 -m map file
+)
+
+// readSymbols reads a symbol file in the given format, or if format is ""
+// the format is detected from its content.
+func readSymbols(path, format string) (debugSymbols, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = detectSymbolFormat(data)
+	}
+
+	var symbols debugSymbols
+	switch strings.ToLower(format) {
+	case SymbolsDebug:
+		symbols, err = readDebugSymbols(bytes.NewReader(data))
+	case SymbolsVice:
+		symbols, err = readViceSymbols(bytes.NewReader(data))
+	case SymbolsMap:
+		symbols, err = readMapSymbols(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("Unsupported symbol format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return symbols, nil
+}
+
+// detectSymbolFormat guesses the format of a symbol file from its content.
+func detectSymbolFormat(data []byte) string {
+	switch {
+	case bytes.Contains(data, []byte("Exports list by name:")):
+		return SymbolsMap
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("al ")):
+		return SymbolsVice
+	default:
+		return SymbolsDebug
+	}
+}
+
+// readViceSymbols reads VICE monitor labels, one per line:
+//
+//	al C:1234 .label
+//
+// ld65 -Ln writes the same format with a 24 bit address and no memory space.
+func readViceSymbols(r io.Reader) (symbols debugSymbols, err error) {
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || fields[0] != "al" {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected al <address> .<label>", line)
+		}
+
+		value := fields[1]
+		if i := strings.Index(value, ":"); i >= 0 {
+			value = value[i+1:]
+		}
+		addr, err := strconv.ParseUint(value, 16, 32)
+		if err != nil || addr > 0xFFFF {
+			return nil, fmt.Errorf("line %d: invalid address %q", line, fields[1])
+		}
+		symbols = append(symbols, debugSymbol{address: uint16(addr), name: strings.TrimPrefix(fields[2], ".")})
+	}
+	return symbols, s.Err()
+}
+
+// readMapSymbols reads the exports from an ld65 map file. These are listed
+// in columns of name, hex value and type:
+//
+//	Exports list by name:
+//	---------------------
+//	main                      00C000 RLA    reset                     00C010 RLA
+func readMapSymbols(r io.Reader) (symbols debugSymbols, err error) {
+	s := bufio.NewScanner(r)
+	exports := false
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		switch {
+		case text == "Exports list by name:":
+			exports = true
+			continue
+		case !exports || strings.HasPrefix(text, "---"):
+			continue
+		case text == "":
+			// End of the section
+			if len(symbols) > 0 {
+				return symbols, nil
+			}
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields)%3 != 0 {
+			return nil, fmt.Errorf("line %d: expected name value type", line)
+		}
+		for i := 0; i < len(fields); i += 3 {
+			addr, err := strconv.ParseUint(fields[i+1], 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid value %q", line, fields[i+1])
+			}
+			// Exports above $FFFF are constants rather than addresses
+			if addr <= 0xFFFF {
+				symbols = append(symbols, debugSymbol{address: uint16(addr), name: fields[i]})
+			}
+		}
+	}
+	return symbols, s.Err()
+}
@@ -0,0 +1,55 @@
+package debugger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestSymbolFormats(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"labels.vice": "al C:E000 .reset\nal 00E010 .main\n",
+		"kernel.map": "Modules list:\n-------------\n\n" +
+			"Exports list by name:\n---------------------\n" +
+			"main                      00E010 RLA    reset                     00E000 RLA    \n" +
+			"BIGCONST                  012345 REA    \n\n" +
+			"Exports list by value:\n----------------------\n" +
+			"other                     001234 RLA    \n",
+	}
+
+	for name, content := range files {
+		path := dir + "/" + name
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		labels, err := ReadSymbolFile(path, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(labels) != 2 || labels["reset"] != 0xE000 || labels["main"] != 0xE010 {
+			t.Error(fmt.Sprintf("%s: expected reset $E000 main $E010 got %v", name, labels))
+		}
+	}
+}
+
+func TestReadDebugSymbolsErrors(t *testing.T) {
+	for _, dbg := range []string{
+		"sym\tid=0,name=\"reset\",val=0xZZZZ,type=lab\n",
+		"sym\tid\n",
+	} {
+		if _, err := readDebugSymbols(strings.NewReader(dbg)); err == nil {
+			t.Error(fmt.Sprintf("expected %q to fail", dbg))
+		}
+	}
+
+	symbols, err := readDebugSymbols(strings.NewReader("sym\tid=0,name=\"reset\",val=0xE000,type=lab\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 1 || symbols[0].name != "reset" {
+		t.Error(fmt.Sprintf("expected only reset got %v", symbols))
+	}
+}
@@ -3,7 +3,7 @@ package debugger
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"io"
 	"strconv"
 	"strings"
 )
@@ -53,28 +53,43 @@ func (symbols debugSymbols) uniqueLabels() (result []string) {
 	return
 }
 
-func readDebugSymbols(debugFile string) (symbols debugSymbols, err error) {
-	file, err := os.Open(debugFile)
+// ReadSymbolFile loads the labels from a symbol file, returning a map of
+// label name to address. Labels which resolve to more than one address are
+// omitted. The format is as for readSymbols.
+func ReadSymbolFile(symbolFile, format string) (map[string]uint16, error) {
+	symbols, err := readSymbols(symbolFile, format)
 	if err != nil {
-		return
+		return nil, err
 	}
 
-	symbols = make([]debugSymbol, 128)
+	labels := make(map[string]uint16)
+	for _, l := range symbols.uniqueLabels() {
+		if l != "" {
+			labels[l] = symbols.addressesFor(l)[0]
+		}
+	}
+	return labels, nil
+}
+
+// readDebugSymbols reads an ld65 debug file, as written by ld65 --dbgfile.
+func readDebugSymbols(file io.Reader) (symbols debugSymbols, err error) {
+	symbols = make([]debugSymbol, 0, 128)
 	t := &tokenizer{state: sBegin}
 
-	handleLine := func() {
+	handleLine := func() error {
 		// old format: "label", new format: "lab"
-		if t.line.data["type"][0:3] == "lab" {
+		if strings.HasPrefix(t.line.data["type"], "lab") {
 			val, ok := t.line.data["val"] // new format
 			if !ok {
 				val = t.line.data["value"] // old format
 			}
 			addr, err := strconv.ParseUint(val, 0, 16)
 			if err != nil {
-				panic(err)
+				return fmt.Errorf("Invalid value %q for symbol %s", val, t.line.name)
 			}
 			symbols = append(symbols, debugSymbol{address: uint16(addr), name: t.line.name})
 		}
+		return nil
 	}
 
 	s := bufio.NewScanner(file)
@@ -97,7 +112,7 @@ func readDebugSymbols(debugFile string) (symbols debugSymbols, err error) {
 			if bytes[0] == '\t' {
 				t.enter(sNameOrMap)
 			} else {
-				panic("Expected TAB after line type")
+				return nil, fmt.Errorf("Expected TAB after line type")
 			}
 		case sNameOrMap:
 			if bytes[0] == '"' {
@@ -115,14 +130,16 @@ func readDebugSymbols(debugFile string) (symbols debugSymbols, err error) {
 				t.enter(sMapKey)
 			} else if bytes[0] == '\n' {
 				t.enter(sBegin)
-				handleLine()
+				if err = handleLine(); err != nil {
+					return nil, err
+				}
 			}
 		case sMapKey:
 			t.line.key = s.Text()
 			t.enter(sMapEquals)
 		case sMapEquals:
 			if bytes[0] != '=' {
-				panic("Expected '=' in sMapEquals state")
+				return nil, fmt.Errorf("Expected '=' after %s", t.line.key)
 			} else {
 				t.enter(sMapValue)
 			}
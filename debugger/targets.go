@@ -0,0 +1,60 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// operandTarget returns the address an instruction's operand refers to.
+// dynamic is true if it was read from memory, for indirect addressing, in
+// which case it may change. Indexed indirect targets depend on the registers
+// so are only known for the current instruction.
+func (d *Debugger) operandTarget(in cpu.Instruction, current bool) (addr uint16, dynamic bool, ok bool) {
+	switch in.Addressing() {
+	case "absolute":
+		return in.Op16, false, true
+	case "relative":
+		return in.Address + 2 + uint16(int8(in.Op8)), false, true
+	case "(indirect)":
+		// As the cpu reads it, including the NMOS page wrap if enabled
+		hi := in.Op16 + 1
+		if d.cpu.Quirks.IndirectJumpBug {
+			hi = in.Op16&0xFF00 | (in.Op16+1)&0x00FF
+		}
+		return uint16(d.peek(hi))<<8 | uint16(d.peek(in.Op16)), true, true
+	case "(indirect,X)":
+		if current {
+			return d.peekZeroPage16(in.Op8 + d.cpu.X), true, true
+		}
+	case "(indirect),Y":
+		if current {
+			return d.peekZeroPage16(in.Op8) + uint16(d.cpu.Y), true, true
+		}
+	}
+	return 0, false, false
+}
+
+// peekZeroPage16 reads a pointer from the zero page, wrapping within it.
+func (d *Debugger) peekZeroPage16(addr uint8) uint16 {
+	return uint16(d.peek(uint16(addr+1)))<<8 | uint16(d.peek(uint16(addr)))
+}
+
+// targetSymbols returns the symbols for an instruction's target, to annotate
+// disassembly. A target read from memory is shown as -> label, or -> $1234
+// if it has none.
+func (d *Debugger) targetSymbols(in cpu.Instruction, current bool) []string {
+	addr, dynamic, ok := d.operandTarget(in, current)
+	if !ok {
+		return nil
+	}
+	labels := d.symbols.labelsFor(addr)
+	if !dynamic {
+		return labels
+	}
+	if len(labels) == 0 {
+		return []string{fmt.Sprintf("-> $%04X", addr)}
+	}
+	return []string{"-> " + strings.Join(labels, ",")}
+}
@@ -0,0 +1,47 @@
+package debugger
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestTargetSymbols(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{
+		0xD0, 0xFE, // BNE to itself
+		0x6C, 0x00, 0x03, // JMP ($0300)
+		0xB1, 0x80, // LDA ($80),Y
+	})
+	b.WriteBlock(0x0300, []byte{0x34, 0x12})
+	b.WriteBlock(0x0080, []byte{0x00, 0x20})
+
+	d := &Debugger{
+		cpu:     &cpu.Cpu{Bus: b, PC: 0x1005, Y: 0x10},
+		symbols: debugSymbols{{address: 0x1000, name: "loop"}, {address: 0x1234, name: "handler"}},
+	}
+
+	for _, test := range []struct {
+		addr     uint16
+		current  bool
+		expected string
+	}{
+		{0x1000, false, "[loop]"},
+		{0x1002, false, "[-> handler]"},
+		{0x1005, true, "[-> $2010]"},
+		{0x1005, false, "[]"},
+	} {
+		data, _ := b.ReadBlock(test.addr, 3)
+		in, err := cpu.Decode(test.addr, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := fmt.Sprint(d.targetSymbols(in, test.current)); actual != test.expected {
+			t.Error(fmt.Sprintf("%v: expected %s got %s", in, test.expected, actual))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func init() {
+	commands.register(&command{
+		name:    "trace",
+		usage:   "[on <file>|off]",
+		maxArgs: 2,
+		summary: "Record every executed instruction to a file.",
+		detail: "Each line holds the address, bytes and instruction with the registers before it executes.\n" +
+			"Tracing continues while running, independent of breakpoints, until trace off.\n" +
+			"With no arguments shows whether tracing is on.",
+		handler: (*Debugger).commandTrace,
+	})
+}
+
+func (d *Debugger) commandTrace(c *cmd, _ cpu.Instruction) (bool, error) {
+	if len(c.arguments) == 0 {
+		if d.traceFile == nil {
+			fmt.Println("Trace off")
+		} else {
+			fmt.Println("Tracing to", d.traceFile.Name())
+		}
+		return false, nil
+	}
+
+	switch c.arguments[0] {
+	case "on":
+		if len(c.arguments) != 2 {
+			return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+		}
+		return false, d.startTrace(c.arguments[1])
+	case "off":
+		if d.traceFile == nil {
+			return false, fmt.Errorf("Trace is not on")
+		}
+		name := d.traceFile.Name()
+		err := d.stopTrace()
+		if err == nil {
+			fmt.Println("Trace written to", name)
+		}
+		return false, err
+	default:
+		return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+	}
+}
+
+// startTrace starts writing executed instructions to a file, replacing any
+// trace already running.
+func (d *Debugger) startTrace(filename string) error {
+	if err := d.stopTrace(); err != nil {
+		return err
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	d.traceFile = f
+	d.trace = bufio.NewWriter(f)
+	fmt.Println("Tracing to", filename)
+	return nil
+}
+
+// stopTrace flushes and closes the trace file, if any.
+func (d *Debugger) stopTrace() error {
+	if d.traceFile == nil {
+		return nil
+	}
+	err := d.trace.Flush()
+	if cerr := d.traceFile.Close(); err == nil {
+		err = cerr
+	}
+	d.traceFile = nil
+	d.trace = nil
+	return err
+}
+
+// traceInstruction records the instruction about to execute.
+func (d *Debugger) traceInstruction(in cpu.Instruction) {
+	if d.trace == nil {
+		return
+	}
+
+	c := d.cpu
+	_, err := fmt.Fprintf(d.trace, "%04X  %-9s %-24v A:%02X X:%02X Y:%02X SP:%02X SR:%02X CYC:%d\n",
+		in.Address, hexBytes(instructionBytes(in)), in, c.AC, c.X, c.Y, c.SP, c.SR, c.Cycles)
+	if err != nil {
+		fmt.Println("Trace failed:", err)
+		_ = d.stopTrace()
+	}
+}
+
+// instructionBytes returns the bytes encoding an instruction.
+func instructionBytes(in cpu.Instruction) []byte {
+	switch in.Bytes {
+	case 3:
+		return []byte{in.Opcode, byte(in.Op16), byte(in.Op16 >> 8)}
+	case 2:
+		return []byte{in.Opcode, in.Op8}
+	default:
+		return []byte{in.Opcode}
+	}
+}
@@ -0,0 +1,124 @@
+package debugger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// traceMessage is the message a tracepoint prints when hit, with {expr}
+// replaced by the value of an expression in hex, or {expr:d} in decimal.
+type traceMessage struct {
+	text  string
+	parts []messagePart
+}
+
+type messagePart struct {
+	literal string
+	eval    evalFunc
+	decimal bool
+}
+
+func init() {
+	commands.register(&command{
+		name:    "trace-address",
+		aliases: []string{"ta"},
+		usage:   `<address> "message" [if <condition>]`,
+		minArgs: 2,
+		maxArgs: -1,
+		summary: `Print a message when PC reaches address, without stopping, e.g. ta main "A={A} X={X:d}"`,
+		detail: "{expr} in the message is replaced by the value of an expression in hex, {expr:d} in decimal.\n" +
+			"Tracepoints are listed, enabled, disabled and deleted as breakpoints.\n" +
+			conditionHelp,
+		handler: (*Debugger).commandTraceAddress,
+	})
+}
+
+func (d *Debugger) commandTraceAddress(c *cmd, _ cpu.Instruction) (bool, error) {
+	addr, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+
+	i := strings.Index(c.input, `"`)
+	if i < 0 {
+		return false, fmt.Errorf("Usage: %s", c.command.synopsis())
+	}
+	quoted, err := strconv.QuotedPrefix(c.input[i:])
+	if err != nil {
+		return false, fmt.Errorf("Invalid message %s", c.input[i:])
+	}
+	text, _ := strconv.Unquote(quoted)
+
+	var cond *condition
+	if rest := strings.TrimSpace(c.input[i+len(quoted):]); rest != "" {
+		fields := strings.Fields(rest)
+		if !strings.EqualFold(fields[0], "if") {
+			return false, fmt.Errorf("Unexpected %s after message", rest)
+		}
+		if cond, err = d.parseCondition(strings.Join(fields[1:], " ")); err != nil {
+			return false, err
+		}
+	}
+
+	message, err := d.parseMessage(text)
+	if err != nil {
+		return false, err
+	}
+	d.addBreakpoint(&breakpoint{kind: breakAddress, address: addr, message: message, cond: cond})
+	return false, nil
+}
+
+// parseMessage parses the {expr} substitutions in a tracepoint message.
+func (d *Debugger) parseMessage(text string) (*traceMessage, error) {
+	m := &traceMessage{text: text}
+	for s := text; s != ""; {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			m.parts = append(m.parts, messagePart{literal: s})
+			break
+		}
+		closing := strings.IndexByte(s[open:], '}')
+		if closing < 0 {
+			return nil, fmt.Errorf("Missing } in message")
+		}
+		closing += open
+
+		expr, decimal := s[open+1:closing], false
+		if strings.HasSuffix(expr, ":d") {
+			expr, decimal = strings.TrimSuffix(expr, ":d"), true
+		}
+		eval, err := d.parseExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		m.parts = append(m.parts,
+			messagePart{literal: s[:open]},
+			messagePart{eval: eval, decimal: decimal})
+		s = s[closing+1:]
+	}
+	return m, nil
+}
+
+// format returns the message with the current values substituted.
+func (m *traceMessage) format(d *Debugger) string {
+	var b strings.Builder
+	for _, p := range m.parts {
+		switch {
+		case p.eval == nil:
+			b.WriteString(p.literal)
+		case p.decimal:
+			fmt.Fprintf(&b, "%d", p.eval(d))
+		default:
+			v := p.eval(d)
+			if v >= 0 && v <= 0xFF {
+				fmt.Fprintf(&b, "$%02X", v)
+			} else {
+				fmt.Fprintf(&b, "$%04X", v)
+			}
+		}
+	}
+	return b.String()
+}
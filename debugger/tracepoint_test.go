@@ -0,0 +1,39 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestTracepoint(t *testing.T) {
+	d := &Debugger{cpu: &cpu.Cpu{PC: 0x1000, AC: 0x41, X: 10}, run: true}
+	input := `ta $1000 "A={A} X={X:d} pc={.}" if X>2`
+	c := &cmd{input: input, arguments: strings.Fields(input)[1:]}
+	if _, err := d.commandTraceAddress(c, cpu.Instruction{}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := d.breakpoints[0]
+	expected := "A=$41 X=10 pc=$1000"
+	if actual := b.message.format(d); actual != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, actual))
+	}
+	if b.cond == nil || b.cond.text != "X>2" {
+		t.Error(fmt.Sprintf("expected condition X>2 got %v", b.cond))
+	}
+
+	d.doBreakpoints(cpu.Instruction{})
+	if !d.run || b.hits != 1 {
+		t.Error("expected tracepoint to be hit without stopping")
+	}
+
+	for _, bad := range []string{`ta $1000`, `ta $1000 "{A"`, `ta $1000 "{Q}"`, `ta $1000 "A" X>2`} {
+		c := &cmd{command: commands.lookup("ta"), input: bad, arguments: strings.Fields(bad)[1:]}
+		if _, err := d.commandTraceAddress(c, cpu.Instruction{}); err == nil {
+			t.Error(fmt.Sprintf("expected %s to fail", bad))
+		}
+	}
+}
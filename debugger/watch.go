@@ -0,0 +1,156 @@
+package debugger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// watchpoint is a memory location the debugger breaks on when accessed.
+type watchpoint struct {
+	address uint16
+	access  bus.Access
+	id      int // bus watch id
+}
+
+func init() {
+	commands.register(&command{
+		name:    "watch",
+		aliases: []string{"w"},
+		usage:   "[address] [r|w|rw]",
+		maxArgs: 2,
+		summary: "Break when an address is accessed, e.g. w $0200 w",
+		detail: "Breaks after the instruction reading or writing the address, default rw, reporting the PC and value.\n" +
+			"With no arguments lists the watchpoints.",
+		handler: (*Debugger).commandWatch,
+	})
+	commands.register(&command{
+		name:    "unwatch",
+		usage:   "<address>",
+		minArgs: 1,
+		maxArgs: 1,
+		summary: "Remove the watchpoint on an address.",
+		handler: (*Debugger).commandUnwatch,
+	})
+}
+
+// accessNames are the arguments to watch for each type of access.
+var accessNames = map[bus.Access]string{
+	bus.AccessRead:      "r",
+	bus.AccessWrite:     "w",
+	bus.AccessReadWrite: "rw",
+}
+
+// parseAccess parses the type of access a watchpoint breaks on.
+func parseAccess(s string) (bus.Access, error) {
+	switch strings.ToLower(s) {
+	case "r", "read":
+		return bus.AccessRead, nil
+	case "w", "write":
+		return bus.AccessWrite, nil
+	case "rw", "wr", "read/write":
+		return bus.AccessReadWrite, nil
+	default:
+		return 0, fmt.Errorf("Invalid access %q, expected r, w or rw", s)
+	}
+}
+
+func (d *Debugger) commandWatch(c *cmd, _ cpu.Instruction) (bool, error) {
+	if len(c.arguments) == 0 {
+		d.listWatchpoints()
+		return false, nil
+	}
+
+	addr, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+
+	access := bus.AccessReadWrite
+	if len(c.arguments) > 1 {
+		access, err = parseAccess(c.arguments[1])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Replace any existing watchpoint so the access can be changed
+	d.removeWatchpoint(addr)
+
+	if d.watchpoints == nil {
+		d.watchpoints = make(map[uint16]*watchpoint)
+	}
+	d.watchpoints[addr] = &watchpoint{
+		address: addr,
+		access:  access,
+		id:      d.cpu.Bus.Watch(addr, addr, access, d.watchTriggered),
+	}
+	fmt.Printf("Watchpoint set: %v $%04X%s\n", access, addr, d.labelSuffix(addr))
+	return false, nil
+}
+
+func (d *Debugger) commandUnwatch(c *cmd, _ cpu.Instruction) (bool, error) {
+	addr, err := d.parseUint16(c.arguments[0])
+	if err != nil {
+		return false, err
+	}
+	if !d.removeWatchpoint(addr) {
+		return false, fmt.Errorf("No watchpoint at $%04X", addr)
+	}
+	fmt.Printf("Watchpoint removed: $%04X\n", addr)
+	return false, nil
+}
+
+// removeWatchpoint removes the watchpoint on addr, returning false if there
+// was none.
+func (d *Debugger) removeWatchpoint(addr uint16) bool {
+	w, exists := d.watchpoints[addr]
+	if !exists {
+		return false
+	}
+	d.cpu.Bus.Unwatch(w.id)
+	delete(d.watchpoints, addr)
+	return true
+}
+
+func (d *Debugger) listWatchpoints() {
+	if len(d.watchpoints) == 0 {
+		fmt.Println("No watchpoints.")
+		return
+	}
+
+	var addresses []int
+	for addr := range d.watchpoints {
+		addresses = append(addresses, int(addr))
+	}
+	sort.Ints(addresses)
+
+	for _, addr := range addresses {
+		w := d.watchpoints[uint16(addr)]
+		fmt.Printf("$%04X %v%s\n", w.address, w.access, d.labelSuffix(w.address))
+	}
+}
+
+// watchTriggered is called by the bus when a watched address is accessed.
+// The instruction completes, so execution stops before the next one.
+func (d *Debugger) watchTriggered(access bus.Access, addr uint16, value byte, pc uint16) {
+	// Ignore accesses made by debugger commands, e.g. read
+	if d.prompting {
+		return
+	}
+	fmt.Printf("Watchpoint: %v $%04X%s = $%02X pc:$%04X\n", access, addr, d.labelSuffix(addr), value, pc)
+	d.run = false
+}
+
+// labelSuffix returns the labels for an address in brackets, or "" if it has
+// none.
+func (d *Debugger) labelSuffix(addr uint16) string {
+	labels := d.symbols.labelsFor(addr)
+	if len(labels) == 0 {
+		return ""
+	}
+	return " (" + d.paint(ansiSymbol, strings.Join(labels, ",")) + ")"
+}
@@ -0,0 +1,371 @@
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// webServer is an HTTP front end to the debugger. The page polls the state,
+// which is only read while the debugger is waiting for a command so the cpu
+// is stopped, and posts commands which are run as if typed at the prompt.
+// The JSON-RPC interface shares it to run commands and read the state.
+type webServer struct {
+	mu       sync.Mutex
+	stopped  bool
+	commands chan remoteCommand
+	pause    int32        // set to break at the next instruction
+	pending  chan<- error // told when the last command has finished
+	failures int          // before the pending command ran
+}
+
+// remoteCommand is a command sent from the web front end or JSON-RPC. If done
+// is set it receives nil once the debugger is waiting for the next command,
+// or an error if the command failed.
+type remoteCommand struct {
+	input string
+	done  chan<- error
+}
+
+// terminalInput is a line read from the terminal while also waiting for web
+// commands.
+type terminalInput struct {
+	input string
+	err   error
+}
+
+func newWebServer() *webServer {
+	return &webServer{commands: make(chan remoteCommand, 16)}
+}
+
+func (w *webServer) setStopped(stopped bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = stopped
+}
+
+// Serve starts the web front end listening on addr, e.g. localhost:6502.
+func (d *Debugger) Serve(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	d.web = newWebServer()
+	go func() {
+		if err := http.Serve(listener, d.webHandler()); err != nil {
+			fmt.Println("Debugger web UI:", err)
+		}
+	}()
+	fmt.Printf("Debugger web UI on http://%s/\n", listener.Addr())
+	return nil
+}
+
+func (d *Debugger) webHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, webPage)
+	})
+	mux.HandleFunc("/api/state", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.webState(r.URL.Query().Get("memory")))
+	})
+	mux.HandleFunc("/api/command", func(w http.ResponseWriter, r *http.Request) {
+		if !webPost(w, r) {
+			return
+		}
+		command := strings.TrimSpace(r.FormValue("command"))
+		if command == "" {
+			http.Error(w, "Missing command", http.StatusBadRequest)
+			return
+		}
+		select {
+		case d.web.commands <- remoteCommand{input: command}:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Too many queued commands", http.StatusServiceUnavailable)
+		}
+	})
+	mux.HandleFunc("/api/pause", func(w http.ResponseWriter, r *http.Request) {
+		if !webPost(w, r) {
+			return
+		}
+		atomic.StoreInt32(&d.web.pause, 1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// webPost checks a request may change the debugger: it must be a POST, and
+// if a browser says which page sent it that page must be our own.
+func webPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST required", http.StatusMethodNotAllowed)
+		return false
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// webPause breaks if pause was requested from the web front end.
+func (d *Debugger) webPause() {
+	if d.web != nil && atomic.SwapInt32(&d.web.pause, 0) == 1 {
+		d.Break("paused from web UI")
+	}
+}
+
+// webInput waits for a command from either the terminal or the web front
+// end. A terminal read left pending by a web command is used next time.
+func (d *Debugger) webInput(prompt string) (string, error) {
+	if d.terminal == nil {
+		d.terminal = make(chan terminalInput, 1)
+		go func(ch chan terminalInput) {
+			input, err := d.readInput(prompt)
+			ch <- terminalInput{input: input, err: err}
+		}(d.terminal)
+	}
+
+	d.web.setStopped(true)
+	defer d.web.setStopped(false)
+	d.finishRemoteCommand()
+
+	select {
+	case t := <-d.terminal:
+		d.terminal = nil
+		return t.input, t.err
+	case c := <-d.web.commands:
+		fmt.Printf("\n%s%s (remote)\n", prompt, c.input)
+		d.web.pending, d.web.failures = c.done, d.batch.failures
+		return c.input, nil
+	}
+}
+
+// finishRemoteCommand tells the sender of the last command that it has
+// finished, as the debugger is waiting for another.
+func (d *Debugger) finishRemoteCommand() {
+	if d.web.pending == nil {
+		return
+	}
+	var err error
+	if d.batch.failures > d.web.failures {
+		err = fmt.Errorf("Command failed, see the debugger output")
+	}
+	d.web.pending <- err
+	d.web.pending = nil
+}
+
+// State is the debugger state shown by the web front end and returned over
+// JSON-RPC. Only Running is set while the cpu is running.
+type State struct {
+	Running     bool              `json:"running"`
+	Registers   *RegisterState    `json:"registers,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Disassembly []DisassemblyLine `json:"disassembly,omitempty"`
+	Breakpoints []BreakpointState `json:"breakpoints,omitempty"`
+	Memory      []string          `json:"memory,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// RegisterState is the cpu registers, with the status flags as in cpu.Cpu.String().
+type RegisterState struct {
+	PC    uint16 `json:"pc"`
+	A     uint8  `json:"a"`
+	X     uint8  `json:"x"`
+	Y     uint8  `json:"y"`
+	SP    uint8  `json:"sp"`
+	SR    uint8  `json:"sr"`
+	Flags string `json:"flags"`
+}
+
+// DisassemblyLine is an instruction, with the labels at its address.
+type DisassemblyLine struct {
+	Labels  []string `json:"labels,omitempty"`
+	Text    string   `json:"text"`
+	Current bool     `json:"current"`
+}
+
+// BreakpointState describes a breakpoint.
+type BreakpointState struct {
+	Id          int    `json:"id"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description"`
+	Hits        int    `json:"hits"`
+}
+
+// webDisassembly is how many instructions are shown from the PC.
+const webDisassembly = 16
+
+// webState returns the state shown by the web front end, with a hexdump of
+// 256 bytes from the memory expression, or the PC if it is "".
+func (d *Debugger) webState(memory string) State {
+	d.web.mu.Lock()
+	defer d.web.mu.Unlock()
+
+	if !d.web.stopped {
+		return State{Running: true}
+	}
+
+	c := d.cpu
+	state := State{
+		Registers: &RegisterState{PC: c.PC, A: c.AC, X: c.X, Y: c.Y, SP: c.SP, SR: c.SR, Flags: statusFlags(c.SR)},
+	}
+
+	if loc, exists := d.sources.at(c.PC); exists {
+		state.Source = loc.String()
+	}
+
+	for addr, i := c.PC, 0; i < webDisassembly; i++ {
+		line, next, err := d.decodeAt(addr)
+		if err != nil || next < addr {
+			break
+		}
+		state.Disassembly = append(state.Disassembly, DisassemblyLine{
+			Labels:  line.labels,
+			Text:    line.String(),
+			Current: addr == c.PC,
+		})
+		addr = next
+	}
+
+	for _, b := range d.breakpoints {
+		state.Breakpoints = append(state.Breakpoints, BreakpointState{
+			Id:          b.id,
+			Enabled:     b.enabled,
+			Description: b.String(),
+			Hits:        b.hits,
+		})
+	}
+
+	addr := c.PC
+	if memory != "" {
+		v, err := d.parseUint16(memory)
+		if err != nil {
+			state.Error = err.Error()
+		}
+		addr = v
+	}
+	for row := 0; row < 16; row++ {
+		a := uint32(addr) + uint32(row)*16
+		if a > 0xFFF0 {
+			break
+		}
+		data, err := c.Bus.PeekBlock(uint16(a), 16)
+		if err != nil {
+			state.Memory = append(state.Memory, fmt.Sprintf("$%04X  unmapped or I/O", a))
+			continue
+		}
+		state.Memory = append(state.Memory, fmt.Sprintf("$%04X  %s  %s", a, hexBytes(data), printable(data)))
+	}
+	return state
+}
+
+// statusFlags formats the status register as cpu.Cpu.String() does.
+func statusFlags(sr uint8) string {
+	const chars = "nv_bdizc"
+	flags := []byte(chars)
+	for i := range flags {
+		if sr&(0x80>>uint(i)) == 0 {
+			flags[i] = '-'
+		}
+	}
+	return string(flags)
+}
+
+// printable returns data as ASCII, with . for unprintable bytes.
+func printable(data []byte) string {
+	s := make([]byte, len(data))
+	for i, b := range data {
+		if b >= 0x20 && b < 0x7F {
+			s[i] = b
+		} else {
+			s[i] = '.'
+		}
+	}
+	return string(s)
+}
+
+// webPage polls the state and posts commands.
+const webPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>go6502 debugger</title>
+<style>
+body { font-family: monospace; margin: 1em; }
+.panes { display: flex; flex-wrap: wrap; gap: 1em; }
+section { border: 1px solid #ccc; padding: 0.5em; min-width: 24em; }
+h2 { font-size: 1em; margin: 0 0 0.5em 0; }
+pre { margin: 0; }
+.current { background: #ffe680; }
+.label { color: #06c; }
+</style>
+</head>
+<body>
+<p>
+<button onclick="send('step')">Step</button>
+<button onclick="send('next')">Next</button>
+<button onclick="send('continue')">Continue</button>
+<button onclick="pause()">Pause</button>
+<input id="command" size="40" placeholder="debugger command, output is shown in the terminal">
+<span id="status"></span>
+</p>
+<div class="panes">
+<section><h2>Registers</h2><pre id="registers"></pre></section>
+<section><h2>Disassembly</h2><pre id="disassembly"></pre></section>
+<section><h2>Breakpoints</h2><pre id="breakpoints"></pre></section>
+<section><h2>Memory <input id="memory" size="12" placeholder="address"></h2><pre id="dump"></pre></section>
+</div>
+<script>
+function hex(v, n) { return '$' + v.toString(16).toUpperCase().padStart(n, '0'); }
+function text(id, s) { document.getElementById(id).textContent = s; }
+function send(command) {
+  fetch('/api/command', {method: 'POST', body: new URLSearchParams({command: command})});
+}
+function pause() { fetch('/api/pause', {method: 'POST'}); }
+document.getElementById('command').addEventListener('keydown', function(e) {
+  if (e.key === 'Enter' && this.value) { send(this.value); this.value = ''; }
+});
+function refresh() {
+  var memory = document.getElementById('memory').value;
+  fetch('/api/state?memory=' + encodeURIComponent(memory)).then(function(r) { return r.json(); }).then(function(s) {
+    text('status', s.running ? 'running' : (s.error || 'stopped'));
+    if (s.running) { return; }
+    var r = s.registers;
+    text('registers', 'PC ' + hex(r.pc, 4) + '  A ' + hex(r.a, 2) + '  X ' + hex(r.x, 2) +
+      '  Y ' + hex(r.y, 2) + '  SP ' + hex(r.sp, 2) + '  SR ' + r.flags + (s.source ? '\n' + s.source : ''));
+    var dis = document.getElementById('disassembly');
+    dis.innerHTML = '';
+    (s.disassembly || []).forEach(function(l) {
+      (l.labels || []).forEach(function(label) {
+        var e = document.createElement('div'); e.className = 'label'; e.textContent = label + ':'; dis.appendChild(e);
+      });
+      var e = document.createElement('div');
+      e.textContent = (l.current ? '=> ' : '   ') + l.text;
+      if (l.current) { e.className = 'current'; }
+      dis.appendChild(e);
+    });
+    text('breakpoints', (s.breakpoints || []).map(function(b) {
+      return b.id + ' ' + (b.enabled ? 'enabled ' : 'disabled') + ' ' + b.description + ' (hits ' + b.hits + ')';
+    }).join('\n') || 'No breakpoints.');
+    text('dump', (s.memory || []).join('\n'));
+  }).catch(function() { text('status', 'disconnected'); });
+}
+setInterval(refresh, 500);
+refresh();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,79 @@
+package debugger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestWebState(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x10000), "ram", 0)
+	b.WriteBlock(0x1000, []byte{0xEA, 0xA9, 0x41}) // NOP, LDA #$41
+
+	d := &Debugger{cpu: &cpu.Cpu{Bus: b, PC: 0x1000, AC: 0x12}, web: newWebServer()}
+	server := httptest.NewServer(d.webHandler())
+	defer server.Close()
+
+	state := func() (s State) {
+		resp, err := http.Get(server.URL + "/api/state?memory=$1000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	if s := state(); !s.Running || s.Registers != nil {
+		t.Error("expected only running while the debugger is not waiting for a command")
+	}
+
+	d.web.setStopped(true)
+	s := state()
+	if s.Running || s.Registers == nil || s.Registers.PC != 0x1000 || s.Registers.A != 0x12 {
+		t.Fatal(fmt.Sprintf("expected stopped state at $1000 got %+v", s))
+	}
+	if len(s.Disassembly) < 2 || !s.Disassembly[0].Current || !strings.Contains(s.Disassembly[1].Text, "LDA") {
+		t.Error(fmt.Sprintf("expected disassembly from the PC got %v", s.Disassembly))
+	}
+	expected := "$1000  EA A9 41 00 00 00 00 00 00 00 00 00 00 00 00 00  ..A............."
+	if len(s.Memory) != 16 || s.Memory[0] != expected {
+		t.Error(fmt.Sprintf("expected memory %q got %v", expected, s.Memory))
+	}
+
+	resp, err := http.PostForm(server.URL+"/api/command", url.Values{"command": {"step"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if command := <-d.web.commands; command.input != "step" {
+		t.Error(fmt.Sprintf("expected step to be queued got %q", command.input))
+	}
+
+	// A page on another site must not run commands through the browser
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/command", strings.NewReader("command=go"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "http://example.com")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("expected a cross-origin command to be refused got %s", resp.Status))
+	}
+	if len(d.web.commands) != 0 {
+		t.Error("expected the cross-origin command not to be queued")
+	}
+}
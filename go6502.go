@@ -19,6 +19,7 @@ import (
 	"github.com/peter-mount/go6502/debugger"
 	"github.com/peter-mount/go6502/ili9340"
 	"github.com/peter-mount/go6502/memory"
+	"github.com/peter-mount/go6502/romedit"
 	"github.com/peter-mount/go6502/sd"
 	"github.com/peter-mount/go6502/speedometer"
 	"github.com/peter-mount/go6502/spi"
@@ -29,7 +30,6 @@ import (
 const (
 	kernalPath = "kernel/kernel.rom"
 	//kernalPath  = "rom/kernal.rom"
-	//charRomPath = "rom/char.rom"
 )
 
 func main() {
@@ -38,6 +38,14 @@ func main() {
 
 func mainReturningStatus() int {
 
+	if len(os.Args) > 1 && os.Args[1] == "romedit" {
+		if err := romedit.Main(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return 0
+	}
+
 	options := cli.ParseFlags()
 
 	// Create addressable devices.
@@ -47,14 +55,15 @@ func mainReturningStatus() int {
 		panic(err)
 	}
 
-	/*
-		charRom, err := memory.RomFromFile(charRomPath)
+	var charRom *memory.CharRom
+	if options.CharRom != "" {
+		charRom, err = memory.CharRomFromFile(options.CharRom, 8)
 		if err != nil {
 			panic(err)
 		}
-	*/
+	}
 
-	ram := &memory.Ram{}
+	ram := memory.NewRam(0x8000)
 
 	via := via6522.NewVia6522(via6522.Options{
 		DumpAscii:  options.ViaDumpAscii,
@@ -108,22 +117,42 @@ func mainReturningStatus() int {
 	_ = addressBus.Attach(ram, "ram", 0x0000)
 	_ = addressBus.Attach(via, "VIA", 0x9000)
 	_ = addressBus.Attach(console, "Console", 0x9010)
-	//_=addressBus.Attach(charRom, "char", 0xB000)
+	if charRom != nil {
+		_ = addressBus.Attach(charRom, "char", 0xB000)
+	}
 	_ = addressBus.Attach(kernal, "kernal", 0xF000)
 
 	exitChan := make(chan int, 0)
 
 	cpu := &cpu.Cpu{Bus: addressBus, ExitChan: exitChan}
 	defer cpu.Shutdown()
-	if options.Debug {
-		debugger := debugger.NewDebugger(cpu, options.DebugSymbolFile)
+	if options.Debug || options.DebugBatch || options.DebugWeb != "" || options.DebugRPC != "" {
+		debugger := debugger.NewDebugger(cpu, options.DebugSymbolFile, options.DebugSymbolFormat)
+		if options.DebugBatch {
+			debugger.Batch()
+		}
+		if options.DebugWeb != "" {
+			if err := debugger.Serve(options.DebugWeb); err != nil {
+				panic(err)
+			}
+		}
+		if options.DebugRPC != "" {
+			if err := debugger.ServeRPC(options.DebugRPC); err != nil {
+				panic(err)
+			}
+		}
 		debugger.QueueCommands(options.DebugCmds)
+		if options.DebugScript != "" {
+			if err := debugger.Source(options.DebugScript); err != nil {
+				panic(err)
+			}
+		}
 		cpu.AttachMonitor(debugger)
 	} else if options.Speedometer {
 		speedo := speedometer.NewSpeedometer()
 		cpu.AttachMonitor(speedo)
 	}
-	cpu.Reset()
+	cpu.PowerOn()
 
 	// Dispatch CPU in a goroutine.
 	go func() {
@@ -0,0 +1,133 @@
+/*
+	Package heatmap records how often each address on the bus is read, written
+	and executed, writing a report at shutdown. This helps find dead code,
+	unexpected hot loops and stray writes into data areas.
+
+	The report is a CSV file listing each address accessed, or a 256x256 PNG
+	image with one pixel per address, rows being pages. Writes are shown in
+	red, reads in green and executes in blue, brighter for more accesses.
+*/
+package heatmap
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// HeatMap is a cpu.Monitor counting accesses to each address.
+type HeatMap struct {
+	mutex    sync.Mutex
+	file     string
+	reads    [0x10000]uint64
+	writes   [0x10000]uint64
+	executes [0x10000]uint64
+}
+
+// NewHeatMap creates a HeatMap watching the bus, which writes its report to
+// file at shutdown. The format is PNG if the file ends in .png, else CSV.
+func NewHeatMap(b *bus.Bus, file string) *HeatMap {
+	h := &HeatMap{file: file}
+	b.Watch(0x0000, 0xFFFF, bus.AccessReadWrite, h.access)
+	return h
+}
+
+func (h *HeatMap) access(access bus.Access, address uint16, _ byte, _ uint16) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if access == bus.AccessWrite {
+		h.writes[address]++
+	} else {
+		h.reads[address]++
+	}
+}
+
+// BeforeExecute meets the cpu.Monitor interface, counting executes.
+func (h *HeatMap) BeforeExecute(in cpu.Instruction) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.executes[in.Address]++
+}
+
+// Shutdown meets the cpu.Monitor interface, writing the report.
+func (h *HeatMap) Shutdown() {
+	if err := h.Save(h.file); err != nil {
+		fmt.Println("Heat map:", err)
+	}
+}
+
+// Save writes the report to file.
+func (h *HeatMap) Save(file string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if strings.ToLower(filepath.Ext(file)) == ".png" {
+		err = png.Encode(w, h.image())
+	} else {
+		err = h.csv(w)
+	}
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (h *HeatMap) csv(w *bufio.Writer) error {
+	fmt.Fprintln(w, "address,reads,writes,executes")
+	for a := range h.reads {
+		if h.reads[a] > 0 || h.writes[a] > 0 || h.executes[a] > 0 {
+			fmt.Fprintf(w, "%04X,%d,%d,%d\n", a, h.reads[a], h.writes[a], h.executes[a])
+		}
+	}
+	return nil
+}
+
+func (h *HeatMap) image() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 256))
+	writes, reads, executes := peak(h.writes[:]), peak(h.reads[:]), peak(h.executes[:])
+	for a := range h.reads {
+		img.Set(a&0xFF, a>>8, color.RGBA{
+			R: scale(h.writes[a], writes),
+			G: scale(h.reads[a], reads),
+			B: scale(h.executes[a], executes),
+			A: 0xFF,
+		})
+	}
+	return img
+}
+
+func peak(counts []uint64) uint64 {
+	var m uint64
+	for _, c := range counts {
+		if c > m {
+			m = c
+		}
+	}
+	return m
+}
+
+// scale returns the brightness for a count on a log scale, so rarely accessed
+// addresses are still visible next to hot loops.
+func scale(count, peak uint64) byte {
+	if count == 0 {
+		return 0
+	}
+	return byte(64 + 191*math.Log1p(float64(count))/math.Log1p(float64(peak)))
+}
@@ -0,0 +1,35 @@
+package heatmap
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestHeatMapCsv(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x1000), "ram", 0x0000)
+	h := NewHeatMap(b, "")
+
+	b.Write(0x0010, 1)
+	b.Read(0x0010)
+	b.Read(0x0010)
+
+	file := t.TempDir() + "/heat.csv"
+	if err := h.Save(file); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(file)
+	expected := "address,reads,writes,executes\n0010,2,1,0\n"
+	if string(data) != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, data))
+	}
+
+	if err := h.Save(strings.Replace(file, ".csv", ".png", 1)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -98,6 +98,15 @@ func (d *Display) String() string {
 	return "ILI9340"
 }
 
+// DebugState describes the command state and drawing window, for the
+// debugger.
+func (d *Display) DebugState() string {
+	return fmt.Sprintf("State %d  data mode %v  param %d $%08X\n"+
+		"Columns %d-%d  Rows %d-%d  Next %d,%d\n",
+		d.state, d.dataMode, d.paramIndex, d.paramData,
+		d.startCol, d.endCol, d.startRow, d.endRow, d.nextX, d.nextY)
+}
+
 func (d *Display) Shutdown() {
 	d.writeImage()
 }
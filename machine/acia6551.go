@@ -1,12 +1,55 @@
 package machine
 
 import (
+	"fmt"
 	"github.com/peter-mount/go6502/acia6551"
+	"github.com/peter-mount/go6502/charset"
 	"github.com/peter-mount/go6502/memory"
+	"sort"
+	"strings"
 )
 
+// linkPrefix names a serial link to another 6551 as its peripheral
+const linkPrefix = "link:"
+
+// linkTable holds the far end of each serial link until the other 6551 using
+// its name claims it. A machine shares its table with those run alongside.
+type linkTable map[string]*acia6551.LinkPort
+
+// link returns an end of the named serial link. The first 6551 to use the
+// name gets one end, and the second the other.
+func (l linkTable) link(name string) *acia6551.LinkPort {
+	if port, exists := l[name]; exists {
+		delete(l, name)
+		return port
+	}
+	port, other := acia6551.NewLink()
+	l[name] = other
+	return port
+}
+
+// unpaired reports the links only one 6551 used, once every machine sharing
+// the table has started.
+func (l linkTable) unpaired() error {
+	if len(l) == 0 {
+		return nil
+	}
+	var names []string
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("Serial link %s has only one 6551", strings.Join(names, ", "))
+}
+
 type Acia6551Chip struct {
-	Peripheral string `yaml:"peripheral"`
+	Peripheral string                `yaml:"peripheral"`
+	Script     []acia6551.ScriptStep `yaml:"script"`
+	Charset    string                `yaml:"charset"`
+	clock      func() uint64
+	record     func(b byte)
+	replay     []acia6551.InputEvent
+	links      linkTable
 }
 
 func (c *Acia6551Chip) Configure() (memory.Memory, error) {
@@ -14,6 +57,29 @@ func (c *Acia6551Chip) Configure() (memory.Memory, error) {
 
 	if c.Peripheral == "console" {
 		peripheral = acia6551.NewConsole()
+	} else if strings.HasPrefix(c.Peripheral, linkPrefix) {
+		peripheral = c.links.link(strings.TrimPrefix(c.Peripheral, linkPrefix))
+	}
+
+	if len(c.Script) > 0 {
+		peripheral = acia6551.NewScript(peripheral, c.Script, c.clock)
+	}
+
+	// Translate outside of any script so scripts are written in host text
+	if c.Charset != "" && peripheral != nil {
+		cs, err := charset.Lookup(c.Charset)
+		if err != nil {
+			return nil, err
+		}
+		peripheral = acia6551.NewTranslator(peripheral, cs)
+	}
+
+	// Input is recorded and replayed as the guest sees it
+	if len(c.replay) > 0 {
+		peripheral = acia6551.NewReplay(peripheral, c.replay, c.clock)
+	}
+	if c.record != nil {
+		peripheral = acia6551.NewRecorder(peripheral, c.record)
 	}
 
 	return acia6551.NewAcia6551(acia6551.Options{
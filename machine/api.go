@@ -0,0 +1,271 @@
+package machine
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/golib/kernel"
+)
+
+// maxApiRead is the most memory one request may read.
+const maxApiRead = 0x10000
+
+// HttpApi is an optional service controlling the running machine over HTTP,
+// for external test drivers and dashboards, and serving Prometheus metrics on
+// /metrics. It is enabled by -http-api.
+type HttpApi struct {
+	machine *Machine
+	addr    *string
+	server  *http.Server
+}
+
+// ApiState is the state of the cpu returned by the HTTP API.
+type ApiState struct {
+	cpu.State
+	Paused bool `json:"paused"`
+}
+
+// ApiMemory is a block of memory read or written by the HTTP API, the data
+// being in hex.
+type ApiMemory struct {
+	Address string `json:"address"`
+	Data    string `json:"data"`
+}
+
+func (a *HttpApi) Name() string {
+	return "http-api"
+}
+
+func (a *HttpApi) Init(k *kernel.Kernel) error {
+	svce, err := k.AddService(&Machine{})
+	if err != nil {
+		return err
+	}
+	a.machine = (svce).(*Machine)
+
+	a.addr = flag.String("http-api", "", "Serve the machine control API on this address, e.g. localhost:6503")
+
+	return nil
+}
+
+func (a *HttpApi) PostInit() error {
+	a.machine.collectMetrics = *a.addr != ""
+	return nil
+}
+
+func (a *HttpApi) Start() error {
+	if *a.addr == "" {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", *a.addr)
+	if err != nil {
+		return err
+	}
+	a.server = &http.Server{Handler: a.machine.apiHandler()}
+	go func() {
+		if err := a.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			a.machine.config.logger().Println("HTTP API:", err)
+		}
+	}()
+	a.machine.config.logger().Printf("HTTP API on http://%s/api/", listener.Addr())
+	return nil
+}
+
+func (a *HttpApi) Stop() {
+	if a.server != nil {
+		a.server.Close()
+	}
+}
+
+func (m *Machine) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/cpu", func(w http.ResponseWriter, r *http.Request) {
+		state, err := m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+			var state ApiState
+			err := machine.control.do(func() {
+				state = ApiState{State: machine.cpu.State(), Paused: machine.control.isPaused()}
+			})
+			return state, err
+		})(r)
+		apiReply(w, state, err)
+	})
+	mux.HandleFunc("/api/pause", apiPost(m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+		return nil, machine.control.setPaused(true)
+	})))
+	mux.HandleFunc("/api/resume", apiPost(m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+		return nil, machine.control.setPaused(false)
+	})))
+	mux.HandleFunc("/api/step", apiPost(m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+		return nil, machine.control.step()
+	})))
+	mux.HandleFunc("/api/reset", apiPost(m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+		return nil, machine.control.do(machine.cpu.Reset)
+	})))
+	mux.HandleFunc("/api/snapshot", apiPost(m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+		name := r.FormValue("name")
+		if name == "" {
+			return nil, fmt.Errorf("Missing name")
+		}
+		// Snapshots are kept in the storage, not wherever a path leads
+		if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			return nil, fmt.Errorf("Invalid name %q", name)
+		}
+		var err error
+		if cerr := machine.control.do(func() { err = machine.saveState(name) }); cerr != nil {
+			return nil, cerr
+		}
+		return nil, err
+	})))
+	mux.HandleFunc("/api/memory", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			apiPost(m.apiFor((*Machine).apiWriteMemory))(w, r)
+			return
+		}
+		mem, err := m.apiFor(func(machine *Machine, r *http.Request) (interface{}, error) {
+			return machine.apiReadMemory(r)
+		})(r)
+		apiReply(w, mem, err)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if m.metrics == nil {
+			http.NotFound(w, r)
+			return
+		}
+		all := []*metrics{m.metrics}
+		for _, machine := range m.machines {
+			if machine.metrics != nil {
+				all = append(all, machine.metrics)
+			}
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w, all)
+	})
+	return mux
+}
+
+// apiReadMemory reads length bytes from address, e.g. ?address=0200&length=16.
+// Memory is peeked so I/O devices are left alone, and unmapped or I/O
+// addresses fail the request.
+func (m *Machine) apiReadMemory(r *http.Request) (*ApiMemory, error) {
+	address, err := parseAddress("address", r.FormValue("address"))
+	if err != nil {
+		return nil, err
+	}
+	length := 1
+	if s := r.FormValue("length"); s != "" {
+		length, err = strconv.Atoi(s)
+		if err != nil || length < 1 || length > maxApiRead {
+			return nil, fmt.Errorf("Invalid length %q", s)
+		}
+	}
+
+	if int(address)+length > 0x10000 {
+		return nil, fmt.Errorf("Block of %d bytes at %04X passes the end of memory", length, address)
+	}
+
+	var data []byte
+	var perr error
+	if err := m.control.do(func() { data, perr = m.config.addressBus.PeekBlock(address, length) }); err != nil {
+		return nil, err
+	}
+	if perr != nil {
+		return nil, perr
+	}
+	return &ApiMemory{Address: fmt.Sprintf("%04X", address), Data: fmt.Sprintf("%X", data)}, nil
+}
+
+// apiWriteMemory writes an ApiMemory posted as JSON. The write goes straight
+// to the devices, so watches don't fire, and fails if any of it is unmapped.
+func (m *Machine) apiWriteMemory(r *http.Request) (interface{}, error) {
+	var mem ApiMemory
+	if err := json.NewDecoder(r.Body).Decode(&mem); err != nil {
+		return nil, err
+	}
+	address, err := parseAddress("address", mem.Address)
+	if err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(strings.ReplaceAll(mem.Data, " ", ""))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid data: %v", err)
+	}
+
+	if int(address)+len(data) > 0x10000 {
+		return nil, fmt.Errorf("Block of %d bytes at %04X passes the end of memory", len(data), address)
+	}
+
+	var werr error
+	if err := m.control.do(func() { werr = m.config.addressBus.WriteBlock(address, data) }); err != nil {
+		return nil, err
+	}
+	return nil, werr
+}
+
+// apiFor wraps a handler for the machine named by the machine parameter, or
+// this one if there is none, e.g. /api/pause?machine=node.
+func (m *Machine) apiFor(fn func(machine *Machine, r *http.Request) (interface{}, error)) func(r *http.Request) (interface{}, error) {
+	return func(r *http.Request) (interface{}, error) {
+		name := r.URL.Query().Get("machine")
+		if name == "" || name == m.machineName() {
+			return fn(m, r)
+		}
+		for _, machine := range m.machines {
+			if machine.machineName() == name {
+				return fn(machine, r)
+			}
+		}
+		return nil, fmt.Errorf("No machine %s", name)
+	}
+}
+
+// apiPost wraps a handler which only accepts POST. Requests from a page on
+// another site are refused, as a browser will send them without asking.
+func apiPost(fn func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST required", http.StatusMethodNotAllowed)
+			return
+		}
+		if !sameOrigin(r) {
+			http.Error(w, "Cross-origin request refused", http.StatusForbidden)
+			return
+		}
+		result, err := fn(r)
+		apiReply(w, result, err)
+	}
+}
+
+// sameOrigin returns true unless the request has an Origin header for a
+// different host. Clients other than browsers don't send one.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// apiReply writes the result as JSON, or the error.
+func apiReply(w http.ResponseWriter, result interface{}, err error) {
+	switch {
+	case err == errMachineBusy || err == errMachineStopped:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case result == nil:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
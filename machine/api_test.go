@@ -0,0 +1,256 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/scheduler"
+	"gopkg.in/yaml.v3"
+)
+
+func TestHttpApi(t *testing.T) {
+	c := &Config{}
+	config := "storage: {type: memory}\nhardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 65536}\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.addressBus.Fill(0, 0x10000, 0xEA)
+
+	m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+	m.cpu.PC = 0x0200
+
+	// Run NOPs until the test finishes
+	s := scheduler.NewScheduler(0)
+	schedule(t, s, m)
+	defer runScheduler(s)()
+
+	server := httptest.NewServer(m.apiHandler())
+	defer server.Close()
+
+	post := func(path, contentType, body string) {
+		resp, err := http.Post(server.URL+path, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal(fmt.Sprintf("%s returned %s", path, resp.Status))
+		}
+	}
+	get := func(path string, result interface{}) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	post("/api/pause", "", "")
+	var before, after ApiState
+	get("/api/cpu", &before)
+	get("/api/cpu", &after)
+	if !before.Paused || before.PC != after.PC {
+		t.Error(fmt.Sprintf("expected the cpu paused got %+v then %+v", before, after))
+	}
+
+	post("/api/memory", "application/json", `{"address": "0010", "data": "A9 42"}`)
+	var mem ApiMemory
+	get("/api/memory?address=0010&length=2", &mem)
+	if mem.Data != "A942" {
+		t.Error(fmt.Sprintf("expected A942 got %+v", mem))
+	}
+
+	post("/api/reset", "", "")
+	get("/api/cpu", &after)
+	if after.PC != 0xEAEA {
+		t.Error(fmt.Sprintf("expected reset to $EAEA got $%04X", after.PC))
+	}
+
+	post("/api/snapshot", "application/x-www-form-urlencoded", url.Values{"name": {"api.state"}}.Encode())
+	if _, err := c.storage.Load("api.state"); err != nil {
+		t.Error(err)
+	}
+
+	for _, name := range []string{"../escape.state", "dir/api.state", `dir\api.state`, ".."} {
+		resp, err := http.PostForm(server.URL+"/api/snapshot", url.Values{"name": {name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("expected snapshot %q to be refused got %s", name, resp.Status))
+		}
+	}
+
+	// A page on another site can't drive the machine from a browser
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/reset", nil)
+	req.Header.Set("Origin", "http://example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Error(fmt.Sprintf("expected a cross-origin reset to be refused got %s", resp.Status))
+	}
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/api/step", nil)
+	req.Header.Set("Origin", server.URL)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Error(fmt.Sprintf("expected a same-origin step to be allowed got %s", resp.Status))
+	}
+
+	post("/api/resume", "", "")
+	get("/api/cpu", &after)
+	if after.Paused {
+		t.Error("expected the cpu resumed")
+	}
+}
+
+func TestHttpApiMemoryErrors(t *testing.T) {
+	c := &Config{}
+	config := "hardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 1024}\n  - name: Vectors\n    address: \"FC00\"\n    ram: {size: 1024}\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+	s := scheduler.NewScheduler(0)
+	schedule(t, s, m)
+	defer runScheduler(s)()
+
+	server := httptest.NewServer(m.apiHandler())
+	defer server.Close()
+
+	// Unmapped memory would panic the cpu if read through the bus
+	for _, path := range []string{"/api/memory?address=8000", "/api/memory?address=03FF&length=2", "/api/memory?address=FFFF&length=2"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("expected %s to be refused got %s", path, resp.Status))
+		}
+	}
+	for _, body := range []string{`{"address": "8000", "data": "00"}`, `{"address": "FFFF", "data": "0000"}`} {
+		resp, err := http.Post(server.URL+"/api/memory", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error(fmt.Sprintf("expected write %s to be refused got %s", body, resp.Status))
+		}
+	}
+}
+
+func TestHttpApiPausesOneMachine(t *testing.T) {
+	s := scheduler.NewScheduler(0)
+	var machines []*Machine
+	for _, name := range []string{"main", "node"} {
+		c := &Config{}
+		config := "name: " + name + "\nhardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 65536}\n"
+		if err := yaml.Unmarshal([]byte(config), c); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		c.addressBus.Fill(0, 0x10000, 0xEA)
+		m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+		schedule(t, s, m)
+		machines = append(machines, m)
+	}
+	m := machines[0]
+	m.machines = machines[1:]
+	defer runScheduler(s)()
+
+	server := httptest.NewServer(m.apiHandler())
+	defer server.Close()
+
+	cpuState := func(query string) ApiState {
+		resp, err := http.Get(server.URL + "/api/cpu" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var state ApiState
+		if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+			t.Fatal(err)
+		}
+		return state
+	}
+
+	resp, err := http.Post(server.URL+"/api/pause?machine=node", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatal(fmt.Sprintf("pause returned %s", resp.Status))
+	}
+
+	before, after := cpuState("?machine=node"), cpuState("?machine=node")
+	if !before.Paused || before.Cycles != after.Cycles {
+		t.Error(fmt.Sprintf("expected node paused got %+v then %+v", before, after))
+	}
+	before, after = cpuState(""), cpuState("?machine=main")
+	if before.Paused || before.Cycles == after.Cycles {
+		t.Error(fmt.Sprintf("expected main running got %+v then %+v", before, after))
+	}
+
+	resp, err = http.Get(server.URL + "/api/cpu?machine=missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Error(fmt.Sprintf("expected an unknown machine to fail got %s", resp.Status))
+	}
+}
+
+// schedule adds the cpu of a machine to s, with a control for requests.
+func schedule(t *testing.T, s *scheduler.Scheduler, m *Machine) {
+	m.scheduler = s
+	m.control = newControl(s, m.machineName(), m.cpu)
+	m.cpu.AttachMonitor(m.control)
+	if err := s.Add(m.machineName(), m.cpu); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetIdle(m.machineName(), m.control.whilePaused); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// runScheduler runs s until the returned function is called.
+func runScheduler(s *scheduler.Scheduler) func() {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		s.Run()
+	}()
+	return func() {
+		s.Stop()
+		<-stopped
+	}
+}
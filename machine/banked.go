@@ -0,0 +1,23 @@
+package machine
+
+import (
+	"github.com/peter-mount/go6502/memory"
+)
+
+// BankedChip is bank switched memory. The hardware address is the window the
+// selected bank is visible through, and the latch address is where the
+// paging register is attached.
+type BankedChip struct {
+	BankSize int    `yaml:"bankSize"`
+	Banks    int    `yaml:"banks"`
+	Latch    string `yaml:"latch"`
+	// Backing is a file the banks are memory mapped from.
+	Backing string `yaml:"backing"`
+}
+
+func (c *BankedChip) Configure() (memory.Memory, error) {
+	if c.Backing != "" {
+		return memory.NewMappedBanked(c.Backing, c.BankSize, c.Banks)
+	}
+	return memory.NewBanked(c.BankSize, c.Banks)
+}
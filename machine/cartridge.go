@@ -0,0 +1,33 @@
+package machine
+
+import (
+	"github.com/peter-mount/go6502/memory"
+)
+
+// CartridgeChip is a banked ROM image. The hardware address is the window
+// the selected bank is visible through, and the latch address is where the
+// bank register is attached.
+type CartridgeChip struct {
+	Filename string `yaml:"filename"`
+	BankSize int    `yaml:"bankSize"`
+	Latch    string `yaml:"latch"`
+	// Writes and Strict report writes to the window as for RomChip.
+	Writes  string `yaml:"writes"`
+	Strict  bool   `yaml:"strict"`
+	onWrite memory.WriteHandler
+}
+
+func (c *CartridgeChip) Configure() (memory.Memory, error) {
+	if err := validateWrites(c.Writes); err != nil {
+		return nil, err
+	}
+
+	cart, err := memory.CartridgeFromFile(c.Filename, c.BankSize)
+	if err != nil {
+		return nil, err
+	}
+	if c.onWrite != nil {
+		cart.OnWrite(c.onWrite)
+	}
+	return cart, nil
+}
@@ -0,0 +1,21 @@
+package machine
+
+import (
+	"github.com/peter-mount/go6502/memory"
+)
+
+// CharRomChip is a character generator ROM. With no hardware address it is
+// not visible to the cpu, but is available to video devices via
+// Config.CharRom.
+type CharRomChip struct {
+	Filename string `yaml:"filename"`
+	Height   int    `yaml:"height"`
+}
+
+func (c *CharRomChip) Configure() (memory.Memory, error) {
+	height := c.Height
+	if height == 0 {
+		height = 8
+	}
+	return memory.CharRomFromFile(c.Filename, height)
+}
@@ -1,39 +1,171 @@
 package machine
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"github.com/peter-mount/go6502/acia6551"
 	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
 	"github.com/peter-mount/go6502/memory"
+	"github.com/peter-mount/go6502/storage"
+	"github.com/peter-mount/go6502/via6522"
 	"github.com/peter-mount/golib/kernel"
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 )
 
 type Config struct {
+	MachineName string   `yaml:"name"`
+	Include     []string `yaml:"include"`
+	Machines    []string `yaml:"machines"`
+	Cpu         struct {
+		Profile         string `yaml:"profile"`
+		IndirectJumpBug *bool  `yaml:"indirectJumpBug"`
+		DummyWrite      *bool  `yaml:"dummyWrite"`
+		DummyRead       *bool  `yaml:"dummyRead"`
+		CycleAccurate   bool   `yaml:"cycleAccurate"`
+		ClockHz         uint64 `yaml:"clockHz"`
+		Throttle        *bool  `yaml:"throttle"`
+		InterruptTiming struct {
+			Latency uint64 `yaml:"latency"`
+			Cycles  uint64 `yaml:"cycles"`
+		} `yaml:"interrupts"`
+	} `yaml:"cpu"`
+	Exit struct {
+		Opcode  string `yaml:"opcode"`
+		Address string `yaml:"address"`
+		Message string `yaml:"message"`
+	} `yaml:"exit"`
+	Limits struct {
+		MaxCycles  uint64  `yaml:"maxCycles"`
+		MaxSeconds float64 `yaml:"maxSeconds"`
+		UntilWrite string  `yaml:"untilWrite"`
+		Status     *int    `yaml:"status"`
+		DumpCore   string  `yaml:"dumpCore"`
+	} `yaml:"limits"`
+	Input struct {
+		Record string `yaml:"record"`
+		Replay string `yaml:"replay"`
+	} `yaml:"input"`
 	Debug struct {
 		Debugger      bool     `yaml:"debugger"`
+		Batch         bool     `yaml:"batch"`
+		Web           string   `yaml:"web"`
+		RPC           string   `yaml:"rpc"`
 		DebugCommands []string `yaml:"debugCommands"`
+		DebugScript   string   `yaml:"debugScript"`
 		SymbolFile    string   `yaml:"symbolFile"`
+		SymbolFormat  string   `yaml:"symbolFormat"`
 		Speedometer   bool     `yaml:"speedometer"`
-		CoreFile      string   `yaml:"dumpCore"`
+		Regions       []Region `yaml:"regions"`
+		Stats         bool     `yaml:"stats"`
+		HeatMap       string   `yaml:"heatMap"`
+		Watchdog      string   `yaml:"watchdog"`
+		Trace         struct {
+			File   string   `yaml:"file"`
+			Ranges []Region `yaml:"ranges"`
+		} `yaml:"trace"`
+		CoreFile   string `yaml:"dumpCore"`
+		CoreFormat string `yaml:"dumpCoreFormat"`
 	} `yaml:"debug"`
-	Hardware   []Hardware `yaml:"hardware"`
+	Bus struct {
+		Unmapped string `yaml:"unmapped"`
+		Fault    bool   `yaml:"fault"`
+		Strict   bool   `yaml:"strict"`
+	} `yaml:"bus"`
+	Hardware   []Hardware     `yaml:"hardware"`
+	Programs   []Program      `yaml:"programs"`
+	Protect    []Protect      `yaml:"protect"`
+	Storage    storage.Config `yaml:"storage"`
 	configFile *string
+	heatMap    *string
+	batch      *bool
+	maxCycles  *uint64
+	maxSeconds *float64
+	untilWrite *string
+	record     *string
+	replayFile *string
+	rom        *string
+	ramSize    *int
+	debug      *bool
+	console    *bool
+	storage    storage.Storage
+	cpu        *cpu.Cpu
+	traceFile  *os.File
+	trace      *bufio.Writer
+	inputFile  *os.File
+	input      *bufio.Writer
+	replay     map[string][]acia6551.InputEvent
+	log        *log.Logger
 	addressBus *bus.Bus
 	memory     []memory.Memory
+	addresses  []uint16 // bus address of each memory
+	names      []string // hardware name of each memory
+	fault      faultFunc
+	charRoms   map[string]*memory.CharRom
+	vias       map[string]*via6522.Via6522
+	roms       map[string]*memory.Rom
+	links      linkTable
+	// sharedLinks is set when links belong to the machine this one runs
+	// alongside
+	sharedLinks bool
 }
 
+// faultFunc reports a fault in the guest, optionally breaking into the
+// debugger or stopping the machine.
+type faultFunc func(reason string, brk bool, stop bool)
+
 type Hardware struct {
-	Name     string        `yaml:"name"`
-	Address  string        `yaml:"address"`
-	Ram      *RamChip      `yaml:"ram"`
-	Rom      *RomChip      `yaml:"rom"`
-	Acia6551 *Acia6551Chip `yaml:"6551"`
-	Via6522  *Via6522Chip  `yaml:"6522"`
+	Name       string          `yaml:"name"`
+	Address    string          `yaml:"address"`
+	Mirror     int             `yaml:"mirror"`
+	Ram        *RamChip        `yaml:"ram"`
+	Rom        *RomChip        `yaml:"rom"`
+	Acia6551   *Acia6551Chip   `yaml:"6551"`
+	Via6522    *Via6522Chip    `yaml:"6522"`
+	Banked     *BankedChip     `yaml:"banked"`
+	Overlay    *OverlayChip    `yaml:"overlay"`
+	Cartridge  *CartridgeChip  `yaml:"cartridge"`
+	CharRom    *CharRomChip    `yaml:"charRom"`
+	RomOverRam *RomOverRamChip `yaml:"romOverRam"`
+	Faults     *FaultConfig    `yaml:"faults"`
+	Ssd1306    *Ssd1306Chip    `yaml:"ssd1306"`
+	Ili9340    *Ili9340Chip    `yaml:"ili9340"`
+	SdCard     *SdCardChip     `yaml:"sd"`
+	// WaitStates are extra cycles added to each access, for slow devices.
+	WaitStates int `yaml:"waitStates"`
+	// Plugins holds the options of chips registered with RegisterChip.
+	Plugins map[string]yaml.Node `yaml:",inline"`
+}
+
+// Program is a file loaded into memory after the cpu is powered on and reset.
+type Program struct {
+	File string `yaml:"file"`
+	// Format is "ihex", "srec" or "raw", or if empty is determined by the
+	// file extension.
+	Format string `yaml:"format"`
+	// Address is where a raw binary is loaded.
+	Address string `yaml:"address"`
+	// Reset points the reset vector at the program entry point.
+	Reset bool `yaml:"reset"`
+}
+
+// Region is a named address range reported on by the speedometer.
+// Start and End are either hex addresses or labels from the symbol file.
+type Region struct {
+	Name  string `yaml:"name"`
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
 }
 
 type Chip interface {
@@ -46,79 +178,641 @@ func (c *Config) Name() string {
 
 func (c *Config) Init(k *kernel.Kernel) error {
 	c.configFile = flag.String("c", "", "The config file to use")
+	c.heatMap = flag.String("heatmap", "", "Write a memory access heat map to this .csv or .png file on exit")
+	c.batch = flag.Bool("debug-batch", false, "Run the debugger commands without a terminal then exit, non-zero if any failed")
+	c.maxCycles = flag.Uint64("max-cycles", 0, "Exit once this many cycles have run")
+	c.maxSeconds = flag.Float64("max-seconds", 0, "Exit once the machine has run for this many seconds")
+	c.untilWrite = flag.String("until-write", "", "Exit once this hex address is written to")
+	c.record = flag.String("record-input", "", "Record the input read by the guest to this file")
+	c.replayFile = flag.String("replay-input", "", "Replay the input recorded with -record-input")
+	c.debug = flag.Bool("debug", false, "Run the debugger")
+	c.rom = flag.String("rom", "", "ROM image at the top of memory, without a config file")
+	c.ramSize = flag.Int("ram", 0x8000, "Bytes of RAM from $0000, without a config file")
+	c.console = flag.Bool("console", true, "Console on a 6551 at $9010, without a config file")
 
 	return nil
 }
 
 func (c *Config) PostInit() error {
-	// Verify then load the config file
+	// Verify then load the config file, or build the machine from the flags
 	if *c.configFile == "" {
-		*c.configFile = "config.yaml"
+		if _, err := os.Stat("config.yaml"); err == nil {
+			*c.configFile = "config.yaml"
+		}
 	}
 
-	if filename, err := filepath.Abs(*c.configFile); err != nil {
+	if *c.configFile != "" {
+		if err := c.load(*c.configFile, nil); err != nil {
+			return err
+		}
+	} else if err := c.defaultConfig(*c.rom, *c.ramSize, *c.console); err != nil {
 		return err
-	} else if in, err := ioutil.ReadFile(filename); err != nil {
+	}
+
+	if *c.debug {
+		c.Debug.Debugger = true
+	}
+
+	if *c.heatMap != "" {
+		c.Debug.HeatMap = *c.heatMap
+	}
+
+	if *c.batch {
+		c.Debug.Batch = true
+	}
+
+	if *c.maxCycles != 0 {
+		c.Limits.MaxCycles = *c.maxCycles
+	}
+	if *c.maxSeconds != 0 {
+		c.Limits.MaxSeconds = *c.maxSeconds
+	}
+	if *c.untilWrite != "" {
+		c.Limits.UntilWrite = *c.untilWrite
+	}
+	if *c.record != "" {
+		c.Input.Record = *c.record
+	}
+	if *c.replayFile != "" {
+		c.Input.Replay = *c.replayFile
+	}
+
+	return nil
+}
+
+// defaultConfig describes the machine used without a config file: RAM from
+// $0000, a 6522 at $9000, optionally the console on a 6551 at $9010, and the
+// ROM, if any, ending at $FFFF.
+func (c *Config) defaultConfig(rom string, ramSize int, console bool) error {
+	c.Hardware = []Hardware{
+		{Name: "RAM", Address: "0000", Ram: &RamChip{Size: ramSize}},
+		{Name: "VIA", Address: "9000", Via6522: &Via6522Chip{}},
+	}
+
+	if console {
+		c.Hardware = append(c.Hardware, Hardware{
+			Name:     "Console",
+			Address:  "9010",
+			Acia6551: &Acia6551Chip{Peripheral: "console"},
+		})
+	}
+
+	if rom != "" {
+		info, err := os.Stat(rom)
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 || info.Size() > 0x10000 {
+			return fmt.Errorf("ROM %s must be between 1 byte and 64K", rom)
+		}
+		c.Hardware = append(c.Hardware, Hardware{
+			Name:    "ROM",
+			Address: fmt.Sprintf("%04X", 0x10000-info.Size()),
+			Rom:     &RomChip{Filename: rom},
+		})
+	}
+
+	return nil
+}
+
+// LoadConfig reads a machine config file, e.g. for NewMachine.
+func LoadConfig(file string) (*Config, error) {
+	c := &Config{configFile: &file}
+	if err := c.load(file, nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads a config file over the config, after the files it includes.
+// Included files are relative to the including file, and later files
+// override earlier ones. Hardware is merged by name, so an entry replaces any
+// included entry with the same name.
+func (c *Config) load(file string, including []string) error {
+	filename, err := filepath.Abs(file)
+	if err != nil {
 		return err
-	} else if err := yaml.Unmarshal(in, c); err != nil {
+	}
+	for _, f := range including {
+		if f == filename {
+			return fmt.Errorf("Config %s includes itself via %s", file, strings.Join(including, ", "))
+		}
+	}
+
+	in, err := ioutil.ReadFile(filename)
+	if err != nil {
 		return err
 	}
 
+	// JSON is YAML, except that tabs may not indent YAML. They can only be
+	// whitespace in valid JSON, as tabs within strings must be escaped.
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		var v interface{}
+		if err := json.Unmarshal(in, &v); err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		in = bytes.ReplaceAll(in, []byte("\t"), []byte(" "))
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(in, &doc); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	var problems configError
+	checkSchema(&doc, reflect.TypeOf(Config{}), "", &problems)
+	if err := problems.result(); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	var includes struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal(in, &includes); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for _, include := range includes.Include {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(filename), include)
+		}
+		if err := c.load(include, append(including, filename)); err != nil {
+			return err
+		}
+	}
+
+	included := c.Hardware
+	c.Hardware = nil
+	if err := yaml.Unmarshal(in, c); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	c.Hardware = mergeHardware(included, c.Hardware)
 	return nil
 }
 
-func (c *Config) Start() error {
+// mergeHardware replaces included entries with those of the same name, and
+// appends new ones.
+func mergeHardware(included, hardware []Hardware) []Hardware {
+	merged := append([]Hardware(nil), included...)
+	for _, h := range hardware {
+		replaced := false
+		for i := range merged {
+			if h.Name != "" && merged[i].Name == h.Name {
+				merged[i], replaced = h, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+func (c *Config) Start() (err error) {
+	if err := c.validate(); err != nil {
+		return err
+	}
+
 	addressBus, err := bus.CreateBus()
 	if err != nil {
 		return err
 	}
 
+	// Whatever was started before a failure is stopped again, e.g. restoring
+	// the terminal and unmapping backing files
+	defer func() {
+		if err != nil {
+			c.stopTrace()
+			c.stopInput()
+			c.shutdownDevices(shutdownTimeout)
+		}
+	}()
+
 	c.addressBus = addressBus
+	c.log = newLogger(c.MachineName)
+	c.charRoms = make(map[string]*memory.CharRom)
+	c.vias = make(map[string]*via6522.Via6522)
+	c.roms = make(map[string]*memory.Rom)
+	if !c.sharedLinks {
+		c.links = make(linkTable)
+	}
+
+	c.storage, err = storage.New(c.Storage)
+	if err != nil {
+		return err
+	}
+
+	if c.Debug.Trace.File != "" {
+		err = c.startTrace()
+		if err != nil {
+			return err
+		}
+	}
 
+	if err := c.startInput(); err != nil {
+		return err
+	}
+
+	var peripherals []Hardware
 	for _, h := range c.Hardware {
-		var address uint16
+		// Peripherals are attached to a 6522 once they are all on the bus
+		if h.peripheral() != nil {
+			peripherals = append(peripherals, h)
+			continue
+		}
+
+		// Character ROMs need not be visible to the cpu
+		if h.CharRom != nil && h.Address == "" {
+			err = c.addCharRom(h.Name, h.CharRom)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 
 		if h.Address == "" {
 			return fmt.Errorf("Invalid Hardware entry, name %s", h.Name)
 		}
 
-		b, err := hex.DecodeString(h.Address)
+		address, err := parseAddress(h.Name, h.Address)
 		if err != nil {
 			return err
 		}
-		if len(b) != 2 {
-			return fmt.Errorf("Invalid Address, name %s, got %s", h.Name, h.Address)
-		}
-		address = (uint16(b[0]) << 8) | uint16(b[1])
 
 		err = errors.New("No chip defined")
 		if h.Ram != nil {
-			err = c.attach(h.Name, address, h.Ram)
+			err = c.attach(&h, address, h.Ram)
 		} else if h.Rom != nil {
-			err = c.attach(h.Name, address, h.Rom)
+			h.Rom.onWrite = c.romWriteHandler(h.Name, address, h.Rom.Writes, h.Rom.Strict)
+			err = c.attach(&h, address, h.Rom)
+			if err == nil {
+				c.roms[h.Name] = c.memory[len(c.memory)-1].(*memory.Rom)
+			}
 		} else if h.Acia6551 != nil {
-			err = c.attach(h.Name, address, h.Acia6551)
+			h.Acia6551.clock = c.cycles
+			h.Acia6551.record = c.recordInput(h.Name)
+			h.Acia6551.replay = c.replay[h.Name]
+			h.Acia6551.links = c.links
+			err = c.attach(&h, address, h.Acia6551)
 		} else if h.Via6522 != nil {
-			err = c.attach(h.Name, address, h.Via6522)
+			err = c.attach(&h, address, h.Via6522)
+			if err == nil {
+				c.vias[h.Name] = c.memory[len(c.memory)-1].(*via6522.Via6522)
+			}
+		} else if h.Banked != nil {
+			err = c.attachBanked(&h, address, h.Banked)
+		} else if h.Overlay != nil {
+			err = c.attachOverlay(&h, address, h.Overlay)
+		} else if h.CharRom != nil {
+			err = c.attach(&h, address, h.CharRom)
+			if err == nil {
+				c.charRoms[h.Name] = c.memory[len(c.memory)-1].(*memory.CharRom)
+			}
+		} else if h.RomOverRam != nil {
+			err = c.attachRomOverRam(&h, address, h.RomOverRam)
+		} else if h.Cartridge != nil {
+			err = c.attachCartridge(&h, address, h.Cartridge)
+		} else if len(h.Plugins) > 0 {
+			var chip Chip
+			chip, err = h.plugin()
+			if err == nil {
+				err = c.attach(&h, address, chip)
+			}
 		}
 		if err != nil {
 			return err
 		}
 	}
 
+	for _, h := range peripherals {
+		if err := c.attachPeripheral(&h, h.peripheral()); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
-func (c *Config) attach(name string, address uint16, chip Chip) error {
+
+// loadPrograms loads each program into memory.
+func (c *Config) loadPrograms() error {
+	for _, p := range c.Programs {
+		var address uint16
+		if p.Address != "" {
+			var err error
+			address, err = parseAddress(p.File, p.Address)
+			if err != nil {
+				return err
+			}
+		}
+
+		program, err := memory.LoadFile(p.File, p.Format, address, c.addressBus)
+		if err != nil {
+			return fmt.Errorf("Failed to load %s: %v", p.File, err)
+		}
+		c.logger().Printf("Loaded %s: %v", p.File, program)
+
+		if p.Reset {
+			err = program.SetResetVector(c.addressBus)
+			if err != nil {
+				return fmt.Errorf("Failed to set reset vector for %s: %v", p.File, err)
+			}
+		}
+	}
+	return nil
+}
+
+// addCharRom loads a character ROM which is not attached to the bus.
+func (c *Config) addCharRom(name string, chip *CharRomChip) error {
 	m, err := chip.Configure()
 	if err != nil {
 		return err
 	}
+	c.charRoms[name] = m.(*memory.CharRom)
+	return nil
+}
+
+// CharRom returns the named character ROM, for video devices to read glyphs
+// from.
+func (c *Config) CharRom(name string) (*memory.CharRom, error) {
+	rom, exists := c.charRoms[name]
+	if !exists {
+		return nil, fmt.Errorf("No character ROM named %q", name)
+	}
+	return rom, nil
+}
 
-	err = c.addressBus.Attach(m, name, address)
+// parseAddress parses a 4 digit hex address for the named entry.
+func parseAddress(name, s string) (uint16, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) != 2 {
+		return 0, fmt.Errorf("Invalid Address, name %s, got %s", name, s)
+	}
+	return (uint16(b[0]) << 8) | uint16(b[1]), nil
+}
+
+// Quirks returns the NMOS quirks to emulate. The profile sets the defaults,
+// which can then be overridden individually.
+func (c *Config) Quirks() (cpu.Quirks, error) {
+	var quirks cpu.Quirks
+	if c.Cpu.Profile != "" {
+		var err error
+		quirks, err = cpu.QuirksFor(c.Cpu.Profile)
+		if err != nil {
+			return quirks, err
+		}
+	}
+	if c.Cpu.IndirectJumpBug != nil {
+		quirks.IndirectJumpBug = *c.Cpu.IndirectJumpBug
+	}
+	if c.Cpu.DummyWrite != nil {
+		quirks.DummyWrite = *c.Cpu.DummyWrite
+	}
+	if c.Cpu.DummyRead != nil {
+		quirks.DummyRead = *c.Cpu.DummyRead
+	}
+	return quirks, nil
+}
+
+// governor returns a monitor keeping the cpu to the configured clock speed,
+// or nil if it runs as fast as possible.
+func (c *Config) governor() (*governor, error) {
+	throttle := c.Cpu.ClockHz > 0
+	if c.Cpu.Throttle != nil {
+		throttle = *c.Cpu.Throttle
+	}
+	if !throttle {
+		return nil, nil
+	}
+	if c.Cpu.ClockHz == 0 {
+		return nil, fmt.Errorf("cpu throttle requires clockHz")
+	}
+	return newGovernor(c.Cpu.ClockHz, c.cycles), nil
+}
+
+// ExitTrap returns the exit trap for test ROMs, if one is configured.
+func (c *Config) ExitTrap() (cpu.ExitTrap, error) {
+	var trap cpu.ExitTrap
+
+	if c.Exit.Opcode != "" {
+		b, err := hex.DecodeString(c.Exit.Opcode)
+		if err != nil {
+			return trap, err
+		}
+		if len(b) != 1 {
+			return trap, fmt.Errorf("Invalid exit opcode, got %s", c.Exit.Opcode)
+		}
+		trap.Opcode = true
+		trap.OpcodeValue = b[0]
+	}
+
+	if c.Exit.Address != "" {
+		address, err := parseAddress("exit", c.Exit.Address)
+		if err != nil {
+			return trap, err
+		}
+		trap.Address = true
+		trap.AddressValue = address
+	}
+
+	if c.Exit.Message != "" {
+		address, err := parseAddress("exit message", c.Exit.Message)
+		if err != nil {
+			return trap, err
+		}
+		trap.Message = true
+		trap.MessageAddress = address
+	}
+
+	return trap, nil
+}
+
+// attachBanked attaches banked memory at its window address, and its latch.
+func (c *Config) attachBanked(h *Hardware, address uint16, chip *BankedChip) error {
+	latch, err := parseAddress(h.Name, chip.Latch)
 	if err != nil {
 		return err
 	}
 
-	c.memory = append(c.memory, m)
+	err = c.attach(h, address, chip)
+	if err != nil {
+		return err
+	}
+
+	banked := c.memory[len(c.memory)-1].(*memory.Banked)
+	return c.addressBus.Attach(banked.Latch(), h.Name+" latch", latch)
+}
+
+// attachOverlay attaches an overlay at its address, and its latch.
+func (c *Config) attachOverlay(h *Hardware, address uint16, chip *OverlayChip) error {
+	latch, err := parseAddress(h.Name, chip.Latch)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range []*OverlayLayer{&chip.Over, &chip.Under} {
+		if l.Rom != nil {
+			l.Rom.onWrite = c.romWriteHandler(h.Name, address, l.Rom.Writes, l.Rom.Strict)
+		}
+	}
+
+	err = c.attach(h, address, chip)
+	if err != nil {
+		return err
+	}
+
+	overlay := c.memory[len(c.memory)-1].(*memory.Overlay)
+	return c.addressBus.Attach(overlay.Latch(), h.Name+" latch", latch)
+}
+
+// attachRomOverRam attaches a rom over ram overlay, and its control register.
+func (c *Config) attachRomOverRam(h *Hardware, address uint16, chip *RomOverRamChip) error {
+	control, err := parseAddress(h.Name, chip.Control)
+	if err != nil {
+		return err
+	}
+
+	chip.onWrite = c.romWriteHandler(h.Name, address, chip.Writes, chip.Strict)
+	err = c.attach(h, address, chip)
+	if err != nil {
+		return err
+	}
+
+	overlay := c.memory[len(c.memory)-1].(*memory.Overlay)
+	return c.addressBus.Attach(overlay.Latch(), h.Name+" control", control)
+}
+
+// attachCartridge attaches a cartridge at its window address, and its latch.
+func (c *Config) attachCartridge(h *Hardware, address uint16, chip *CartridgeChip) error {
+	latch, err := parseAddress(h.Name, chip.Latch)
+	if err != nil {
+		return err
+	}
+
+	chip.onWrite = c.romWriteHandler(h.Name, address, chip.Writes, chip.Strict)
+	err = c.attach(h, address, chip)
+	if err != nil {
+		return err
+	}
+
+	cart := c.memory[len(c.memory)-1].(*memory.Cartridge)
+	return c.addressBus.Attach(cart.Latch(), h.Name+" latch", latch)
+}
+
+// startTrace logs bus accesses to the trace file.
+func (c *Config) startTrace() error {
+	var ranges []bus.Region
+	for _, r := range c.Debug.Trace.Ranges {
+		start, err := parseAddress(r.Name, r.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseAddress(r.Name, r.End)
+		if err != nil {
+			return err
+		}
+		ranges = append(ranges, bus.Region{Name: r.Name, Start: start, End: end})
+	}
+
+	f, err := os.Create(c.Debug.Trace.File)
+	if err != nil {
+		return err
+	}
+	c.traceFile = f
+	c.trace = bufio.NewWriter(f)
+	c.addressBus.Trace(c.trace, ranges...)
 	return nil
 }
+
+// stopTrace flushes and closes the trace file.
+func (c *Config) stopTrace() {
+	if c.traceFile != nil {
+		c.addressBus.Trace(nil)
+		_ = c.trace.Flush()
+		_ = c.traceFile.Close()
+		c.traceFile = nil
+	}
+}
+
+// romWriteHandler returns a handler reporting writes to a rom chip.
+func (c *Config) romWriteHandler(name string, address uint16, writes string, strict bool) memory.WriteHandler {
+	return func(a uint16, value byte) {
+		if writes == "ignore" && !strict {
+			return
+		}
+		if c.fault != nil {
+			reason := fmt.Sprintf("Write $%02X to ROM %s at $%04X pc:$%04X", value, name, address+a, c.addressBus.PC())
+			c.fault(reason, writes == "break", strict)
+		}
+	}
+}
+
+// cycles returns the cpu cycle count, for devices which are timed by the cpu.
+func (c *Config) cycles() uint64 {
+	if c.cpu == nil {
+		return 0
+	}
+	return c.cpu.Cycles
+}
+
+// attach a chip to the bus. If mirror is set the chip repeats across a window
+// of that many bytes. If faults are configured they are injected into reads.
+func (c *Config) attach(h *Hardware, address uint16, chip Chip) error {
+	var model memory.FaultModel
+	if h.Faults != nil {
+		var err error
+		model, err = h.Faults.model(h.Name, address)
+		if err != nil {
+			return err
+		}
+	}
+
+	m, err := chip.Configure()
+	if err != nil {
+		return err
+	}
+
+	device := m
+	if h.Faults != nil {
+		device = memory.NewFaulty(m, model)
+	}
+
+	if h.Mirror > 0 {
+		err = c.addressBus.AttachMirrored(device, h.Name, address, h.Mirror)
+	} else {
+		err = c.addressBus.Attach(device, h.Name, address)
+	}
+	if err != nil {
+		m.Shutdown()
+		return err
+	}
+
+	// Only record the chip once it is on the bus, so the memory, addresses
+	// and names stay in step with what is attached
+	c.memory = append(c.memory, m)
+	c.addresses = append(c.addresses, address)
+	c.names = append(c.names, h.Name)
+
+	if h.WaitStates == 0 {
+		return nil
+	}
+	return c.addressBus.SetWaitStates(h.Name, h.WaitStates)
+}
+
+// newLogger returns a logger prefixing messages with the machine name, if it
+// has one, so the output of several machines can be told apart.
+func newLogger(name string) *log.Logger {
+	if name == "" {
+		return log.Default()
+	}
+	return log.New(log.Writer(), name+": ", log.Flags()|log.Lmsgprefix)
+}
+
+// logger returns the logger of the machine.
+func (c *Config) logger() *log.Logger {
+	if c.log == nil {
+		return log.Default()
+	}
+	return c.log
+}
@@ -0,0 +1,176 @@
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigIncludes(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(dir+"/base.yaml", []byte(`
+cpu:
+  clockHz: 1000000
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {size: 32768}
+  - name: kernal
+    address: "F000"
+    rom: {filename: kernal.rom}
+`), 0640)
+	ioutil.WriteFile(dir+"/experiment.yaml", []byte(`
+include: [base.yaml]
+debug:
+  debugger: true
+hardware:
+  - name: kernal
+    address: "E000"
+    rom: {filename: experiment.rom}
+  - name: VIA
+    address: "9000"
+    6522: {}
+`), 0640)
+
+	c := &Config{}
+	if err := c.load(dir+"/experiment.yaml", nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.Cpu.ClockHz != 1000000 || !c.Debug.Debugger {
+		t.Error("expected settings from both files")
+	}
+
+	var hardware []string
+	for _, h := range c.Hardware {
+		hardware = append(hardware, h.Name+" "+h.Address)
+	}
+	expected := "[ram 0000 kernal E000 VIA 9000]"
+	if actual := fmt.Sprint(hardware); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+	if c.Hardware[1].Rom.Filename != "experiment.rom" {
+		t.Error("expected the kernal to be replaced")
+	}
+
+	ioutil.WriteFile(dir+"/loop.yaml", []byte("include: [loop.yaml]\n"), 0640)
+	if err := (&Config{}).load(dir+"/loop.yaml", nil); err == nil {
+		t.Error("expected a config including itself to fail")
+	}
+}
+
+func TestConfigLoadsProgramsAfterStart(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(dir+"/a.bin", []byte{0xEA}, 0640)
+	ioutil.WriteFile(dir+"/b.bin", []byte{0x4C, 0x00, 0x02}, 0640)
+	file := dir + "/config.yaml"
+	ioutil.WriteFile(file, []byte(`
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {size: 65536}
+programs:
+  - {file: `+dir+`/a.bin, address: "0100"}
+  - {file: `+dir+`/b.bin, address: "0200", reset: true}
+`), 0640)
+
+	c := &Config{}
+	if err := c.load(file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.addressBus.Read(0x0200); v != 0 {
+		t.Error(fmt.Sprintf("expected programs loaded after start got $%02X", v))
+	}
+
+	if err := c.loadPrograms(); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.addressBus.Read(0x0100); v != 0xEA {
+		t.Error(fmt.Sprintf("expected program at $0100 got $%02X", v))
+	}
+	if v := c.addressBus.Read(0x0200); v != 0x4C {
+		t.Error(fmt.Sprintf("expected programs at $0200 got $%02X", v))
+	}
+	if v := c.addressBus.Read16(0xFFFC); v != 0x0200 {
+		t.Error(fmt.Sprintf("expected reset vector $0200 got $%04X", v))
+	}
+}
+
+func TestInputRecording(t *testing.T) {
+	file := t.TempDir() + "/input.txt"
+	c := &Config{}
+	c.Input.Record = file
+	if err := c.startInput(); err != nil {
+		t.Fatal(err)
+	}
+	c.recordInput("ACIA 1")('h')
+	c.recordInput("ACIA 1")('i')
+	c.stopInput()
+
+	replay, err := readInput(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "map[ACIA 1:[{0 104} {0 105}]]"
+	if s := fmt.Sprint(replay); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
+
+func TestConfigWithoutFile(t *testing.T) {
+	rom := t.TempDir() + "/test.rom"
+	image := make([]byte, 0x1000)
+	image[0xFFF] = 0x42
+	ioutil.WriteFile(rom, image, 0640)
+
+	c := &Config{}
+	if err := c.defaultConfig(rom, 0x4000, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var regions []string
+	for _, r := range c.addressBus.Regions() {
+		regions = append(regions, fmt.Sprintf("%s %04X-%04X", r.Name, r.Start, r.End))
+	}
+	expected := "[RAM 0000-3FFF VIA 9000-900F ROM F000-FFFF]"
+	if actual := fmt.Sprint(regions); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+	if b := c.addressBus.Read(0xFFFF); b != 0x42 {
+		t.Error(fmt.Sprintf("expected the ROM to end at $FFFF got $%02X", b))
+	}
+
+	if err := (&Config{}).defaultConfig(t.TempDir()+"/missing.rom", 0x4000, true); err == nil {
+		t.Error("expected a missing ROM to fail")
+	}
+}
+
+func TestConfigFailedAttachIsNotRecorded(t *testing.T) {
+	c := &Config{}
+	err := yaml.Unmarshal([]byte(`
+hardware:
+  - name: RAM
+    address: "0000"
+    ram: {size: 4096}
+  - name: HIGH
+    address: "F800"
+    testchip: {k: 4}
+`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The size of testchip isn't known until the bus refuses it
+	if err := c.Start(); err == nil {
+		t.Fatal("expected a chip past the top of memory to fail")
+	}
+	if len(c.memory) != 1 || len(c.addresses) != 1 || len(c.names) != 1 || c.names[0] != "RAM" {
+		t.Error(fmt.Sprintf("expected only RAM recorded got %v", c.names))
+	}
+}
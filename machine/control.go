@@ -0,0 +1,136 @@
+package machine
+
+import (
+	"errors"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/scheduler"
+)
+
+// controlTimeout is how long a request waits for the cpu to reach the next
+// instruction, e.g. while it is stopped in the debugger.
+const controlTimeout = 5 * time.Second
+
+var (
+	errMachineBusy    = errors.New("Machine is busy, e.g. stopped in the debugger")
+	errMachineStopped = errors.New("Machine has stopped")
+)
+
+// control runs requests from other goroutines, such as the HTTP API, on the
+// goroutine running the scheduler between instructions, as the bus and
+// registers may only be touched there. Requests for a running cpu run before
+// its next instruction, and for a paused one when the scheduler idles it, so
+// pausing one machine does not hold up the others.
+type control struct {
+	requests  chan func()
+	done      chan struct{}
+	scheduler *scheduler.Scheduler
+	name      string
+	cpu       *cpu.Cpu
+	// idle is set while requests run for a paused cpu
+	idle bool
+}
+
+func newControl(s *scheduler.Scheduler, name string, c *cpu.Cpu) *control {
+	return &control{
+		requests:  make(chan func()),
+		done:      make(chan struct{}),
+		scheduler: s,
+		name:      name,
+		cpu:       c,
+	}
+}
+
+// BeforeExecute meets the cpu.Monitor interface, running any waiting requests.
+func (c *control) BeforeExecute(_ cpu.Instruction) {
+	if !c.idle {
+		c.serve()
+	}
+}
+
+// whilePaused runs any waiting requests for a paused cpu. The scheduler calls
+// it each round while the cpu is paused.
+func (c *control) whilePaused() {
+	c.idle = true
+	defer func() { c.idle = false }()
+	c.serve()
+}
+
+// serve runs the waiting requests. Once a request pauses a running cpu the
+// rest wait until it is idled, so they see it paused.
+func (c *control) serve() {
+	for {
+		select {
+		case fn := <-c.requests:
+			fn()
+			if !c.idle && c.isPaused() {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Shutdown meets the cpu.Monitor interface, failing any further requests.
+func (c *control) Shutdown() {
+	c.stop()
+}
+
+// stop fails any further requests.
+func (c *control) stop() {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+}
+
+// do runs fn on the cpu goroutine, waiting for it to complete.
+func (c *control) do(fn func()) error {
+	finished := make(chan struct{})
+	timeout := time.NewTimer(controlTimeout)
+	defer timeout.Stop()
+
+	select {
+	case c.requests <- func() {
+		fn()
+		close(finished)
+	}:
+	case <-c.done:
+		return errMachineStopped
+	case <-timeout.C:
+		return errMachineBusy
+	}
+	<-finished
+	return nil
+}
+
+// setPaused pauses or resumes the cpu before its next instruction.
+func (c *control) setPaused(paused bool) error {
+	var err error
+	if cerr := c.do(func() {
+		if paused {
+			err = c.scheduler.Pause(c.name)
+		} else {
+			err = c.scheduler.Resume(c.name)
+		}
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// step runs the next instruction of a paused cpu. A running cpu is unchanged.
+func (c *control) step() error {
+	return c.do(func() {
+		if c.idle {
+			c.cpu.Step()
+		}
+	})
+}
+
+func (c *control) isPaused() bool {
+	return c.scheduler.Paused(c.name)
+}
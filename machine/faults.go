@@ -0,0 +1,47 @@
+package machine
+
+import (
+	"fmt"
+	"github.com/peter-mount/go6502/memory"
+)
+
+// FaultConfig injects faults into reads from a chip, to test how code copes
+// with flaky hardware.
+type FaultConfig struct {
+	StuckHigh byte    `yaml:"stuckHigh"`
+	StuckLow  byte    `yaml:"stuckLow"`
+	FlipRate  float64 `yaml:"flipRate"`
+	// ReadErrors are bus address ranges where reads return random values.
+	ReadErrors []Region `yaml:"readErrors"`
+	Seed       int64    `yaml:"seed"`
+}
+
+// model returns the fault model for a chip at the given address.
+func (c *FaultConfig) model(name string, address uint16) (memory.FaultModel, error) {
+	if c.FlipRate < 0 || c.FlipRate > 1 {
+		return memory.FaultModel{}, fmt.Errorf("Invalid flip rate %v, name %s", c.FlipRate, name)
+	}
+
+	model := memory.FaultModel{
+		StuckHigh: c.StuckHigh,
+		StuckLow:  c.StuckLow,
+		FlipRate:  c.FlipRate,
+		Seed:      c.Seed,
+	}
+
+	for _, r := range c.ReadErrors {
+		start, err := parseAddress(name, r.Start)
+		if err != nil {
+			return model, err
+		}
+		end, err := parseAddress(name, r.End)
+		if err != nil {
+			return model, err
+		}
+		if start < address || end < start {
+			return model, fmt.Errorf("Invalid read error range %s-%s, name %s", r.Start, r.End, name)
+		}
+		model.ReadErrors = append(model.ReadErrors, memory.FaultRange{Start: start - address, End: end - address})
+	}
+	return model, nil
+}
@@ -0,0 +1,63 @@
+package machine
+
+import (
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+const (
+	// governorInterval is how often, in emulated time, the governor compares
+	// the cycles executed with the time taken.
+	governorInterval = time.Millisecond
+
+	// governorSlack is how far the cpu may fall behind before the governor
+	// stops trying to catch up, e.g. after being stopped in the debugger,
+	// rather than running flat out until it has.
+	governorSlack = 100 * time.Millisecond
+)
+
+// governor keeps the cpu to a clock speed by sleeping whenever it gets ahead
+// of real time.
+type governor struct {
+	hz     uint64
+	cycles func() uint64
+	now    func() time.Time
+	sleep  func(time.Duration)
+	start  time.Time // when the cycle count was base
+	base   uint64
+	next   uint64 // cycle count of the next check
+}
+
+func newGovernor(hz uint64, cycles func() uint64) *governor {
+	return &governor{hz: hz, cycles: cycles, now: time.Now, sleep: time.Sleep}
+}
+
+// BeforeExecute meets the cpu.Monitor interface, sleeping if the cpu is
+// ahead of its clock.
+func (g *governor) BeforeExecute(_ cpu.Instruction) {
+	cycles := g.cycles()
+	if cycles < g.next && cycles >= g.base {
+		return
+	}
+	g.next = cycles + g.hz*uint64(governorInterval)/uint64(time.Second) + 1
+
+	// Start timing afresh at the first instruction and after a reset
+	if g.start.IsZero() || cycles < g.base {
+		g.start, g.base = g.now(), cycles
+		return
+	}
+
+	due := g.start.Add(time.Duration(float64(cycles-g.base) * float64(time.Second) / float64(g.hz)))
+	now := g.now()
+	switch {
+	case due.After(now):
+		g.sleep(due.Sub(now))
+	case now.Sub(due) > governorSlack:
+		g.start, g.base = now, cycles
+	}
+}
+
+// Shutdown meets the cpu.Monitor interface.
+func (g *governor) Shutdown() {
+}
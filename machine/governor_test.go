@@ -0,0 +1,65 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+func TestGovernorSleepsWhenAhead(t *testing.T) {
+	var (
+		cycles uint64
+		now    = time.Unix(0, 0)
+		slept  time.Duration
+	)
+	g := newGovernor(1000000, func() uint64 { return cycles })
+	g.now = func() time.Time { return now }
+	g.sleep = func(d time.Duration) { slept += d; now = now.Add(d) }
+
+	g.BeforeExecute(cpu.Instruction{})
+
+	// 10ms of cycles in 4ms of real time
+	cycles = 10000
+	now = now.Add(4 * time.Millisecond)
+	g.BeforeExecute(cpu.Instruction{})
+	if slept != 6*time.Millisecond {
+		t.Error(fmt.Sprintf("expected to sleep 6ms got %v", slept))
+	}
+
+	// Stopped for a second, so timing restarts rather than running flat out
+	cycles = 20000
+	now = now.Add(time.Second)
+	g.BeforeExecute(cpu.Instruction{})
+	cycles = 30000
+	now = now.Add(5 * time.Millisecond)
+	g.BeforeExecute(cpu.Instruction{})
+	if slept != 11*time.Millisecond {
+		t.Error(fmt.Sprintf("expected to sleep 11ms in total got %v", slept))
+	}
+}
+
+func TestGovernorConfig(t *testing.T) {
+	c := &Config{}
+	if g, err := c.governor(); g != nil || err != nil {
+		t.Error("expected no governor by default")
+	}
+
+	c.Cpu.ClockHz = 1000000
+	if g, err := c.governor(); g == nil || err != nil {
+		t.Error("expected clockHz to throttle")
+	}
+
+	throttle := false
+	c.Cpu.Throttle = &throttle
+	if g, err := c.governor(); g != nil || err != nil {
+		t.Error("expected throttle false to run flat out")
+	}
+
+	throttle = true
+	c.Cpu.ClockHz = 0
+	if _, err := c.governor(); err == nil {
+		t.Error("expected throttle without clockHz to fail")
+	}
+}
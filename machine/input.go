@@ -0,0 +1,84 @@
+package machine
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/peter-mount/go6502/acia6551"
+)
+
+// startInput opens the input recording and reads the input to replay. A
+// recording has a line for each byte the guest reads from a device: the cpu
+// cycle count, the byte in hex and the device name.
+func (c *Config) startInput() error {
+	if c.Input.Replay != "" {
+		replay, err := readInput(c.Input.Replay)
+		if err != nil {
+			return err
+		}
+		c.replay = replay
+	}
+
+	if c.Input.Record != "" {
+		f, err := os.Create(c.Input.Record)
+		if err != nil {
+			return err
+		}
+		c.inputFile = f
+		c.input = bufio.NewWriter(f)
+	}
+	return nil
+}
+
+// readInput reads a recording, returning the input of each device.
+func readInput(filename string) (map[string][]acia6551.InputEvent, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	replay := make(map[string][]acia6551.InputEvent)
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		f := strings.SplitN(line, " ", 3)
+		if len(f) != 3 {
+			return nil, fmt.Errorf("%s:%d: expected cycle, byte and device", filename, i+1)
+		}
+		cycle, err := strconv.ParseUint(f[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid cycle %q", filename, i+1, f[0])
+		}
+		b, err := strconv.ParseUint(f[1], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid byte %q", filename, i+1, f[1])
+		}
+		replay[f[2]] = append(replay[f[2]], acia6551.InputEvent{Cycle: cycle, Data: byte(b)})
+	}
+	return replay, nil
+}
+
+// recordInput returns the function recording input read from a device, or
+// nil if input is not being recorded.
+func (c *Config) recordInput(device string) func(b byte) {
+	if c.input == nil {
+		return nil
+	}
+	return func(b byte) {
+		fmt.Fprintf(c.input, "%d %02X %s\n", c.cycles(), b, device)
+	}
+}
+
+// stopInput flushes and closes the input recording.
+func (c *Config) stopInput() {
+	if c.inputFile != nil {
+		_ = c.input.Flush()
+		_ = c.inputFile.Close()
+		c.inputFile = nil
+	}
+}
@@ -0,0 +1,106 @@
+package machine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// defaultLimitStatus is the exit status when a cycle or time limit is reached,
+// the same as timeout(1).
+const defaultLimitStatus = 124
+
+// limitCheckInterval is how many instructions run between checks of the time.
+const limitCheckInterval = 1024
+
+// limiter bounds a headless run, stopping the machine once it has run for a
+// number of cycles or seconds, or once an address is written to.
+type limiter struct {
+	maxCycles uint64
+	maxTime   time.Duration
+	status    int
+	cycles    func() uint64
+	now       func() time.Time
+	exit      func(reason string, status int)
+	start     time.Time
+	count     int
+	until     uint16
+	written   bool
+	stopped   bool
+}
+
+// BeforeExecute meets the cpu.Monitor interface, stopping the machine once a
+// limit is reached.
+func (l *limiter) BeforeExecute(_ cpu.Instruction) {
+	if l.stopped {
+		return
+	}
+
+	if l.written {
+		l.stop(fmt.Sprintf("Write to $%04X", l.until), 0)
+		return
+	}
+
+	if l.maxCycles > 0 {
+		if cycles := l.cycles(); cycles >= l.maxCycles {
+			l.stop(fmt.Sprintf("Reached %d cycles", cycles), l.status)
+			return
+		}
+	}
+
+	if l.maxTime > 0 {
+		if l.start.IsZero() {
+			l.start = l.now()
+		}
+		l.count++
+		if l.count%limitCheckInterval == 0 && l.now().Sub(l.start) >= l.maxTime {
+			l.stop(fmt.Sprintf("Reached %v", l.maxTime), l.status)
+		}
+	}
+}
+
+// Shutdown meets the cpu.Monitor interface.
+func (l *limiter) Shutdown() {
+}
+
+func (l *limiter) stop(reason string, status int) {
+	l.stopped = true
+	l.exit(reason, status)
+}
+
+// limiter returns a monitor enforcing the configured limits, or nil if there
+// are none.
+func (c *Config) limiter(exit func(reason string, status int)) (*limiter, error) {
+	if c.Limits.MaxCycles == 0 && c.Limits.MaxSeconds == 0 && c.Limits.UntilWrite == "" {
+		return nil, nil
+	}
+	if c.Limits.MaxSeconds < 0 {
+		return nil, fmt.Errorf("Invalid limits maxSeconds %v", c.Limits.MaxSeconds)
+	}
+
+	l := &limiter{
+		maxCycles: c.Limits.MaxCycles,
+		maxTime:   time.Duration(c.Limits.MaxSeconds * float64(time.Second)),
+		status:    defaultLimitStatus,
+		cycles:    c.cycles,
+		now:       time.Now,
+		exit:      exit,
+	}
+	if c.Limits.Status != nil {
+		l.status = *c.Limits.Status
+	}
+
+	if c.Limits.UntilWrite != "" {
+		address, err := parseAddress("limits untilWrite", c.Limits.UntilWrite)
+		if err != nil {
+			return nil, err
+		}
+		l.until = address
+		c.addressBus.Watch(address, address, bus.AccessWrite, func(_ bus.Access, _ uint16, _ byte, _ uint16) {
+			l.written = true
+		})
+	}
+	return l, nil
+}
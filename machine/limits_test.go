@@ -0,0 +1,79 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+	"gopkg.in/yaml.v3"
+)
+
+func newLimitedConfig(t *testing.T, limits string) (*Config, *limiter, *[]string) {
+	c := &Config{}
+	config := "hardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 1024}\nlimits: " + limits + "\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.cpu = &cpu.Cpu{Bus: c.addressBus}
+
+	var exits []string
+	l, err := c.limiter(func(reason string, status int) {
+		exits = append(exits, fmt.Sprintf("%s: %d", reason, status))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, l, &exits
+}
+
+func TestLimitMaxCycles(t *testing.T) {
+	c, l, exits := newLimitedConfig(t, "{maxCycles: 100}")
+
+	c.cpu.Cycles = 99
+	l.BeforeExecute(cpu.Instruction{})
+	c.cpu.Cycles = 100
+	l.BeforeExecute(cpu.Instruction{})
+	l.BeforeExecute(cpu.Instruction{})
+
+	expected := "[Reached 100 cycles: 124]"
+	if s := fmt.Sprint(*exits); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
+
+func TestLimitMaxSeconds(t *testing.T) {
+	_, l, exits := newLimitedConfig(t, "{maxSeconds: 2, status: 3}")
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < limitCheckInterval; i++ {
+		l.BeforeExecute(cpu.Instruction{})
+	}
+	now = now.Add(2 * time.Second)
+	for i := 0; i < limitCheckInterval; i++ {
+		l.BeforeExecute(cpu.Instruction{})
+	}
+
+	expected := "[Reached 2s: 3]"
+	if s := fmt.Sprint(*exits); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
+
+func TestLimitUntilWrite(t *testing.T) {
+	c, l, exits := newLimitedConfig(t, "{untilWrite: \"0200\"}")
+
+	c.addressBus.Write(0x0201, 1)
+	l.BeforeExecute(cpu.Instruction{})
+	c.addressBus.Write(0x0200, 1)
+	l.BeforeExecute(cpu.Instruction{})
+
+	expected := "[Write to $0200: 0]"
+	if s := fmt.Sprint(*exits); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
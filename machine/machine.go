@@ -1,19 +1,57 @@
 package machine
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"github.com/peter-mount/go6502/bus"
 	"github.com/peter-mount/go6502/cpu"
 	"github.com/peter-mount/go6502/debugger"
+	"github.com/peter-mount/go6502/heatmap"
 	"github.com/peter-mount/go6502/memory"
+	"github.com/peter-mount/go6502/scheduler"
 	"github.com/peter-mount/go6502/speedometer"
+	"github.com/peter-mount/go6502/stats"
 	"github.com/peter-mount/golib/kernel"
-	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 type Machine struct {
-	config   *Config
-	cpu      *cpu.Cpu
-	exitChan chan int
+	config    *Config
+	cpu       *cpu.Cpu
+	exitChan  chan int
+	scheduler *scheduler.Scheduler
+	watchdog  *watchdog
+	reloader  *reloader
+	control   *control
+	metrics   *metrics
+	saveFile  *string
+	loadFile  *string
+	halted    chan struct{}
+	haltOnce  sync.Once
+	// machines run alongside this one, from the machines in the config
+	machines []*Machine
+	// collectMetrics is set by the HTTP API to serve metrics
+	collectMetrics bool
+	// stopping is set once the machine has been told to exit
+	stopping bool
+	// limited is set when a limit in the config stopped the machine
+	limited bool
+	// exitMessage is written by the guest to the exit message address
+	exitMessage []byte
+	// exitStatus is the status the machine stopped with
+	exitStatus int
+}
+
+// NewMachine creates a machine from a config, e.g. one returned by
+// LoadConfig, so several can be run in one process without the kernel.
+// Config.Start must be called before Machine.Start.
+func NewMachine(config *Config) *Machine {
+	return &Machine{config: config, saveFile: new(string), loadFile: new(string)}
 }
 
 func (m *Machine) Name() string {
@@ -27,56 +65,456 @@ func (m *Machine) Init(k *kernel.Kernel) error {
 	}
 	m.config = (svce).(*Config)
 
+	m.saveFile = flag.String("save-state", "", "Save the machine state to this file on exit")
+	m.loadFile = flag.String("load-state", "", "Resume from a machine state saved with -save-state")
+
 	return nil
 }
 
 func (m *Machine) Start() error {
 	m.exitChan = make(chan int, 0)
+	m.halted = make(chan struct{})
 
-	m.cpu = &cpu.Cpu{Bus: m.config.addressBus, ExitChan: m.exitChan}
+	quirks, err := m.config.Quirks()
+	if err != nil {
+		return err
+	}
 
-	if m.config.Debug.Debugger {
-		debug := debugger.NewDebugger(m.cpu, m.config.Debug.SymbolFile)
+	exitTrap, err := m.config.ExitTrap()
+	if err != nil {
+		return err
+	}
+	exitTrap.OnMessage = func(b byte) {
+		m.exitMessage = append(m.exitMessage, b)
+	}
+
+	m.cpu = &cpu.Cpu{
+		Bus:      m.config.addressBus,
+		ExitChan: m.exitChan,
+		Quirks:   quirks,
+		Exit:     exitTrap,
+
+		CycleAccurate: m.config.Cpu.CycleAccurate,
+		InterruptTiming: cpu.InterruptTiming{
+			Latency: m.config.Cpu.InterruptTiming.Latency,
+			Cycles:  m.config.Cpu.InterruptTiming.Cycles,
+		},
+	}
+	m.config.cpu = m.cpu
+
+	// Attached first as resetting the cpu skips the later monitors
+	m.reloader = &reloader{cpu: m.cpu, reload: m.config.reload, log: m.config.logger()}
+	m.cpu.AttachMonitor(m.reloader)
+
+	// Machines run alongside this one share its scheduler
+	if m.scheduler == nil {
+		m.scheduler = scheduler.NewScheduler(scheduler.DefaultQuantum)
+	}
+
+	// Runs requests from the HTTP API, before the debugger so it can pause
+	m.control = newControl(m.scheduler, m.machineName(), m.cpu)
+	m.cpu.AttachMonitor(m.control)
+
+	var debug *debugger.Debugger
+	if m.config.Debug.Debugger || m.config.Debug.Batch || m.config.Debug.Web != "" || m.config.Debug.RPC != "" {
+		debug = debugger.NewDebugger(m.cpu, m.config.Debug.SymbolFile, m.config.Debug.SymbolFormat)
+		if m.config.Debug.Batch {
+			debug.Batch()
+		}
+		debug.SetReload(m.config.reload)
+		if m.config.Debug.Web != "" {
+			if err := debug.Serve(m.config.Debug.Web); err != nil {
+				return err
+			}
+		}
+		if m.config.Debug.RPC != "" {
+			if err := debug.ServeRPC(m.config.Debug.RPC); err != nil {
+				return err
+			}
+		}
 		debug.QueueCommands(m.config.Debug.DebugCommands)
+		if m.config.Debug.DebugScript != "" {
+			if err := debug.Source(m.config.Debug.DebugScript); err != nil {
+				return err
+			}
+		}
+	}
+
+	m.config.fault = func(reason string, brk bool, stop bool) {
+		m.fault(debug, reason, brk, stop)
+	}
+
+	// Attached before the debugger so it breaks at the offending instruction
+	if len(m.config.Protect) > 0 {
+		protect, err := newProtector(m.config.Protect, m.config.addressBus, m.config.fault)
+		if err != nil {
+			return err
+		}
+		m.cpu.AttachMonitor(protect)
+	}
+
+	limiter, err := m.config.limiter(func(reason string, status int) {
+		m.config.logger().Println(reason)
+		m.limited = true
+		m.exit(status)
+	})
+	if err != nil {
+		return err
+	}
+	if limiter != nil {
+		m.cpu.AttachMonitor(limiter)
+	}
+
+	if debug != nil {
 		m.cpu.AttachMonitor(debug)
 	}
 
+	err = m.configureOpenBus()
+	if err != nil {
+		return err
+	}
+
+	if m.collectMetrics {
+		m.metrics = newMetrics(m.config.MachineName, m.cpu, m.config.addressBus)
+		m.cpu.AttachMonitor(m.metrics)
+	}
+
 	if m.config.Debug.Speedometer {
-		m.cpu.AttachMonitor(speedometer.NewSpeedometer())
+		speedo, err := m.newSpeedometer()
+		if err != nil {
+			return err
+		}
+		m.cpu.AttachMonitor(speedo)
+	}
+
+	if m.config.Debug.Stats {
+		m.cpu.AttachMonitor(stats.NewStats())
+	}
+
+	if m.config.Debug.HeatMap != "" {
+		m.cpu.AttachMonitor(heatmap.NewHeatMap(m.config.addressBus, m.config.Debug.HeatMap))
+	}
+
+	if m.config.Debug.Watchdog != "" {
+		interval, err := time.ParseDuration(m.config.Debug.Watchdog)
+		if err != nil {
+			return err
+		}
+		m.watchdog = newWatchdog(interval, m.cpu, m.control.isPaused, m.config.logger())
+		m.cpu.AttachMonitor(m.watchdog)
+	}
+
+	// Attached last so time stopped in the debugger is not made up for
+	governor, err := m.config.governor()
+	if err != nil {
+		return err
 	}
+	if governor != nil {
+		m.cpu.AttachMonitor(governor)
+	}
+
+	if err := m.scheduler.Add(m.machineName(), m.cpu); err != nil {
+		return err
+	}
+	if err := m.scheduler.SetIdle(m.machineName(), m.control.whilePaused); err != nil {
+		return err
+	}
+	if err := m.startMachines(); err != nil {
+		return err
+	}
+	if m.config.sharedLinks {
+		return nil
+	}
+	return m.config.links.unpaired()
+}
 
+// machineName returns the name the machine is scheduled under.
+func (m *Machine) machineName() string {
+	if m.config.MachineName != "" {
+		return m.config.MachineName
+	}
+	return m.Name()
+}
+
+// configureOpenBus sets how unmapped accesses are handled. Faults are logged
+// and passed to the debugger if enabled, and in strict mode stop the machine.
+func (m *Machine) configureOpenBus() error {
+	mode, err := bus.ParseOpenBus(m.config.Bus.Unmapped)
+	if err != nil {
+		return err
+	}
+
+	if !m.config.Bus.Fault && !m.config.Bus.Strict {
+		m.config.addressBus.SetOpenBus(mode, nil)
+		return nil
+	}
+
+	// Reporting faults is pointless if the bus then panics
+	if m.config.Bus.Unmapped == "" {
+		mode = bus.OpenBusFF
+	}
+
+	m.config.addressBus.SetOpenBus(mode, func(access bus.Access, address uint16, pc uint16) {
+		reason := fmt.Sprintf("Unmapped %v $%04X pc:$%04X", access, address, pc)
+		m.config.fault(reason, true, m.config.Bus.Strict)
+	})
 	return nil
 }
 
+// fault logs a fault in the guest. If brk is set the debugger, if enabled,
+// breaks before the next instruction. If stop is set the machine is stopped.
+func (m *Machine) fault(debug *debugger.Debugger, reason string, brk bool, stop bool) {
+	m.config.logger().Println(reason)
+	if brk && debug != nil {
+		debug.Break(reason)
+	}
+	if stop {
+		m.exit(1)
+	}
+}
+
+// exit stops the machine with the given status, unless it is already stopping.
+// It is called from the cpu goroutine.
+func (m *Machine) exit(status int) {
+	if !m.stopping {
+		m.stopping = true
+		m.exitChan <- status
+	}
+}
+
+// newSpeedometer creates a Speedometer reporting on each attached device
+// and any regions in the config.
+func (m *Machine) newSpeedometer() (*speedometer.Speedometer, error) {
+	speedo := speedometer.NewSpeedometer()
+
+	for _, r := range m.config.addressBus.Regions() {
+		speedo.AddRegion(r.Name, r.Start, r.End)
+	}
+
+	var symbols map[string]uint16
+	if m.config.Debug.SymbolFile != "" && len(m.config.Debug.Regions) > 0 {
+		var err error
+		symbols, err = debugger.ReadSymbolFile(m.config.Debug.SymbolFile, m.config.Debug.SymbolFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range m.config.Debug.Regions {
+		start, err := resolveAddress(r.Name, r.Start, symbols)
+		if err != nil {
+			return nil, err
+		}
+		end, err := resolveAddress(r.Name, r.End, symbols)
+		if err != nil {
+			return nil, err
+		}
+		speedo.AddRegion(r.Name, start, end)
+	}
+
+	return speedo, nil
+}
+
+// resolveAddress returns the address of a symbol, or parses s as a hex address.
+func resolveAddress(name, s string, symbols map[string]uint16) (uint16, error) {
+	if address, exists := symbols[s]; exists {
+		return address, nil
+	}
+	return parseAddress(name, s)
+}
+
 func (m *Machine) Stop() {
+	// The other machines were started last so are stopped first
+	for i := len(m.machines) - 1; i >= 0; i-- {
+		m.machines[i].Stop()
+	}
+
 	fmt.Println(m.cpu)
 
+	if *m.saveFile != "" {
+		if err := m.saveState(*m.saveFile); err != nil {
+			m.config.logger().Println(err)
+		}
+	}
+
 	core := m.config.Debug.CoreFile
+	if m.limited && m.config.Limits.DumpCore != "" {
+		core = m.config.Limits.DumpCore
+	}
 	if core != "" {
 		for id, mem := range m.config.memory {
 			if ram, ok := mem.(*memory.Ram); ok {
 				filename := fmt.Sprintf("%s-%d.core", core, id)
 				fmt.Printf("Dumping ram %d to %s\n", id, filename)
-				ram.Dump(filename)
+				var buf bytes.Buffer
+				err := ram.DumpTo(&buf, memory.DumpOptions{
+					Format: m.config.Debug.CoreFormat,
+					Base:   m.config.addresses[id],
+				})
+				if err == nil {
+					err = m.config.storage.Save(filename, buf.Bytes())
+				}
+				if err != nil {
+					m.config.logger().Println(err)
+				}
 			}
 		}
 	}
+
+	m.config.stopTrace()
+	m.config.stopInput()
+
+	// Shutdown the devices on the bus, then the monitors so they report
+	m.config.shutdownDevices(shutdownTimeout)
+	shutdown("monitors", m.cpu.ShutdownMonitors, shutdownTimeout, m.config.logger())
+}
+
+// halt stops the machine from another goroutine, e.g. when a machine running
+// alongside it has stopped.
+func (m *Machine) halt() {
+	m.haltOnce.Do(func() {
+		close(m.halted)
+	})
+}
+
+// powerOn powers on the cpu, then loads the programs and any saved state.
+func (m *Machine) powerOn() error {
+	m.cpu.PowerOn()
+
+	// Programs are loaded after reset so banked and overlaid memory is as the
+	// cpu will see it, then the cpu starts at any reset vector they set
+	if err := m.config.loadPrograms(); err != nil {
+		return err
+	}
+	for _, p := range m.config.Programs {
+		if p.Reset {
+			m.cpu.PC = m.config.addressBus.Read16(0xFFFC)
+		}
+	}
+
+	if *m.loadFile != "" {
+		return m.loadState(*m.loadFile)
+	}
+	return nil
 }
 
+// Run runs this machine and those alongside it on the one scheduler, until
+// any of them stops.
 func (m *Machine) Run() error {
-	m.cpu.Reset()
+	all := append([]*Machine{m}, m.machines...)
+	for _, machine := range all {
+		if err := machine.powerOn(); err != nil {
+			return err
+		}
+		if machine.watchdog != nil {
+			machine.watchdog.start()
+		}
+	}
+
+	// SIGHUP reloads the config, ROMs and programs without restarting
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer func() {
+		signal.Stop(hup)
+		close(hup)
+	}()
+	go func() {
+		for range hup {
+			m.hangup()
+		}
+	}()
+
+	defer m.controlSignals()()
 
-	running := true
+	// SIGINT and SIGTERM stop the machine so Stop shuts the devices down
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
 
+	finished := make(chan struct{})
 	go func() {
-		exitStatus := <-m.exitChan
-		log.Println("Exit status", exitStatus)
-		running = false
+		select {
+		case m.exitStatus = <-m.exitChan:
+			m.config.logger().Println("Exit status", m.exitStatus)
+		case sig := <-signals:
+			m.config.logger().Println("Got signal", sig)
+			m.exitStatus = 128 + int(sig.(syscall.Signal))
+		case <-m.halted:
+		}
+		m.scheduler.Stop()
+		for _, machine := range all {
+			machine.control.stop()
+		}
+		// A halted cpu may be exiting, so let it finish the instruction
+		for {
+			select {
+			case <-m.exitChan:
+			case <-finished:
+				return
+			}
+		}
 	}()
 
-	for running {
-		m.cpu.Step()
+	// Any of the other machines stopping stops them all
+	var wg sync.WaitGroup
+	for _, machine := range m.machines {
+		wg.Add(1)
+		go func(machine *Machine) {
+			defer wg.Done()
+			select {
+			case machine.exitStatus = <-machine.exitChan:
+				machine.config.logger().Println("Exit status", machine.exitStatus)
+				m.halt()
+			case <-finished:
+				return
+			}
+			for {
+				select {
+				case <-machine.exitChan:
+				case <-finished:
+					return
+				}
+			}
+		}(machine)
+	}
+
+	m.scheduler.Run()
+	close(finished)
+	wg.Wait()
+
+	for _, machine := range m.machines {
+		if message := bytes.TrimSpace(machine.exitMessage); len(message) > 0 {
+			machine.config.logger().Printf("Exit message: %s", message)
+		}
+	}
+	if message := bytes.TrimSpace(m.exitMessage); len(message) > 0 {
+		m.config.logger().Printf("Exit message: %s", message)
 	}
 
+	// Fail the launch so the process exits with the status
+	if m.exitStatus != 0 {
+		return ExitStatus(m.exitStatus)
+	}
 	return nil
 }
+
+// hangup reloads the machine on SIGHUP. The config is only read again if it
+// came from a file, otherwise the ROMs and programs are reloaded from the
+// flags.
+func (m *Machine) hangup() {
+	if m.config.configFile == nil || *m.config.configFile == "" {
+		m.config.logger().Println("SIGHUP, reloading ROMs and programs")
+		m.reloader.requestReload(false)
+		return
+	}
+	m.config.logger().Println("SIGHUP, reloading")
+	m.reloader.requestReload(true)
+}
+
+// ExitStatus is returned by Run when the machine stopped with a non-zero
+// status, which the process should exit with.
+type ExitStatus int
+
+func (s ExitStatus) Error() string {
+	return fmt.Sprintf("Exit status %d", int(s))
+}
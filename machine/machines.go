@@ -0,0 +1,41 @@
+package machine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// startMachines starts the machines to run alongside this one, on its
+// scheduler. Their config files are relative to this one's, and they are
+// named after the file unless they have a name.
+func (m *Machine) startMachines() error {
+	for _, file := range m.config.Machines {
+		if !filepath.IsAbs(file) && m.config.configFile != nil {
+			file = filepath.Join(filepath.Dir(*m.config.configFile), file)
+		}
+		config, err := LoadConfig(file)
+		if err != nil {
+			return err
+		}
+		if len(config.Machines) > 0 {
+			return fmt.Errorf("%s: machines cannot be nested", file)
+		}
+		if config.MachineName == "" {
+			config.MachineName = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+
+		machine := NewMachine(config)
+		machine.scheduler = m.scheduler
+		machine.collectMetrics = m.collectMetrics
+		config.links, config.sharedLinks = m.config.links, true
+		if err := config.Start(); err != nil {
+			return err
+		}
+		if err := machine.Start(); err != nil {
+			return err
+		}
+		m.machines = append(m.machines, machine)
+	}
+	return nil
+}
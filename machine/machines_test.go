@@ -0,0 +1,114 @@
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const linkedConfig = `hardware:
+  - name: RAM
+    address: "0000"
+    ram: {size: 32768}
+  - name: ACIA
+    address: "9000"
+    6551: {peripheral: "link:test"}
+  - name: TOP
+    address: "F000"
+    ram: {size: 4096}
+`
+
+// TestLinkedMachines runs a machine which sends a byte over a serial link to
+// another, which replies with the next byte for the first to exit with.
+func TestLinkedMachines(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"main.yaml": linkedConfig + `machines: [node.yaml]
+exit: {address: "8000"}
+programs:
+  - {file: ` + dir + `/main.bin, address: "0200", reset: true}
+`,
+		"node.yaml": linkedConfig + `programs:
+  - {file: ` + dir + `/node.bin, address: "0200", reset: true}
+`,
+		// LDA #$41, STA $9000, wait for a reply then STA $8000
+		"main.bin": "\xA9\x41\x8D\x00\x90\xAD\x01\x90\x29\x08\xF0\xF9\xAD\x00\x90\x8D\x00\x80",
+		// Wait for a byte, then send it back plus one
+		"node.bin": "\xAD\x01\x90\x29\x08\xF0\xF9\xAD\x00\x90\x18\x69\x01\x8D\x00\x90\x4C\x00\x02",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := LoadConfig(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMachine(c)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.machines) != 1 || m.machines[0].config.MachineName != "node" {
+		t.Fatal("expected the machine named node to be started")
+	}
+
+	err = m.Run()
+	m.Stop()
+	if status, ok := err.(ExitStatus); !ok || status != 66 {
+		t.Error(fmt.Sprintf("expected Exit status 66 got %v", err))
+	}
+}
+
+func TestNestedMachines(t *testing.T) {
+	dir := t.TempDir()
+
+	ram := "hardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 1024}\n"
+	for name, content := range map[string]string{
+		"main.yaml": ram + "machines: [node.yaml]\n",
+		"node.yaml": ram + "machines: [main.yaml]\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := LoadConfig(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	err = NewMachine(c).Start()
+	expected := filepath.Join(dir, "node.yaml") + ": machines cannot be nested"
+	if err == nil || err.Error() != expected {
+		t.Error(fmt.Sprintf("expected %s got %v", expected, err))
+	}
+}
+
+func TestUnpairedLink(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		c := &Config{}
+		if err := yaml.Unmarshal([]byte(linkedConfig), c); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		// Each machine has its own links, so the second isn't paired with the first
+		err := NewMachine(c).Start()
+		expected := "Serial link test has only one 6551"
+		if err == nil || err.Error() != expected {
+			t.Error(fmt.Sprintf("expected %s got %v", expected, err))
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package machine
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// metrics counts what the machine does, for the Prometheus /metrics endpoint
+// of the HTTP API. Counters are updated on the cpu goroutine and read when
+// scraped, with rates calculated since the previous scrape.
+type metrics struct {
+	machine      string
+	cpu          *cpu.Cpu
+	now          func() time.Time
+	instructions uint64
+	cycles       uint64
+	interrupts   [2]uint64 // by cpu.Interrupt
+	devices      []*deviceMetrics
+	mu           sync.Mutex
+	last         metricsSample
+}
+
+// deviceMetrics counts the accesses to one device on the bus.
+type deviceMetrics struct {
+	name   string
+	reads  uint64
+	writes uint64
+}
+
+// metricsSample is the counters as of a scrape.
+type metricsSample struct {
+	at           time.Time
+	instructions uint64
+	cycles       uint64
+	interrupts   [2]uint64
+}
+
+// newMetrics counts the instructions and interrupts of c, and the accesses
+// to each device on the bus. Samples are labelled with the machine name, if
+// it has one.
+func newMetrics(machine string, c *cpu.Cpu, b *bus.Bus) *metrics {
+	m := &metrics{machine: machine, cpu: c, now: time.Now}
+	m.last.at = m.now()
+
+	for _, r := range b.Regions() {
+		d := &deviceMetrics{name: r.Name}
+		m.devices = append(m.devices, d)
+		b.Watch(r.Start, r.End, bus.AccessReadWrite, func(access bus.Access, _ uint16, _ byte, _ uint16) {
+			if access == bus.AccessWrite {
+				atomic.AddUint64(&d.writes, 1)
+			} else {
+				atomic.AddUint64(&d.reads, 1)
+			}
+		})
+	}
+	return m
+}
+
+// BeforeExecute meets the cpu.Monitor interface, counting instructions.
+func (m *metrics) BeforeExecute(_ cpu.Instruction) {
+	atomic.AddUint64(&m.instructions, 1)
+	atomic.StoreUint64(&m.cycles, m.cpu.Cycles)
+}
+
+// AfterInterrupt meets the cpu.InterruptMonitor interface, counting
+// interrupts.
+func (m *metrics) AfterInterrupt(source cpu.Interrupt, _ uint16) {
+	atomic.AddUint64(&m.interrupts[source], 1)
+}
+
+// Shutdown meets the cpu.Monitor interface.
+func (m *metrics) Shutdown() {
+}
+
+// sample returns the counters now.
+func (m *metrics) sample() metricsSample {
+	s := metricsSample{
+		at:           m.now(),
+		instructions: atomic.LoadUint64(&m.instructions),
+		cycles:       atomic.LoadUint64(&m.cycles),
+	}
+	for i := range s.interrupts {
+		s.interrupts[i] = atomic.LoadUint64(&m.interrupts[i])
+	}
+	return s
+}
+
+// scrape is the metrics of a machine now, and as of the previous scrape.
+type scrape struct {
+	m    *metrics
+	s    metricsSample
+	last metricsSample
+}
+
+// scrape samples the counters, remembering them for the next scrape.
+func (m *metrics) scrape() scrape {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sc := scrape{m: m, s: m.sample(), last: m.last}
+	m.last = sc.s
+	return sc
+}
+
+// rate returns the rate per second of a counter since the previous scrape.
+func (sc scrape) rate(now, then uint64) float64 {
+	seconds := sc.s.at.Sub(sc.last.at).Seconds()
+	if seconds <= 0 {
+		return 0
+	}
+	return float64(now-then) / seconds
+}
+
+// labels returns the labels of a sample, starting with the machine name if
+// it has one.
+func (m *metrics) labels(labels ...string) string {
+	if m.machine != "" {
+		labels = append([]string{fmt.Sprintf("machine=%q", m.machine)}, labels...)
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(labels, ",") + "}"
+}
+
+// writeMetrics writes the metrics of each machine in the Prometheus text
+// format.
+func writeMetrics(w io.Writer, all []*metrics) {
+	var scrapes []scrape
+	for _, m := range all {
+		scrapes = append(scrapes, m.scrape())
+	}
+
+	metric := func(name, kind, help string, samples func(sc scrape)) {
+		fmt.Fprintf(w, "# HELP go6502_%s %s\n# TYPE go6502_%s %s\n", name, help, name, kind)
+		for _, sc := range scrapes {
+			samples(sc)
+		}
+	}
+
+	metric("instructions_total", "counter", "Instructions executed.", func(sc scrape) {
+		fmt.Fprintf(w, "go6502_instructions_total%s %d\n", sc.m.labels(), sc.s.instructions)
+	})
+	metric("cycles_total", "counter", "Clock cycles executed.", func(sc scrape) {
+		fmt.Fprintf(w, "go6502_cycles_total%s %d\n", sc.m.labels(), sc.s.cycles)
+	})
+	metric("instructions_per_second", "gauge", "Instructions executed per second since the last scrape.", func(sc scrape) {
+		fmt.Fprintf(w, "go6502_instructions_per_second%s %g\n", sc.m.labels(), sc.rate(sc.s.instructions, sc.last.instructions))
+	})
+	metric("clock_mhz", "gauge", "Effective clock speed in MHz since the last scrape.", func(sc scrape) {
+		fmt.Fprintf(w, "go6502_clock_mhz%s %g\n", sc.m.labels(), sc.rate(sc.s.cycles, sc.last.cycles)/1e6)
+	})
+
+	sources := []cpu.Interrupt{cpu.IRQ, cpu.NMI}
+	metric("interrupts_total", "counter", "Hardware interrupts serviced.", func(sc scrape) {
+		for _, source := range sources {
+			fmt.Fprintf(w, "go6502_interrupts_total%s %d\n", sc.m.labels(fmt.Sprintf("source=%q", source)), sc.s.interrupts[source])
+		}
+	})
+	metric("interrupts_per_second", "gauge", "Hardware interrupts serviced per second since the last scrape.", func(sc scrape) {
+		for _, source := range sources {
+			fmt.Fprintf(w, "go6502_interrupts_per_second%s %g\n", sc.m.labels(fmt.Sprintf("source=%q", source)), sc.rate(sc.s.interrupts[source], sc.last.interrupts[source]))
+		}
+	})
+
+	metric("device_accesses_total", "counter", "Bus accesses to each device.", func(sc scrape) {
+		for _, d := range sc.m.devices {
+			device := fmt.Sprintf("device=%q", d.name)
+			fmt.Fprintf(w, "go6502_device_accesses_total%s %d\n", sc.m.labels(device, `access="read"`), atomic.LoadUint64(&d.reads))
+			fmt.Fprintf(w, "go6502_device_accesses_total%s %d\n", sc.m.labels(device, `access="write"`), atomic.LoadUint64(&d.writes))
+		}
+	})
+}
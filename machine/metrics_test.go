@@ -0,0 +1,92 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMetrics(t *testing.T) {
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(stateConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	cp := &cpu.Cpu{Bus: c.addressBus}
+	m := newMetrics("", cp, c.addressBus)
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+	m.last.at = now
+
+	for i := 0; i < 4; i++ {
+		cp.Cycles += 500000
+		m.BeforeExecute(cpu.Instruction{})
+	}
+	m.AfterInterrupt(cpu.IRQ, 0)
+	c.addressBus.Write(0x0010, 1)
+	c.addressBus.Read(0x0010)
+	c.addressBus.Read(0x9000)
+	now = now.Add(2 * time.Second)
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, []*metrics{m})
+	for _, expected := range []string{
+		"# TYPE go6502_instructions_total counter\ngo6502_instructions_total 4\n",
+		"go6502_cycles_total 2000000\n",
+		"go6502_instructions_per_second 2\n",
+		"go6502_clock_mhz 1\n",
+		`go6502_interrupts_total{source="IRQ"} 1`,
+		`go6502_interrupts_per_second{source="IRQ"} 0.5`,
+		`go6502_interrupts_total{source="NMI"} 0`,
+		`go6502_device_accesses_total{device="RAM",access="read"} 1`,
+		`go6502_device_accesses_total{device="RAM",access="write"} 1`,
+		`go6502_device_accesses_total{device="VIA",access="read"} 1`,
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Error(fmt.Sprintf("expected %q in\n%s", expected, buf.String()))
+		}
+	}
+
+	// Rates are since the previous scrape
+	now = now.Add(time.Second)
+	buf.Reset()
+	writeMetrics(&buf, []*metrics{m})
+	if !strings.Contains(buf.String(), "go6502_instructions_per_second 0\n") {
+		t.Error(fmt.Sprintf("expected no instructions since the last scrape in\n%s", buf.String()))
+	}
+}
+
+func TestMetricsOfNamedMachines(t *testing.T) {
+	var all []*metrics
+	for _, name := range []string{"main", "node"} {
+		c := &Config{}
+		if err := yaml.Unmarshal([]byte(stateConfig), c); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		m := newMetrics(name, &cpu.Cpu{Bus: c.addressBus}, c.addressBus)
+		m.BeforeExecute(cpu.Instruction{})
+		all = append(all, m)
+	}
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, all)
+	expected := "# TYPE go6502_instructions_total counter\n" +
+		"go6502_instructions_total{machine=\"main\"} 1\n" +
+		"go6502_instructions_total{machine=\"node\"} 1\n"
+	if !strings.Contains(buf.String(), expected) {
+		t.Error(fmt.Sprintf("expected %q in\n%s", expected, buf.String()))
+	}
+	if s := `go6502_interrupts_total{machine="node",source="NMI"} 0`; !strings.Contains(buf.String(), s) {
+		t.Error(fmt.Sprintf("expected %q in\n%s", s, buf.String()))
+	}
+}
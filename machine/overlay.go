@@ -0,0 +1,53 @@
+package machine
+
+import (
+	"errors"
+	"github.com/peter-mount/go6502/memory"
+)
+
+// OverlayChip is a device, usually ROM, overlaying another, usually RAM, at
+// the same address. The latch address is where the register switching the
+// overlay is attached.
+type OverlayChip struct {
+	Over         OverlayLayer `yaml:"over"`
+	Under        OverlayLayer `yaml:"under"`
+	Latch        string       `yaml:"latch"`
+	WriteThrough bool         `yaml:"writeThrough"`
+}
+
+// OverlayLayer is one of the devices in an overlay.
+type OverlayLayer struct {
+	Ram *RamChip `yaml:"ram"`
+	Rom *RomChip `yaml:"rom"`
+}
+
+func (l *OverlayLayer) chip() (Chip, error) {
+	if l.Ram != nil {
+		return l.Ram, nil
+	} else if l.Rom != nil {
+		return l.Rom, nil
+	}
+	return nil, errors.New("No overlay chip defined")
+}
+
+func (c *OverlayChip) Configure() (memory.Memory, error) {
+	var layers []memory.Memory
+	for _, l := range []*OverlayLayer{&c.Over, &c.Under} {
+		chip, err := l.chip()
+		if err != nil {
+			return nil, err
+		}
+		m, err := chip.Configure()
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, m)
+	}
+
+	overlay, err := memory.NewOverlay(layers[0], layers[1])
+	if err != nil {
+		return nil, err
+	}
+	overlay.WriteThrough = c.WriteThrough
+	return overlay, nil
+}
@@ -0,0 +1,136 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peter-mount/go6502/ili9340"
+	"github.com/peter-mount/go6502/sd"
+	"github.com/peter-mount/go6502/spi"
+	"github.com/peter-mount/go6502/ssd1306"
+	"github.com/peter-mount/go6502/via6522"
+)
+
+// ViaPort is where a peripheral is attached: the name of a 6522 hardware
+// entry, and its port A or B.
+type ViaPort struct {
+	Via  string `yaml:"via"`
+	Port string `yaml:"port"`
+}
+
+// SpiPins are the port pins, 0 to 7, an SPI device is wired to.
+type SpiPins struct {
+	Sclk uint `yaml:"sclk"`
+	Mosi uint `yaml:"mosi"`
+	Miso uint `yaml:"miso"`
+	Ss   uint `yaml:"ss"`
+}
+
+// pinMap returns the pins, or the defaults if none are configured.
+func (p *SpiPins) pinMap(name string, defaults spi.PinMap) (spi.PinMap, error) {
+	if p == nil {
+		return defaults, nil
+	}
+	for _, pin := range []uint{p.Sclk, p.Mosi, p.Miso, p.Ss} {
+		if pin > 7 {
+			return spi.PinMap{}, fmt.Errorf("Invalid pin %d for %s, expected 0 to 7", pin, name)
+		}
+	}
+	return spi.PinMap{Sclk: p.Sclk, Mosi: p.Mosi, Miso: p.Miso, Ss: p.Ss}, nil
+}
+
+// Peripheral is a device attached to a port of a 6522 rather than the bus.
+type Peripheral interface {
+	Configure() (via6522.ParallelPeripheral, error)
+	viaPort() ViaPort
+}
+
+func (p ViaPort) viaPort() ViaPort {
+	return p
+}
+
+// Ssd1306Chip is an SSD1306 OLED display, with fixed pins.
+type Ssd1306Chip struct {
+	ViaPort `yaml:",inline"`
+}
+
+func (c *Ssd1306Chip) Configure() (via6522.ParallelPeripheral, error) {
+	return ssd1306.NewSsd1306(), nil
+}
+
+// Ili9340Chip is an ILI9340 TFT display on SPI.
+type Ili9340Chip struct {
+	ViaPort `yaml:",inline"`
+	Pins    *SpiPins `yaml:"pins"`
+}
+
+func (c *Ili9340Chip) Configure() (via6522.ParallelPeripheral, error) {
+	pm, err := c.Pins.pinMap("ili9340", spi.PinMap{Sclk: 0, Mosi: 6, Miso: 7, Ss: 5})
+	if err != nil {
+		return nil, err
+	}
+	return ili9340.NewDisplay(pm)
+}
+
+// SdCardChip is an SD card on SPI, loaded from an image file.
+type SdCardChip struct {
+	ViaPort `yaml:",inline"`
+	Pins    *SpiPins `yaml:"pins"`
+	File    string   `yaml:"file"`
+}
+
+func (c *SdCardChip) Configure() (via6522.ParallelPeripheral, error) {
+	pm, err := c.Pins.pinMap("sd", spi.PinMap{Sclk: 0, Mosi: 6, Miso: 7, Ss: 4})
+	if err != nil {
+		return nil, err
+	}
+	card, err := sd.NewSdCardPeripheral(pm)
+	if err != nil {
+		return nil, err
+	}
+	if c.File != "" {
+		if err := card.LoadFile(c.File); err != nil {
+			return nil, err
+		}
+	}
+	return card, nil
+}
+
+// peripheral returns the peripheral of a hardware entry, or nil if it is not
+// one.
+func (h *Hardware) peripheral() Peripheral {
+	switch {
+	case h.Ssd1306 != nil:
+		return h.Ssd1306
+	case h.Ili9340 != nil:
+		return h.Ili9340
+	case h.SdCard != nil:
+		return h.SdCard
+	}
+	return nil
+}
+
+// attachPeripheral attaches a peripheral to the port of its 6522, which must
+// already be attached.
+func (c *Config) attachPeripheral(h *Hardware, chip Peripheral) error {
+	port := chip.viaPort()
+	via, exists := c.vias[port.Via]
+	if !exists {
+		return fmt.Errorf("No 6522 named %q for %s", port.Via, h.Name)
+	}
+
+	p, err := chip.Configure()
+	if err != nil {
+		return fmt.Errorf("%s: %v", h.Name, err)
+	}
+
+	switch strings.ToUpper(port.Port) {
+	case "A":
+		via.AttachToPortA(p)
+	case "B":
+		via.AttachToPortB(p)
+	default:
+		return fmt.Errorf("Invalid port %q for %s, expected A or B", port.Port, h.Name)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const peripheralConfig = `
+hardware:
+  - name: tft
+    ili9340:
+      via: VIA
+      port: B
+      pins: {sclk: 0, mosi: 6, miso: 7, ss: 3}
+  - name: VIA
+    address: "9000"
+    6522: {}
+  - name: sd
+    sd:
+      via: VIA
+      port: b
+`
+
+func TestPeripheralsAttachToVia(t *testing.T) {
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(peripheralConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	state := c.vias["VIA"].DebugState()
+	for _, expected := range []string{"PORTB ILI9340 (pinmask: 11001101)", "PORTB SD card (pinmask: 11010001)"} {
+		if !strings.Contains(state, expected) {
+			t.Error(fmt.Sprintf("expected %q in\n%s", expected, state))
+		}
+	}
+}
+
+func TestPeripheralConfigErrors(t *testing.T) {
+	for _, test := range []string{
+		"{via: VIA, port: C}",
+		"{via: nothing, port: A}",
+		"{via: VIA, port: A, pins: {sclk: 8}}",
+	} {
+		c := &Config{}
+		config := "hardware:\n  - name: VIA\n    address: \"9000\"\n    6522: {}\n  - name: tft\n    ili9340: " + test + "\n"
+		if err := yaml.Unmarshal([]byte(config), c); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err == nil {
+			t.Error(fmt.Sprintf("expected %s to fail", test))
+		}
+	}
+}
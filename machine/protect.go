@@ -0,0 +1,80 @@
+package machine
+
+import (
+	"fmt"
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// Protect marks an address range as read-only or no-execute. Violations are
+// reported with the offending PC, breaking into the debugger if enabled.
+type Protect struct {
+	Name      string `yaml:"name"`
+	Start     string `yaml:"start"`
+	End       string `yaml:"end"`
+	ReadOnly  bool   `yaml:"readOnly"`
+	NoExecute bool   `yaml:"noExecute"`
+	// Strict stops the machine on a violation.
+	Strict bool `yaml:"strict"`
+}
+
+// protectedRegion is a parsed Protect entry.
+type protectedRegion struct {
+	Protect
+	start uint16
+	end   uint16
+}
+
+// protector is a cpu.Monitor reporting execution within no-execute regions.
+type protector struct {
+	regions []protectedRegion
+	fault   faultFunc
+}
+
+// newProtector parses the protected regions, watching the bus for writes to
+// read-only ones.
+func newProtector(protects []Protect, b *bus.Bus, fault faultFunc) (*protector, error) {
+	p := &protector{fault: fault}
+	for _, pr := range protects {
+		start, err := parseAddress(pr.Name, pr.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseAddress(pr.Name, pr.End)
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("Invalid protected region %s, end before start", pr.Name)
+		}
+		if !pr.ReadOnly && !pr.NoExecute {
+			return nil, fmt.Errorf("Protected region %s is neither readOnly nor noExecute", pr.Name)
+		}
+
+		region := protectedRegion{Protect: pr, start: start, end: end}
+		if pr.ReadOnly {
+			b.Watch(start, end, bus.AccessWrite, func(_ bus.Access, a uint16, value byte, pc uint16) {
+				reason := fmt.Sprintf("Write $%02X to read-only %s at $%04X pc:$%04X", value, region.Name, a, pc)
+				fault(reason, true, region.Strict)
+			})
+		}
+		if pr.NoExecute {
+			p.regions = append(p.regions, region)
+		}
+	}
+	return p, nil
+}
+
+// BeforeExecute meets the cpu.Monitor interface, reporting execution within
+// a no-execute region.
+func (p *protector) BeforeExecute(in cpu.Instruction) {
+	for _, r := range p.regions {
+		if in.Address >= r.start && in.Address <= r.end {
+			p.fault(fmt.Sprintf("Execute in no-execute %s pc:$%04X", r.Name, in.Address), true, r.Strict)
+		}
+	}
+}
+
+// Shutdown meets the cpu.Monitor interface.
+func (p *protector) Shutdown() {
+}
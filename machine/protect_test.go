@@ -0,0 +1,37 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestProtectedRegions(t *testing.T) {
+	b, _ := bus.CreateBus()
+	b.Attach(memory.NewRam(0x1000), "ram", 0x0000)
+
+	var faults []string
+	p, err := newProtector([]Protect{
+		{Name: "stack", Start: "0100", End: "01FF", ReadOnly: true},
+		{Name: "data", Start: "0800", End: "0FFF", NoExecute: true},
+	}, b, func(reason string, brk bool, stop bool) {
+		faults = append(faults, reason)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.SetPC(0x0400)
+	b.Write(0x0180, 0x42)
+	b.Write(0x0200, 0x42)
+	p.BeforeExecute(cpu.Instruction{Address: 0x0400})
+	p.BeforeExecute(cpu.Instruction{Address: 0x0800})
+
+	expected := "[Write $42 to read-only stack at $0180 pc:$0400 Execute in no-execute data pc:$0800]"
+	if fmt.Sprint(faults) != expected {
+		t.Error(fmt.Sprintf("expected %s got %v", expected, faults))
+	}
+}
@@ -6,15 +6,20 @@ import (
 )
 
 type RamChip struct {
-	Size uint16 `yaml:"size"`
+	Size int `yaml:"size"`
+	// Backing is a file the RAM is memory mapped from, preserving its
+	// contents across restarts.
+	Backing string `yaml:"backing"`
 }
 
 func (c *RamChip) Configure() (memory.Memory, error) {
-	// Min 1K chip
-	if c.Size < 1024 {
+	// Min 1K chip, max the entire address space
+	if c.Size < 1024 || c.Size > 0x10000 {
 		return nil, fmt.Errorf("Invalid ram size %d", c.Size)
 	}
 
-	// FIXME make ram size configurable
-	return &memory.Ram{}, nil
+	if c.Backing != "" {
+		return memory.NewMappedRam(c.Backing, c.Size)
+	}
+	return memory.NewRam(c.Size), nil
 }
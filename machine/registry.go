@@ -0,0 +1,84 @@
+package machine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChipFactory creates a chip from the options given for it in a hardware
+// entry, e.g. by decoding them into a struct with options.Decode.
+type ChipFactory func(options *yaml.Node) (Chip, error)
+
+// SizedChip is implemented by a registered chip which knows its size from
+// its options, so overlapping chips are reported before any is attached.
+type SizedChip interface {
+	Chip
+	Size() int
+}
+
+var (
+	chipsMutex    sync.RWMutex
+	chipFactories = make(map[string]ChipFactory)
+
+	hardwareType = reflect.TypeOf(Hardware{})
+	nodeType     = reflect.TypeOf(yaml.Node{})
+)
+
+// RegisterChip makes a chip available by name in the hardware section of the
+// config, so packages outside machine can add devices:
+//
+//	hardware:
+//	  - name: mine
+//	    address: "A000"
+//	    mychip: {option: 1}
+//
+// It is intended to be called from init, and panics if the name is in use.
+func RegisterChip(name string, factory ChipFactory) {
+	if factory == nil {
+		panic("machine: RegisterChip factory is nil for " + name)
+	}
+	if _, exists := fields(hardwareType)[name]; exists {
+		panic("machine: RegisterChip called twice for " + name)
+	}
+
+	chipsMutex.Lock()
+	defer chipsMutex.Unlock()
+	chipFactories[name] = factory
+}
+
+// chipFactory returns the factory registered for a chip.
+func chipFactory(name string) (ChipFactory, bool) {
+	chipsMutex.RLock()
+	defer chipsMutex.RUnlock()
+	factory, exists := chipFactories[name]
+	return factory, exists
+}
+
+// registeredChips returns the names of the registered chips, sorted.
+func registeredChips() []string {
+	chipsMutex.RLock()
+	defer chipsMutex.RUnlock()
+	var names []string
+	for name := range chipFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// plugin returns the chip a hardware entry defines from a registered factory.
+func (h *Hardware) plugin() (Chip, error) {
+	for name, options := range h.Plugins {
+		factory, exists := chipFactory(name)
+		if !exists {
+			return nil, fmt.Errorf("Unknown chip %s for %s", name, h.Name)
+		}
+		options := options
+		return factory(&options)
+	}
+	return nil, nil
+}
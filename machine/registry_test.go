@@ -0,0 +1,75 @@
+package machine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/memory"
+	"gopkg.in/yaml.v3"
+)
+
+// testChip is a plugin RAM whose size is given in K.
+type testChip struct {
+	K int `yaml:"k"`
+}
+
+func (c *testChip) Configure() (memory.Memory, error) {
+	return memory.NewRam(c.K * 1024), nil
+}
+
+func init() {
+	RegisterChip("testchip", func(options *yaml.Node) (Chip, error) {
+		chip := &testChip{}
+		return chip, options.Decode(chip)
+	})
+}
+
+func TestRegisteredChip(t *testing.T) {
+	c := &Config{}
+	config := "hardware:\n  - name: plugin\n    address: \"4000\"\n    testchip: {k: 2}\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	regions := fmt.Sprint(c.addressBus.Regions())
+	if !strings.Contains(regions, "plugin") || !strings.Contains(regions, "47FF") {
+		t.Error(fmt.Sprintf("expected plugin at $4000-$47FF in %s", regions))
+	}
+}
+
+func TestUnknownChip(t *testing.T) {
+	c := &Config{}
+	config := "hardware:\n  - name: plugin\n    address: \"4000\"\n    nochip: {}\n    testchip: {}\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Start()
+	if err == nil {
+		t.Fatal("expected an unknown chip to fail")
+	}
+	for _, expected := range []string{
+		"plugin: more than one chip defined: nochip, testchip",
+		"plugin: unknown chip nochip",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error(fmt.Sprintf("expected %q in %v", expected, err))
+		}
+	}
+}
+
+func TestRegisterChipTwice(t *testing.T) {
+	for _, name := range []string{"ram", "testchip"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error(fmt.Sprintf("expected registering %s to panic", name))
+				}
+			}()
+			RegisterChip(name, func(*yaml.Node) (Chip, error) { return nil, nil })
+		}()
+	}
+}
@@ -0,0 +1,99 @@
+package machine
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+const (
+	reloadRoms   = 1
+	reloadConfig = 2
+)
+
+// reloader reloads the machine between instructions when requested, e.g. on
+// SIGHUP, as the bus may only be touched from the cpu goroutine.
+type reloader struct {
+	cpu     *cpu.Cpu
+	reload  func(config bool) error
+	log     *log.Logger
+	request int32
+}
+
+// requestReload reloads the machine before the next instruction, re-reading
+// the config if config is set.
+func (r *reloader) requestReload(config bool) {
+	request := int32(reloadRoms)
+	if config {
+		request = reloadConfig
+	}
+	atomic.StoreInt32(&r.request, request)
+}
+
+// BeforeExecute meets the cpu.Monitor interface, reloading and resetting the
+// cpu if a reload was requested. A failed reload leaves the machine running.
+func (r *reloader) BeforeExecute(_ cpu.Instruction) {
+	request := atomic.SwapInt32(&r.request, 0)
+	if request == 0 {
+		return
+	}
+	if err := r.reload(request == reloadConfig); err != nil {
+		r.log.Println("Reload failed:", err)
+		return
+	}
+	r.cpu.Reset()
+	r.log.Printf("Reloaded, reset to $%04X", r.cpu.PC)
+}
+
+// Shutdown meets the cpu.Monitor interface.
+func (r *reloader) Shutdown() {
+}
+
+// reload reloads the ROM images and programs in place, preserving RAM. If
+// rereadConfig is set the config file is read again first, so files may be
+// changed, but the hardware must otherwise be unchanged as it stays attached.
+func (c *Config) reload(rereadConfig bool) error {
+	hardware, programs := c.Hardware, c.Programs
+	if rereadConfig {
+		if c.configFile == nil || *c.configFile == "" {
+			return fmt.Errorf("No config file to reload")
+		}
+		fresh := &Config{}
+		if err := fresh.load(*c.configFile, nil); err != nil {
+			return err
+		}
+		hardware, programs = fresh.Hardware, fresh.Programs
+	}
+
+	// Read every image before changing any so a failure leaves them intact
+	images := make(map[string][]byte)
+	for _, h := range hardware {
+		if h.Rom == nil {
+			continue
+		}
+		rom, exists := c.roms[h.Name]
+		if !exists {
+			return fmt.Errorf("Rom %s is not attached, needs a restart", h.Name)
+		}
+		m, err := h.Rom.Configure()
+		if err != nil {
+			return err
+		}
+		data := m.(*memory.Rom).Data()
+		if len(data) != rom.Size() {
+			return fmt.Errorf("Rom %s changed size from %d to %d, needs a restart", h.Name, rom.Size(), len(data))
+		}
+		images[h.Name] = data
+	}
+
+	for name, data := range images {
+		copy(c.roms[name].Data(), data)
+		c.logger().Printf("Reloaded rom %s", name)
+	}
+
+	c.Programs = programs
+	return c.loadPrograms()
+}
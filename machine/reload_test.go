@@ -0,0 +1,71 @@
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestReloadConfigReplacesRom(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(dir+"/a.rom", []byte{0x01, 0x02}, 0640)
+	ioutil.WriteFile(dir+"/b.rom", []byte{0x03, 0x04}, 0640)
+	ioutil.WriteFile(dir+"/big.rom", []byte{0x05, 0x06, 0x07}, 0640)
+	file := dir + "/config.yaml"
+	write := func(rom string) {
+		config := "hardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 1024}\n" +
+			"  - name: ROM\n    address: \"FFFE\"\n    rom: {filename: " + dir + "/" + rom + "}\n"
+		ioutil.WriteFile(file, []byte(config), 0640)
+	}
+
+	write("a.rom")
+	c := &Config{configFile: &file}
+	if err := c.load(file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.addressBus.Write(0x0010, 0x42)
+
+	write("b.rom")
+	if err := c.reload(false); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.addressBus.Read16(0xFFFE); v != 0x0201 {
+		t.Error(fmt.Sprintf("expected $0201 without rereading the config got $%04X", v))
+	}
+
+	if err := c.reload(true); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.addressBus.Read16(0xFFFE); v != 0x0403 {
+		t.Error(fmt.Sprintf("expected $0403 got $%04X", v))
+	}
+	if v := c.addressBus.Read(0x0010); v != 0x42 {
+		t.Error(fmt.Sprintf("expected ram preserved got $%02X", v))
+	}
+
+	write("big.rom")
+	if err := c.reload(true); err == nil {
+		t.Error("expected a rom changing size to fail")
+	}
+	if v := c.addressBus.Read16(0xFFFE); v != 0x0403 {
+		t.Error(fmt.Sprintf("expected rom unchanged after failure got $%04X", v))
+	}
+}
+
+func TestHangupWithoutConfigFile(t *testing.T) {
+	m := &Machine{config: &Config{}, reloader: &reloader{}}
+	m.hangup()
+	if m.reloader.request != reloadRoms {
+		t.Error(fmt.Sprintf("expected the ROMs reloaded without a config file got request %d", m.reloader.request))
+	}
+
+	file := "config.yaml"
+	m.config.configFile = &file
+	m.hangup()
+	if m.reloader.request != reloadConfig {
+		t.Error(fmt.Sprintf("expected the config reloaded got request %d", m.reloader.request))
+	}
+}
@@ -1,14 +1,163 @@
 package machine
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"github.com/peter-mount/go6502/memory"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
 )
 
 type RomChip struct {
 	Filename string `yaml:"filename"`
+	// Parts assemble the image from several files instead of Filename.
+	Parts []RomPart `yaml:"parts"`
+	// Size of an image assembled from parts, defaults to the extent of the
+	// parts. Gaps are filled with $FF.
+	Size int `yaml:"size"`
+	// Writes is how writes are reported: "log" (the default), "break" into
+	// the debugger, or "ignore".
+	Writes string `yaml:"writes"`
+	// Strict stops the machine on a write, catching them immediately.
+	Strict bool `yaml:"strict"`
+	// CRC32 and SHA256 are the expected checksums of the image in hex. The
+	// machine will not start if they do not match.
+	CRC32   string `yaml:"crc32"`
+	SHA256  string `yaml:"sha256"`
+	onWrite memory.WriteHandler
+}
+
+// validateWrites checks how rom writes are to be reported.
+func validateWrites(writes string) error {
+	switch writes {
+	case "", "log", "break", "ignore":
+		return nil
+	default:
+		return fmt.Errorf("Invalid rom writes %q", writes)
+	}
 }
 
 func (c *RomChip) Configure() (memory.Memory, error) {
-	rom, err := memory.RomFromFile(c.Filename)
-	return rom, err
+	if err := validateWrites(c.Writes); err != nil {
+		return nil, err
+	}
+
+	var rom *memory.Rom
+	var err error
+	if len(c.Parts) > 0 {
+		rom, err = c.assemble()
+	} else {
+		rom, err = memory.RomFromFile(c.Filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := c.verify(rom.Data()); err != nil {
+		return nil, err
+	}
+	if c.onWrite != nil {
+		rom.OnWrite(c.onWrite)
+	}
+	return rom, nil
+}
+
+// RomPart is one file of a ROM assembled from several, e.g. separate lo/hi
+// chips or multiple 4K parts. Byte i of the file is placed at
+// Offset + i*Interleave + Lane within the image, so Interleave 2 with Lane 0
+// and 1 combines even and odd byte chips.
+type RomPart struct {
+	Filename   string `yaml:"filename"`
+	Offset     string `yaml:"offset"`
+	Interleave int    `yaml:"interleave"`
+	Lane       int    `yaml:"lane"`
+}
+
+// name describes the assembled image in errors and the ROM description.
+func (c *RomChip) name() string {
+	if c.Filename != "" || len(c.Parts) == 0 {
+		return c.Filename
+	}
+	return c.Parts[0].Filename + "+"
+}
+
+// assemble builds the image from its parts.
+func (c *RomChip) assemble() (*memory.Rom, error) {
+	type placement struct {
+		data   []byte
+		offset int
+		stride int
+		lane   int
+	}
+
+	var (
+		placements []placement
+		extent     int
+	)
+	for _, part := range c.Parts {
+		data, err := ioutil.ReadFile(part.Filename)
+		if err != nil {
+			return nil, err
+		}
+
+		p := placement{data: data, stride: part.Interleave, lane: part.Lane}
+		if part.Offset != "" {
+			offset, err := parseAddress(part.Filename, part.Offset)
+			if err != nil {
+				return nil, err
+			}
+			p.offset = int(offset)
+		} else {
+			// Parts without an offset are concatenated
+			p.offset = extent
+		}
+		if p.stride == 0 {
+			p.stride = 1
+		}
+		if p.stride < 1 || p.lane < 0 || p.lane >= p.stride {
+			return nil, fmt.Errorf("Invalid interleave %d lane %d for %s", part.Interleave, part.Lane, part.Filename)
+		}
+
+		end := p.offset + (len(data)-1)*p.stride + p.lane + 1
+		if end > extent {
+			extent = end
+		}
+		placements = append(placements, p)
+	}
+
+	size := c.Size
+	if size == 0 {
+		size = extent
+	}
+	if extent > size || size > 0x10000 {
+		return nil, fmt.Errorf("ROM parts of %d bytes do not fit size %d", extent, size)
+	}
+
+	image := make([]byte, size)
+	for i := range image {
+		image[i] = 0xFF
+	}
+	for _, p := range placements {
+		for i, b := range p.data {
+			image[p.offset+i*p.stride+p.lane] = b
+		}
+	}
+	return memory.NewRom(c.name(), image), nil
+}
+
+// verify checks the image against the expected checksums.
+func (c *RomChip) verify(data []byte) error {
+	if c.CRC32 != "" {
+		actual := fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+		if !strings.EqualFold(actual, c.CRC32) {
+			return fmt.Errorf("ROM %s CRC32 is %s, expected %s", c.name(), actual, c.CRC32)
+		}
+	}
+	if c.SHA256 != "" {
+		actual := fmt.Sprintf("%x", sha256.Sum256(data))
+		if !strings.EqualFold(actual, c.SHA256) {
+			return fmt.Errorf("ROM %s SHA256 is %s, expected %s", c.name(), actual, c.SHA256)
+		}
+	}
+	return nil
 }
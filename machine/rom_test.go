@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestRomChipVerify(t *testing.T) {
+	data := []byte("123456789")
+	chip := &RomChip{
+		Filename: "test.rom",
+		CRC32:    "CBF43926",
+		SHA256:   "15e2b0d3c33891ebb0f1ef609ec419420c20e320ce94c65fbc8c3312448eb225",
+	}
+	if err := chip.verify(data); err != nil {
+		t.Error(err)
+	}
+
+	chip.CRC32 = "00000000"
+	if err := chip.verify(data); err == nil {
+		t.Error("expected CRC32 mismatch")
+	}
+}
+
+func TestRomChipAssemblesParts(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(dir+"/even.bin", []byte{0x00, 0x02}, 0640)
+	ioutil.WriteFile(dir+"/odd.bin", []byte{0x01, 0x03}, 0640)
+	ioutil.WriteFile(dir+"/tail.bin", []byte{0xEE}, 0640)
+
+	chip := &RomChip{
+		Parts: []RomPart{
+			{Filename: dir + "/even.bin", Interleave: 2, Lane: 0},
+			{Filename: dir + "/odd.bin", Offset: "0000", Interleave: 2, Lane: 1},
+			{Filename: dir + "/tail.bin"},
+		},
+		Size: 6,
+	}
+	m, err := chip.Configure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x00, 0x01, 0x02, 0x03, 0xEE, 0xFF}
+	if data := m.(*memory.Rom).Data(); !bytes.Equal(data, expected) {
+		t.Error(fmt.Sprintf("expected % X got % X", expected, data))
+	}
+}
+
+func TestRomOverRamCopiesKernelToRam(t *testing.T) {
+	file := t.TempDir() + "/kernel.rom"
+	ioutil.WriteFile(file, []byte{0xA9, 0x42}, 0640)
+
+	chip := &RomOverRamChip{RomChip: RomChip{Filename: file}, Control: "0000"}
+	m, err := chip.Configure()
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay := m.(*memory.Overlay)
+
+	// Copy the rom onto the ram beneath it, then bank the rom out
+	for a := uint16(0); a < 2; a++ {
+		overlay.Write(a, overlay.Read(a)+1)
+	}
+	overlay.Latch().Write(0, 0)
+
+	if overlay.Read(0) != 0xAA || overlay.Read(1) != 0x43 {
+		t.Error(fmt.Sprintf("expected ram $AA $43 got $%02X $%02X", overlay.Read(0), overlay.Read(1)))
+	}
+}
@@ -0,0 +1,29 @@
+package machine
+
+import (
+	"github.com/peter-mount/go6502/memory"
+)
+
+// RomOverRamChip is a ROM image overlaying RAM of the same size, a common
+// design letting a kernel copy itself into RAM then bank the ROM out. Writing
+// to the control address with bit 0 clear reveals the RAM, set restores the
+// ROM. Writes go to the RAM unless WriteThrough is false.
+type RomOverRamChip struct {
+	RomChip      `yaml:",inline"`
+	Control      string `yaml:"control"`
+	WriteThrough *bool  `yaml:"writeThrough"`
+}
+
+func (c *RomOverRamChip) Configure() (memory.Memory, error) {
+	rom, err := c.RomChip.Configure()
+	if err != nil {
+		return nil, err
+	}
+
+	overlay, err := memory.NewOverlay(rom, memory.NewRam(rom.Size()))
+	if err != nil {
+		return nil, err
+	}
+	overlay.WriteThrough = c.WriteThrough == nil || *c.WriteThrough
+	return overlay, nil
+}
@@ -0,0 +1,220 @@
+package machine
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configField is a config key of a struct, and where it is in the struct.
+type configField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	// owner is the struct declaring the key, which for inline structs is not
+	// the struct it is a key of
+	owner reflect.Type
+}
+
+// configFields returns the config keys of a struct in the order they are
+// declared, including those of inline structs.
+func configFields(t reflect.Type) []configField {
+	var result []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		if f.PkgPath != "" || tag[0] == "-" {
+			continue
+		}
+		if len(tag) > 1 && tag[1] == "inline" {
+			// Inline maps hold the keys not otherwise known
+			if f.Type.Kind() == reflect.Map {
+				continue
+			}
+			for _, inline := range configFields(f.Type) {
+				inline.index = append([]int{i}, inline.index...)
+				result = append(result, inline)
+			}
+			continue
+		}
+		name := tag[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		result = append(result, configField{name: name, index: []int{i}, typ: f.Type, owner: t})
+	}
+	return result
+}
+
+// fields returns the config keys of a struct and their types, including
+// those of inline structs. Hardware also has the registered chips.
+func fields(t reflect.Type) map[string]reflect.Type {
+	result := make(map[string]reflect.Type)
+	if t == hardwareType {
+		for _, name := range registeredChips() {
+			result[name] = nodeType
+		}
+	}
+	for _, f := range configFields(t) {
+		result[f.name] = f.typ
+	}
+	return result
+}
+
+// checkSchema checks a parsed config against the type it is decoded into,
+// reporting each unknown key or value of the wrong type by its path, e.g.
+// hardware[2].6522.dumpAscii: expected bool.
+func checkSchema(node *yaml.Node, t reflect.Type, path string, problems *configError) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			checkSchema(n, t, path, problems)
+		}
+		return
+	case yaml.AliasNode:
+		checkSchema(node.Alias, t, path, problems)
+		return
+	}
+	if node.Tag == "!!null" || t == nodeType {
+		return
+	}
+
+	expected := ""
+	switch t.Kind() {
+	case reflect.Ptr:
+		checkSchema(node, t.Elem(), path, problems)
+
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			expected = "map"
+			break
+		}
+		known := fields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			ft, exists := known[key]
+			if !exists {
+				problems.add("%s: unknown field", schemaPath(path, key))
+				continue
+			}
+			checkSchema(value, ft, schemaPath(path, key), problems)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			expected = "map"
+			break
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkSchema(node.Content[i+1], t.Elem(), schemaPath(path, node.Content[i].Value), problems)
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			expected = "list"
+			break
+		}
+		for i, n := range node.Content {
+			checkSchema(n, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+
+	case reflect.Bool:
+		if node.Tag != "!!bool" {
+			expected = "bool"
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if node.Tag != "!!int" {
+			expected = "integer"
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if node.Tag != "!!int" || strings.HasPrefix(node.Value, "-") {
+			expected = "non-negative integer"
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if node.Tag != "!!int" && node.Tag != "!!float" {
+			expected = "number"
+		}
+
+	case reflect.String:
+		// Unquoted addresses such as 9000 are numbers but decode as strings
+		if node.Kind != yaml.ScalarNode {
+			expected = "string"
+		}
+	}
+
+	if expected != "" {
+		problems.add("%s: expected %s", path, expected)
+	}
+}
+
+func schemaPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Schema returns the JSON Schema of the machine config, which applies to both
+// the YAML and JSON forms, including any registered chips. The schema with
+// only the built in chips is published as config.schema.json.
+func Schema() ([]byte, error) {
+	schema := jsonSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "go6502 machine config"
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	if t == nodeType {
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return jsonSchema(t.Elem())
+
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for name, ft := range fields(t) {
+			properties[name] = jsonSchema(ft)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": jsonSchema(t.Elem()),
+		}
+
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchema(t.Elem()),
+		}
+
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,96 @@
+package machine
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "Write config.schema.json")
+
+func TestSchemaReportsPaths(t *testing.T) {
+	file := t.TempDir() + "/config.yaml"
+	ioutil.WriteFile(file, []byte(`
+cpu:
+  clockHz: -1
+hardware:
+  - name: ram
+    address: 0000
+    ram: {size: 1024}
+  - name: rom
+    address: "E000"
+    rom: {filename: kernal.rom, size: big}
+  - name: VIA
+    address: "9000"
+    6522: {dumpAscii: "yes please"}
+    colour: red
+programs: basic.bin
+`), 0640)
+
+	err := (&Config{}).load(file, nil)
+	if err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+	for _, expected := range []string{
+		"cpu.clockHz: expected non-negative integer",
+		"hardware[1].rom.size: expected integer",
+		"hardware[2].6522.dumpAscii: expected bool",
+		"hardware[2].colour: unknown field",
+		"programs: expected list",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error(fmt.Sprintf("expected %q in %v", expected, err))
+		}
+	}
+	if strings.Contains(err.Error(), "hardware[0]") {
+		t.Error(fmt.Sprintf("expected an unquoted address to be valid in %v", err))
+	}
+}
+
+func TestJsonConfig(t *testing.T) {
+	file := t.TempDir() + "/config.json"
+	ioutil.WriteFile(file, []byte("{\n\t\"cpu\": {\"clockHz\": 1000000},\n\t\"hardware\": [\n\t\t{\"name\": \"ram\", \"address\": \"0000\", \"ram\": {\"size\": 1024}}\n\t]\n}\n"), 0640)
+
+	c := &Config{}
+	if err := c.load(file, nil); err != nil {
+		t.Fatal(err)
+	}
+	if c.Cpu.ClockHz != 1000000 || len(c.Hardware) != 1 || c.Hardware[0].Ram.Size != 1024 {
+		t.Error(fmt.Sprintf("expected the json config to load, got %+v", c.Hardware))
+	}
+
+	ioutil.WriteFile(file, []byte(`{"hardware": [}`), 0640)
+	if err := c.load(file, nil); err == nil {
+		t.Error("expected invalid json to fail")
+	}
+}
+
+func TestSchemaIsPublished(t *testing.T) {
+	// The published schema has only the built in chips
+	registered := chipFactories
+	chipFactories = make(map[string]ChipFactory)
+	defer func() { chipFactories = registered }()
+
+	schema, err := Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema = append(schema, '\n')
+
+	if *updateSchema {
+		if err := ioutil.WriteFile("../config.schema.json", schema, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	published, err := ioutil.ReadFile("../config.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(schema, published) {
+		t.Error("config.schema.json is out of date, run go test ./machine -update-schema")
+	}
+}
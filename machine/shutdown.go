@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"log"
+	"time"
+)
+
+// shutdownTimeout is how long a device has to shut down before it is
+// abandoned, so one which hangs cannot stop the rest shutting down.
+const shutdownTimeout = 5 * time.Second
+
+// shutdown runs a shutdown function, giving up after timeout. It returns
+// false if the function timed out, leaving it running.
+func shutdown(name string, fn func(), timeout time.Duration, log *log.Logger) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Shutdown of %s failed: %v", name, r)
+			}
+		}()
+		fn()
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		log.Printf("Shutdown of %s timed out after %v", name, timeout)
+		return false
+	}
+}
+
+// shutdownDevices shuts down the devices on the bus in the reverse of the
+// order they were attached, so devices go before those they were attached
+// after, and a 6522 shuts down its peripherals. Each is shut down even if
+// an earlier one failed or timed out, so terminals are restored and files
+// written.
+func (c *Config) shutdownDevices(timeout time.Duration) {
+	for i := len(c.memory) - 1; i >= 0; i-- {
+		shutdown(c.names[i], c.memory[i].Shutdown, timeout, c.logger())
+	}
+}
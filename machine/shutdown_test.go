@@ -0,0 +1,44 @@
+package machine
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/memory"
+)
+
+func TestShutdownDevicesInReverse(t *testing.T) {
+	var mutex sync.Mutex
+	var shutdown []string
+	hang := make(chan struct{})
+	defer close(hang)
+
+	c := &Config{}
+	for _, name := range []string{"RAM", "ROM", "VIA", "ACIA"} {
+		name := name
+		c.memory = append(c.memory, &memory.Handler{Name: name, OnClose: func() {
+			switch name {
+			case "VIA":
+				<-hang
+			case "ROM":
+				panic("failed")
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			shutdown = append(shutdown, name)
+		}})
+		c.names = append(c.names, name)
+	}
+
+	// Devices after one which hangs or fails are still shut down
+	c.shutdownDevices(10 * time.Millisecond)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	expected := "[ACIA RAM]"
+	if s := fmt.Sprint(shutdown); s != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, s))
+	}
+}
@@ -0,0 +1,51 @@
+package machine
+
+import (
+	"fmt"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// pauseOrResume pauses a running machine, or resumes a paused one, then logs
+// its state.
+func (m *Machine) pauseOrResume() error {
+	if err := m.control.setPaused(!m.control.isPaused()); err != nil {
+		return err
+	}
+	return m.logState()
+}
+
+// stepOrShow steps one instruction of a paused machine, then logs its state.
+// A running machine just logs its state.
+func (m *Machine) stepOrShow() error {
+	if m.control.isPaused() {
+		if err := m.control.step(); err != nil {
+			return err
+		}
+	}
+	return m.logState()
+}
+
+// logState logs the registers and the next instruction, between instructions.
+func (m *Machine) logState() error {
+	return m.control.do(func() {
+		m.config.logger().Println(m.describeState())
+	})
+}
+
+// describeState describes whether the machine is paused, the registers, and
+// the next instruction. It must be called on the cpu goroutine.
+func (m *Machine) describeState() string {
+	state := "Running"
+	if m.control.isPaused() {
+		state = "Paused"
+	}
+
+	next := "?"
+	if data, err := m.config.addressBus.PeekBlock(m.cpu.PC, 3); err == nil {
+		if in, err := cpu.Decode(m.cpu.PC, data); err == nil {
+			next = in.String()
+		}
+	}
+	return fmt.Sprintf("%s %s Cycles:%d Next:%s", state, m.cpu, m.cpu.Cycles, next)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package machine
+
+// controlSignals does nothing as there are no SIGUSR1 or SIGUSR2 signals on
+// this platform. The HTTP API can pause, resume and step the machine instead.
+func (m *Machine) controlSignals() func() {
+	return func() {}
+}
@@ -0,0 +1,60 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/scheduler"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPauseStepAndResume(t *testing.T) {
+	c := &Config{}
+	config := "hardware:\n  - name: RAM\n    address: \"0000\"\n    ram: {size: 65536}\n"
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.addressBus.Fill(0, 0x10000, 0xEA)
+	var buf bytes.Buffer
+	c.log = log.New(&buf, "", 0)
+
+	m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+
+	// Run NOPs until the test finishes
+	s := scheduler.NewScheduler(0)
+	schedule(t, s, m)
+	defer runScheduler(s)()
+
+	if err := m.pauseOrResume(); err != nil {
+		t.Fatal(err)
+	}
+	var pc uint16
+	m.control.do(func() { pc = m.cpu.PC })
+	for i := 0; i < 2; i++ {
+		if err := m.stepOrShow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.pauseOrResume(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, expected := range []string{
+		fmt.Sprintf("Paused CPU PC:0x%04X", pc),
+		fmt.Sprintf("Paused CPU PC:0x%04X", pc+1),
+		fmt.Sprintf("Paused CPU PC:0x%04X", pc+2),
+		"Running CPU",
+	} {
+		if i >= len(lines) || !strings.HasPrefix(lines[i], expected) || !strings.HasSuffix(lines[i], "Next:NOP implied") {
+			t.Error(fmt.Sprintf("expected line %d to start %q and end Next:NOP implied in\n%s", i+1, expected, buf.String()))
+		}
+	}
+}
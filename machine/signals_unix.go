@@ -0,0 +1,34 @@
+//go:build unix
+
+package machine
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// controlSignals lets a machine run without the debugger be inspected until
+// the returned function is called. SIGUSR1 pauses or resumes it, and SIGUSR2
+// steps one instruction while paused, each logging its state.
+func (m *Machine) controlSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range signals {
+			var err error
+			if sig == syscall.SIGUSR1 {
+				err = m.pauseOrResume()
+			} else {
+				err = m.stepOrShow()
+			}
+			if err != nil {
+				m.config.logger().Println(err)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
@@ -0,0 +1,67 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// stateVersion is bumped when the saved state format changes.
+const stateVersion = 1
+
+// machineState is a saved machine, the cpu registers and the state of each
+// device on the bus, e.g. RAM contents and 6522 registers.
+type machineState struct {
+	Version int
+	Cpu     cpu.State
+	Bus     []byte
+}
+
+// saveState writes the state of the machine to storage.
+func (m *Machine) saveState(name string) error {
+	var snapshot bytes.Buffer
+	if err := m.config.addressBus.Snapshot(&snapshot); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(machineState{
+		Version: stateVersion,
+		Cpu:     m.cpu.State(),
+		Bus:     snapshot.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := m.config.storage.Save(name, buf.Bytes()); err != nil {
+		return err
+	}
+	m.config.logger().Printf("Saved state to %s at $%04X", name, m.cpu.PC)
+	return nil
+}
+
+// loadState restores a state written by saveState. The machine must be
+// configured with the same hardware as when it was saved.
+func (m *Machine) loadState(name string) error {
+	data, err := m.config.storage.Load(name)
+	if err != nil {
+		return err
+	}
+
+	var state machineState
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+		return fmt.Errorf("Invalid state %s: %v", name, err)
+	}
+	if state.Version != stateVersion {
+		return fmt.Errorf("State %s is version %d, expected %d", name, state.Version, stateVersion)
+	}
+
+	if err := m.config.addressBus.Restore(bytes.NewReader(state.Bus)); err != nil {
+		return fmt.Errorf("State %s does not match the machine: %v", name, err)
+	}
+	m.cpu.SetState(state.Cpu)
+	m.config.logger().Printf("Loaded state from %s at $%04X", name, m.cpu.PC)
+	return nil
+}
@@ -0,0 +1,92 @@
+package machine
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/peter-mount/go6502/cpu"
+	"gopkg.in/yaml.v3"
+)
+
+const stateConfig = `
+storage: {type: memory}
+hardware:
+  - name: RAM
+    address: "0000"
+    ram: {size: 1024}
+  - name: VIA
+    address: "9000"
+    6522: {}
+`
+
+func TestSaveAndLoadState(t *testing.T) {
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(stateConfig), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+
+	m.cpu.PC, m.cpu.AC, m.cpu.SP, m.cpu.Cycles = 0x0200, 0x42, 0xF0, 1234
+	m.cpu.SetIRQ(true)
+	c.addressBus.Write(0x0010, 0x55)
+	c.addressBus.Write(0x9002, 0xFF) // DDRB
+	if err := m.saveState("test.state"); err != nil {
+		t.Fatal(err)
+	}
+	saved := m.cpu.State()
+
+	m.cpu.SetState(cpu.State{})
+	c.addressBus.Write(0x0010, 0x00)
+	c.addressBus.Write(0x9002, 0x00)
+	if err := m.loadState("test.state"); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := m.cpu.State(); state != saved {
+		t.Error(fmt.Sprintf("expected %+v got %+v", saved, state))
+	}
+	if v := c.addressBus.Read(0x0010); v != 0x55 {
+		t.Error(fmt.Sprintf("expected ram $55 got $%02X", v))
+	}
+	if v := c.addressBus.Read(0x9002); v != 0xFF {
+		t.Error(fmt.Sprintf("expected DDRB $FF got $%02X", v))
+	}
+
+	if err := m.loadState("missing.state"); err == nil {
+		t.Error("expected a missing state to fail")
+	}
+}
+
+func TestSaveAndLoadStateWithFaults(t *testing.T) {
+	c := &Config{}
+	err := yaml.Unmarshal([]byte(`
+storage: {type: memory}
+hardware:
+  - name: RAM
+    address: "0000"
+    ram: {size: 1024}
+    faults: {stuckHigh: 0x80}
+`), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	m := &Machine{config: c, cpu: &cpu.Cpu{Bus: c.addressBus}}
+
+	c.addressBus.Write(0x0010, 0x55)
+	if err := m.saveState("test.state"); err != nil {
+		t.Fatal(err)
+	}
+	c.addressBus.Write(0x0010, 0x00)
+	if err := m.loadState("test.state"); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.memory[0].Read(0x0010); v != 0x55 {
+		t.Error(fmt.Sprintf("expected faulty ram restored to $55 got $%02X", v))
+	}
+}
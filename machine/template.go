@@ -0,0 +1,311 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Template returns a commented example config describing every option,
+// including those of any registered chips. Options are commented out with
+// their default value, except for the hardware of the machine built without
+// a config file, so the template runs as it is.
+func Template() ([]byte, error) {
+	defaults := &Config{}
+	if err := defaults.defaultConfig("", 0x8000, true); err != nil {
+		return nil, err
+	}
+
+	tp := &template{all: true}
+	tp.printf("# go6502 machine config, written by go6502 init.\n#\n")
+	tp.printf("# Each option is commented out with its default value, and described\n")
+	tp.printf("# above. Remove the # to set one, and those of the keys it is under.\n")
+	tp.printf("# The hardware is that used without a config file.\n")
+	tp.fields(reflect.ValueOf(Config{Hardware: defaults.Hardware}), "", "", "")
+	return tp.buf.Bytes(), nil
+}
+
+// template writes a config as commented YAML.
+type template struct {
+	buf bytes.Buffer
+	// all writes every option commented out, else only those that are set
+	all bool
+}
+
+func (tp *template) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&tp.buf, format, args...)
+}
+
+// fields writes the fields of a struct at indent, the first at first, which
+// for a list entry is where the "- " goes. doc is the key of the struct in
+// templateDocs, for fields of structs without a type name.
+func (tp *template) fields(v reflect.Value, doc, indent, first string) {
+	for _, f := range configFields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if !tp.all && fv.IsZero() {
+			continue
+		}
+		key := templateDocKey(f, doc)
+		if tp.all {
+			if indent == "" || templateSection(f.typ) {
+				tp.printf("\n")
+			}
+			tp.printf("%s# %s\n", indent, templateDocs[key])
+		}
+		if key == "machine.Config.hardware" {
+			tp.hardware(fv)
+		} else {
+			tp.value(fv, f.name, key, indent, first)
+		}
+		first = indent
+	}
+}
+
+// templateDocKey returns the key of a field in templateDocs: the type
+// declaring it and its name, e.g. machine.RamChip.size, or its path from
+// the nearest named type, e.g. machine.Config.cpu.clockHz.
+func templateDocKey(f configField, doc string) string {
+	if f.owner.Name() != "" {
+		return f.owner.String() + "." + f.name
+	}
+	return doc + "." + f.name
+}
+
+// templateSection is true for fields with fields of their own, which are
+// spaced from the previous field.
+func templateSection(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != nodeType
+}
+
+// value writes a key and its value at first, with any fields under it
+// indented from indent.
+func (tp *template) value(v reflect.Value, key, doc, indent, first string) {
+	comment := ""
+	if tp.all {
+		comment = "#"
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsNil() {
+			v = reflect.Zero(t)
+		} else {
+			v = v.Elem()
+		}
+	}
+
+	switch {
+	case t == nodeType || t.Kind() == reflect.Map:
+		tp.printf("%s%s%s: {}\n", comment, first, key)
+
+	case t.Kind() == reflect.Struct && !tp.all && v.IsZero():
+		tp.printf("%s%s: {}\n", first, key)
+
+	case t.Kind() == reflect.Struct:
+		tp.printf("%s%s%s:\n", comment, first, key)
+		tp.fields(v, doc, indent+"  ", indent+"  ")
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		tp.printf("%s%s%s:\n", comment, first, key)
+		if tp.all {
+			tp.fields(reflect.Zero(t.Elem()), doc, indent+"    ", indent+"  - ")
+		}
+		for i := 0; !tp.all && i < v.Len(); i++ {
+			tp.fields(v.Index(i), doc, indent+"    ", indent+"  - ")
+		}
+
+	case t.Kind() == reflect.Slice:
+		var values []string
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, templateScalar(v.Index(i)))
+		}
+		tp.printf("%s%s%s: [%s]\n", comment, first, key, strings.Join(values, ", "))
+
+	default:
+		tp.printf("%s%s%s: %s\n", comment, first, key, templateScalar(v))
+	}
+}
+
+// hardware writes the hardware, followed by a commented out entry with the
+// options of every chip.
+func (tp *template) hardware(v reflect.Value) {
+	tp.printf("hardware:\n")
+	tp.all = false
+	for i := 0; i < v.Len(); i++ {
+		tp.fields(v.Index(i), "", "    ", "  - ")
+	}
+	tp.all = true
+
+	tp.printf("\n  # Each entry has a name, an address and one of these chips. The faults\n")
+	tp.printf("  # and waitStates apply to any chip.\n\n")
+	tp.fields(reflect.Zero(hardwareType), "", "    ", "  - ")
+	for _, name := range registeredChips() {
+		tp.printf("\n    # The options of the registered %s chip.\n", name)
+		tp.printf("#    %s: {}\n", name)
+	}
+}
+
+func templateScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// templateDocs describes each option in the template, by the type declaring
+// it and its name, or its path from the nearest named type.
+var templateDocs = map[string]string{
+	"machine.Config.name":     "Labels the log lines and metrics of the machine.",
+	"machine.Config.include":  "Configs read first, relative to this one. This config's settings win.",
+	"machine.Config.machines": "Configs of machines to run alongside this one, relative to this one.",
+
+	"machine.Config.cpu":                    "The cpu.",
+	"machine.Config.cpu.profile":            "6502 (or nmos) or 65c02 (or cmos), setting the quirks below.",
+	"machine.Config.cpu.indirectJumpBug":    "JMP ($xxFF) fetches the high byte of the target from $xx00.",
+	"machine.Config.cpu.dummyWrite":         "Read-modify-write instructions write the unmodified value first.",
+	"machine.Config.cpu.dummyRead":          "Indexed addressing reads the address before fixing the page.",
+	"machine.Config.cpu.cycleAccurate":      "Count the cycles taken by page crossings and taken branches.",
+	"machine.Config.cpu.clockHz":            "Clock speed in Hz, 0 to run as fast as possible.",
+	"machine.Config.cpu.throttle":           "Run no faster than clockHz, the default when it is set.",
+	"machine.Config.cpu.interrupts":         "When pending interrupts are serviced.",
+	"machine.Config.cpu.interrupts.latency": "Cycles an interrupt must be pending before an instruction boundary.",
+	"machine.Config.cpu.interrupts.cycles":  "Cycles taken by the interrupt sequence, 0 for the 6502's 7.",
+	"machine.Config.exit":                   "Lets test ROMs stop the machine.",
+	"machine.Config.exit.opcode":            "Hex opcode which exits with the accumulator as the status.",
+	"machine.Config.exit.address":           "Hex address which exits with the byte written as the status.",
+	"machine.Config.exit.message":           "Hex address whose bytes written are logged on exit.",
+	"machine.Config.limits":                 "Bounds headless runs.",
+	"machine.Config.limits.maxCycles":       "Exit after this many cycles.",
+	"machine.Config.limits.maxSeconds":      "Exit after running for this many seconds.",
+	"machine.Config.limits.untilWrite":      "Exit with status 0 once this hex address is written to.",
+	"machine.Config.limits.status":          "Exit status on reaching maxCycles or maxSeconds, default 124.",
+	"machine.Config.limits.dumpCore":        "Dump RAM to files with this prefix on reaching a limit.",
+	"machine.Config.input":                  "Records and replays the input read by the guest.",
+	"machine.Config.input.record":           "Record each byte read from a 6551 to this file.",
+	"machine.Config.input.replay":           "Replay a recording at the cycles it was read at.",
+	"machine.Config.debug":                  "The debugger and diagnostics.",
+	"machine.Config.debug.debugger":         "Run the debugger on the terminal.",
+	"machine.Config.debug.batch":            "Run the debugger commands without a terminal, then exit.",
+	"machine.Config.debug.web":              "Serve the debugger web UI on this address, e.g. localhost:6502.",
+	"machine.Config.debug.rpc":              "Serve the debugger JSON-RPC interface on this address.",
+	"machine.Config.debug.debugCommands":    "Debugger commands to run.",
+	"machine.Config.debug.debugScript":      "File of debugger commands to run.",
+	"machine.Config.debug.symbolFile":       "Symbol file to load.",
+	"machine.Config.debug.symbolFormat":     "Symbol file format: dbg, vice or map. Detected if empty.",
+	"machine.Config.debug.speedometer":      "Report the effective clock speed.",
+	"machine.Config.debug.regions":          "Address ranges the speedometer reports on.",
+	"machine.Config.debug.stats":            "Report instruction statistics on exit.",
+	"machine.Config.debug.heatMap":          "Write a memory access heat map to this .csv or .png file on exit.",
+	"machine.Config.debug.watchdog":         "Report where the cpu is stuck if no instruction completes in this, e.g. 5s.",
+	"machine.Config.debug.trace":            "Logs bus accesses.",
+	"machine.Config.debug.trace.file":       "File to log to.",
+	"machine.Config.debug.trace.ranges":     "Address ranges to log, or all if empty.",
+	"machine.Config.debug.dumpCore":         "Dump RAM to files with this prefix on exit.",
+	"machine.Config.debug.dumpCoreFormat":   "Core file format: binary (the default), hexdump, ihex or srec.",
+	"machine.Config.bus":                    "The address bus.",
+	"machine.Config.bus.unmapped":           "Accesses to unmapped addresses: panic (the default), ff or last.",
+	"machine.Config.bus.fault":              "Log unmapped accesses and break into the debugger.",
+	"machine.Config.bus.strict":             "Stop the machine on an unmapped access.",
+	"machine.Config.hardware":               "The chips, on the bus unless attached to a 6522.",
+	"machine.Config.programs":               "Programs loaded after the cpu is reset.",
+	"machine.Config.protect":                "Memory protection, faulting on disallowed accesses.",
+	"machine.Config.storage":                "Where saved state and snapshots are stored.",
+	"machine.Region.name":                   "Name of the range.",
+	"machine.Region.start":                  "Hex start address, or a label from the symbol file.",
+	"machine.Region.end":                    "Hex end address, inclusive, or a label from the symbol file.",
+	"machine.Program.file":                  "Program file.",
+	"machine.Program.format":                "ihex, srec or raw. Taken from the file extension if empty.",
+	"machine.Program.address":               "Hex address raw programs are loaded at.",
+	"machine.Program.reset":                 "Point the reset vector at the program's entry point.",
+	"machine.Protect.name":                  "Name of the region.",
+	"machine.Protect.start":                 "Hex start address, or a label from the symbol file.",
+	"machine.Protect.end":                   "Hex end address, inclusive, or a label from the symbol file.",
+	"machine.Protect.readOnly":              "Fault on writes.",
+	"machine.Protect.noExecute":             "Fault on executing an instruction.",
+	"machine.Protect.strict":                "Stop the machine on a fault.",
+	"storage.Config.type":                   "local (the default), memory or http.",
+	"storage.Config.path":                   "Directory used by local storage.",
+	"storage.Config.url":                    "Base URL used by http storage.",
+	"storage.Config.headers":                "Headers added to each http request, e.g. for authorisation.",
+	"machine.Hardware.name":                 "Name of the chip, used by the debugger and other entries.",
+	"machine.Hardware.address":              "Hex address on the bus.",
+	"machine.Hardware.mirror":               "Number of copies of the chip on the bus, one after another.",
+	"machine.Hardware.ram":                  "RAM.",
+	"machine.Hardware.rom":                  "ROM image.",
+	"machine.Hardware.6551":                 "6551 ACIA serial port.",
+	"machine.Hardware.6522":                 "6522 VIA, with ports peripherals attach to.",
+	"machine.Hardware.banked":               "Bank switched RAM, seen through a window at the address.",
+	"machine.Hardware.overlay":              "A chip overlaying another at the same address.",
+	"machine.Hardware.cartridge":            "Banked ROM image, seen through a window at the address.",
+	"machine.Hardware.charRom":              "Character generator ROM, for video devices. Needs no address.",
+	"machine.Hardware.romOverRam":           "ROM image overlaying RAM, which can be revealed.",
+	"machine.Hardware.faults":               "Faults injected into reads of the chip.",
+	"machine.Hardware.ssd1306":              "SSD1306 OLED display on a 6522 port.",
+	"machine.Hardware.ili9340":              "ILI9340 TFT display on SPI on a 6522 port.",
+	"machine.Hardware.sd":                   "SD card on SPI on a 6522 port.",
+	"machine.Hardware.waitStates":           "Extra cycles added to each access, for slow chips.",
+	"machine.RamChip.size":                  "Bytes of RAM, at least 1K.",
+	"machine.RamChip.backing":               "File the RAM is memory mapped from, keeping it across restarts.",
+	"machine.RomChip.filename":              "ROM image file.",
+	"machine.RomChip.parts":                 "Files to assemble the image from instead of filename.",
+	"machine.RomChip.size":                  "Size of an image from parts, by default their extent. Gaps are $FF.",
+	"machine.RomChip.writes":                "How writes are reported: log (the default), break or ignore.",
+	"machine.RomChip.strict":                "Stop the machine on a write.",
+	"machine.RomChip.crc32":                 "Expected CRC32 of the image in hex.",
+	"machine.RomChip.sha256":                "Expected SHA256 of the image in hex.",
+	"machine.RomPart.filename":              "Part file.",
+	"machine.RomPart.offset":                "Hex offset of the part in the image.",
+	"machine.RomPart.interleave":            "Bytes between those of the part, e.g. 2 for lo/hi chips.",
+	"machine.RomPart.lane":                  "Which of the interleaved bytes are this part's.",
+	"machine.Acia6551Chip.peripheral":       "console, or link:NAME for the other 6551 using the name.",
+	"machine.Acia6551Chip.script":           "Input typed into the port.",
+	"machine.Acia6551Chip.charset":          "Translate the host's text: ascii, petscii, atascii or a file.",
+	"acia6551.ScriptStep.afterCycles":       "Cycles to wait after the previous step, or power on.",
+	"acia6551.ScriptStep.afterOutput":       "Text to wait for the guest to output, e.g. a prompt.",
+	"acia6551.ScriptStep.type":              "Text to type.",
+	"machine.Via6522Chip.dumpAscii":         "Dump the port output as ASCII.",
+	"machine.Via6522Chip.dumpBinary":        "Dump the port output as binary.",
+	"machine.BankedChip.bankSize":           "Bytes in each bank, the size of the window.",
+	"machine.BankedChip.banks":              "Number of banks.",
+	"machine.BankedChip.latch":              "Hex address of the register selecting the bank.",
+	"machine.BankedChip.backing":            "File the banks are memory mapped from.",
+	"machine.OverlayChip.over":              "The chip seen while the overlay is enabled.",
+	"machine.OverlayChip.under":             "The chip seen while the overlay is disabled.",
+	"machine.OverlayChip.latch":             "Hex address of the register enabling the overlay.",
+	"machine.OverlayChip.writeThrough":      "Write to the chip underneath even while the overlay is enabled.",
+	"machine.OverlayLayer.ram":              "RAM.",
+	"machine.OverlayLayer.rom":              "ROM image.",
+	"machine.CartridgeChip.filename":        "Cartridge image file.",
+	"machine.CartridgeChip.bankSize":        "Bytes in each bank, the size of the window.",
+	"machine.CartridgeChip.latch":           "Hex address of the register selecting the bank.",
+	"machine.CartridgeChip.writes":          "How writes are reported: log (the default), break or ignore.",
+	"machine.CartridgeChip.strict":          "Stop the machine on a write.",
+	"machine.CharRomChip.filename":          "Character ROM image file.",
+	"machine.CharRomChip.height":            "Pixel rows in each character, default 8.",
+	"machine.RomOverRamChip.control":        "Hex address where bit 0 clear reveals the RAM, and set the ROM.",
+	"machine.RomOverRamChip.writeThrough":   "Write to the RAM while the ROM is visible, the default.",
+	"machine.FaultConfig.stuckHigh":         "Bits which always read as 1.",
+	"machine.FaultConfig.stuckLow":          "Bits which always read as 0.",
+	"machine.FaultConfig.flipRate":          "Probability, 0 to 1, of a read having a random bit flipped.",
+	"machine.FaultConfig.readErrors":        "Address ranges where reads return random values.",
+	"machine.FaultConfig.seed":              "Random seed, so faults are reproducible.",
+	"machine.ViaPort.via":                   "Name of the 6522 it is attached to.",
+	"machine.ViaPort.port":                  "Port of the 6522, A or B.",
+	"machine.Ili9340Chip.pins":              "Port pins, 0 to 7, by default those of --ili9340.",
+	"machine.SdCardChip.pins":               "Port pins, 0 to 7, by default those of --sd-card.",
+	"machine.SdCardChip.file":               "SD card image file.",
+	"machine.SpiPins.sclk":                  "Clock pin.",
+	"machine.SpiPins.mosi":                  "Data out pin.",
+	"machine.SpiPins.miso":                  "Data in pin.",
+	"machine.SpiPins.ss":                    "Slave select pin.",
+}
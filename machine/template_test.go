@@ -0,0 +1,52 @@
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestTemplateDescribesEveryOption(t *testing.T) {
+	template, err := Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, line := range strings.Split(string(template), "\n") {
+		if strings.TrimSpace(line) == "#" && i > 1 {
+			t.Error(fmt.Sprintf("expected a description in templateDocs for the option on line %d:\n%s", i+2, template))
+		}
+	}
+	for key, doc := range templateDocs {
+		if !strings.Contains(string(template), "# "+doc+"\n") {
+			t.Error(fmt.Sprintf("expected %s to be an option", key))
+		}
+	}
+}
+
+func TestTemplateRuns(t *testing.T) {
+	template, err := Template()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := t.TempDir() + "/config.yaml"
+	ioutil.WriteFile(file, template, 0640)
+
+	c, err := LoadConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var regions []string
+	for _, r := range c.addressBus.Regions() {
+		regions = append(regions, fmt.Sprintf("%s %04X", r.Name, r.Start))
+	}
+	expected := "[RAM 0000 VIA 9000 Console 9010]"
+	if actual := fmt.Sprint(regions); actual != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, actual))
+	}
+}
@@ -0,0 +1,246 @@
+package machine
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/peter-mount/go6502/bus"
+)
+
+// configError lists every problem found in a config, so they can all be
+// fixed at once.
+type configError []string
+
+func (e configError) Error() string {
+	return "Invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+// result returns the problems as an error, or nil if there were none.
+func (e configError) result() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *configError) add(format string, a ...interface{}) {
+	*e = append(*e, fmt.Sprintf(format, a...))
+}
+
+// chips returns the yaml names of the chips a hardware entry defines.
+func (h *Hardware) chips() []string {
+	var names []string
+	for _, chip := range []struct {
+		name    string
+		defined bool
+	}{
+		{"ram", h.Ram != nil},
+		{"rom", h.Rom != nil},
+		{"6551", h.Acia6551 != nil},
+		{"6522", h.Via6522 != nil},
+		{"banked", h.Banked != nil},
+		{"overlay", h.Overlay != nil},
+		{"cartridge", h.Cartridge != nil},
+		{"charRom", h.CharRom != nil},
+		{"romOverRam", h.RomOverRam != nil},
+		{"ssd1306", h.Ssd1306 != nil},
+		{"ili9340", h.Ili9340 != nil},
+		{"sd", h.SdCard != nil},
+	} {
+		if chip.defined {
+			names = append(names, chip.name)
+		}
+	}
+	for name := range h.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names[len(names)-len(h.Plugins):])
+	return names
+}
+
+// validate checks the hardware list before anything is attached, reporting
+// all the problems found.
+func (c *Config) validate() error {
+	var problems configError
+
+	names := make(map[string]bool)
+	vias := make(map[string]bool)
+	var regions []bus.Region
+	links := make(map[string]int)
+	for _, h := range c.Hardware {
+		if h.Via6522 != nil {
+			vias[h.Name] = true
+		}
+	}
+
+	for i, h := range c.Hardware {
+		name := h.Name
+		if name == "" {
+			name = fmt.Sprintf("hardware entry %d", i+1)
+			problems.add("%s: no name", name)
+		} else if names[name] {
+			problems.add("%s: name used more than once", name)
+		}
+		names[name] = true
+
+		chips := h.chips()
+		switch {
+		case len(chips) == 0:
+			problems.add("%s: no chip defined", name)
+		case len(chips) > 1:
+			problems.add("%s: more than one chip defined: %s", name, strings.Join(chips, ", "))
+		}
+		for chip := range h.Plugins {
+			if _, exists := chipFactory(chip); !exists {
+				problems.add("%s: unknown chip %s", name, chip)
+			}
+		}
+
+		checkAddress := func(field, s string) (uint16, bool) {
+			a, err := parseAddress(name, s)
+			if err != nil {
+				problems.add("%s: invalid %s %q, expected 4 hex digits", name, field, s)
+			}
+			return a, err == nil
+		}
+		// Latches take a byte at their own address
+		checkLatch := func(field, s string) {
+			if a, ok := checkAddress(field, s); ok {
+				regions = append(regions, bus.Region{Name: h.Name + " " + field, Start: a, End: a})
+			}
+		}
+
+		if h.Acia6551 != nil && strings.HasPrefix(h.Acia6551.Peripheral, linkPrefix) {
+			link := strings.TrimPrefix(h.Acia6551.Peripheral, linkPrefix)
+			if link == "" {
+				problems.add("%s: link has no name", name)
+			}
+			links[link]++
+		}
+
+		if p := h.peripheral(); p != nil {
+			if h.Address != "" {
+				problems.add("%s: attached to a 6522, so has no address", name)
+			}
+			port := p.viaPort()
+			if !vias[port.Via] {
+				problems.add("%s: no 6522 named %q", name, port.Via)
+			}
+			if p := strings.ToUpper(port.Port); p != "A" && p != "B" {
+				problems.add("%s: invalid port %q, expected A or B", name, port.Port)
+			}
+			continue
+		}
+
+		switch {
+		case h.Address != "":
+			address, ok := checkAddress("address", h.Address)
+			if span := h.span(); ok && span > 0 {
+				end := int(address) + span - 1
+				if end > 0xFFFF {
+					problems.add("%s: %d bytes at $%04X pass the end of memory", name, span, address)
+					end = 0xFFFF
+				}
+				regions = append(regions, bus.Region{Name: h.Name, Start: address, End: uint16(end)})
+			}
+		case h.CharRom == nil:
+			problems.add("%s: no address", name)
+		}
+
+		switch {
+		case h.Banked != nil:
+			checkLatch("latch", h.Banked.Latch)
+		case h.Overlay != nil:
+			checkLatch("latch", h.Overlay.Latch)
+		case h.Cartridge != nil:
+			checkLatch("latch", h.Cartridge.Latch)
+		case h.RomOverRam != nil:
+			checkLatch("control", h.RomOverRam.Control)
+		}
+	}
+	// The other end of a link used once may be in a machine run alongside
+	var linkNames []string
+	for link, uses := range links {
+		if uses > 2 {
+			linkNames = append(linkNames, link)
+		}
+	}
+	sort.Strings(linkNames)
+	for _, link := range linkNames {
+		problems.add("link %q used by %d 6551s, a link joins two", link, links[link])
+	}
+
+	checkOverlaps(regions, &problems)
+	return problems.result()
+}
+
+// checkOverlaps reports devices whose address ranges overlap, as the bus
+// only reaches the first attached.
+func checkOverlaps(regions []bus.Region, problems *configError) {
+	for i, a := range regions {
+		for _, b := range regions[i+1:] {
+			if a.Start <= b.End && b.Start <= a.End {
+				problems.add("%v overlaps %v", a, b)
+			}
+		}
+	}
+}
+
+// span returns the number of bytes a hardware entry takes on the bus, as far
+// as its config says, or 0 if that is only known once the chip is
+// configured, e.g. from a ROM file which is missing.
+func (h *Hardware) span() int {
+	if h.Mirror > 0 {
+		return h.Mirror
+	}
+	switch {
+	case h.Ram != nil:
+		return h.Ram.Size
+	case h.Rom != nil:
+		return h.Rom.span()
+	case h.RomOverRam != nil:
+		return h.RomOverRam.RomChip.span()
+	case h.Acia6551 != nil:
+		return 4
+	case h.Via6522 != nil:
+		return 16
+	case h.Banked != nil:
+		return h.Banked.BankSize
+	case h.Cartridge != nil:
+		return h.Cartridge.BankSize
+	case h.Overlay != nil:
+		if l := h.Overlay.Over; l.Ram != nil {
+			return l.Ram.Size
+		} else if l.Rom != nil {
+			return l.Rom.span()
+		}
+	case h.CharRom != nil:
+		return fileSize(h.CharRom.Filename)
+	case len(h.Plugins) > 0:
+		if chip, err := h.plugin(); err == nil {
+			if sized, ok := chip.(SizedChip); ok {
+				return sized.Size()
+			}
+		}
+	}
+	return 0
+}
+
+// span returns the size of the ROM image.
+func (c *RomChip) span() int {
+	if len(c.Parts) > 0 {
+		return c.Size
+	}
+	return fileSize(c.Filename)
+}
+
+// fileSize returns the size of a file, or 0 if it can't be read.
+func fileSize(name string) int {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return 0
+	}
+	return int(fi.Size())
+}
@@ -0,0 +1,143 @@
+package machine
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/peter-mount/go6502/memory"
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	config := `
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {}
+    rom: {}
+  - name: empty
+    address: "8000"
+  - name: ram
+    address: "12345"
+    ram: {}
+  - name: banks
+    address: "4000"
+    banked: {latch: "zz"}
+  - name: oled
+    ssd1306: {via: VIA, port: C}
+  - name: serial1
+    address: "9000"
+    6551: {peripheral: "link:net"}
+  - name: serial2
+    address: "9010"
+    6551: {peripheral: "link:net"}
+  - name: serial3
+    address: "9020"
+    6551: {peripheral: "link:net"}
+`
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Start()
+	if err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+	for _, expected := range []string{
+		"ram: more than one chip defined: ram, rom",
+		"empty: no chip defined",
+		"ram: name used more than once",
+		`ram: invalid address "12345"`,
+		`banks: invalid latch "zz"`,
+		`oled: no 6522 named "VIA"`,
+		`oled: invalid port "C"`,
+		`link "net" used by 3 6551s`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error(fmt.Sprintf("expected %q in %v", expected, err))
+		}
+	}
+}
+
+func TestValidateReportsOverlaps(t *testing.T) {
+	config := `
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {size: 32768}
+  - name: VIA
+    address: "7FF0"
+    6522: {}
+`
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Start()
+	expected := "ram $0000-$7FFF overlaps VIA $7FF0-$7FFF"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Error(fmt.Sprintf("expected %q got %v", expected, err))
+	}
+}
+
+func TestValidateReportsOverlapsBeforeAttaching(t *testing.T) {
+	config := `
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {size: 32768}
+  - name: banks
+    address: "8000"
+    banked: {bankSize: 16384, banks: 2, latch: "4000"}
+  - name: high
+    address: "F000"
+    ram: {size: 8192}
+  - name: rom
+    address: "C000"
+    rom: {filename: missing.rom}
+`
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Start()
+	if err == nil {
+		t.Fatal("expected the config to be invalid")
+	}
+	for _, expected := range []string{
+		"ram $0000-$7FFF overlaps banks latch $4000-$4000",
+		"high: 8192 bytes at $F000 pass the end of memory",
+		"Invalid config",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Error(fmt.Sprintf("expected %q in %v", expected, err))
+		}
+	}
+	if c.addressBus != nil {
+		t.Error("expected nothing attached")
+	}
+}
+
+func TestStartShutsDownAttachedDevices(t *testing.T) {
+	config := `
+hardware:
+  - name: ram
+    address: "0000"
+    ram: {size: 1024, backing: ` + filepath.Join(t.TempDir(), "ram.bin") + `}
+  - name: rom
+    address: "C000"
+    rom: {filename: missing.rom}
+`
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(config), c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err == nil {
+		t.Fatal("expected the missing rom to fail")
+	}
+	if len(c.memory) != 1 || c.memory[0].(*memory.Ram).Data() != nil {
+		t.Error("expected the ram to be unmapped")
+	}
+}
@@ -0,0 +1,128 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// modulePrefix identifies stack frames within go6502.
+const modulePrefix = "github.com/peter-mount/go6502/"
+
+// watchdog detects when the cpu has stopped retiring instructions, e.g. when
+// a peripheral is blocked reading stdin or on a channel, and reports where
+// the cpu goroutine is blocked rather than letting the emulator silently hang.
+// Nothing is reported while the machine is paused.
+type watchdog struct {
+	interval time.Duration
+	cpu      *cpu.Cpu
+	paused   func() bool
+	log      *log.Logger
+	retired  uint64
+	stop     chan struct{}
+}
+
+func newWatchdog(interval time.Duration, c *cpu.Cpu, paused func() bool, log *log.Logger) *watchdog {
+	return &watchdog{interval: interval, cpu: c, paused: paused, log: log, stop: make(chan struct{})}
+}
+
+// BeforeExecute meets the cpu.Monitor interface, counting instructions.
+func (w *watchdog) BeforeExecute(_ cpu.Instruction) {
+	atomic.AddUint64(&w.retired, 1)
+}
+
+// Shutdown meets the cpu.Monitor interface, stopping the watchdog.
+func (w *watchdog) Shutdown() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+}
+
+// start checks for progress every interval until shutdown. A stall is only
+// reported once, until the cpu makes progress again.
+func (w *watchdog) start() {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		last := atomic.LoadUint64(&w.retired)
+		reported := false
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+				retired := atomic.LoadUint64(&w.retired)
+				if w.paused() {
+					last = retired
+					reported = false
+				} else if retired != last {
+					last = retired
+					reported = false
+				} else if !reported {
+					reported = w.report()
+				}
+			}
+		}
+	}()
+}
+
+// report logs where the cpu goroutine is blocked. Returns false if the cpu is
+// waiting at the debugger prompt, which is not a stall.
+func (w *watchdog) report() bool {
+	stack := cpuGoroutine(w.cpu)
+	blocker := blockingFrame(stack)
+	if strings.HasPrefix(blocker, modulePrefix+"debugger.") {
+		return false
+	}
+
+	w.log.Printf("Watchdog: no instructions retired for %v, blocked in %s", w.interval, blocker)
+	w.log.Printf("Watchdog: cpu goroutine\n%s", stack)
+	return true
+}
+
+// cpuGoroutine returns the stack trace of the goroutine running the cpu,
+// which is found by its address as several machines may be running. They
+// share the scheduler's goroutine, so if another machine is holding it up
+// that goroutine is returned instead.
+func cpuGoroutine(c *cpu.Cpu) string {
+	step := []byte(fmt.Sprintf("%scpu.(*Cpu).Step(%p", modulePrefix, c))
+	slice := []byte(modulePrefix + "scheduler.(*Scheduler).Slice(")
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	shared := "cpu goroutine not found"
+	for _, g := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.Contains(g, step) {
+			return string(g)
+		}
+		if bytes.Contains(g, slice) {
+			shared = string(g)
+		}
+	}
+	return shared
+}
+
+// blockingFrame returns the innermost go6502 function outside of the cpu
+// package in a stack trace, which is the device or monitor the cpu is
+// waiting on.
+func blockingFrame(stack string) string {
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, modulePrefix) && !strings.HasPrefix(line, modulePrefix+"cpu.") {
+			return line
+		}
+	}
+	for _, line := range strings.Split(stack, "\n") {
+		if strings.HasPrefix(line, modulePrefix) {
+			return line
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,56 @@
+package machine
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// syncBuffer is a buffer the watchdog can log to while the test reads it.
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchdogIgnoresPausedMachine(t *testing.T) {
+	var paused int32 = 1
+	var out syncBuffer
+	w := newWatchdog(time.Millisecond, &cpu.Cpu{}, func() bool {
+		return atomic.LoadInt32(&paused) != 0
+	}, log.New(&out, "", 0))
+	w.start()
+	defer w.Shutdown()
+
+	time.Sleep(20 * time.Millisecond)
+	if s := out.String(); s != "" {
+		t.Fatal(fmt.Sprintf("expected no report while paused got %q", s))
+	}
+
+	atomic.StoreInt32(&paused, 0)
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(out.String(), "no instructions retired") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a stall to be reported once resumed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
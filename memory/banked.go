@@ -0,0 +1,169 @@
+package memory
+
+import "fmt"
+
+// Banked is a large backing store divided into equally sized banks, one of
+// which is visible through a window on the bus at a time. The visible bank is
+// selected by writing to its BankLatch, which is attached to the bus
+// separately. This allows machines with more than 64K of memory.
+type Banked struct {
+	bankSize int
+	banks    int
+	bank     int
+	data     []byte
+	unmap    func() error
+}
+
+// NewBanked creates banked memory of banks * bankSize bytes, with bank 0
+// selected.
+func NewBanked(bankSize, banks int) (*Banked, error) {
+	if err := validateBanks(bankSize, banks); err != nil {
+		return nil, err
+	}
+	return &Banked{
+		bankSize: bankSize,
+		banks:    banks,
+		data:     make([]byte, bankSize*banks),
+	}, nil
+}
+
+// NewMappedBanked creates banked memory backed by a memory mapped file, so
+// its contents survive restarts without being copied.
+func NewMappedBanked(path string, bankSize, banks int) (*Banked, error) {
+	if err := validateBanks(bankSize, banks); err != nil {
+		return nil, err
+	}
+	data, unmap, err := mapFile(path, bankSize*banks)
+	if err != nil {
+		return nil, err
+	}
+	return &Banked{
+		bankSize: bankSize,
+		banks:    banks,
+		data:     data,
+		unmap:    unmap,
+	}, nil
+}
+
+func validateBanks(bankSize, banks int) error {
+	if bankSize < 1 || bankSize > 0x10000 {
+		return fmt.Errorf("Invalid bank size %d", bankSize)
+	}
+	if banks < 1 || banks > 256 {
+		return fmt.Errorf("Invalid number of banks %d", banks)
+	}
+	return nil
+}
+
+// Shutdown unmaps the backing file, if any, writing back its contents.
+// The memory must not be used afterwards.
+func (b *Banked) Shutdown() {
+	if b.unmap != nil {
+		if err := b.unmap(); err != nil {
+			fmt.Printf("%s unmap failed: %v\n", b, err)
+		}
+		b.unmap = nil
+		b.data = nil
+	}
+}
+
+func (b *Banked) String() string {
+	return fmt.Sprintf("(Banked %dx%dK bank %d)", b.banks, b.bankSize/1024, b.bank)
+}
+
+// Read a byte from the selected bank.
+func (b *Banked) Read(a uint16) byte {
+	return b.data[b.bank*b.bankSize+int(a)]
+}
+
+// Peek reads a byte as for Read, which has no side effects.
+func (b *Banked) Peek(a uint16) (byte, bool) {
+	return b.Read(a), true
+}
+
+// Write a byte to the selected bank.
+func (b *Banked) Write(a uint16, value byte) {
+	b.data[b.bank*b.bankSize+int(a)] = value
+}
+
+// Size of the window in bytes, i.e. the size of one bank.
+func (b *Banked) Size() int {
+	return b.bankSize
+}
+
+// Bank returns the selected bank.
+func (b *Banked) Bank() int {
+	return b.bank
+}
+
+// SelectBank makes the given bank visible in the window. Bank numbers wrap
+// at the number of banks, as if the unused latch bits were not connected.
+func (b *Banked) SelectBank(bank int) {
+	b.bank = bank % b.banks
+}
+
+// Reset selects bank 0.
+func (b *Banked) Reset() {
+	b.bank = 0
+}
+
+// Snapshot returns the selected bank followed by the contents of all banks.
+func (b *Banked) Snapshot() ([]byte, error) {
+	return append([]byte{byte(b.bank)}, b.data...), nil
+}
+
+// Restore the selected bank and contents from a snapshot.
+func (b *Banked) Restore(data []byte) error {
+	if len(data) != len(b.data)+1 {
+		return fmt.Errorf("Snapshot of %d bytes does not fit %s", len(data), b)
+	}
+	b.SelectBank(int(data[0]))
+	copy(b.data, data[1:])
+	return nil
+}
+
+// Latch returns the paging register which selects the visible bank.
+func (b *Banked) Latch() *BankLatch {
+	return &BankLatch{banked: b}
+}
+
+// bankSelector is implemented by devices with a BankLatch.
+type bankSelector interface {
+	Bank() int
+	SelectBank(int)
+}
+
+// BankLatch is a single byte paging register. Writing to it selects the bank
+// visible in the Banked or Cartridge window, reading returns the selected
+// bank.
+type BankLatch struct {
+	banked bankSelector
+}
+
+// Shutdown is part of the Memory interface, but takes no action for BankLatch.
+func (l *BankLatch) Shutdown() {
+}
+
+func (l *BankLatch) String() string {
+	return "(Bank latch)"
+}
+
+// Read returns the selected bank.
+func (l *BankLatch) Read(_ uint16) byte {
+	return byte(l.banked.Bank())
+}
+
+// Peek returns the selected bank as for Read.
+func (l *BankLatch) Peek(_ uint16) (byte, bool) {
+	return l.Read(0), true
+}
+
+// Write selects the bank.
+func (l *BankLatch) Write(_ uint16, value byte) {
+	l.banked.SelectBank(int(value))
+}
+
+// Size of the latch, a single byte.
+func (l *BankLatch) Size() int {
+	return 1
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBankedSelectsBankViaLatch(t *testing.T) {
+	banked, err := NewBanked(0x1000, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latch := banked.Latch()
+
+	for bank := 0; bank < 4; bank++ {
+		latch.Write(0, byte(bank))
+		banked.Write(0x0010, byte(0xA0+bank))
+	}
+
+	for bank := 0; bank < 4; bank++ {
+		latch.Write(0, byte(bank))
+		if v := banked.Read(0x0010); v != byte(0xA0+bank) {
+			t.Error(fmt.Sprintf("bank %d read $%02X expected $%02X", bank, v, 0xA0+bank))
+		}
+	}
+
+	// Unconnected latch bits are ignored.
+	latch.Write(0, 5)
+	if latch.Read(0) != 1 {
+		t.Error(fmt.Sprintf("expected bank 1 got %d", latch.Read(0)))
+	}
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// Cartridge is a ROM image larger than its window on the bus, such as a
+// homebrew cartridge. The window shows one bank of the image at a time,
+// selected by writing to its BankLatch, which is attached to the bus
+// separately.
+type Cartridge struct {
+	name     string
+	bankSize int
+	banks    int
+	bank     int
+	data     []byte
+	onWrite  WriteHandler
+}
+
+// CartridgeFromFile loads a cartridge image divided into banks of bankSize
+// bytes, with bank 0 selected.
+func CartridgeFromFile(path string, bankSize int) (*Cartridge, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bankSize < 1 || bankSize > 0x10000 || len(data) == 0 || len(data)%bankSize != 0 {
+		return nil, fmt.Errorf("Image %s of %d bytes is not a multiple of bank size %d", path, len(data), bankSize)
+	}
+	return &Cartridge{
+		name:     path,
+		bankSize: bankSize,
+		banks:    len(data) / bankSize,
+		data:     data,
+	}, nil
+}
+
+// Shutdown is part of the Memory interface, but takes no action for Cartridge.
+func (c *Cartridge) Shutdown() {
+}
+
+func (c *Cartridge) String() string {
+	return fmt.Sprintf("(Cartridge %s %dx%dK bank %d)", c.name, c.banks, c.bankSize/1024, c.bank)
+}
+
+// Read a byte from the selected bank.
+func (c *Cartridge) Read(a uint16) byte {
+	return c.data[c.bank*c.bankSize+int(a)]
+}
+
+// Peek reads a byte as for Read, which has no side effects.
+func (c *Cartridge) Peek(a uint16) (byte, bool) {
+	return c.Read(a), true
+}
+
+// Write is passed to the handler set with OnWrite, otherwise it will cause
+// an error as the cartridge is read-only.
+func (c *Cartridge) Write(a uint16, value byte) {
+	if c.onWrite == nil {
+		panic(fmt.Sprintf("%v is read-only", c))
+	}
+	c.onWrite(a, value)
+}
+
+// OnWrite sets a handler to report writes, which are then ignored.
+func (c *Cartridge) OnWrite(fn WriteHandler) {
+	c.onWrite = fn
+}
+
+// Poke writes a byte into the selected bank, e.g. when loading a program.
+func (c *Cartridge) Poke(a uint16, value byte) error {
+	c.data[c.bank*c.bankSize+int(a)] = value
+	return nil
+}
+
+// Size of the window in bytes, i.e. the size of one bank.
+func (c *Cartridge) Size() int {
+	return c.bankSize
+}
+
+// Bank returns the selected bank.
+func (c *Cartridge) Bank() int {
+	return c.bank
+}
+
+// SelectBank makes the given bank visible in the window. Bank numbers wrap
+// at the number of banks.
+func (c *Cartridge) SelectBank(bank int) {
+	c.bank = bank % c.banks
+}
+
+// Reset selects bank 0.
+func (c *Cartridge) Reset() {
+	c.bank = 0
+}
+
+// Snapshot returns the selected bank.
+func (c *Cartridge) Snapshot() ([]byte, error) {
+	return []byte{byte(c.bank)}, nil
+}
+
+// Restore the selected bank from a snapshot.
+func (c *Cartridge) Restore(data []byte) error {
+	if len(data) != 1 {
+		return fmt.Errorf("Invalid %s snapshot of %d bytes", c, len(data))
+	}
+	c.SelectBank(int(data[0]))
+	return nil
+}
+
+// Latch returns the register which selects the visible bank.
+func (c *Cartridge) Latch() *BankLatch {
+	return &BankLatch{banked: c}
+}
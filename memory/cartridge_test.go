@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCartridgeSelectsBankViaLatch(t *testing.T) {
+	path := t.TempDir() + "/cart.bin"
+	image := make([]byte, 4*0x2000)
+	for bank := 0; bank < 4; bank++ {
+		image[bank*0x2000] = byte(0xC0 + bank)
+	}
+	if err := ioutil.WriteFile(path, image, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := CartridgeFromFile(path, 0x2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latch := cart.Latch()
+
+	for _, bank := range []int{2, 0, 3, 5} {
+		latch.Write(0, byte(bank))
+		expected := byte(0xC0 + bank%4)
+		if v := cart.Read(0); v != expected {
+			t.Error(fmt.Sprintf("bank %d expected $%02X got $%02X", bank, expected, v))
+		}
+	}
+
+	if _, err := CartridgeFromFile(path, 0x3000); err == nil {
+		t.Error("expected error for image not a multiple of bank size")
+	}
+}
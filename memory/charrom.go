@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"fmt"
+)
+
+// CharRom is a character generator ROM holding the glyphs of a character set,
+// each 8 pixels wide and Height rows high, one byte per row with the most
+// significant bit leftmost. It can be attached to the bus like any Rom, or
+// kept outside the cpu visible map and queried by video devices with Glyph.
+type CharRom struct {
+	*Rom
+	height int
+}
+
+// CharRomFromFile loads a character ROM with glyphs of the given height.
+func CharRomFromFile(path string, height int) (*CharRom, error) {
+	rom, err := RomFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if height < 1 || rom.Size() == 0 || rom.Size()%height != 0 {
+		return nil, fmt.Errorf("Character ROM %s of %d bytes is not a multiple of glyph height %d", path, rom.Size(), height)
+	}
+	return &CharRom{Rom: rom, height: height}, nil
+}
+
+// NewCharRom creates a character ROM from glyph data, e.g. a font compiled
+// into a video device.
+func NewCharRom(name string, data []byte, height int) (*CharRom, error) {
+	if height < 1 || len(data) == 0 || len(data)%height != 0 {
+		return nil, fmt.Errorf("Character ROM %s of %d bytes is not a multiple of glyph height %d", name, len(data), height)
+	}
+	return &CharRom{
+		Rom:    &Rom{name: name, size: len(data), data: append([]byte(nil), data...)},
+		height: height,
+	}, nil
+}
+
+func (c *CharRom) String() string {
+	return fmt.Sprintf("CharROM[%d glyphs 8x%d:%s]", c.Glyphs(), c.height, c.name)
+}
+
+// Height of each glyph in rows.
+func (c *CharRom) Height() int {
+	return c.height
+}
+
+// Glyphs returns the number of glyphs in the ROM.
+func (c *CharRom) Glyphs() int {
+	return c.size / c.height
+}
+
+// Glyph returns the rows of a glyph. Codes beyond the end of the ROM wrap,
+// as they would with unconnected address lines.
+func (c *CharRom) Glyph(code int) []byte {
+	start := (code % c.Glyphs()) * c.height
+	return c.data[start : start+c.height]
+}
+
+// Pixel returns true if the pixel at x, y of a glyph is set.
+func (c *CharRom) Pixel(code, x, y int) bool {
+	return c.Glyph(code)[y]&(0x80>>uint(x)) != 0
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCharRomGlyphs(t *testing.T) {
+	data := make([]byte, 2*8)
+	data[8] = 0x81 // top row of glyph 1
+	rom, err := NewCharRom("test", data, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rom.Glyphs() != 2 {
+		t.Error(fmt.Sprintf("expected 2 glyphs got %d", rom.Glyphs()))
+	}
+	if !rom.Pixel(1, 0, 0) || !rom.Pixel(1, 7, 0) || rom.Pixel(1, 1, 0) {
+		t.Error(fmt.Sprintf("unexpected glyph 1 %08b", rom.Glyph(1)[0]))
+	}
+	if rom.Glyph(3)[0] != 0x81 {
+		t.Error("expected glyph codes to wrap")
+	}
+
+	// Attached to the bus it reads like any ROM
+	if v := rom.Read(8); v != 0x81 {
+		t.Error(fmt.Sprintf("expected $81 got $%02X", v))
+	}
+
+	if _, err := NewCharRom("bad", make([]byte, 10), 8); err == nil {
+		t.Error("expected error for partial glyph")
+	}
+}
+
+func TestCharRomFromEmptyFile(t *testing.T) {
+	file := t.TempDir() + "/empty.rom"
+	ioutil.WriteFile(file, nil, 0640)
+	if _, err := CharRomFromFile(file, 8); err == nil {
+		t.Error("expected error for an empty file")
+	}
+}
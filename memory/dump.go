@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DumpOptions select the format and range of a memory dump.
+type DumpOptions struct {
+	// Format is "binary" (the default), "hexdump", "ihex" or "srec".
+	Format string
+	// Start and End are the inclusive range dumped, relative to the start of
+	// the device. If both are zero the whole device is dumped.
+	Start uint16
+	End   uint16
+	// Base is the bus address of the device, added to the addresses written
+	// to hexdump, ihex and srec dumps and subtracted when loading them.
+	Base uint16
+}
+
+// dumpRecordSize is the number of bytes per line of text dumps.
+const dumpRecordSize = 16
+
+// DumpTo writes the RAM contents to w.
+func (mem *Ram) DumpTo(w io.Writer, opts DumpOptions) error {
+	start, end := int(opts.Start), int(opts.End)
+	if start == 0 && end == 0 {
+		end = len(mem.data) - 1
+	}
+	if start > end || end >= len(mem.data) {
+		return fmt.Errorf("Invalid dump range 0x%04X-0x%04X for %s", start, end, mem)
+	}
+	data := mem.data[start : end+1]
+	address := opts.Base + uint16(start)
+
+	switch opts.Format {
+	case "", "binary":
+		_, err := w.Write(data)
+		return err
+	case "hexdump":
+		return WriteHexdump(w, address, data)
+	case "ihex":
+		return WriteIHex(w, address, data)
+	case "srec":
+		return WriteSRec(w, address, data)
+	default:
+		return fmt.Errorf("Unknown dump format %q", opts.Format)
+	}
+}
+
+// DumpFile writes the RAM contents to a file.
+func (mem *Ram) DumpFile(path string, opts DumpOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = mem.DumpTo(w, opts)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Load reads a dump written by DumpFile back into the RAM. Binary dumps are
+// loaded at opts.Start.
+func (mem *Ram) Load(path string, opts DumpOptions) (Program, error) {
+	format := opts.Format
+	if format == "" {
+		format = "binary"
+	}
+	if format == "binary" {
+		format = "raw"
+	}
+	return LoadFile(path, format, opts.Base+opts.Start, offsetPoker{mem: mem, base: opts.Base})
+}
+
+// offsetPoker translates bus addresses to device addresses.
+type offsetPoker struct {
+	mem  *Ram
+	base uint16
+}
+
+func (p offsetPoker) Poke(a uint16, value byte) error {
+	a -= p.base
+	if int(a) >= p.mem.Size() {
+		return fmt.Errorf("Address 0x%04X outside %s", a+p.base, p.mem)
+	}
+	return p.mem.Poke(a, value)
+}
+
+// WriteHexdump writes data as a hexdump, 16 bytes per line prefixed with the
+// address and followed by the printable characters.
+func WriteHexdump(w io.Writer, address uint16, data []byte) error {
+	for offset := 0; offset < len(data); offset += dumpRecordSize {
+		line := data[offset:min(offset+dumpRecordSize, len(data))]
+
+		var ascii strings.Builder
+		for _, b := range line {
+			if b >= 0x20 && b < 0x7F {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+
+		hexBytes := strings.ToUpper(hex.EncodeToString(line))
+		var spaced strings.Builder
+		for i := 0; i < len(hexBytes); i += 2 {
+			spaced.WriteString(hexBytes[i : i+2])
+			spaced.WriteByte(' ')
+		}
+
+		_, err := fmt.Fprintf(w, "%04X  %-48s |%s|\n", int(address)+offset, spaced.String(), ascii.String())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadHexdump parses a dump written by WriteHexdump.
+func LoadHexdump(r io.Reader, mem Poker) (Program, error) {
+	var program Program
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := s.Text()
+		if i := strings.IndexByte(text, '|'); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		address, err := hex.DecodeString(fields[0])
+		if err != nil || len(address) != 2 {
+			return program, fmt.Errorf("Line %d: Invalid address %q", line, fields[0])
+		}
+		a := uint16(address[0])<<8 | uint16(address[1])
+
+		for i, field := range fields[1:] {
+			b, err := hex.DecodeString(field)
+			if err != nil || len(b) != 1 {
+				return program, fmt.Errorf("Line %d: Invalid byte %q", line, field)
+			}
+			if err := program.poke(mem, a+uint16(i), b[0]); err != nil {
+				return program, fmt.Errorf("Line %d: %v", line, err)
+			}
+		}
+	}
+	return program, s.Err()
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestDumpAndLoadRoundTrip(t *testing.T) {
+	for _, format := range []string{"binary", "hexdump", "ihex", "srec"} {
+		ram := NewRam(0x100)
+		for i := 0; i < 0x100; i++ {
+			ram.Write(uint16(i), byte(i*7))
+		}
+
+		opts := DumpOptions{Format: format, Start: 0x10, End: 0x2F, Base: 0x4000}
+		path := t.TempDir() + "/core." + format
+		if err := ram.DumpFile(path, opts); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded := NewRam(0x100)
+		program, err := loaded.Load(path, opts)
+		if err != nil {
+			t.Fatal(fmt.Sprintf("%s: %v", format, err))
+		}
+		if program.Bytes != 0x20 {
+			t.Error(fmt.Sprintf("%s: expected 32 bytes got %v", format, program))
+		}
+		if !bytes.Equal(loaded.Data()[0x10:0x30], ram.Data()[0x10:0x30]) || loaded.Read(0x0F) != 0 {
+			t.Error(fmt.Sprintf("%s: loaded data does not match", format))
+		}
+	}
+}
+
+func TestWriteHexdump(t *testing.T) {
+	var buf bytes.Buffer
+	WriteHexdump(&buf, 0x0200, []byte("Hi\x00"))
+	expected := "0200  48 69 00                                         |Hi.|\n"
+	if buf.String() != expected {
+		t.Error(fmt.Sprintf("expected %q got %q", expected, buf.String()))
+	}
+}
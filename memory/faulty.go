@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// FaultModel describes the faults injected by Faulty. Addresses are relative
+// to the start of the device.
+type FaultModel struct {
+	StuckHigh  byte         // Bits which always read as 1
+	StuckLow   byte         // Bits which always read as 0
+	FlipRate   float64      // Probability of a read having a random bit flipped
+	ReadErrors []FaultRange // Ranges where reads return random values
+	Seed       int64        // Random seed, so faults are reproducible
+}
+
+// FaultRange is an inclusive range of addresses.
+type FaultRange struct {
+	Start uint16
+	End   uint16
+}
+
+// Faulty wraps a device, injecting faults into the values read, to test how
+// code copes with flaky hardware. Writes are passed through unchanged.
+type Faulty struct {
+	Memory
+	model FaultModel
+	rand  *rand.Rand
+}
+
+// NewFaulty wraps a device with the given fault model.
+func NewFaulty(mem Memory, model FaultModel) *Faulty {
+	return &Faulty{
+		Memory: mem,
+		model:  model,
+		rand:   rand.New(rand.NewSource(model.Seed)),
+	}
+}
+
+func (f *Faulty) String() string {
+	return fmt.Sprintf("(Faulty %v)", f.Memory)
+}
+
+// Read a byte from the device, applying the fault model.
+func (f *Faulty) Read(a uint16) byte {
+	if f.readError(a) {
+		return byte(f.rand.Intn(0x100))
+	}
+	return f.fault(f.Memory.Read(a))
+}
+
+// Peek reads a byte from the device without injecting faults, as doing so
+// would advance the random faults seen by the cpu.
+func (f *Faulty) Peek(a uint16) (byte, bool) {
+	if p, ok := f.Memory.(Peeker); ok {
+		return p.Peek(a)
+	}
+	return 0, false
+}
+
+// ReadBlock reads a block from the device, applying the fault model to each
+// byte as for Read.
+func (f *Faulty) ReadBlock(a uint16, buf []byte) {
+	bm, ok := f.Memory.(BlockMemory)
+	if !ok {
+		for i := range buf {
+			buf[i] = f.Read(a + uint16(i))
+		}
+		return
+	}
+
+	bm.ReadBlock(a, buf)
+	for i, v := range buf {
+		if f.readError(a + uint16(i)) {
+			buf[i] = byte(f.rand.Intn(0x100))
+		} else {
+			buf[i] = f.fault(v)
+		}
+	}
+}
+
+// WriteBlock passes the block through to the device unchanged.
+func (f *Faulty) WriteBlock(a uint16, data []byte) {
+	if bm, ok := f.Memory.(BlockMemory); ok {
+		bm.WriteBlock(a, data)
+		return
+	}
+	for i, v := range data {
+		f.Memory.Write(a+uint16(i), v)
+	}
+}
+
+// readError returns true if reads of a return random values.
+func (f *Faulty) readError(a uint16) bool {
+	for _, r := range f.model.ReadErrors {
+		if a >= r.Start && a <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// fault applies the flipped and stuck bits to a value read.
+func (f *Faulty) fault(v byte) byte {
+	if f.model.FlipRate > 0 && f.rand.Float64() < f.model.FlipRate {
+		v ^= 1 << uint(f.rand.Intn(8))
+	}
+	return (v | f.model.StuckHigh) &^ f.model.StuckLow
+}
+
+// Snapshot returns the snapshot of the device, or nothing if it has no state.
+func (f *Faulty) Snapshot() ([]byte, error) {
+	if s, ok := f.Memory.(Snapshotable); ok {
+		return s.Snapshot()
+	}
+	return nil, nil
+}
+
+// Restore the device from a snapshot.
+func (f *Faulty) Restore(data []byte) error {
+	if s, ok := f.Memory.(Snapshotable); ok {
+		return s.Restore(data)
+	}
+	if len(data) > 0 {
+		return fmt.Errorf("%v cannot be restored", f.Memory)
+	}
+	return nil
+}
+
+// Reset passes the reset on to the device.
+func (f *Faulty) Reset() {
+	if r, ok := f.Memory.(Resetter); ok {
+		r.Reset()
+	}
+}
+
+// Poke passes through to the device so programs load unaffected.
+func (f *Faulty) Poke(a uint16, value byte) error {
+	if p, ok := f.Memory.(Poker); ok {
+		return p.Poke(a, value)
+	}
+	f.Memory.Write(a, value)
+	return nil
+}
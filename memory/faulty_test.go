@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFaultyStuckBitsAndReadErrors(t *testing.T) {
+	ram := NewRam(0x100)
+	f := NewFaulty(ram, FaultModel{
+		StuckHigh:  0x80,
+		StuckLow:   0x01,
+		ReadErrors: []FaultRange{{Start: 0x10, End: 0x1F}},
+	})
+
+	f.Write(0x00, 0x0F)
+	if v := f.Read(0x00); v != 0x8E {
+		t.Error(fmt.Sprintf("expected $8E got $%02X", v))
+	}
+	if ram.Read(0x00) != 0x0F {
+		t.Error("write was not passed through")
+	}
+
+	// Random values in the error range are reproducible from the seed
+	a, b := NewFaulty(ram, f.model), NewFaulty(ram, f.model)
+	for i := uint16(0x10); i < 0x20; i++ {
+		if a.Read(i) != b.Read(i) {
+			t.Error("read errors not reproducible")
+		}
+	}
+}
+
+func TestFaultyFlipsBits(t *testing.T) {
+	f := NewFaulty(NewRam(0x100), FaultModel{FlipRate: 1})
+	for i := uint16(0); i < 0x100; i++ {
+		if v := f.Read(i); v == 0 || v&(v-1) != 0 {
+			t.Error(fmt.Sprintf("expected a single bit flipped at $%02X got $%02X", i, v))
+		}
+	}
+}
+
+func TestFaultyForwardsBlocksAndSnapshots(t *testing.T) {
+	ram := NewRam(0x100)
+	f := NewFaulty(ram, FaultModel{StuckHigh: 0x80})
+
+	f.WriteBlock(0x10, []byte{0x01, 0x02})
+	if ram.Read(0x10) != 0x01 || ram.Read(0x11) != 0x02 {
+		t.Error("block write was not passed through")
+	}
+	buf := make([]byte, 2)
+	f.ReadBlock(0x10, buf)
+	if buf[0] != 0x81 || buf[1] != 0x82 {
+		t.Error(fmt.Sprintf("expected block read to be faulted got % X", buf))
+	}
+
+	snapshot, err := f.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ram.Write(0x10, 0x00)
+	if err := f.Restore(snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if v := ram.Read(0x10); v != 0x01 {
+		t.Error(fmt.Sprintf("expected ram restored to $01 got $%02X", v))
+	}
+
+	// A device without state snapshots as nothing
+	h := NewFaulty(&Handler{Name: "latch", Length: 1}, FaultModel{})
+	if snapshot, err := h.Snapshot(); err != nil || snapshot != nil {
+		t.Error(fmt.Sprintf("expected an empty snapshot got %v %v", snapshot, err))
+	}
+	if err := h.Restore([]byte{1}); err == nil {
+		t.Error("expected restoring a device without state to fail")
+	}
+}
@@ -0,0 +1,60 @@
+package memory
+
+import "fmt"
+
+// Handler is Memory whose reads and writes are delegated to Go functions,
+// for prototyping memory mapped devices or trapping accesses on the host
+// without writing a full chip emulation. Addresses passed to the functions
+// are relative to the start of the handler.
+//
+//	bus.Attach(&memory.Handler{
+//		Name:   "clock",
+//		Length: 1,
+//		OnRead: func(_ uint16) byte { return byte(time.Now().Second()) },
+//	}, "clock", 0x8800)
+type Handler struct {
+	Name    string             // Name shown when describing the handler
+	Length  int                // Size in bytes
+	OnRead  func(uint16) byte  // Called on read, reads return 0 if nil
+	OnWrite func(uint16, byte) // Called on write, writes are ignored if nil
+	OnReset func()             // Called on reset, optional
+	OnClose func()             // Called on shutdown, optional
+}
+
+func (h *Handler) String() string {
+	return fmt.Sprintf("(Handler %s %d bytes)", h.Name, h.Length)
+}
+
+// Shutdown calls OnClose if set.
+func (h *Handler) Shutdown() {
+	if h.OnClose != nil {
+		h.OnClose()
+	}
+}
+
+// Reset calls OnReset if set.
+func (h *Handler) Reset() {
+	if h.OnReset != nil {
+		h.OnReset()
+	}
+}
+
+// Read returns the value from OnRead, or 0 if it is not set.
+func (h *Handler) Read(a uint16) byte {
+	if h.OnRead == nil {
+		return 0
+	}
+	return h.OnRead(a)
+}
+
+// Write passes the value to OnWrite if it is set.
+func (h *Handler) Write(a uint16, value byte) {
+	if h.OnWrite != nil {
+		h.OnWrite(a, value)
+	}
+}
+
+// Size of the handler in bytes.
+func (h *Handler) Size() int {
+	return h.Length
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHandlerDelegates(t *testing.T) {
+	var written []string
+	h := &Handler{
+		Name:    "test",
+		Length:  4,
+		OnRead:  func(a uint16) byte { return byte(a) + 0x10 },
+		OnWrite: func(a uint16, v byte) { written = append(written, fmt.Sprintf("%d=$%02X", a, v)) },
+	}
+
+	if v := h.Read(3); v != 0x13 {
+		t.Error(fmt.Sprintf("expected $13 got $%02X", v))
+	}
+	h.Write(2, 0x42)
+	if len(written) != 1 || written[0] != "2=$42" {
+		t.Error(fmt.Sprintf("expected [2=$42] got %v", written))
+	}
+
+	// Unset functions are safe
+	empty := &Handler{Length: 1}
+	empty.Write(0, 1)
+	empty.Reset()
+	empty.Shutdown()
+	if v := empty.Read(0); v != 0 {
+		t.Error(fmt.Sprintf("expected $00 got $%02X", v))
+	}
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Intel HEX record types
+const (
+	ihexData          = 0x00
+	ihexEOF           = 0x01
+	ihexSegment       = 0x02
+	ihexStartSegment  = 0x03
+	ihexLinear        = 0x04
+	ihexStartLinear   = 0x05
+	ihexMaxAddress    = 0xFFFF
+	ihexMinRecordSize = 5 // length, address, type & checksum
+)
+
+// LoadIHex parses Intel HEX records, writing the data into memory at the
+// encoded addresses. The entry point is taken from any start address record.
+func LoadIHex(r io.Reader, mem Poker) (Program, error) {
+	var (
+		program Program
+		base    uint32 // from extended segment or linear address records
+	)
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		if text[0] != ':' {
+			return program, fmt.Errorf("Line %d: Record does not start with ':'", line)
+		}
+
+		rec, err := hex.DecodeString(text[1:])
+		if err != nil {
+			return program, fmt.Errorf("Line %d: %v", line, err)
+		}
+		if len(rec) < ihexMinRecordSize || len(rec) != int(rec[0])+ihexMinRecordSize {
+			return program, fmt.Errorf("Line %d: Invalid record length", line)
+		}
+
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		if sum != 0 {
+			return program, fmt.Errorf("Line %d: Invalid checksum", line)
+		}
+
+		address := uint32(rec[1])<<8 | uint32(rec[2])
+		data := rec[4 : len(rec)-1]
+
+		switch rec[3] {
+		case ihexData:
+			for i, b := range data {
+				a := base + address + uint32(i)
+				if a > ihexMaxAddress {
+					return program, fmt.Errorf("Line %d: Address 0x%X out of range", line, a)
+				}
+				if err := program.poke(mem, uint16(a), b); err != nil {
+					return program, fmt.Errorf("Line %d: %v", line, err)
+				}
+			}
+
+		case ihexEOF:
+			return program, nil
+
+		case ihexSegment, ihexLinear:
+			if len(data) != 2 {
+				return program, fmt.Errorf("Line %d: Invalid address record", line)
+			}
+			base = uint32(data[0])<<8 | uint32(data[1])
+			if rec[3] == ihexSegment {
+				base <<= 4
+			} else {
+				base <<= 16
+			}
+
+		case ihexStartSegment, ihexStartLinear:
+			if len(data) != 4 {
+				return program, fmt.Errorf("Line %d: Invalid start address record", line)
+			}
+			// Only the low 16 bits of a CS:IP or linear address are meaningful
+			program.Entry = uint16(data[2])<<8 | uint16(data[3])
+			program.HasEntry = true
+
+		default:
+			return program, fmt.Errorf("Line %d: Unknown record type %02X", line, rec[3])
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return program, err
+	}
+	return program, fmt.Errorf("Missing end of file record")
+}
+
+// WriteIHex writes data as Intel HEX records starting at address, followed by
+// an end of file record.
+func WriteIHex(w io.Writer, address uint16, data []byte) error {
+	for offset := 0; offset < len(data); offset += dumpRecordSize {
+		line := data[offset:min(offset+dumpRecordSize, len(data))]
+		a := address + uint16(offset)
+		rec := append([]byte{byte(len(line)), byte(a >> 8), byte(a), ihexData}, line...)
+		if err := writeIHexRecord(w, rec); err != nil {
+			return err
+		}
+	}
+	return writeIHexRecord(w, []byte{0, 0, 0, ihexEOF})
+}
+
+func writeIHexRecord(w io.Writer, rec []byte) error {
+	var sum byte
+	for _, b := range rec {
+		sum += b
+	}
+	_, err := fmt.Fprintf(w, ":%s%02X\n", strings.ToUpper(hex.EncodeToString(rec)), -sum)
+	return err
+}
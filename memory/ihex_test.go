@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadIHex(t *testing.T) {
+	ram := NewRam(0x10000)
+	in := strings.Join([]string{
+		":0402000078D8A2FF09",
+		":0400000500000200F5",
+		":00000001FF",
+	}, "\n")
+
+	program, err := LoadIHex(strings.NewReader(in), ram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x78, 0xD8, 0xA2, 0xFF}
+	for i, b := range expected {
+		if v := ram.Read(0x0200 + uint16(i)); v != b {
+			t.Error(fmt.Sprintf("$%04X expected $%02X got $%02X", 0x0200+i, b, v))
+		}
+	}
+
+	if s := program.String(); s != "4 bytes $0200-$0203 entry $0200" {
+		t.Error(fmt.Sprintf("unexpected program %s", s))
+	}
+}
+
+func TestLoadIHexRejectsBadChecksum(t *testing.T) {
+	ram := NewRam(0x10000)
+	_, err := LoadIHex(strings.NewReader(":0402000078D8A2FF0A\n:00000001FF"), ram)
+	if err == nil {
+		t.Error("expected checksum error")
+	}
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Poker is implemented by memory which can be written to bypassing any write
+// protection or side effects, e.g. to load a program into ROM.
+type Poker interface {
+	Poke(a uint16, value byte) error
+}
+
+// Program describes the memory written by a loader.
+type Program struct {
+	Low      uint16 // Lowest address written
+	High     uint16 // Highest address written
+	Bytes    int    // Number of bytes written
+	Entry    uint16 // Execution start address, if HasEntry is set
+	HasEntry bool
+}
+
+func (p Program) String() string {
+	s := fmt.Sprintf("%d bytes $%04X-$%04X", p.Bytes, p.Low, p.High)
+	if p.HasEntry {
+		s += fmt.Sprintf(" entry $%04X", p.Entry)
+	}
+	return s
+}
+
+// SetResetVector points the reset vector at the entry point.
+func (p Program) SetResetVector(mem Poker) error {
+	if !p.HasEntry {
+		return fmt.Errorf("Program has no entry point")
+	}
+	if err := mem.Poke(0xFFFC, byte(p.Entry)); err != nil {
+		return err
+	}
+	return mem.Poke(0xFFFD, byte(p.Entry>>8))
+}
+
+// poke writes a byte, tracking the range written.
+func (p *Program) poke(mem Poker, a uint16, value byte) error {
+	if err := mem.Poke(a, value); err != nil {
+		return err
+	}
+	if p.Bytes == 0 || a < p.Low {
+		p.Low = a
+	}
+	if p.Bytes == 0 || a > p.High {
+		p.High = a
+	}
+	p.Bytes++
+	return nil
+}
+
+// LoadFile loads a program file into memory. The format is "ihex", "srec",
+// "hexdump" or "raw", or if empty is determined by the file extension. Raw binaries are
+// loaded at address, which is ignored by the other formats.
+func LoadFile(path, format string, address uint16, mem Poker) (Program, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".hex", ".ihx", ".ihex":
+			format = "ihex"
+		case ".s19", ".s28", ".s37", ".srec", ".mot":
+			format = "srec"
+		case ".hexdump", ".txt":
+			format = "hexdump"
+		case ".bin", ".raw":
+			format = "raw"
+		default:
+			return Program{}, fmt.Errorf("Unknown format for %s", path)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return Program{}, err
+	}
+	defer f.Close()
+
+	switch format {
+	case "ihex":
+		return LoadIHex(f, mem)
+	case "srec":
+		return LoadSRec(f, mem)
+	case "hexdump":
+		return LoadHexdump(f, mem)
+	case "raw":
+		return LoadRaw(f, address, mem)
+	default:
+		return Program{}, fmt.Errorf("Unknown format %q", format)
+	}
+}
@@ -0,0 +1,59 @@
+package memory
+
+// LongMemory is Memory addressed with up to 24 bits, for devices on a
+// bus.LongBus such as the memory of a 65816 or large banked systems.
+type LongMemory interface {
+	Shutdown()
+	ReadLong(uint32) byte
+	WriteLong(uint32, byte)
+	Size() int
+}
+
+// Long adapts 16-bit Memory to the LongMemory interface. Devices which
+// already implement LongMemory are returned unchanged.
+func Long(m Memory) LongMemory {
+	if l, ok := m.(LongMemory); ok {
+		return l
+	}
+	return longAdapter{m}
+}
+
+type longAdapter struct {
+	Memory
+}
+
+func (l longAdapter) ReadLong(a uint32) byte {
+	return l.Read(uint16(a))
+}
+
+func (l longAdapter) WriteLong(a uint32, value byte) {
+	l.Write(uint16(a), value)
+}
+
+// LongRam is read/write memory of up to 16MB, addressed with 24 bits.
+type LongRam []byte
+
+// NewLongRam creates LongRam of the given size in bytes.
+func NewLongRam(size int) *LongRam {
+	ram := make(LongRam, size)
+	return &ram
+}
+
+// Shutdown is part of the LongMemory interface, but takes no action for LongRam.
+func (r *LongRam) Shutdown() {
+}
+
+// ReadLong reads a byte from a 24-bit address.
+func (r *LongRam) ReadLong(a uint32) byte {
+	return (*r)[a]
+}
+
+// WriteLong writes a byte to a 24-bit address.
+func (r *LongRam) WriteLong(a uint32, value byte) {
+	(*r)[a] = value
+}
+
+// Size of the RAM in bytes.
+func (r *LongRam) Size() int {
+	return len(*r)
+}
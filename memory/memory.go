@@ -11,3 +11,38 @@ type Memory interface {
 	Write(uint16, byte)
 	Size() int
 }
+
+// Resetter is implemented by devices which respond to the RESB line,
+// e.g. I/O controllers which clear their registers on reset.
+type Resetter interface {
+	Reset()
+}
+
+// Snapshotable is implemented by devices whose state can be saved and later
+// restored, e.g. RAM contents or device registers.
+type Snapshotable interface {
+	Snapshot() ([]byte, error)
+	Restore([]byte) error
+}
+
+// DebugState is implemented by devices which can describe their internal
+// state for the debugger, e.g. control registers, buffers and timers which
+// reading their address range doesn't reveal.
+type DebugState interface {
+	DebugState() string
+}
+
+// Peeker is implemented by devices which can be read without side effects,
+// e.g. by the debugger, which must not consume a received byte or clear a
+// flag just by looking. Peek returns false if the address can't be read that
+// way.
+type Peeker interface {
+	Peek(a uint16) (byte, bool)
+}
+
+// BlockMemory is implemented by devices which can read or write a block of
+// bytes more efficiently than one at a time, e.g. RAM.
+type BlockMemory interface {
+	ReadBlock(a uint16, buf []byte)
+	WriteBlock(a uint16, data []byte)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package memory
+
+import "errors"
+
+func mapFile(_ string, _ int) ([]byte, func() error, error) {
+	return nil, nil, errors.New("Memory mapped RAM is not supported on this platform")
+}
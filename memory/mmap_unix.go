@@ -0,0 +1,34 @@
+//go:build unix
+
+package memory
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps size bytes of a file into memory, creating or extending the
+// file as required. Writes to the mapping are written back to the file.
+func mapFile(path string, size int) ([]byte, func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() < int64(size) {
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Overlay is two devices sharing an address range, e.g. ROM which can be
+// banked out to reveal RAM underneath. Reads come from Over while the overlay
+// is enabled, otherwise from Under. The overlay is switched by writing to
+// its OverlayLatch, which is attached to the bus separately.
+type Overlay struct {
+	over    Memory
+	under   Memory
+	enabled bool
+	// WriteThrough sends writes to Under even while the overlay is enabled,
+	// as on machines where writes to ROM land in the RAM beneath it.
+	WriteThrough bool
+}
+
+// NewOverlay creates an overlay of two equally sized devices, with over
+// enabled.
+func NewOverlay(over, under Memory) (*Overlay, error) {
+	if over.Size() != under.Size() {
+		return nil, fmt.Errorf("Overlay size %d does not match %d", over.Size(), under.Size())
+	}
+	return &Overlay{over: over, under: under, enabled: true}, nil
+}
+
+// Shutdown both devices.
+func (o *Overlay) Shutdown() {
+	o.over.Shutdown()
+	o.under.Shutdown()
+}
+
+func (o *Overlay) String() string {
+	return fmt.Sprintf("(Overlay %v over %v enabled %v)", o.over, o.under, o.enabled)
+}
+
+// selected returns the device currently visible.
+func (o *Overlay) selected() Memory {
+	if o.enabled {
+		return o.over
+	}
+	return o.under
+}
+
+// Read a byte from the visible device.
+func (o *Overlay) Read(a uint16) byte {
+	return o.selected().Read(a)
+}
+
+// Peek reads a byte from the visible device, if it can be read without side
+// effects.
+func (o *Overlay) Peek(a uint16) (byte, bool) {
+	if p, ok := o.selected().(Peeker); ok {
+		return p.Peek(a)
+	}
+	return 0, false
+}
+
+// Write a byte to the visible device, or to Under if WriteThrough is set.
+func (o *Overlay) Write(a uint16, value byte) {
+	if o.WriteThrough {
+		o.under.Write(a, value)
+	} else {
+		o.selected().Write(a, value)
+	}
+}
+
+// Poke writes a byte to the visible device bypassing write protection.
+func (o *Overlay) Poke(a uint16, value byte) error {
+	if p, ok := o.selected().(Poker); ok {
+		return p.Poke(a, value)
+	}
+	o.selected().Write(a, value)
+	return nil
+}
+
+// Size of the overlay in bytes.
+func (o *Overlay) Size() int {
+	return o.over.Size()
+}
+
+// Enabled returns true if Over is visible.
+func (o *Overlay) Enabled() bool {
+	return o.enabled
+}
+
+// Enable makes Over visible, or Under if false.
+func (o *Overlay) Enable(enabled bool) {
+	o.enabled = enabled
+}
+
+// Reset enables the overlay, and resets both devices.
+func (o *Overlay) Reset() {
+	o.enabled = true
+	for _, m := range []Memory{o.over, o.under} {
+		if r, ok := m.(Resetter); ok {
+			r.Reset()
+		}
+	}
+}
+
+// Snapshot returns whether the overlay is enabled followed by the snapshots
+// of both devices, each prefixed by its length.
+func (o *Overlay) Snapshot() ([]byte, error) {
+	data := []byte{0}
+	if o.enabled {
+		data[0] = 1
+	}
+	for _, m := range []Memory{o.over, o.under} {
+		var snapshot []byte
+		if s, ok := m.(Snapshotable); ok {
+			var err error
+			snapshot, err = s.Snapshot()
+			if err != nil {
+				return nil, err
+			}
+		}
+		data = binary.BigEndian.AppendUint32(data, uint32(len(snapshot)))
+		data = append(data, snapshot...)
+	}
+	return data, nil
+}
+
+// Restore the overlay and both devices from a snapshot.
+func (o *Overlay) Restore(data []byte) error {
+	if len(data) < 1 {
+		return fmt.Errorf("Invalid overlay snapshot")
+	}
+	o.enabled = data[0] != 0
+	data = data[1:]
+	for _, m := range []Memory{o.over, o.under} {
+		if len(data) < 4 {
+			return fmt.Errorf("Invalid overlay snapshot")
+		}
+		n := int(binary.BigEndian.Uint32(data))
+		if len(data) < 4+n {
+			return fmt.Errorf("Invalid overlay snapshot")
+		}
+		if s, ok := m.(Snapshotable); ok && n > 0 {
+			if err := s.Restore(data[4 : 4+n]); err != nil {
+				return err
+			}
+		}
+		data = data[4+n:]
+	}
+	return nil
+}
+
+// Latch returns the register which switches the overlay.
+func (o *Overlay) Latch() *OverlayLatch {
+	return &OverlayLatch{overlay: o}
+}
+
+// OverlayLatch is a single byte register. Writing a value with bit 0 set
+// enables the overlay, clear reveals the device underneath. Reading returns
+// 1 if the overlay is enabled.
+type OverlayLatch struct {
+	overlay *Overlay
+}
+
+// Shutdown is part of the Memory interface, but takes no action for OverlayLatch.
+func (l *OverlayLatch) Shutdown() {
+}
+
+func (l *OverlayLatch) String() string {
+	return "(Overlay latch)"
+}
+
+// Read returns 1 if the overlay is enabled.
+func (l *OverlayLatch) Read(_ uint16) byte {
+	if l.overlay.enabled {
+		return 1
+	}
+	return 0
+}
+
+// Peek returns whether the overlay is enabled as for Read.
+func (l *OverlayLatch) Peek(_ uint16) (byte, bool) {
+	return l.Read(0), true
+}
+
+// Write enables the overlay if bit 0 is set.
+func (l *OverlayLatch) Write(_ uint16, value byte) {
+	l.overlay.Enable(value&1 != 0)
+}
+
+// Size of the latch, a single byte.
+func (l *OverlayLatch) Size() int {
+	return 1
+}
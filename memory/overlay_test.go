@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOverlaySwitchesViaLatch(t *testing.T) {
+	rom := &Rom{name: "test", size: 2, data: []byte{0xAA, 0xBB}}
+	ram := NewRam(2)
+	overlay, err := NewOverlay(rom, ram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlay.WriteThrough = true
+	latch := overlay.Latch()
+
+	// Writes pass through the rom to the ram beneath
+	overlay.Write(0, 0x11)
+	if v := overlay.Read(0); v != 0xAA {
+		t.Error(fmt.Sprintf("expected rom $AA got $%02X", v))
+	}
+
+	latch.Write(0, 0)
+	if v := overlay.Read(0); v != 0x11 {
+		t.Error(fmt.Sprintf("expected ram $11 got $%02X", v))
+	}
+
+	overlay.Reset()
+	if !overlay.Enabled() || latch.Read(0) != 1 {
+		t.Error("expected reset to enable the overlay")
+	}
+
+	if _, err := NewOverlay(rom, NewRam(4)); err == nil {
+		t.Error("expected error for mismatched sizes")
+	}
+}
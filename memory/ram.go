@@ -1,36 +1,145 @@
 package memory
 
-import "io/ioutil"
+import (
+	"fmt"
+	"io/ioutil"
+)
 
-// Ram (32 KiB)
-type Ram [0x8000]byte
+// PageSize is the size of the pages tracked by Ram.DirtyPages.
+const PageSize = 0x100
 
-// Shutdown is part of the Memory interface, but takes no action for Ram.
+// Ram provides read/write memory of a fixed size.
+type Ram struct {
+	data    []byte
+	dirty   [0x10000 / PageSize / 64]uint64 // bitmap of pages written
+	backing string                          // file the RAM is mapped from
+	unmap   func() error
+}
+
+// NewRam creates Ram of the given size in bytes, up to 64K.
+func NewRam(size int) *Ram {
+	return &Ram{data: make([]byte, size)}
+}
+
+// NewMappedRam creates Ram backed by a memory mapped file, so its contents
+// survive restarts. The file is created or extended to size bytes if needed.
+func NewMappedRam(path string, size int) (*Ram, error) {
+	data, unmap, err := mapFile(path, size)
+	if err != nil {
+		return nil, err
+	}
+	return &Ram{data: data, backing: path, unmap: unmap}, nil
+}
+
+// Shutdown unmaps the backing file, if any, writing back its contents.
+// The memory must not be used afterwards.
 func (r *Ram) Shutdown() {
+	if r.unmap != nil {
+		if err := r.unmap(); err != nil {
+			fmt.Printf("%s unmap failed: %v\n", r, err)
+		}
+		r.unmap = nil
+		r.data = nil
+	}
 }
 
 func (r *Ram) String() string {
-	return "(RAM 32K)"
+	if r.backing != "" {
+		return fmt.Sprintf("(RAM %dK:%s)", r.Size()/1024, r.backing)
+	}
+	return fmt.Sprintf("(RAM %dK)", r.Size()/1024)
 }
 
 // Read a byte from a 16-bit address.
 func (mem *Ram) Read(a uint16) byte {
-	return mem[a]
+	return mem.data[a]
+}
+
+// Peek reads a byte as for Read, which has no side effects.
+func (mem *Ram) Peek(a uint16) (byte, bool) {
+	return mem.data[a], true
 }
 
 // Write a byte to a 16-bit address.
 func (mem *Ram) Write(a uint16, value byte) {
-	mem[a] = value
+	mem.data[a] = value
+	mem.markDirty(a)
+}
+
+// Poke writes a byte, meeting the Poker interface.
+func (mem *Ram) Poke(a uint16, value byte) error {
+	mem.Write(a, value)
+	return nil
+}
+
+// ReadBlock copies len(buf) bytes starting at address a into buf.
+func (mem *Ram) ReadBlock(a uint16, buf []byte) {
+	copy(buf, mem.data[a:])
+}
+
+// WriteBlock copies data into the RAM starting at address a.
+func (mem *Ram) WriteBlock(a uint16, data []byte) {
+	copy(mem.data[a:], data)
+	for page := int(a) / PageSize; page <= (int(a)+len(data)-1)/PageSize; page++ {
+		mem.markDirty(uint16(page * PageSize))
+	}
 }
 
 // Size of the RAM in bytes.
 func (mem *Ram) Size() int {
-	return 0x8000 // 32K
+	return len(mem.data)
+}
+
+// Data returns the RAM contents. The slice is shared with the RAM so is only
+// valid until the next write.
+func (mem *Ram) Data() []byte {
+	return mem.data
+}
+
+func (mem *Ram) markDirty(a uint16) {
+	page := a / PageSize
+	mem.dirty[page/64] |= 1 << (page % 64)
+}
+
+// DirtyPages returns the pages, relative to the start of the RAM, which have
+// been written since the last ClearDirty. Page n covers n*PageSize onwards.
+func (mem *Ram) DirtyPages() []int {
+	var pages []int
+	for page := 0; page*PageSize < len(mem.data); page++ {
+		if mem.dirty[page/64]&(1<<uint(page%64)) != 0 {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// ClearDirty marks every page as clean.
+func (mem *Ram) ClearDirty() {
+	for i := range mem.dirty {
+		mem.dirty[i] = 0
+	}
+}
+
+// Snapshot returns a copy of the RAM contents.
+func (mem *Ram) Snapshot() ([]byte, error) {
+	return append([]byte(nil), mem.data...), nil
+}
+
+// Restore the RAM contents from a snapshot. Every page is marked dirty.
+func (mem *Ram) Restore(data []byte) error {
+	if len(data) != len(mem.data) {
+		return fmt.Errorf("Snapshot of %d bytes does not fit %s", len(data), mem)
+	}
+	copy(mem.data, data)
+	for i := range mem.dirty {
+		mem.dirty[i] = ^uint64(0)
+	}
+	return nil
 }
 
 // Dump writes the RAM contents to the specified file path.
 func (mem *Ram) Dump(path string) {
-	err := ioutil.WriteFile(path, mem[:], 0640)
+	err := ioutil.WriteFile(path, mem.data, 0640)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,44 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRamDirtyPages(t *testing.T) {
+	ram := NewRam(0x8000)
+	ram.Write(0x0010, 1)
+	ram.Write(0x00FF, 1)
+	ram.Write(0x4100, 1)
+	ram.Poke(0x7FFF, 1)
+
+	expected := "[0 65 127]"
+	if pages := fmt.Sprint(ram.DirtyPages()); pages != expected {
+		t.Error(fmt.Sprintf("expected %s got %s", expected, pages))
+	}
+
+	ram.ClearDirty()
+	if pages := ram.DirtyPages(); len(pages) != 0 {
+		t.Error(fmt.Sprintf("expected no dirty pages got %v", pages))
+	}
+}
+
+func TestMappedRamPersists(t *testing.T) {
+	path := t.TempDir() + "/ram.bin"
+
+	ram, err := NewMappedRam(path, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ram.Write(0x0123, 0x42)
+	ram.Shutdown()
+
+	ram, err = NewMappedRam(path, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ram.Shutdown()
+	if v := ram.Read(0x0123); v != 0x42 {
+		t.Error(fmt.Sprintf("expected $42 got $%02X", v))
+	}
+}
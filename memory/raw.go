@@ -0,0 +1,28 @@
+package memory
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LoadRaw writes a raw binary into memory starting at address, which is also
+// its entry point.
+func LoadRaw(r io.Reader, address uint16, mem Poker) (Program, error) {
+	program := Program{Entry: address, HasEntry: true}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return program, err
+	}
+	if int(address)+len(data) > 0x10000 {
+		return program, fmt.Errorf("%d bytes at 0x%04X exceeds address space", len(data), address)
+	}
+
+	for i, b := range data {
+		if err := program.poke(mem, address+uint16(i), b); err != nil {
+			return program, err
+		}
+	}
+	return program, nil
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestLoadRawAndSetResetVector(t *testing.T) {
+	ram := NewRam(0x10000)
+	program, err := LoadRaw(bytes.NewReader([]byte{0xEA, 0x60}), 0x0800, ram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ram.Read(0x0800) != 0xEA || ram.Read(0x0801) != 0x60 {
+		t.Error("program not loaded at $0800")
+	}
+
+	if err := program.SetResetVector(ram); err != nil {
+		t.Fatal(err)
+	}
+	if v := uint16(ram.Read(0xFFFD))<<8 | uint16(ram.Read(0xFFFC)); v != 0x0800 {
+		t.Error(fmt.Sprintf("expected reset vector $0800 got $%04X", v))
+	}
+
+	if _, err := LoadRaw(bytes.NewReader([]byte{1, 2}), 0xFFFF, ram); err == nil {
+		t.Error("expected error loading beyond $FFFF")
+	}
+}
@@ -8,11 +8,16 @@ import (
 
 // A Rom provides read-only memory, with data generally pre-loaded from a file.
 type Rom struct {
-	name string
-	size int // bytes
-	data []byte
+	name    string
+	size    int // bytes
+	data    []byte
+	onWrite WriteHandler
 }
 
+// WriteHandler is called when a Rom is written to, with the address relative
+// to the start of the Rom and the value written.
+type WriteHandler func(a uint16, value byte)
+
 // Shutdown is part of the Memory interface, but takes no action for Rom.
 func (r *Rom) Shutdown() {
 }
@@ -22,6 +27,11 @@ func (rom *Rom) Read(a uint16) byte {
 	return rom.data[a]
 }
 
+// Peek reads a byte as for Read, which has no side effects.
+func (rom *Rom) Peek(a uint16) (byte, bool) {
+	return rom.data[a], true
+}
+
 // Create a new ROM, loading the contents from a file.
 // The size of the ROM is determined by the size of the file.
 func RomFromFile(path string) (*Rom, error) {
@@ -32,6 +42,16 @@ func RomFromFile(path string) (*Rom, error) {
 	return &Rom{name: path, size: len(data), data: data}, nil
 }
 
+// Data returns the contents of the Rom.
+func (r *Rom) Data() []byte {
+	return r.data
+}
+
+// NewRom creates a ROM from an image, e.g. one assembled from several files.
+func NewRom(name string, data []byte) *Rom {
+	return &Rom{name: name, size: len(data), data: data}
+}
+
 // Size of the Rom in bytes.
 func (r *Rom) Size() int {
 	return r.size
@@ -45,8 +65,24 @@ func (r *Rom) String() string {
 		hex.EncodeToString(r.data[len(r.data)-2:]))
 }
 
-// Rom meets the go6502.Memory interface, but Write is not supported, and will
-// cause an error.
-func (r *Rom) Write(_ uint16, _ byte) {
-	panic(fmt.Sprintf("%v is read-only", r))
+// Rom meets the go6502.Memory interface, but Write is not supported. The
+// write is passed to the handler set with OnWrite, otherwise it will cause
+// an error.
+func (r *Rom) Write(a uint16, value byte) {
+	if r.onWrite == nil {
+		panic(fmt.Sprintf("%v is read-only", r))
+	}
+	r.onWrite(a, value)
+}
+
+// Poke writes a byte into the Rom, e.g. when loading a program.
+func (r *Rom) Poke(a uint16, value byte) error {
+	r.data[a] = value
+	return nil
+}
+
+// OnWrite sets a handler to report writes, which are then ignored rather than
+// causing an error.
+func (r *Rom) OnWrite(fn WriteHandler) {
+	r.onWrite = fn
 }
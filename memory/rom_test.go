@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRomWriteHandler(t *testing.T) {
+	rom := &Rom{name: "test", size: 4, data: []byte{1, 2, 3, 4}}
+
+	var writes []string
+	rom.OnWrite(func(a uint16, value byte) {
+		writes = append(writes, fmt.Sprintf("%d=$%02X", a, value))
+	})
+	rom.Write(2, 0xFF)
+
+	if len(writes) != 1 || writes[0] != "2=$FF" {
+		t.Error(fmt.Sprintf("expected [2=$FF] got %v", writes))
+	}
+	if v := rom.Read(2); v != 3 {
+		t.Error(fmt.Sprintf("expected rom unchanged got $%02X", v))
+	}
+}
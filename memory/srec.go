@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LoadSRec parses Motorola S-records (S19, S28 or S37), writing the data into
+// memory at the encoded addresses. The entry point is taken from any S7, S8
+// or S9 termination record.
+func LoadSRec(r io.Reader, mem Poker) (Program, error) {
+	var program Program
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" {
+			continue
+		}
+		if len(text) < 4 || text[0] != 'S' {
+			return program, fmt.Errorf("Line %d: Record does not start with 'S'", line)
+		}
+
+		rec, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return program, fmt.Errorf("Line %d: %v", line, err)
+		}
+		if len(rec) < 2 || len(rec) != int(rec[0])+1 {
+			return program, fmt.Errorf("Line %d: Invalid record length", line)
+		}
+
+		var sum byte
+		for _, b := range rec {
+			sum += b
+		}
+		if sum != 0xFF {
+			return program, fmt.Errorf("Line %d: Invalid checksum", line)
+		}
+
+		// The address size depends on the record type
+		var addressSize int
+		switch text[1] {
+		case '0', '1', '5', '9':
+			addressSize = 2
+		case '2', '6', '8':
+			addressSize = 3
+		case '3', '7':
+			addressSize = 4
+		default:
+			return program, fmt.Errorf("Line %d: Unknown record type S%c", line, text[1])
+		}
+		if len(rec) < addressSize+2 {
+			return program, fmt.Errorf("Line %d: Invalid record length", line)
+		}
+
+		var address uint32
+		for _, b := range rec[1 : 1+addressSize] {
+			address = address<<8 | uint32(b)
+		}
+		data := rec[1+addressSize : len(rec)-1]
+
+		switch text[1] {
+		case '1', '2', '3':
+			for i, b := range data {
+				a := address + uint32(i)
+				if a > 0xFFFF {
+					return program, fmt.Errorf("Line %d: Address 0x%X out of range", line, a)
+				}
+				if err := program.poke(mem, uint16(a), b); err != nil {
+					return program, fmt.Errorf("Line %d: %v", line, err)
+				}
+			}
+
+		case '7', '8', '9':
+			program.Entry = uint16(address)
+			program.HasEntry = true
+			return program, nil
+		}
+	}
+
+	return program, s.Err()
+}
+
+// WriteSRec writes data as S19 records starting at address, followed by an
+// S9 termination record.
+func WriteSRec(w io.Writer, address uint16, data []byte) error {
+	for offset := 0; offset < len(data); offset += dumpRecordSize {
+		line := data[offset:min(offset+dumpRecordSize, len(data))]
+		a := address + uint16(offset)
+		if err := writeSRecord(w, '1', append([]byte{byte(a >> 8), byte(a)}, line...)); err != nil {
+			return err
+		}
+	}
+	return writeSRecord(w, '9', []byte{byte(address >> 8), byte(address)})
+}
+
+func writeSRecord(w io.Writer, recType byte, body []byte) error {
+	rec := append([]byte{byte(len(body) + 1)}, body...)
+	var sum byte
+	for _, b := range rec {
+		sum += b
+	}
+	_, err := fmt.Fprintf(w, "S%c%s%02X\n", recType, strings.ToUpper(hex.EncodeToString(rec)), ^sum)
+	return err
+}
@@ -0,0 +1,32 @@
+package memory
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLoadSRec(t *testing.T) {
+	ram := NewRam(0x10000)
+	in := strings.Join([]string{
+		"S0050000686929",
+		"S107020078D8A2FF05",
+		"S9030200FA",
+	}, "\n")
+
+	program, err := LoadSRec(strings.NewReader(in), ram)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := ram.Read(0x0203); v != 0xFF {
+		t.Error(fmt.Sprintf("$0203 expected $FF got $%02X", v))
+	}
+	if s := program.String(); s != "4 bytes $0200-$0203 entry $0200" {
+		t.Error(fmt.Sprintf("unexpected program %s", s))
+	}
+
+	if _, err := LoadSRec(strings.NewReader("S107020078D8A2FF06"), ram); err == nil {
+		t.Error("expected checksum error")
+	}
+}
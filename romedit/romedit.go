@@ -0,0 +1,262 @@
+/*
+	Package romedit builds and edits ROM images for go6502.
+
+	An image covers a contiguous range of the address space ending at or
+	below $FFFF. Binary fragments can be spliced into it at absolute addresses,
+	the NMI, RESET and IRQ vectors set, and the image padded to the size of
+	the target chip. A symbol stub file can be written alongside the image so
+	other fragments can be assembled against the addresses used.
+
+	Example
+
+		go6502 romedit -o kernel.rom -base E000 -size 8192 \
+			-splice boot.bin@E000 -splice monitor.bin@F000 \
+			-vector reset=E000 -vector irq=F000 -symbols kernel.inc
+*/
+package romedit
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// vectors maps the 6502 vector names to their addresses.
+var vectors = map[string]uint16{
+	"nmi":   0xFFFA,
+	"reset": 0xFFFC,
+	"irq":   0xFFFE,
+}
+
+// Image is a ROM image mapped at a base address.
+type Image struct {
+	Base    uint16
+	Data    []byte
+	Fill    byte
+	symbols map[string]uint16
+}
+
+// NewImage creates an image of the given size at base, filled with fill.
+func NewImage(base uint16, size int, fill byte) (*Image, error) {
+	if size <= 0 || int(base)+size > 0x10000 {
+		return nil, fmt.Errorf("Invalid image size %d at $%04X", size, base)
+	}
+	img := &Image{Base: base, Fill: fill, symbols: make(map[string]uint16)}
+	img.Data = make([]byte, size)
+	for i := range img.Data {
+		img.Data[i] = fill
+	}
+	return img, nil
+}
+
+// ImageFromFile loads an existing image to edit.
+func ImageFromFile(path string, base uint16, fill byte) (*Image, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if int(base)+len(data) > 0x10000 {
+		return nil, fmt.Errorf("%s does not fit at $%04X", path, base)
+	}
+	return &Image{Base: base, Data: data, Fill: fill, symbols: make(map[string]uint16)}, nil
+}
+
+// End returns the last address covered by the image.
+func (img *Image) End() uint16 {
+	return img.Base + uint16(len(img.Data)-1)
+}
+
+func (img *Image) contains(address uint16, length int) bool {
+	return address >= img.Base && int(address)+length-1 <= int(img.End())
+}
+
+// Splice copies data into the image at an absolute address, recording the
+// address against name for the symbol stubs.
+func (img *Image) Splice(name string, address uint16, data []byte) error {
+	if !img.contains(address, len(data)) {
+		return fmt.Errorf("%s ($%04X-$%04X) outside image $%04X-$%04X",
+			name, address, int(address)+len(data)-1, img.Base, img.End())
+	}
+	copy(img.Data[address-img.Base:], data)
+	img.symbols[name] = address
+	return nil
+}
+
+// SpliceFile splices the contents of a file into the image. The symbol name
+// is taken from the file name without its extension.
+func (img *Image) SpliceFile(path string, address uint16) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return img.Splice(name, address, data)
+}
+
+// SetVector sets one of the nmi, reset or irq vectors to an address.
+func (img *Image) SetVector(name string, address uint16) error {
+	vector, exists := vectors[strings.ToLower(name)]
+	if !exists {
+		return fmt.Errorf("Unknown vector %q, expected nmi, reset or irq", name)
+	}
+	if !img.contains(vector, 2) {
+		return fmt.Errorf("Vector %s at $%04X outside image", name, vector)
+	}
+	img.Data[vector-img.Base] = byte(address)
+	img.Data[vector-img.Base+1] = byte(address >> 8)
+	img.symbols[strings.ToUpper(name)+"_VECTOR"] = address
+	return nil
+}
+
+// Pad extends the image to size bytes using the fill byte.
+func (img *Image) Pad(size int) error {
+	if size < len(img.Data) {
+		return fmt.Errorf("Image is %d bytes, larger than %d", len(img.Data), size)
+	}
+	if int(img.Base)+size > 0x10000 {
+		return fmt.Errorf("Image of %d bytes at $%04X exceeds 64K", size, img.Base)
+	}
+	for len(img.Data) < size {
+		img.Data = append(img.Data, img.Fill)
+	}
+	return nil
+}
+
+// WriteSymbols writes an assembler include file defining a symbol for each
+// spliced fragment and vector, in ca65 syntax.
+func (img *Image) WriteSymbols(w io.Writer) error {
+	var names []string
+	for name := range img.symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s = $%04X\n", name, img.symbols[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save writes the image to a file.
+func (img *Image) Save(path string) error {
+	return ioutil.WriteFile(path, img.Data, 0644)
+}
+
+// ParseAddress parses an address in hex, with an optional $ or 0x prefix.
+func ParseAddress(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(s), "$"), "0x")
+	result, err := strconv.ParseUint(s, 16, 16)
+	return uint16(result), err
+}
+
+// argList collects a repeatable flag.
+type argList []string
+
+func (l *argList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func (l *argList) String() string {
+	return fmt.Sprint(*l)
+}
+
+// Main runs the romedit subcommand with the given arguments.
+func Main(args []string) error {
+	var (
+		splices argList
+		vecs    argList
+	)
+
+	fs := flag.NewFlagSet("romedit", flag.ContinueOnError)
+	in := fs.String("i", "", "Existing image to edit")
+	out := fs.String("o", "", "Image file to write")
+	baseStr := fs.String("base", "", "Base address of the image, e.g. E000")
+	size := fs.Int("size", 0, "Size to pad the image to, defaults to the end of memory")
+	fill := fs.Uint("fill", 0xFF, "Byte used for padding")
+	symbols := fs.String("symbols", "", "Write symbol stubs to this file")
+	fs.Var(&splices, "splice", "Splice a binary into the image, file@address. May be repeated.")
+	fs.Var(&vecs, "vector", "Set a vector, nmi|reset|irq=address. May be repeated.")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || *baseStr == "" {
+		fs.Usage()
+		return fmt.Errorf("-o and -base are required")
+	}
+
+	base, err := ParseAddress(*baseStr)
+	if err != nil {
+		return err
+	}
+	if *size == 0 {
+		*size = 0x10000 - int(base)
+	}
+
+	var img *Image
+	if *in != "" {
+		img, err = ImageFromFile(*in, base, byte(*fill))
+		if err == nil {
+			err = img.Pad(*size)
+		}
+	} else {
+		img, err = NewImage(base, *size, byte(*fill))
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range splices {
+		i := strings.LastIndex(s, "@")
+		if i < 0 {
+			return fmt.Errorf("Invalid splice %q, expected file@address", s)
+		}
+		address, err := ParseAddress(s[i+1:])
+		if err != nil {
+			return err
+		}
+		if err = img.SpliceFile(s[:i], address); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range vecs {
+		i := strings.Index(v, "=")
+		if i < 0 {
+			return fmt.Errorf("Invalid vector %q, expected name=address", v)
+		}
+		address, err := ParseAddress(v[i+1:])
+		if err != nil {
+			return err
+		}
+		if err = img.SetVector(v[:i], address); err != nil {
+			return err
+		}
+	}
+
+	if err = img.Save(*out); err != nil {
+		return err
+	}
+
+	if *symbols != "" {
+		f, err := os.Create(*symbols)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err = img.WriteSymbols(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package romedit
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSpliceAndVectors(t *testing.T) {
+	img, err := NewImage(0xE000, 0x2000, 0xFF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = img.Splice("boot", 0xE010, []byte{0xA9, 0x01}); err != nil {
+		t.Error(err)
+	}
+	if err = img.SetVector("reset", 0xE010); err != nil {
+		t.Error(err)
+	}
+
+	if img.Data[0x10] != 0xA9 || img.Data[0x11] != 0x01 || img.Data[0x12] != 0xFF {
+		t.Error(fmt.Sprintf("splice not applied: % X", img.Data[0x10:0x13]))
+	}
+	if img.Data[0x1FFC] != 0x10 || img.Data[0x1FFD] != 0xE0 {
+		t.Error(fmt.Sprintf("reset vector not set: % X", img.Data[0x1FFC:0x1FFE]))
+	}
+
+	var buf bytes.Buffer
+	img.WriteSymbols(&buf)
+	expected := "RESET_VECTOR = $E010\nboot = $E010\n"
+	if buf.String() != expected {
+		t.Error(fmt.Sprintf("expected symbols %q got %q", expected, buf.String()))
+	}
+
+	if err = img.Splice("overflow", 0xFFFF, []byte{1, 2}); err == nil {
+		t.Error("expected splice past end of image to fail")
+	}
+}
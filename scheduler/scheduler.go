@@ -0,0 +1,229 @@
+/*
+	Package scheduler advances one or more cpu.Cpu instances within a single
+	process, sharing emulated time fairly between them.
+
+	Each round, every running Cpu is stepped until it has consumed a quantum
+	of clock cycles before the next Cpu gets its turn, so a machine spinning
+	in a tight loop can't starve the others. Individual machines can be paused
+	and resumed without affecting the rest.
+*/
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// DefaultQuantum is the number of cycles each Cpu runs for per round.
+const DefaultQuantum = 1000
+
+// idleInterval is how long Run sleeps between rounds while nothing is runnable.
+const idleInterval = 10 * time.Millisecond
+
+// Scheduler runs a set of named Cpu's round-robin by cycle quantum.
+type Scheduler struct {
+	// Quantum is the number of cycles each Cpu is advanced by per round.
+	Quantum uint64
+
+	mutex   sync.Mutex
+	tasks   []*task
+	stopped int32
+}
+
+// task is a scheduled Cpu. The cpu and deadline belong to the goroutine
+// running the scheduler, so pausing and resuming only set flags: paused is
+// accessed atomically so a quantum can be cut short, and restart and idle are
+// guarded by the mutex.
+type task struct {
+	name     string
+	cpu      *cpu.Cpu
+	paused   int32
+	restart  bool   // Restart the deadline from the current cycle count
+	idle     func() // Called each round while paused
+	deadline uint64 // Cpu cycle count at which this task yields
+}
+
+// NewScheduler creates a Scheduler with the given quantum. A quantum of 0
+// uses DefaultQuantum.
+func NewScheduler(quantum uint64) *Scheduler {
+	if quantum == 0 {
+		quantum = DefaultQuantum
+	}
+	return &Scheduler{Quantum: quantum}
+}
+
+// Add a Cpu to the scheduler under the given name.
+func (s *Scheduler) Add(name string, c *cpu.Cpu) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.find(name) != nil {
+		return fmt.Errorf("Machine %s already scheduled", name)
+	}
+	s.tasks = append(s.tasks, &task{name: name, cpu: c, restart: true})
+	return nil
+}
+
+// Remove the named Cpu from the scheduler.
+func (s *Scheduler) Remove(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, t := range s.tasks {
+		if t.name == name {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			return
+		}
+	}
+}
+
+// Pause the named Cpu. It will not be stepped until resumed.
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume a paused Cpu. It restarts from its current cycle count rather than
+// trying to catch up on the time it spent paused.
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+// SetIdle sets fn to be called on the goroutine running the scheduler once a
+// round while the named Cpu is paused, so it can be inspected or stepped
+// without racing the scheduler.
+func (s *Scheduler) SetIdle(name string, fn func()) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := s.find(name)
+	if t == nil {
+		return fmt.Errorf("No machine %s scheduled", name)
+	}
+	t.idle = fn
+	return nil
+}
+
+// Paused returns true if the named Cpu is currently paused.
+func (s *Scheduler) Paused(name string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := s.find(name)
+	return t != nil && t.isPaused()
+}
+
+// Names returns the names of the scheduled Cpu's in the order they are run.
+func (s *Scheduler) Names() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var names []string
+	for _, t := range s.tasks {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := s.find(name)
+	if t == nil {
+		return fmt.Errorf("No machine %s scheduled", name)
+	}
+	if t.isPaused() && !paused {
+		t.restart = true
+	}
+	var flag int32
+	if paused {
+		flag = 1
+	}
+	atomic.StoreInt32(&t.paused, flag)
+	return nil
+}
+
+func (t *task) isPaused() bool {
+	return atomic.LoadInt32(&t.paused) != 0
+}
+
+func (s *Scheduler) find(name string) *task {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// runnable returns the tasks which are not paused, and the idle functions of
+// those which are. A task added or resumed since the last round starts from
+// its current cycle count rather than trying to catch up.
+func (s *Scheduler) runnable() ([]*task, []func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var tasks []*task
+	var idle []func()
+	for _, t := range s.tasks {
+		if t.isPaused() {
+			if t.idle != nil {
+				idle = append(idle, t.idle)
+			}
+			continue
+		}
+		if t.restart {
+			t.deadline = t.cpu.Cycles
+			t.restart = false
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, idle
+}
+
+// Slice runs a single round, advancing each running Cpu by one quantum.
+// Any cycles a Cpu overshoots its quantum by are deducted from its next turn.
+// A Cpu paused part way through its quantum stops at the next instruction.
+// Returns false if there was nothing to run.
+func (s *Scheduler) Slice() bool {
+	tasks, idle := s.runnable()
+	for _, fn := range idle {
+		fn()
+	}
+	for _, t := range tasks {
+		t.deadline += s.Quantum
+		for t.cpu.Cycles < t.deadline {
+			if s.isStopped() {
+				return false
+			}
+			if t.isPaused() {
+				break
+			}
+			t.cpu.Step()
+		}
+	}
+	return len(tasks) > 0
+}
+
+// Run rounds until Stop is called. While every Cpu is paused Run idles rather
+// than returning, so a paused machine can later be resumed.
+func (s *Scheduler) Run() {
+	for !s.isStopped() {
+		if !s.Slice() {
+			time.Sleep(idleInterval)
+		}
+	}
+}
+
+// Stop causes Run to return once the current instruction has completed.
+func (s *Scheduler) Stop() {
+	atomic.StoreInt32(&s.stopped, 1)
+}
+
+func (s *Scheduler) isStopped() bool {
+	return atomic.LoadInt32(&s.stopped) != 0
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/peter-mount/go6502/bus"
+	"github.com/peter-mount/go6502/cpu"
+	"github.com/peter-mount/go6502/memory"
+)
+
+// createCpu returns a Cpu running the given program from $8000 in a loop.
+func createCpu(program ...byte) *cpu.Cpu {
+	addressBus, _ := bus.CreateBus()
+	addressBus.Attach(memory.NewRam(0x8000), "ram", 0x0000)
+	addressBus.Attach(memory.NewRam(0x8000), "rom", 0x8000)
+	for i, b := range program {
+		addressBus.Write(0x8000+uint16(i), b)
+	}
+	// JMP $8000
+	addressBus.Write(0x8000+uint16(len(program)), 0x4C)
+	addressBus.Write16(0x8001+uint16(len(program)), 0x8000)
+	addressBus.Write16(0xFFFC, 0x8000)
+
+	c := &cpu.Cpu{Bus: addressBus}
+	c.PowerOn()
+	return c
+}
+
+func TestSchedulerIsFair(t *testing.T) {
+	fast := createCpu()
+	slow := createCpu(0xEA, 0xEA, 0xEA) // NOP NOP NOP
+
+	s := NewScheduler(100)
+	s.Add("fast", fast)
+	s.Add("slow", slow)
+
+	for i := 0; i < 10; i++ {
+		s.Slice()
+	}
+
+	for _, c := range []*cpu.Cpu{fast, slow} {
+		if c.Cycles < 1000 || c.Cycles > 1000+3 {
+			t.Error(fmt.Sprintf("expected ~1000 cycles, got %d", c.Cycles))
+		}
+	}
+}
+
+func TestSchedulerPauseAndResume(t *testing.T) {
+	a := createCpu()
+	b := createCpu()
+
+	s := NewScheduler(100)
+	s.Add("a", a)
+	s.Add("b", b)
+
+	if err := s.Pause("b"); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 5; i++ {
+		s.Slice()
+	}
+	if b.Cycles != 0 {
+		t.Error(fmt.Sprintf("paused cpu ran %d cycles", b.Cycles))
+	}
+
+	// b must not try to catch up on the rounds it missed.
+	if err := s.Resume("b"); err != nil {
+		t.Error(err)
+	}
+	s.Slice()
+	if b.Cycles > 100+3 {
+		t.Error(fmt.Sprintf("resumed cpu ran %d cycles in one round", b.Cycles))
+	}
+
+	if err := s.Pause("missing"); err == nil {
+		t.Error("expected error pausing unknown machine")
+	}
+}
+
+func TestSchedulerPauseWhileRunning(t *testing.T) {
+	var reads int64
+	addressBus, _ := bus.CreateBus()
+	addressBus.Attach(memory.NewRam(0x1000), "ram", 0x0000)
+	addressBus.Attach(&memory.Handler{Name: "counter", Length: 1, OnRead: func(uint16) byte {
+		atomic.AddInt64(&reads, 1)
+		return 0
+	}}, "counter", 0x1000)
+	addressBus.Attach(memory.NewRam(0x8000), "rom", 0x8000)
+	// LDA $1000, JMP $8000
+	addressBus.WriteBlock(0x8000, []byte{0xAD, 0x00, 0x10, 0x4C, 0x00, 0x80})
+	addressBus.Write16(0xFFFC, 0x8000)
+	c := &cpu.Cpu{Bus: addressBus}
+	c.PowerOn()
+
+	// The quantum is large enough that a pause must cut it short
+	s := NewScheduler(1 << 40)
+	s.Add("cpu", c)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run()
+	}()
+	defer func() {
+		s.Stop()
+		<-done
+	}()
+
+	for i := 0; i < 100; i++ {
+		if err := s.Pause("cpu"); err != nil {
+			t.Fatal(err)
+		}
+		if !s.Paused("cpu") {
+			t.Error("expected cpu to be paused")
+		}
+		if err := s.Resume("cpu"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitForReads := func(n int64) {
+		deadline := time.Now().Add(time.Second)
+		for atomic.LoadInt64(&reads) < n {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the cpu to be running")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitForReads(1)
+
+	s.Pause("cpu")
+	time.Sleep(10 * time.Millisecond)
+	paused := atomic.LoadInt64(&reads)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt64(&reads); n != paused {
+		t.Error(fmt.Sprintf("paused cpu made %d reads", n-paused))
+	}
+
+	s.Resume("cpu")
+	waitForReads(paused + 1)
+}
+
+func TestSchedulerIdlesPausedCpu(t *testing.T) {
+	c := createCpu()
+
+	s := NewScheduler(100)
+	s.Add("cpu", c)
+	idle := 0
+	if err := s.SetIdle("cpu", func() { idle++ }); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Slice()
+	if idle != 0 {
+		t.Error("expected a running cpu not to idle")
+	}
+
+	s.Pause("cpu")
+	cycles := c.Cycles
+	if s.Slice() {
+		t.Error("expected nothing to run")
+	}
+	if idle != 1 || c.Cycles != cycles {
+		t.Error(fmt.Sprintf("expected one idle call got %d, cycles %d to %d", idle, cycles, c.Cycles))
+	}
+
+	if err := s.SetIdle("missing", func() {}); err == nil {
+		t.Error("expected error idling unknown machine")
+	}
+}
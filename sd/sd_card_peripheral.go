@@ -1,6 +1,7 @@
 package sd
 
 import (
+	"fmt"
 	"io/ioutil"
 
 	"github.com/peter-mount/go6502/spi"
@@ -34,6 +35,22 @@ func (sd *SdCardPeripheral) LoadFile(path string) (err error) {
 	return
 }
 
+// DebugState describes the card's command state and pending response bytes,
+// for the debugger.
+func (sd *SdCardPeripheral) DebugState() string {
+	c := sd.card
+	return fmt.Sprintf("State %v  CMD%d arg $%08X (byte %d) acmd %v\n"+
+		"Previous CMD%d ACMD%d\n"+
+		"MISO queue %d bytes % X\n"+
+		"SPI MOSI $%02X MISO $%02X\n"+
+		"Card %d bytes\n",
+		c.state, c.cmd, c.arg, c.argByte, c.acmd,
+		c.prevCmd, c.prevAcmd,
+		len(c.misoQueue), c.misoQueue,
+		sd.spi.Mosi, sd.spi.Miso,
+		len(c.data))
+}
+
 // via6522.ParallelPeripheral interface
 
 func (sd *SdCardPeripheral) PinMask() byte {
@@ -13,7 +13,18 @@ type Speedometer struct {
 	cycles       uint64
 	instructions uint64
 	timeStart    time.Time
-	cycleChan    chan uint8
+	cycleChan    chan cpu.Instruction
+	regions      []*region
+	other        region
+}
+
+// region is an address range which executed instructions are attributed to.
+type region struct {
+	name         string
+	start        uint16
+	end          uint16
+	cycles       uint64
+	instructions uint64
 }
 
 // NewSpeedometer creates a Speedometer, and starts a goroutine to receive
@@ -21,20 +32,49 @@ type Speedometer struct {
 func NewSpeedometer() *Speedometer {
 	s := &Speedometer{
 		timeStart: time.Now(),
-		cycleChan: make(chan uint8),
+		cycleChan: make(chan cpu.Instruction),
+		other:     region{name: "other"},
 	}
 	go func() {
 		for {
-			s.cycles += uint64(<-s.cycleChan)
+			in := <-s.cycleChan
+			s.cycles += uint64(in.Cycles)
 			s.instructions++
+
+			r := s.regionFor(in.Address)
+			r.cycles += uint64(in.Cycles)
+			r.instructions++
 		}
 	}()
 	return s
 }
 
+// AddRegion adds an address range to the breakdown reported at shutdown.
+// Regions may overlap, in which case instructions are attributed to the
+// smallest region containing them, so a symbol range within ROM is reported
+// separately to the rest of the ROM.
+// Regions must be added before the cpu starts executing instructions.
+func (s *Speedometer) AddRegion(name string, start, end uint16) {
+	s.regions = append(s.regions, &region{name: name, start: start, end: end})
+}
+
+// regionFor returns the smallest region containing the given address.
+func (s *Speedometer) regionFor(a uint16) *region {
+	var match *region
+	for _, r := range s.regions {
+		if a >= r.start && a <= r.end && (match == nil || r.end-r.start < match.end-match.start) {
+			match = r
+		}
+	}
+	if match == nil {
+		return &s.other
+	}
+	return match
+}
+
 // BeforeExecute meets go6502.Monitor interface.
 func (s *Speedometer) BeforeExecute(in cpu.Instruction) {
-	s.cycleChan <- in.Cycles
+	s.cycleChan <- in
 }
 
 // Shutdown the Speedometer session, reporting stats to stdout.
@@ -50,4 +90,32 @@ func (s *Speedometer) Shutdown() {
 	fmt.Printf("MHz:          % 20.2f\n", float64(s.cycles)/us)
 	fmt.Printf("MIPS:         % 20.2f\n", float64(s.instructions)/us)
 	fmt.Printf("----------------------------------\n")
+
+	if len(s.regions) > 0 {
+		s.printRegions()
+	}
+}
+
+// printRegions reports the percentage of instructions and cycles spent in
+// each region.
+func (s *Speedometer) printRegions() {
+	fmt.Printf("%-16s %-11s % 8s % 8s\n", "Region", "Range", "Instr%", "Cycles%")
+	for _, r := range append(s.regions, &s.other) {
+		rng := ""
+		if r != &s.other {
+			rng = fmt.Sprintf("$%04X-$%04X", r.start, r.end)
+		}
+		fmt.Printf("%-16s %-11s % 7.2f%% % 7.2f%%\n",
+			r.name, rng,
+			percent(r.instructions, s.instructions),
+			percent(r.cycles, s.cycles))
+	}
+	fmt.Printf("----------------------------------\n")
+}
+
+func percent(v, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
 }
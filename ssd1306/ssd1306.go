@@ -55,6 +55,11 @@ func (s *Ssd1306) String() string {
 	return "SSD1306"
 }
 
+// DebugState describes the input shift register, for the debugger.
+func (s *Ssd1306) DebugState() string {
+	return fmt.Sprintf("Input $%02X bit %d  Pixel %d\n", s.inputBuffer, s.inputIndex, s.imgPixel)
+}
+
 // PinMask declares the I/O pins the device is connected to.
 func (s *Ssd1306) PinMask() byte {
 	return 0x0F
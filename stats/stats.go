@@ -0,0 +1,100 @@
+/*
+	Package stats provides a cpu.Monitor which records how many cycles are
+	spent executing each instruction mnemonic and addressing mode, printing a
+	summary table at shutdown.
+*/
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/peter-mount/go6502/cpu"
+)
+
+// Stats accumulates instruction counts and cycles per mnemonic and per
+// addressing mode.
+type Stats struct {
+	mutex        sync.Mutex
+	mnemonics    map[string]*counter
+	addressing   map[string]*counter
+	cycles       uint64
+	instructions uint64
+}
+
+type counter struct {
+	name         string
+	instructions uint64
+	cycles       uint64
+}
+
+// NewStats creates an empty Stats monitor.
+func NewStats() *Stats {
+	return &Stats{
+		mnemonics:  make(map[string]*counter),
+		addressing: make(map[string]*counter),
+	}
+}
+
+// BeforeExecute meets the cpu.Monitor interface.
+func (s *Stats) BeforeExecute(in cpu.Instruction) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cycles := uint64(in.Cycles)
+	s.cycles += cycles
+	s.instructions++
+	count(s.mnemonics, in.Name(), cycles)
+	count(s.addressing, in.Addressing(), cycles)
+}
+
+func count(counters map[string]*counter, name string, cycles uint64) {
+	c, exists := counters[name]
+	if !exists {
+		c = &counter{name: name}
+		counters[name] = c
+	}
+	c.instructions++
+	c.cycles += cycles
+}
+
+// Shutdown reports the collected statistics to stdout.
+func (s *Stats) Shutdown() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.printTable("Mnemonic", s.mnemonics)
+	s.printTable("Addressing", s.addressing)
+}
+
+// printTable prints the counters, most cycles first.
+func (s *Stats) printTable(title string, counters map[string]*counter) {
+	var sorted []*counter
+	for _, c := range counters {
+		sorted = append(sorted, c)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].cycles == sorted[j].cycles {
+			return sorted[i].name < sorted[j].name
+		}
+		return sorted[i].cycles > sorted[j].cycles
+	})
+
+	fmt.Printf("%-14s % 14s % 14s % 8s\n", title, "Instructions", "Cycles", "Cycles%")
+	fmt.Printf("-----------------------------------------------------\n")
+	for _, c := range sorted {
+		fmt.Printf("%-14s % 14d % 14d % 7.2f%%\n",
+			c.name, c.instructions, c.cycles, percent(c.cycles, s.cycles))
+	}
+	fmt.Printf("-----------------------------------------------------\n")
+	fmt.Printf("%-14s % 14d % 14d\n", "Total", s.instructions, s.cycles)
+	fmt.Println()
+}
+
+func percent(v, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(v) * 100 / float64(total)
+}
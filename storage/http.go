@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// HTTP stores data as objects under a base URL, using PUT to save and GET to
+// load.
+type HTTP struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// NewHTTP creates HTTP storage under the given base URL.
+func NewHTTP(url string, headers map[string]string) *HTTP {
+	return &HTTP{
+		URL:     strings.TrimSuffix(url, "/"),
+		Headers: headers,
+		Client:  http.DefaultClient,
+	}
+}
+
+func (h *HTTP) do(method, name string, data []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, h.URL+"/"+name, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s", method, req.URL, resp.Status)
+	}
+	return body, nil
+}
+
+// Save PUTs data to URL/name.
+func (h *HTTP) Save(name string, data []byte) error {
+	_, err := h.do(http.MethodPut, name, data)
+	return err
+}
+
+// Load GETs URL/name.
+func (h *HTTP) Load(name string) ([]byte, error) {
+	return h.do(http.MethodGet, name, nil)
+}
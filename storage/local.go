@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Local stores data as files within a directory.
+type Local struct {
+	Dir string
+}
+
+// NewLocal creates Local storage in dir, the current directory if empty.
+func NewLocal(dir string) *Local {
+	return &Local{Dir: dir}
+}
+
+func (l *Local) path(name string) string {
+	return filepath.Join(l.Dir, name)
+}
+
+// Save writes data to the named file, creating the directory if required.
+func (l *Local) Save(name string, data []byte) error {
+	path := l.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0640)
+}
+
+// Load reads the named file.
+func (l *Local) Load(name string) ([]byte, error) {
+	return ioutil.ReadFile(l.path(name))
+}
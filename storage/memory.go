@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Memory keeps data in memory. It is intended for tests.
+type Memory struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+// NewMemory creates empty Memory storage.
+func NewMemory() *Memory {
+	return &Memory{data: make(map[string][]byte)}
+}
+
+// Save stores a copy of data.
+func (m *Memory) Save(name string, data []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.data[name] = append([]byte(nil), data...)
+	return nil
+}
+
+// Load returns a copy of the stored data.
+func (m *Memory) Load(name string) ([]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	data, exists := m.data[name]
+	if !exists {
+		return nil, fmt.Errorf("%s not found", name)
+	}
+	return append([]byte(nil), data...), nil
+}
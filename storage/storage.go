@@ -0,0 +1,56 @@
+/*
+	Package storage abstracts where go6502 persists state such as memory
+	dumps, snapshots and NVRAM contents.
+
+	Three backends are provided: local files, in-memory for tests, and HTTP
+	which PUTs and GETs objects under a base URL. The HTTP backend works with
+	S3 compatible object stores using pre-authorised bucket URLs, or with any
+	server accepting PUT requests, so headless instances running on remote
+	machines can persist state somewhere durable.
+*/
+package storage
+
+import (
+	"fmt"
+)
+
+// Storage persists named blobs of data.
+type Storage interface {
+	// Save stores data under the given name, replacing any existing data.
+	Save(name string, data []byte) error
+
+	// Load returns the data stored under the given name.
+	Load(name string) ([]byte, error)
+}
+
+// Config describes a storage backend in the machine YAML.
+type Config struct {
+	// Type is one of "local" (the default), "memory" or "http".
+	Type string `yaml:"type"`
+
+	// Path is the directory used by the local backend.
+	Path string `yaml:"path"`
+
+	// URL is the base URL used by the http backend.
+	URL string `yaml:"url"`
+
+	// Headers are added to each http request, e.g. for authorisation.
+	Headers map[string]string `yaml:"headers"`
+}
+
+// New creates the Storage described by the config.
+func New(c Config) (Storage, error) {
+	switch c.Type {
+	case "", "local":
+		return NewLocal(c.Path), nil
+	case "memory":
+		return NewMemory(), nil
+	case "http", "s3":
+		if c.URL == "" {
+			return nil, fmt.Errorf("storage type %s requires a url", c.Type)
+		}
+		return NewHTTP(c.URL, c.Headers), nil
+	default:
+		return nil, fmt.Errorf("Unknown storage type %q", c.Type)
+	}
+}
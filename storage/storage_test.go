@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func assertRoundTrip(t *testing.T, s Storage) {
+	data := []byte{0xDE, 0xAD, 0xBE, 0xEF}
+	if err := s.Save("core/ram-0.core", data); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := s.Load("core/ram-0.core")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(loaded) != string(data) {
+		t.Error(fmt.Sprintf("expected % X got % X", data, loaded))
+	}
+	if _, err = s.Load("missing"); err == nil {
+		t.Error("expected error loading missing data")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	assertRoundTrip(t, NewMemory())
+}
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assertRoundTrip(t, NewLocal(dir))
+}
+
+func TestHTTP(t *testing.T) {
+	backing := NewMemory()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path[1:]
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := ioutil.ReadAll(r.Body)
+			backing.Save(name, data)
+		case http.MethodGet:
+			data, err := backing.Load(name)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	assertRoundTrip(t, NewHTTP(server.URL+"/", nil))
+}
@@ -62,6 +62,7 @@ package via6522
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -160,6 +161,45 @@ func (via *Via6522) Shutdown() {
 	}
 }
 
+// Snapshot returns the register state, meeting the memory.Snapshotable
+// interface. Peripherals are not included.
+func (via *Via6522) Snapshot() ([]byte, error) {
+	return []byte{via.ora, via.orb, via.ira, via.irb, via.ddra, via.ddrb, via.pcr}, nil
+}
+
+// Restore the register state from a snapshot.
+func (via *Via6522) Restore(data []byte) error {
+	if len(data) != 7 {
+		return fmt.Errorf("Invalid %s snapshot of %d bytes", via, len(data))
+	}
+	via.ora, via.orb, via.ira, via.irb = data[0], data[1], data[2], data[3]
+	via.ddra, via.ddrb, via.pcr = data[4], data[5], data[6]
+	return nil
+}
+
+// DebugState describes the registers and peripherals, meeting the
+// memory.DebugState interface. Peripherals which describe their own state
+// are included.
+func (via *Via6522) DebugState() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ORA $%02X  IRA $%02X  DDRA $%08b\n", via.ora, via.ira, via.ddra)
+	fmt.Fprintf(&b, "ORB $%02X  IRB $%02X  DDRB $%08b\n", via.orb, via.irb, via.ddrb)
+	fmt.Fprintf(&b, "PCR $%02X  CA1 %d CA2 %d  CB1 %d CB2 %d\n", via.pcr,
+		via.control1Mode(viaPcrOffsetA), via.control2Mode(viaPcrOffsetA),
+		via.control1Mode(viaPcrOffsetB), via.control2Mode(viaPcrOffsetB))
+	for port, peripherals := range [][]ParallelPeripheral{via.paPeripherals, via.pbPeripherals} {
+		for _, p := range peripherals {
+			fmt.Fprintf(&b, "PORT%c %s (pinmask: %08b)\n", 'A'+port, p, p.PinMask())
+			if s, ok := p.(interface{ DebugState() string }); ok {
+				for _, line := range strings.Split(strings.TrimRight(s.DebugState(), "\n"), "\n") {
+					fmt.Fprintf(&b, "  %s\n", line)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
 // CA1 or CB1 1-bit mode for the given port offset (viaPCR_OFFSET_x)
 func (via *Via6522) control1Mode(portOffset uint8) byte {
 	return (via.pcr >> portOffset) & 1
@@ -209,6 +249,24 @@ func (via *Via6522) Read(a uint16) byte {
 	}
 }
 
+// Peek returns a register as last read or written, without reading from the
+// peripherals on the ports.
+func (via *Via6522) Peek(a uint16) (byte, bool) {
+	switch a {
+	case 0x0:
+		return via.readMixedInputOutput(via.irb, via.orb, via.ddrb), true
+	case 0x1:
+		return via.readMixedInputOutput(via.ira, via.ora, via.ddra), true
+	case 0x2:
+		return via.ddrb, true
+	case 0x3:
+		return via.ddra, true
+	case 0xC:
+		return via.pcr, true
+	}
+	return 0, false
+}
+
 // This represents the correct behavior for reading IRB,
 // and maybe an approximation of the correct behavior for IRA.
 func (via *Via6522) readMixedInputOutput(in byte, out byte, ddr byte) byte {
@@ -179,3 +179,13 @@ func (ff *flipflop) Write(in byte) {
 func (ff *flipflop) String() string {
 	return "flipflop test peripheral"
 }
+
+func TestViaDebugState(t *testing.T) {
+	via := via()
+	via.Write(ddra, 0xF0)
+	via.Write(iora, 0x5A)
+	expected := "ORA $5A  IRA $00  DDRA $11110000\n"
+	if s := via.DebugState(); len(s) < len(expected) || s[:len(expected)] != expected {
+		t.Error(fmt.Errorf("Expected state to start %q got %q", expected, s))
+	}
+}